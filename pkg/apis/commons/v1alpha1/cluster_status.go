@@ -119,6 +119,13 @@ type GenericClusterStatus struct {
 	// +kubebuilder:validation:Optional
 	RoleGroups map[string][]string `json:"roleGroups,omitempty"`
 
+	// ProductVersions records the product version each role group was last deployed at.
+	// Map key is the role name, then the role group name. It is what an upgrade is judged from:
+	// the reconciler refuses a move the product's version catalog does not allow, even when the
+	// new version came from an operator upgrade rather than from the CR.
+	// +kubebuilder:validation:Optional
+	ProductVersions map[string]map[string]string `json:"productVersions,omitempty"`
+
 	// ObservedGeneration is the most recent generation observed for this cluster.
 	// It corresponds to the metadata generation of the CR.
 	// +kubebuilder:validation:Optional
@@ -367,6 +374,36 @@ func (s *GenericClusterStatus) RemoveRoleGroup(roleName, roleGroupName string) {
 	} else {
 		s.RoleGroups[roleName] = newGroups
 	}
+	s.SetProductVersion(roleName, roleGroupName, "")
+}
+
+// GetProductVersion returns the product version a role group was last deployed at, or "".
+func (s *GenericClusterStatus) GetProductVersion(roleName, roleGroupName string) string {
+	return s.ProductVersions[roleName][roleGroupName]
+}
+
+// SetProductVersion records the product version a role group is deployed at. An empty version
+// removes the record.
+func (s *GenericClusterStatus) SetProductVersion(roleName, roleGroupName, version string) {
+	if version == "" {
+		if groups, ok := s.ProductVersions[roleName]; ok {
+			delete(groups, roleGroupName)
+			if len(groups) == 0 {
+				delete(s.ProductVersions, roleName)
+			}
+		}
+		if len(s.ProductVersions) == 0 {
+			s.ProductVersions = nil
+		}
+		return
+	}
+	if s.ProductVersions == nil {
+		s.ProductVersions = make(map[string]map[string]string)
+	}
+	if s.ProductVersions[roleName] == nil {
+		s.ProductVersions[roleName] = make(map[string]string)
+	}
+	s.ProductVersions[roleName][roleGroupName] = version
 }
 
 // GetOrphanedRoleGroups returns role groups that exist in status but not in the desired spec.
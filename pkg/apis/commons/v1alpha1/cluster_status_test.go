@@ -171,4 +171,28 @@ var _ = Describe("GenericClusterStatus", func() {
 			Expect(status.IsProgressing()).To(BeFalse())
 		})
 	})
+
+	Describe("ProductVersions", func() {
+		It("records, replaces and clears a role group's version", func() {
+			status := &v1alpha1.GenericClusterStatus{}
+			status.SetProductVersion("broker", "default", "3.8.0")
+			status.SetProductVersion("broker", "default", "3.9.0")
+			Expect(status.GetProductVersion("broker", "default")).To(Equal("3.9.0"))
+
+			status.SetProductVersion("broker", "default", "")
+			Expect(status.GetProductVersion("broker", "default")).To(BeEmpty())
+			Expect(status.ProductVersions).To(BeNil())
+		})
+
+		It("forgets the version of a removed role group", func() {
+			status := &v1alpha1.GenericClusterStatus{}
+			status.SetRoleGroup("broker", "default")
+			status.SetRoleGroup("broker", "large")
+			status.SetProductVersion("broker", "default", "3.8.0")
+			status.SetProductVersion("broker", "large", "3.8.0")
+
+			status.RemoveRoleGroup("broker", "default")
+			Expect(status.ProductVersions).To(Equal(map[string]map[string]string{"broker": {"large": "3.8.0"}}))
+		})
+	})
 })
@@ -34,6 +34,11 @@ type GenericClusterSpec struct {
 	// +kubebuilder:validation:Optional
 	ClusterOperation *ClusterOperationSpec `json:"clusterOperation,omitempty"`
 
+	// TLS names the SecretClasses the framework mounts on every pod for client-facing and inter-role
+	// TLS. Unset means the framework mounts no TLS material and the product decides on its own.
+	// +kubebuilder:validation:Optional
+	TLS *ClusterTLSSpec `json:"tls,omitempty"`
+
 	// Roles defines the role configurations for the cluster.
 	// Each role represents a logical functional component (e.g., NameNode, DataNode).
 	//
@@ -42,6 +42,14 @@ type RoleGroupConfigSpec struct {
 	GracefulShutdownTimeout *string `json:"gracefulShutdownTimeout,omitempty"`
 	// +kubebuilder:validation:Optional
 	Logging *LoggingSpec `json:"logging,omitempty"`
+	// PersistentVolumeClaimRetentionPolicy decides whether the role group's data volumes are
+	// deleted with it or kept. Unset keeps them.
+	// +kubebuilder:validation:Optional
+	PersistentVolumeClaimRetentionPolicy *PersistentVolumeClaimRetentionPolicySpec `json:"persistentVolumeClaimRetentionPolicy,omitempty"`
+	// Placement holds the pods' nodeSelector, tolerations and topology spread constraints. It
+	// folds across layers like affinity; see PlacementSpec.
+	// +kubebuilder:validation:Optional
+	Placement *PlacementSpec `json:"placement,omitempty"`
 	// +kubebuilder:validation:Optional
 	Resources *ResourcesSpec `json:"resources,omitempty"`
 }
@@ -234,6 +234,38 @@ func (i *ImageSpec) ResolvedProductVersion(defaults ImageSpec) string {
 	return firstNonEmpty(spec.ProductVersion, defaults.ProductVersion)
 }
 
+// imageReferenceRE is the OCI/docker reference grammar: an optional registry host (with a port),
+// lowercase path components, an optional tag and an optional sha256 digest. Only sha256 is accepted
+// as a digest: it is the algorithm every registry serves, and a looser rule would let a truncated
+// digest through to fail at pull time.
+var imageReferenceRE = regexp.MustCompile(`^` +
+	`(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*(?::[0-9]+)?/)?` +
+	`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` +
+	`(?::[A-Za-z0-9_][A-Za-z0-9._-]{0,127})?` +
+	`(?:@sha256:[a-f0-9]{64})?$`)
+
+var imageDigestRE = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// ValidateImageReference reports whether ref is a container image reference a kubelet can pull:
+// `repo/name`, `repo/name:tag`, `repo/name@sha256:<64 hex>` or `repo/name:tag@sha256:<64 hex>`.
+//
+// The API server does not validate container.image at all, so without this a typo in
+// spec.image.custom is accepted, stored, and surfaces as InvalidImageName on a pod nobody is
+// watching.
+func ValidateImageReference(ref string) error {
+	if !imageReferenceRE.MatchString(ref) {
+		return fmt.Errorf("%q is not a valid image reference (expected repo/name, repo/name:tag or "+
+			"repo/name[:tag]@sha256:<64 hex digits>)", ref)
+	}
+	return nil
+}
+
+// IsImageDigest reports whether digest is a sha256 image digest, "sha256:" and 64 lowercase hex
+// digits.
+func IsImageDigest(digest string) bool {
+	return imageDigestRE.MatchString(digest)
+}
+
 // missingImageFields renders the unresolved half of a reference for an error message.
 func missingImageFields(repo, productVersion string) string {
 	var missing []string
@@ -337,3 +337,31 @@ var _ = Describe("ResolvedProductVersion", func() {
 			v1alpha1.ImageSpec{Custom: "pinned:1", ProductVersion: "4.0.1"})).To(BeEmpty())
 	})
 })
+
+var _ = Describe("ValidateImageReference", func() {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+
+	It("accepts tagged and digest-pinned references", func() {
+		for _, ref := range []string{
+			"quay.io/kubedoop/kafka:3.7.1-kubedoop0.2.0",
+			"registry.internal:5000/quay.io/kubedoop/kafka:3.7.1",
+			"kafka",
+			"quay.io/kubedoop/kafka@" + digest,
+			"quay.io/kubedoop/kafka:3.7.1@" + digest,
+		} {
+			Expect(v1alpha1.ValidateImageReference(ref)).To(Succeed(), ref)
+		}
+	})
+
+	It("rejects what a kubelet would report as InvalidImageName", func() {
+		for _, ref := range []string{
+			"quay.io/kubedoop/kafka:3.2.2-kubedoopN/A",
+			"quay.io/kubedoop/Kafka:3.7.1",
+			"quay.io/kubedoop/kafka@sha256:abc",
+			"quay.io/kubedoop/kafka@md5:" + strings.Repeat("ab", 16),
+			"",
+		} {
+			Expect(v1alpha1.ValidateImageReference(ref)).NotTo(Succeed(), ref)
+		}
+	})
+})
@@ -32,6 +32,63 @@ type LoggingConfigSpec struct {
 
 	// +kubebuilder:validation:Optional
 	File *LogLevelSpec `json:"file,omitempty"`
+
+	// Filter selects and scrubs what the Vector agent ships of this container's logs. It acts on
+	// the collected stream only — the product still writes every event to its log file, so
+	// `kubectl logs` and the file on the volume are unaffected — and is ignored unless the agent is
+	// enabled.
+	//
+	// Unlike the levels above it is folded as a whole: a role group that sets it replaces the
+	// role's rules rather than merging into them, and `filter: {}` turns the role's filtering off.
+	// +kubebuilder:validation:Optional
+	Filter *LogFilterSpec `json:"filter,omitempty"`
+}
+
+// LogFilterSpec is one container's shipping rules, applied by the Vector agent in this order:
+// level and logger selection, then redaction, then sampling.
+type LogFilterSpec struct {
+	// MinLevel drops events below this level. Unlike the console and file thresholds it does not
+	// change what the product logs, so DEBUG can stay on in the file for `kubectl exec` while only
+	// INFO and above leave the pod.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=FATAL;ERROR;WARN;INFO;DEBUG;TRACE
+	MinLevel string `json:"minLevel,omitempty"`
+
+	// IncludeLoggers, when set, ships only events of these loggers and their descendants
+	// ("org.apache.kafka" covers "org.apache.kafka.clients"). Events read from stdout and stderr
+	// carry the logger "ROOT".
+	// +kubebuilder:validation:Optional
+	IncludeLoggers []string `json:"includeLoggers,omitempty"`
+
+	// ExcludeLoggers drops events of these loggers and their descendants, and wins over
+	// IncludeLoggers, so a noisy child of an included logger can be silenced.
+	// +kubebuilder:validation:Optional
+	ExcludeLoggers []string `json:"excludeLoggers,omitempty"`
+
+	// Redactions rewrite the message of every shipped event, in order.
+	// +kubebuilder:validation:Optional
+	Redactions []LogRedactionSpec `json:"redactions,omitempty"`
+
+	// SampleRate ships one in every SampleRate events below WARN. WARN, ERROR and FATAL are always
+	// shipped: sampling is for volume, and the events that explain an incident are the few. Unset
+	// or 1 ships everything.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	SampleRate int32 `json:"sampleRate,omitempty"`
+}
+
+// LogRedactionSpec replaces every match of a regular expression in the message.
+type LogRedactionSpec struct {
+	// Pattern is the regular expression, in the RE2 syntax Vector's regex engine shares:
+	// lookaround and backreferences are not supported.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Pattern string `json:"pattern"`
+
+	// Replacement replaces each match and may reference the pattern's capture groups as $1 or
+	// $name. Unset means "[REDACTED]".
+	// +kubebuilder:validation:Optional
+	Replacement string `json:"replacement,omitempty"`
 }
 
 // LogLevelSpec
@@ -16,13 +16,41 @@ limitations under the License.
 
 package v1alpha1
 
+import "k8s.io/apimachinery/pkg/util/intstr"
+
+// PodDisruptionBudgetScope decides which pods one PodDisruptionBudget covers.
+// +kubebuilder:validation:Enum=Role;RoleGroup
+type PodDisruptionBudgetScope string
+
+const (
+	// PodDisruptionBudgetScopeRole builds one PodDisruptionBudget covering every pod of the role,
+	// across all of its role groups. It is the default.
+	PodDisruptionBudgetScopeRole PodDisruptionBudgetScope = "Role"
+	// PodDisruptionBudgetScopeRoleGroup builds one PodDisruptionBudget per role group, each
+	// applying the role's policy to that group's pods alone.
+	PodDisruptionBudgetScopeRoleGroup PodDisruptionBudgetScope = "RoleGroup"
+)
+
+// UnhealthyPodEvictionPolicyType decides when a pod that is running but not Ready may be evicted.
+// The values are the PodDisruptionBudget API's own.
+// +kubebuilder:validation:Enum=IfHealthyBudget;AlwaysAllow
+type UnhealthyPodEvictionPolicyType string
+
+const (
+	// UnhealthyPodEvictionIfHealthyBudget evicts an unhealthy pod only while the budget is met.
+	UnhealthyPodEvictionIfHealthyBudget UnhealthyPodEvictionPolicyType = "IfHealthyBudget"
+	// UnhealthyPodEvictionAlwaysAllow evicts an unhealthy pod regardless of the budget, so a pod
+	// stuck in a crash loop cannot block a node drain.
+	UnhealthyPodEvictionAlwaysAllow UnhealthyPodEvictionPolicyType = "AlwaysAllow"
+)
+
 // This struct is used to configure:
 //  1. If PodDisruptionBudgets are created by the operator
-//  2. The allowed number of Pods to be unavailable (`maxUnavailable`)
+//  2. How many Pods must stay up (`minAvailable`) or may be down (`maxUnavailable`)
+//  3. Whether the budget covers the whole role or each role group (`scope`)
+//
+// +kubebuilder:validation:XValidation:rule="!(has(self.minAvailable) && has(self.maxUnavailable))",message="minAvailable and maxUnavailable are mutually exclusive"
 type PodDisruptionBudgetSpec struct {
-	// +kubebuilder:validation:Optional
-	// MinAvailable *int32 `json:"minAvailable,omitempty"`
-
 	// Whether a PodDisruptionBudget should be written out for this role.
 	// Disabling this enables you to specify your own - custom - one.
 	// Defaults to true.
@@ -34,11 +62,33 @@ type PodDisruptionBudgetSpec struct {
 	// +kubebuilder:validation:Optional
 	Enabled *bool `json:"enabled,omitempty"`
 
-	// The number of Pods that are allowed to be down because of voluntary disruptions.
-	// If you don't explicitly set this, the operator will use a sane default based
-	// upon knowledge about the individual product.
+	// The number, or percentage ("50%"), of Pods that must stay available during voluntary
+	// disruptions. Mutually exclusive with maxUnavailable.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:XIntOrString
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Pattern="^[0-9]+%$"
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+
+	// The number, or percentage ("25%"), of Pods that are allowed to be down because of voluntary
+	// disruptions. If neither this nor minAvailable is set, the operator will use a sane default
+	// based upon knowledge about the individual product: a quorum the product declares, else one.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:XIntOrString
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Pattern="^[0-9]+%$"
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+
+	// UnhealthyPodEvictionPolicy decides whether a running pod that is not Ready may be evicted
+	// while the budget is not met. Unset leaves the Kubernetes default, IfHealthyBudget.
+	// +kubebuilder:validation:Optional
+	UnhealthyPodEvictionPolicy *UnhealthyPodEvictionPolicyType `json:"unhealthyPodEvictionPolicy,omitempty"`
+
+	// Scope decides whether one PodDisruptionBudget covers the whole role (Role, the default) or
+	// each role group gets its own (RoleGroup). A role whose product declares a quorum cannot use
+	// RoleGroup: a quorum spans the role, and per-group budgets cannot protect it.
 	// +kubebuilder:validation:Optional
-	MaxUnavailable *int32 `json:"maxUnavailable,omitempty"`
+	Scope PodDisruptionBudgetScope `json:"scope,omitempty"`
 }
 
 // IsEnabled reports whether the framework should write out a PodDisruptionBudget for this role.
@@ -50,3 +100,11 @@ func (p *PodDisruptionBudgetSpec) IsEnabled() bool {
 	}
 	return *p.Enabled
 }
+
+// GetScope returns the scope, Role when unset.
+func (p *PodDisruptionBudgetSpec) GetScope() PodDisruptionBudgetScope {
+	if p == nil || p.Scope == "" {
+		return PodDisruptionBudgetScopeRole
+	}
+	return p.Scope
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import corev1 "k8s.io/api/core/v1"
+
+// PlacementSpec states where a role group's pods may run and how they spread: the pod-level
+// scheduling fields Kubernetes defines beside affinity. Each member is carried in its corev1 type
+// and reaches the pod spec unchanged, so its meaning is the one `kubectl explain pod.spec` gives.
+//
+// The block folds like affinity: a layer that states any member replaces the placement beneath it
+// WHOLESALE, and the members that discards are reported. The three members answer one question —
+// where may these pods go — and a product's nodeSelector joined with a user's tolerations from
+// another layer is a placement neither of them asked for.
+//
+// Unlike affinity the schema is structural, so the API server prunes inside it and `placement: {}`
+// — which is also what a misspelt member is stored as — states nothing and inherits. A member
+// stated empty (`tolerations: []`) IS a statement: it takes the block over and clears that member.
+type PlacementSpec struct {
+	// NodeSelector must match a node's labels for a pod to be scheduled on it.
+	// +kubebuilder:validation:Optional
+	// +mapType=atomic
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations let the pods schedule onto nodes with matching taints.
+	// +kubebuilder:validation:Optional
+	// +listType=atomic
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// TopologySpreadConstraints spread the pods across topology domains — nodes, zones, racks.
+	//
+	// A constraint without a labelSelector is given the ROLE's selector (cluster and role, not the
+	// role group) when the pod is built. That is what lets a product declare "spread this role
+	// across zones" as a role default, which cannot name the cluster it will be applied to, and it
+	// spreads the role as a whole: two role groups of one quorum in the same zone are one outage.
+	// +kubebuilder:validation:Optional
+	// +listType=atomic
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+}
+
+// IsStated reports whether the block states any member. A nil or empty block inherits.
+func (p *PlacementSpec) IsStated() bool {
+	return p != nil && (p.NodeSelector != nil || p.Tolerations != nil || p.TopologySpreadConstraints != nil)
+}
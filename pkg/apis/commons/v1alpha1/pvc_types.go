@@ -0,0 +1,57 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// PersistentVolumeClaimRetentionPolicyType is what happens to a role group's PVCs when the claims
+// stop being used. The values are the StatefulSet API's own.
+// +kubebuilder:validation:Enum=Retain;Delete
+type PersistentVolumeClaimRetentionPolicyType string
+
+const (
+	// PersistentVolumeClaimRetain keeps the PVCs, and with them the data.
+	PersistentVolumeClaimRetain PersistentVolumeClaimRetentionPolicyType = "Retain"
+	// PersistentVolumeClaimDelete deletes the PVCs, and with them the data.
+	PersistentVolumeClaimDelete PersistentVolumeClaimRetentionPolicyType = "Delete"
+)
+
+// PersistentVolumeClaimRetentionPolicySpec decides whether a role group's data volumes outlive the
+// role group. It maps onto the StatefulSet's native persistentVolumeClaimRetentionPolicy, and the
+// orphan cleaner honours whenDeleted when a role group leaves the spec.
+//
+// Each field is folded on its own: a role can set whenDeleted and a role group only whenScaled. A
+// field nobody sets is Retain, the Kubernetes default and the only value that cannot lose data.
+type PersistentVolumeClaimRetentionPolicySpec struct {
+	// WhenDeleted applies when the role group's StatefulSet is deleted: the role group was removed
+	// from the spec, or the cluster itself was deleted. Retain keeps the PVCs; a role group of the
+	// same name that comes back mounts them again.
+	// +kubebuilder:validation:Optional
+	WhenDeleted PersistentVolumeClaimRetentionPolicyType `json:"whenDeleted,omitempty"`
+
+	// WhenScaled applies to the PVCs of the pods a scale-down removes.
+	// +kubebuilder:validation:Optional
+	WhenScaled PersistentVolumeClaimRetentionPolicyType `json:"whenScaled,omitempty"`
+}
+
+// DeletesWhenDeleted reports whether the role group's PVCs go with its StatefulSet.
+func (p *PersistentVolumeClaimRetentionPolicySpec) DeletesWhenDeleted() bool {
+	return p != nil && p.WhenDeleted == PersistentVolumeClaimDelete
+}
+
+// DeletesWhenScaled reports whether a scale-down deletes the PVCs of the pods it removes.
+func (p *PersistentVolumeClaimRetentionPolicySpec) DeletesWhenScaled() bool {
+	return p != nil && p.WhenScaled == PersistentVolumeClaimDelete
+}
@@ -35,8 +35,57 @@ type ResourcesSpec struct {
 	// +kubebuilder:validation:Optional
 	Memory *MemoryResource `json:"memory,omitempty"`
 
+	// +kubebuilder:validation:Optional
+	EphemeralStorage *EphemeralStorageResource `json:"ephemeralStorage,omitempty"`
+
 	// +kubebuilder:validation:Optional
 	Storage *StorageResource `json:"storage,omitempty"`
+
+	// Volumes declares further data volumes by name, each with its own PVC per replica: the JBOD
+	// disks of an HDFS DataNode or a Kafka broker, a separate journal or WAL disk, a fast cache disk
+	// on another StorageClass. They sit beside Storage, which stays the role's primary data volume
+	// as its RoleDeclaration.DataVolume describes it.
+	//
+	// The map key names the claim template, so it is as immutable as the template itself: renaming
+	// a volume is a new volume, and the old one's PVCs stay behind. It must be a DNS label and must
+	// not name a volume the framework mounts itself. The role group level folds over the role level
+	// per name and then per field, so a role group can resize one disk without restating the rest.
+	// The mount paths a product wires into its configuration are on
+	// RoleGroupBuildContext.DataVolumeMountPaths.
+	//
+	// +kubebuilder:validation:Optional
+	Volumes map[string]VolumeResource `json:"volumes,omitempty"`
+
+	// Sidecars budgets the containers the framework injects beside the product — the Vector agent,
+	// the JMX exporter, an OAuth2 proxy — keyed by container name. Each stated leaf replaces the
+	// provider's own default for that leaf, and the leaves left unset keep it, so raising the Vector
+	// agent's memory limit does not drop its CPU request.
+	//
+	// An entry naming a container the role group's pods do not carry is ignored rather than
+	// rejected: a role-level entry covers every role group, including those where that sidecar is
+	// switched off.
+	//
+	// +kubebuilder:validation:Optional
+	Sidecars map[string]ContainerResources `json:"sidecars,omitempty"`
+
+	// Heap sizes a JVM product's heap from the effective memory limit. See HeapPolicy; the product
+	// reads the result through reconciler.HeapMB.
+	//
+	// +kubebuilder:validation:Optional
+	Heap *HeapPolicy `json:"heap,omitempty"`
+}
+
+// ContainerResources is the CPU, memory and ephemeral-storage budget of one container, with the
+// same fields and meaning as ResourcesSpec's.
+type ContainerResources struct {
+	// +kubebuilder:validation:Optional
+	CPU *CPUResource `json:"cpu,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	Memory *MemoryResource `json:"memory,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	EphemeralStorage *EphemeralStorageResource `json:"ephemeralStorage,omitempty"`
 }
 
 // CPUResource bounds the container's CPU. Both fields are pointers so that "unset" is
@@ -51,11 +100,62 @@ type CPUResource struct {
 	Min *resource.Quantity `json:"min,omitempty"`
 }
 
-// MemoryResource bounds the container's memory. Limit is a pointer for the same reason as
-// CPUResource's fields.
+// MemoryResource bounds the container's memory. Both fields are pointers for the same reason as
+// CPUResource's.
 type MemoryResource struct {
 	// +kubebuilder:validation:Optional
 	Limit *resource.Quantity `json:"limit,omitempty"`
+
+	// Request is the memory the scheduler reserves for the container. Unset means the Limit, which
+	// is what every role group got before the field existed and what keeps a JVM, whose heap is
+	// sized from the limit, out of the eviction order of a node under memory pressure. In a sidecar
+	// budget (ResourcesSpec.Sidecars), unset keeps the provider's default request instead.
+	//
+	// +kubebuilder:validation:Optional
+	Request *resource.Quantity `json:"request,omitempty"`
+}
+
+// EphemeralStorageResource bounds the container's node-local scratch space: its writable layer,
+// its logs and every emptyDir it mounts. The kubelet evicts a pod that exceeds the limit.
+type EphemeralStorageResource struct {
+	// +kubebuilder:validation:Optional
+	Request *resource.Quantity `json:"request,omitempty"`
+
+	// +kubebuilder:validation:Optional
+	Limit *resource.Quantity `json:"limit,omitempty"`
+}
+
+// HeapPolicy derives a JVM heap from the container's effective memory limit:
+//
+//	heap = clamp((limit - reservedOffHeap) * fraction, min, max)
+//
+// and never more than limit - reservedOffHeap. It replaces the bare factor a product used to pass
+// to reconciler.HeapMB, which is still the fraction when the policy states none — a product's
+// historical 0.8 holds until a user or the product's ConfigDefaults say otherwise.
+type HeapPolicy struct {
+	// Fraction of the memory left after ReservedOffHeap that goes to the heap, as a decimal string
+	// such as "0.75". A string because the CRD schema has no portable float.
+	//
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^(0(\.[0-9]+)?|1(\.0+)?)$`
+	Fraction *string `json:"fraction,omitempty"`
+
+	// Min is the smallest heap to configure, however small the limit.
+	//
+	// +kubebuilder:validation:Optional
+	Min *resource.Quantity `json:"min,omitempty"`
+
+	// Max caps the heap, however large the limit: past around 31Gi a JVM loses compressed object
+	// pointers and a larger heap holds fewer objects.
+	//
+	// +kubebuilder:validation:Optional
+	Max *resource.Quantity `json:"max,omitempty"`
+
+	// ReservedOffHeap is taken off the limit before the fraction applies: metaspace, thread stacks,
+	// direct buffers and anything else the JVM allocates outside the heap.
+	//
+	// +kubebuilder:validation:Optional
+	ReservedOffHeap *resource.Quantity `json:"reservedOffHeap,omitempty"`
 }
 
 // StorageResource describes the role group's data PVC.
@@ -94,3 +194,35 @@ func (s *StorageResource) GetCapacity() resource.Quantity {
 	}
 	return *s.Capacity
 }
+
+// VolumeResource describes one named data volume of ResourcesSpec.Volumes.
+type VolumeResource struct {
+	// Capacity and StorageClass size and place every PVC of this volume, with StorageResource's
+	// meaning and defaults.
+	StorageResource `json:",inline"`
+
+	// MountPath is where the volume is mounted in the primary container. Required once the role and
+	// role group levels are folded; with Count above one it is the parent of the numbered mounts.
+	//
+	// +kubebuilder:validation:Optional
+	MountPath string `json:"mountPath,omitempty"`
+
+	// Count is how many identical volumes to create, for JBOD layouts. Unset means one, named after
+	// the map key and mounted at MountPath. Above one, volume i is named "<key>-<i>" and mounted at
+	// "<mountPath>/<i>", counting from zero.
+	//
+	// Lowering it leaves the removed volumes' claim templates on the StatefulSet — they are
+	// immutable — still mounted where they were.
+	//
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	Count *int32 `json:"count,omitempty"`
+}
+
+// GetCount returns the configured count, or one when unset.
+func (v *VolumeResource) GetCount() int32 {
+	if v == nil || v.Count == nil {
+		return 1
+	}
+	return *v.Count
+}
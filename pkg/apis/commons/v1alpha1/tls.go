@@ -47,3 +47,19 @@ type CACert struct {
 
 type WebPki struct {
 }
+
+// ClusterTLSSpec is the cluster-wide TLS switch. Each SecretClass named here is mounted on every pod
+// of every role group by the framework, scoped to that role group's Services, so turning TLS on no
+// longer means touching every product handler.
+type ClusterTLSSpec struct {
+	// ServerSecretClass issues the certificate the product presents to its clients. When set, the
+	// readiness, liveness and startup probes a product declares with an HTTP handler are switched to
+	// HTTPS. Empty leaves client traffic in plaintext.
+	// +kubebuilder:validation:Optional
+	ServerSecretClass string `json:"serverSecretClass,omitempty"`
+
+	// InternalSecretClass issues the certificate used for traffic between the cluster's own roles.
+	// Empty leaves inter-role traffic in plaintext.
+	// +kubebuilder:validation:Optional
+	InternalSecretClass string `json:"internalSecretClass,omitempty"`
+}
@@ -33,8 +33,13 @@ type PDBBuilder struct {
 	Labels         map[string]string
 	Annotations    map[string]string
 	Selector       map[string]string
+	MinAvailable   *intstr.IntOrString
 	MaxUnavailable *intstr.IntOrString
 	Enabled        bool
+
+	// UnhealthyPodEvictionPolicy is copied onto the PDB when set; nil leaves the Kubernetes
+	// default, IfHealthyBudget.
+	UnhealthyPodEvictionPolicy *policyv1.UnhealthyPodEvictionPolicyType
 }
 
 // NewPDBBuilder creates a new PDBBuilder.
@@ -73,7 +78,9 @@ func (b *PDBBuilder) WithSelector(selector map[string]string) *PDBBuilder {
 	return b
 }
 
-// WithSpec sets the PDB spec from v1alpha1.PodDisruptionBudgetSpec.
+// WithSpec sets the PDB spec from v1alpha1.PodDisruptionBudgetSpec. A budget the spec leaves
+// unset keeps whatever the builder already holds, so a caller can set a product default first and
+// let the user's value replace it.
 func (b *PDBBuilder) WithSpec(spec *v1alpha1.PodDisruptionBudgetSpec) *PDBBuilder {
 	if spec == nil {
 		return b
@@ -81,19 +88,37 @@ func (b *PDBBuilder) WithSpec(spec *v1alpha1.PodDisruptionBudgetSpec) *PDBBuilde
 
 	b.Enabled = spec.IsEnabled()
 
+	if spec.MinAvailable != nil {
+		b.WithMinAvailable(*spec.MinAvailable)
+	}
 	if spec.MaxUnavailable != nil {
-		b.MaxUnavailable = &intstr.IntOrString{
-			Type:   intstr.Int,
-			IntVal: *spec.MaxUnavailable,
-		}
+		b.WithMaxUnavailable(*spec.MaxUnavailable)
+	}
+	if spec.UnhealthyPodEvictionPolicy != nil {
+		b.WithUnhealthyPodEvictionPolicy(policyv1.UnhealthyPodEvictionPolicyType(*spec.UnhealthyPodEvictionPolicy))
 	}
 
 	return b
 }
 
-// WithMaxUnavailable sets the max unavailable.
+// WithMinAvailable sets the min available, and clears max unavailable: the API server rejects a
+// PDB stating both, so the later call wins.
+func (b *PDBBuilder) WithMinAvailable(value intstr.IntOrString) *PDBBuilder {
+	b.MinAvailable = &value
+	b.MaxUnavailable = nil
+	return b
+}
+
+// WithMaxUnavailable sets the max unavailable, and clears min available.
 func (b *PDBBuilder) WithMaxUnavailable(value intstr.IntOrString) *PDBBuilder {
 	b.MaxUnavailable = &value
+	b.MinAvailable = nil
+	return b
+}
+
+// WithUnhealthyPodEvictionPolicy sets the unhealthy pod eviction policy.
+func (b *PDBBuilder) WithUnhealthyPodEvictionPolicy(policy policyv1.UnhealthyPodEvictionPolicyType) *PDBBuilder {
+	b.UnhealthyPodEvictionPolicy = &policy
 	return b
 }
 
@@ -130,13 +155,17 @@ func (b *PDBBuilder) Build() *policyv1.PodDisruptionBudget {
 		},
 	}
 
-	if b.MaxUnavailable != nil {
+	switch {
+	case b.MinAvailable != nil:
+		pdb.Spec.MinAvailable = clonePtr(b.MinAvailable)
+	case b.MaxUnavailable != nil:
 		pdb.Spec.MaxUnavailable = clonePtr(b.MaxUnavailable)
-	} else {
+	default:
 		// Default to MaxUnavailable=1
 		maxUnavailable := intstr.FromInt(1)
 		pdb.Spec.MaxUnavailable = &maxUnavailable
 	}
+	pdb.Spec.UnhealthyPodEvictionPolicy = clonePtr(b.UnhealthyPodEvictionPolicy)
 
 	return pdb
 }
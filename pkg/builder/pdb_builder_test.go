@@ -21,6 +21,7 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
 	"github.com/zncdatadev/operator-go/pkg/builder"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/ptr"
 )
@@ -85,6 +86,30 @@ var _ = Describe("PDBBuilder", func() {
 		})
 	})
 
+	Describe("WithMinAvailable", func() {
+		It("should replace max unavailable, which a PDB cannot state alongside it", func() {
+			pdb := pdbBuilder.
+				WithSelector(map[string]string{"app": "test"}).
+				WithMaxUnavailable(intstr.FromInt32(1)).
+				WithMinAvailable(intstr.FromString("50%")).
+				Build()
+
+			Expect(pdb.Spec.MinAvailable).To(HaveValue(Equal(intstr.FromString("50%"))))
+			Expect(pdb.Spec.MaxUnavailable).To(BeNil())
+		})
+	})
+
+	Describe("WithUnhealthyPodEvictionPolicy", func() {
+		It("should set the policy on the built PDB", func() {
+			pdb := pdbBuilder.
+				WithSelector(map[string]string{"app": "test"}).
+				WithUnhealthyPodEvictionPolicy(policyv1.AlwaysAllow).
+				Build()
+
+			Expect(pdb.Spec.UnhealthyPodEvictionPolicy).To(HaveValue(Equal(policyv1.AlwaysAllow)))
+		})
+	})
+
 	Describe("WithEnabled", func() {
 		It("should set enabled to false", func() {
 			result := pdbBuilder.WithEnabled(false)
@@ -179,7 +204,7 @@ var _ = Describe("PDBBuilder", func() {
 		})
 
 		It("should set max unavailable from spec", func() {
-			maxUnavailable := int32(2)
+			maxUnavailable := intstr.FromInt32(2)
 			spec := &v1alpha1.PodDisruptionBudgetSpec{
 				Enabled:        ptr.To(true),
 				MaxUnavailable: &maxUnavailable,
@@ -191,6 +216,27 @@ var _ = Describe("PDBBuilder", func() {
 			Expect(pdbBuilder.MaxUnavailable.IntValue()).To(Equal(2))
 		})
 
+		It("should let the spec replace a budget set before it", func() {
+			pdb := pdbBuilder.
+				WithSelector(map[string]string{"app": "test"}).
+				WithMinAvailable(intstr.FromInt32(3)).
+				WithSpec(&v1alpha1.PodDisruptionBudgetSpec{MaxUnavailable: ptr.To(intstr.FromString("25%"))}).
+				Build()
+
+			Expect(pdb.Spec.MaxUnavailable).To(HaveValue(Equal(intstr.FromString("25%"))))
+			Expect(pdb.Spec.MinAvailable).To(BeNil())
+		})
+
+		It("should keep a budget set before it when the spec states none", func() {
+			pdb := pdbBuilder.
+				WithSelector(map[string]string{"app": "test"}).
+				WithMinAvailable(intstr.FromInt32(3)).
+				WithSpec(&v1alpha1.PodDisruptionBudgetSpec{Enabled: ptr.To(true)}).
+				Build()
+
+			Expect(pdb.Spec.MinAvailable).To(HaveValue(Equal(intstr.FromInt32(3))))
+		})
+
 		It("should handle spec with nil max unavailable", func() {
 			spec := &v1alpha1.PodDisruptionBudgetSpec{
 				Enabled:        ptr.To(true),
@@ -203,7 +249,7 @@ var _ = Describe("PDBBuilder", func() {
 		})
 
 		It("should build PDB with spec values", func() {
-			maxUnavailable := int32(3)
+			maxUnavailable := intstr.FromInt32(3)
 			spec := &v1alpha1.PodDisruptionBudgetSpec{
 				Enabled:        ptr.To(true),
 				MaxUnavailable: &maxUnavailable,
@@ -219,7 +265,7 @@ var _ = Describe("PDBBuilder", func() {
 		})
 
 		It("should combine WithSpec with other builders", func() {
-			maxUnavailable := int32(1)
+			maxUnavailable := intstr.FromInt32(1)
 			spec := &v1alpha1.PodDisruptionBudgetSpec{
 				Enabled:        ptr.To(true),
 				MaxUnavailable: &maxUnavailable,
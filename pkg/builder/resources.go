@@ -0,0 +1,105 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+)
+
+// overlayResources writes every stated leaf of a CPU, memory and ephemeral-storage budget onto
+// req, and leaves the rest of req as it was. Nil means unset, and only an unset leaf is skipped: an
+// explicit zero is honoured, because a user who writes `min: "0"` is asking for no CPU request,
+// which is a legitimate thing to ask for on a burstable workload.
+//
+// With requestFollowsLimit, an unset memory request follows the limit. That is what every primary
+// container got before the request existed, and it is what keeps a JVM, whose heap is sized from
+// the limit, out of the eviction order of a node under memory pressure. A sidecar budget passes
+// false: there an unset leaf keeps the provider's default, the request included, and only a kept
+// request above the new limit is lowered to it, since the API server rejects a request above its
+// limit.
+func overlayResources(
+	req *corev1.ResourceRequirements,
+	cpu *v1alpha1.CPUResource, memory *v1alpha1.MemoryResource, eph *v1alpha1.EphemeralStorageResource,
+	requestFollowsLimit bool,
+) {
+	if req.Requests == nil {
+		req.Requests = corev1.ResourceList{}
+	}
+	if req.Limits == nil {
+		req.Limits = corev1.ResourceList{}
+	}
+	if cpu != nil {
+		if cpu.Min != nil {
+			req.Requests[corev1.ResourceCPU] = cpu.Min.DeepCopy()
+		}
+		if cpu.Max != nil {
+			req.Limits[corev1.ResourceCPU] = cpu.Max.DeepCopy()
+		}
+	}
+	if memory != nil {
+		if memory.Limit != nil {
+			req.Limits[corev1.ResourceMemory] = memory.Limit.DeepCopy()
+		}
+		switch {
+		case memory.Request != nil:
+			req.Requests[corev1.ResourceMemory] = memory.Request.DeepCopy()
+		case memory.Limit == nil:
+		case requestFollowsLimit:
+			req.Requests[corev1.ResourceMemory] = memory.Limit.DeepCopy()
+		default:
+			if kept, ok := req.Requests[corev1.ResourceMemory]; ok && kept.Cmp(*memory.Limit) > 0 {
+				req.Requests[corev1.ResourceMemory] = memory.Limit.DeepCopy()
+			}
+		}
+	}
+	if eph != nil {
+		if eph.Request != nil {
+			req.Requests[corev1.ResourceEphemeralStorage] = eph.Request.DeepCopy()
+		}
+		if eph.Limit != nil {
+			req.Limits[corev1.ResourceEphemeralStorage] = eph.Limit.DeepCopy()
+		}
+	}
+}
+
+// ApplySidecarResources overlays resources.sidecars onto the pod's injected containers, matched by
+// container name across both containers and init containers — a native sidecar is an init
+// container with restartPolicy Always. Each stated leaf replaces the provider's default for that
+// leaf and the unset leaves keep it — the memory request too, which does not follow a stated limit
+// here as it does on the primary container.
+//
+// It runs on the built pod spec, after SidecarManager.InjectAll, because the sidecars are not
+// there before: they are injected after Build so podOverrides cannot address them. A budget naming
+// a container the pod does not carry is skipped (see ResourcesSpec.Sidecars).
+func ApplySidecarResources(podSpec *corev1.PodSpec, budgets map[string]v1alpha1.ContainerResources) {
+	if podSpec == nil || len(budgets) == 0 {
+		return
+	}
+	apply := func(containers []corev1.Container) {
+		for i := range containers {
+			budget, ok := budgets[containers[i].Name]
+			if !ok {
+				continue
+			}
+			overlayResources(&containers[i].Resources, budget.CPU, budget.Memory, budget.EphemeralStorage, false)
+		}
+	}
+	apply(podSpec.Containers)
+	apply(podSpec.InitContainers)
+}
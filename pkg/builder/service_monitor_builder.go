@@ -0,0 +1,176 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder
+
+import (
+	"maps"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ServiceMonitorGVK identifies the Prometheus Operator ServiceMonitor kind. The SDK does not
+// depend on the prometheus-operator Go module, so the object is built and applied as
+// unstructured content; the field names below follow monitoring.coreos.com/v1.
+var ServiceMonitorGVK = schema.GroupVersionKind{
+	Group:   "monitoring.coreos.com",
+	Version: "v1",
+	Kind:    "ServiceMonitor",
+}
+
+// ServiceMonitorTLS is the scrape TLS configuration of a ServiceMonitor endpoint.
+type ServiceMonitorTLS struct {
+	// ServerName is the name the scraper verifies the serving certificate against. It must be a
+	// name the certificate carries — for SecretClass-issued certificates, one of the Service
+	// scopes the pod's volume requested (see reconciler.RoleGroupTLS).
+	ServerName string
+	// CA selects the Secret key holding the CA bundle that signed the serving certificate, in the
+	// ServiceMonitor's namespace. Nil leaves verification to the scraper's own trust store.
+	CA *corev1.SecretKeySelector
+	// InsecureSkipVerify disables certificate verification. Prefer CA.
+	InsecureSkipVerify bool
+}
+
+// ServiceMonitorBuilder constructs a Prometheus Operator ServiceMonitor for a metrics Service
+// built by MetricsServiceBuilder. Conventions applied automatically:
+//   - Name and namespace: those of the metrics Service
+//   - Selector: matchLabels equal to the metrics Service's labels (which carry
+//     "prometheus.io/scrape=true", so the role group's other Services never match)
+//   - Endpoint port: the metrics Service's first port, by name
+//   - Endpoint scheme and path: the Service's "prometheus.io/scheme" and "prometheus.io/path"
+//     annotations, so the two scrape descriptions can never disagree
+//
+// Prometheus Operator ignores the prometheus.io annotations entirely; this builder restates them
+// in the form it does read.
+type ServiceMonitorBuilder struct {
+	service      *corev1.Service
+	labels       map[string]string
+	targetLabels map[string]string
+	tls          *ServiceMonitorTLS
+}
+
+// NewServiceMonitorBuilder creates a builder for a ServiceMonitor scraping metricsService. The
+// Service is read at Build time and never mutated.
+func NewServiceMonitorBuilder(metricsService *corev1.Service) *ServiceMonitorBuilder {
+	return &ServiceMonitorBuilder{service: metricsService}
+}
+
+// WithLabels merges labels into the ServiceMonitor's own metadata labels — typically the label a
+// Prometheus instance's serviceMonitorSelector keys off (for example "release: prometheus").
+// Repeated calls accumulate; the map is copied.
+func (b *ServiceMonitorBuilder) WithLabels(labels map[string]string) *ServiceMonitorBuilder {
+	if b.labels == nil {
+		b.labels = make(map[string]string, len(labels))
+	}
+	maps.Copy(b.labels, labels)
+	return b
+}
+
+// WithTargetLabels adds a static relabeling per entry that writes the value onto every scraped
+// series under the key as label name. The framework uses it for the cluster, role and role group
+// labels so dashboards can aggregate without depending on the pod's Kubernetes labels. Repeated
+// calls accumulate; the relabelings are emitted in key order so the built object is stable.
+func (b *ServiceMonitorBuilder) WithTargetLabels(labels map[string]string) *ServiceMonitorBuilder {
+	if b.targetLabels == nil {
+		b.targetLabels = make(map[string]string, len(labels))
+	}
+	maps.Copy(b.targetLabels, labels)
+	return b
+}
+
+// WithTLS sets the endpoint's tlsConfig. It is only meaningful for an https scheme; nil clears it.
+func (b *ServiceMonitorBuilder) WithTLS(tls *ServiceMonitorTLS) *ServiceMonitorBuilder {
+	b.tls = tls
+	return b
+}
+
+// Build creates the ServiceMonitor.
+func (b *ServiceMonitorBuilder) Build() *unstructured.Unstructured {
+	svc := b.service
+
+	endpoint := map[string]any{}
+	if len(svc.Spec.Ports) > 0 {
+		endpoint["port"] = svc.Spec.Ports[0].Name
+	}
+	scheme := svc.Annotations["prometheus.io/scheme"]
+	if scheme != "" {
+		endpoint["scheme"] = scheme
+	}
+	if path := svc.Annotations["prometheus.io/path"]; path != "" {
+		endpoint["path"] = path
+	}
+
+	if len(b.targetLabels) > 0 {
+		relabelings := make([]any, 0, len(b.targetLabels))
+		for _, key := range slices.Sorted(maps.Keys(b.targetLabels)) {
+			relabelings = append(relabelings, map[string]any{
+				"action":      "replace",
+				"targetLabel": key,
+				"replacement": b.targetLabels[key],
+			})
+		}
+		endpoint["relabelings"] = relabelings
+	}
+
+	if b.tls != nil && scheme == "https" {
+		tlsConfig := map[string]any{}
+		if b.tls.ServerName != "" {
+			tlsConfig["serverName"] = b.tls.ServerName
+		}
+		if b.tls.CA != nil {
+			tlsConfig["ca"] = map[string]any{
+				"secret": map[string]any{
+					"name": b.tls.CA.Name,
+					"key":  b.tls.CA.Key,
+				},
+			}
+		}
+		if b.tls.InsecureSkipVerify {
+			tlsConfig["insecureSkipVerify"] = true
+		}
+		endpoint["tlsConfig"] = tlsConfig
+	}
+
+	matchLabels := map[string]any{}
+	for k, v := range svc.Labels {
+		matchLabels[k] = v
+	}
+
+	sm := &unstructured.Unstructured{}
+	sm.SetGroupVersionKind(ServiceMonitorGVK)
+	sm.SetName(svc.Name)
+	sm.SetNamespace(svc.Namespace)
+	labels := maps.Clone(svc.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	// Applied last so a caller entry wins over the copied Service label for the same key.
+	maps.Copy(labels, b.labels)
+	sm.SetLabels(labels)
+	sm.Object["spec"] = map[string]any{
+		"selector": map[string]any{
+			"matchLabels": matchLabels,
+		},
+		"namespaceSelector": map[string]any{
+			"matchNames": []any{svc.Namespace},
+		},
+		"endpoints": []any{endpoint},
+	}
+	return sm
+}
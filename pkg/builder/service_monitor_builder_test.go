@@ -0,0 +1,91 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package builder_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/zncdatadev/operator-go/pkg/builder"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var _ = Describe("ServiceMonitorBuilder", func() {
+	var svc *corev1.Service
+
+	BeforeEach(func() {
+		svc = builder.NewMetricsServiceBuilder("test-cluster-default", "test-ns", 9505, map[string]string{
+			"app.kubernetes.io/name": "test-cluster",
+		}).WithPortName("http-metrics").WithPath("/prom").Build()
+	})
+
+	endpointOf := func(sm *unstructured.Unstructured) map[string]any {
+		GinkgoHelper()
+		endpoints, found, err := unstructured.NestedSlice(sm.Object, "spec", "endpoints")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(endpoints).To(HaveLen(1))
+		return endpoints[0].(map[string]any)
+	}
+
+	It("should restate the metrics Service's name, selector, port, scheme and path", func() {
+		sm := builder.NewServiceMonitorBuilder(svc).WithLabels(map[string]string{"release": "prometheus"}).Build()
+
+		Expect(sm.GroupVersionKind()).To(Equal(builder.ServiceMonitorGVK))
+		Expect(sm.GetName()).To(Equal("test-cluster-default-metrics"))
+		Expect(sm.GetNamespace()).To(Equal("test-ns"))
+		Expect(sm.GetLabels()).To(HaveKeyWithValue("release", "prometheus"))
+		Expect(sm.GetLabels()).To(HaveKeyWithValue("app.kubernetes.io/name", "test-cluster"))
+
+		matchLabels, _, _ := unstructured.NestedStringMap(sm.Object, "spec", "selector", "matchLabels")
+		Expect(matchLabels).To(Equal(svc.Labels))
+		namespaces, _, _ := unstructured.NestedStringSlice(sm.Object, "spec", "namespaceSelector", "matchNames")
+		Expect(namespaces).To(ConsistOf("test-ns"))
+
+		endpoint := endpointOf(sm)
+		Expect(endpoint).To(HaveKeyWithValue("port", "http-metrics"))
+		Expect(endpoint).To(HaveKeyWithValue("scheme", "http"))
+		Expect(endpoint).To(HaveKeyWithValue("path", "/prom"))
+		Expect(endpoint).NotTo(HaveKey("relabelings"))
+	})
+
+	It("should emit one replace relabeling per target label, in key order", func() {
+		sm := builder.NewServiceMonitorBuilder(svc).
+			WithTargetLabels(map[string]string{"role": "server", "cluster": "zk"}).
+			Build()
+
+		Expect(endpointOf(sm)["relabelings"]).To(Equal([]any{
+			map[string]any{"action": "replace", "targetLabel": "cluster", "replacement": "zk"},
+			map[string]any{"action": "replace", "targetLabel": "role", "replacement": "server"},
+		}))
+	})
+
+	It("should only emit tlsConfig for an https endpoint", func() {
+		tls := &builder.ServiceMonitorTLS{
+			ServerName: "test-cluster-default-headless.test-ns.svc.cluster.local",
+			CA:         &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "tls-ca"}, Key: "ca.crt"},
+		}
+		Expect(endpointOf(builder.NewServiceMonitorBuilder(svc).WithTLS(tls).Build())).NotTo(HaveKey("tlsConfig"))
+
+		svc.Annotations["prometheus.io/scheme"] = "https"
+		endpoint := endpointOf(builder.NewServiceMonitorBuilder(svc).WithTLS(tls).Build())
+		Expect(endpoint).To(HaveKeyWithValue("tlsConfig", map[string]any{
+			"serverName": "test-cluster-default-headless.test-ns.svc.cluster.local",
+			"ca":         map[string]any{"secret": map[string]any{"name": "tls-ca", "key": "ca.crt"}},
+		}))
+	})
+})
@@ -82,6 +82,12 @@ type StatefulSetBuilder struct {
 	// Affinity
 	Affinity *corev1.Affinity
 
+	// NodeSelector, Tolerations and TopologySpreadConstraints are the pod's placement fields
+	// besides affinity. Each is copied onto the pod spec as given; nil leaves the field unset.
+	NodeSelector              map[string]string
+	Tolerations               []corev1.Toleration
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint
+
 	// Service account
 	ServiceAccountName string
 
@@ -105,6 +111,11 @@ type StatefulSetBuilder struct {
 	// (RollingUpdate with a partition that is lowered step by step) possible on a live cluster.
 	UpdateStrategy *appsv1.StatefulSetUpdateStrategy
 
+	// PersistentVolumeClaimRetentionPolicy decides whether the StatefulSet controller deletes the
+	// claim-template PVCs on deletion and on scale-down. Nil leaves the field unset, which
+	// Kubernetes defaults to Retain for both.
+	PersistentVolumeClaimRetentionPolicy *appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy
+
 	// Pod overrides from merged config
 	PodOverrides *corev1.PodTemplateSpec
 
@@ -236,7 +247,9 @@ func (b *StatefulSetBuilder) WithConfig(cfg *config.MergedConfig) *StatefulSetBu
 	return b
 }
 
-// WithResources sets the resource requirements.
+// WithResources sets the primary container's resource requirements from the role group's CPU,
+// memory and ephemeral-storage budget. resources.sidecars is not read here: the sidecars are
+// injected after Build, and ApplySidecarResources budgets them there.
 func (b *StatefulSetBuilder) WithResources(resources *v1alpha1.ResourcesSpec) *StatefulSetBuilder {
 	if resources == nil {
 		return b
@@ -246,23 +259,7 @@ func (b *StatefulSetBuilder) WithResources(resources *v1alpha1.ResourcesSpec) *S
 		Requests: make(corev1.ResourceList),
 		Limits:   make(corev1.ResourceList),
 	}
-
-	// Nil means unset, and only an unset field is skipped. An explicit zero is honoured: a user
-	// who writes `min: "0"` is asking for no CPU request, which is a legitimate thing to ask for
-	// on a burstable workload, and the previous IsZero() check silently ignored it.
-	if resources.CPU != nil {
-		if resources.CPU.Min != nil {
-			req.Requests[corev1.ResourceCPU] = *resources.CPU.Min
-		}
-		if resources.CPU.Max != nil {
-			req.Limits[corev1.ResourceCPU] = *resources.CPU.Max
-		}
-	}
-
-	if resources.Memory != nil && resources.Memory.Limit != nil {
-		req.Limits[corev1.ResourceMemory] = *resources.Memory.Limit
-		req.Requests[corev1.ResourceMemory] = *resources.Memory.Limit
-	}
+	overlayResources(req, resources.CPU, resources.Memory, resources.EphemeralStorage, true)
 
 	b.Resources = req
 	return b
@@ -345,6 +342,25 @@ func (b *StatefulSetBuilder) WithAffinity(affinity *corev1.Affinity) *StatefulSe
 	return b
 }
 
+// WithNodeSelector sets the pod's node selector.
+func (b *StatefulSetBuilder) WithNodeSelector(selector map[string]string) *StatefulSetBuilder {
+	b.NodeSelector = selector
+	return b
+}
+
+// WithTolerations sets the pod's tolerations.
+func (b *StatefulSetBuilder) WithTolerations(tolerations []corev1.Toleration) *StatefulSetBuilder {
+	b.Tolerations = tolerations
+	return b
+}
+
+// WithTopologySpreadConstraints sets the pod's topology spread constraints.
+func (b *StatefulSetBuilder) WithTopologySpreadConstraints(
+	constraints []corev1.TopologySpreadConstraint) *StatefulSetBuilder {
+	b.TopologySpreadConstraints = constraints
+	return b
+}
+
 // WithSecurityContext sets the security context.
 func (b *StatefulSetBuilder) WithSecurityContext(containerCtx *corev1.SecurityContext, podCtx *corev1.PodSecurityContext) *StatefulSetBuilder {
 	b.SecurityContext = containerCtx
@@ -395,6 +411,16 @@ func (b *StatefulSetBuilder) WithUpdateStrategy(strategy appsv1.StatefulSetUpdat
 	return b
 }
 
+// WithPersistentVolumeClaimRetentionPolicy sets what the StatefulSet controller does with the
+// claim-template PVCs when the StatefulSet is deleted or scaled down. Unlike the claim templates
+// themselves the policy is mutable, so it also changes on a live role group.
+func (b *StatefulSetBuilder) WithPersistentVolumeClaimRetentionPolicy(
+	policy *appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy,
+) *StatefulSetBuilder {
+	b.PersistentVolumeClaimRetentionPolicy = policy.DeepCopy()
+	return b
+}
+
 // WithPodOverrides sets the pod template overrides.
 func (b *StatefulSetBuilder) WithPodOverrides(overrides *corev1.PodTemplateSpec) *StatefulSetBuilder {
 	b.PodOverrides = overrides
@@ -412,7 +438,8 @@ func (b *StatefulSetBuilder) WithStorage(storage *v1alpha1.StorageResource, moun
 }
 
 // WithNamedStorage is WithStorage with the claim template's name chosen by the caller. An empty
-// name uses DefaultDataVolumeName.
+// name uses DefaultDataVolumeName. Calls accumulate one claim template per name; calling it again
+// with a name already added replaces that template and its mount.
 //
 // The name is a parameter because a claim template's name is IMMUTABLE and preserved by the apply
 // path, so a product that wanted its volume called anything else could not fix it afterwards
@@ -435,46 +462,64 @@ func (b *StatefulSetBuilder) WithNamedStorage(
 		name = DefaultDataVolumeName
 	}
 
-	b.StorageConfig = &StorageConfig{
-		StorageClass: ptr.Deref(storage.StorageClass, ""),
-		VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
-			{
-				ObjectMeta: metav1.ObjectMeta{
-					Name: name,
-				},
-				Spec: corev1.PersistentVolumeClaimSpec{
-					AccessModes: []corev1.PersistentVolumeAccessMode{
-						corev1.ReadWriteOnce,
-					},
-					Resources: corev1.VolumeResourceRequirements{
-						Requests: corev1.ResourceList{
-							// GetCapacity applies DefaultStorageCapacity when the user set none.
-							// The default lives here rather than in the CRD schema: a
-							// `+kubebuilder:default` is stamped in as soon as the storage block
-							// exists, so overriding only storageClass would silently downgrade the
-							// capacity the role asked for.
-							corev1.ResourceStorage: storage.GetCapacity(),
-						},
-					},
+	template := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{
+				corev1.ReadWriteOnce,
+			},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					// GetCapacity applies DefaultStorageCapacity when the user set none.
+					// The default lives here rather than in the CRD schema: a
+					// `+kubebuilder:default` is stamped in as soon as the storage block
+					// exists, so overriding only storageClass would silently downgrade the
+					// capacity the role asked for.
+					corev1.ResourceStorage: storage.GetCapacity(),
 				},
 			},
 		},
 	}
-
 	if storage.StorageClass != nil {
 		// Set whenever the user said something, INCLUDING the empty string: Kubernetes reads
 		// `storageClassName: ""` as "no class, bind a pre-provisioned PV", which is a different
 		// request from leaving the field out (use the cluster default). Copy rather than point into
 		// the caller's spec, which the caller still owns.
-		b.StorageConfig.VolumeClaimTemplates[0].Spec.StorageClassName = ptr.To(*storage.StorageClass)
+		template.Spec.StorageClassName = ptr.To(*storage.StorageClass)
 	}
-
 	// The mount must carry the SAME name as the claim template, or the pod references a volume that
 	// does not exist.
-	b.VolumeMounts = append(b.VolumeMounts, corev1.VolumeMount{
+	mount := corev1.VolumeMount{
 		Name:      name,
 		MountPath: mountPath,
-	})
+	}
+
+	// Each call adds ONE claim template, so a role with a data volume and named volumes
+	// (resources.volumes) gets them all. A repeated name replaces its template and its mount in
+	// place rather than appending a second: two templates of one name are rejected by the API
+	// server, and two mounts of one volume are a pod that mounts it twice.
+	if b.StorageConfig == nil {
+		b.StorageConfig = &StorageConfig{StorageClass: ptr.Deref(storage.StorageClass, "")}
+	}
+	replaced := false
+	for i := range b.StorageConfig.VolumeClaimTemplates {
+		if b.StorageConfig.VolumeClaimTemplates[i].Name == name {
+			b.StorageConfig.VolumeClaimTemplates[i] = template
+			replaced = true
+		}
+	}
+	if !replaced {
+		b.StorageConfig.VolumeClaimTemplates = append(b.StorageConfig.VolumeClaimTemplates, template)
+	}
+	for i := range b.VolumeMounts {
+		if b.VolumeMounts[i].Name == name {
+			b.VolumeMounts[i] = mount
+			return b
+		}
+	}
+	b.VolumeMounts = append(b.VolumeMounts, mount)
 
 	return b
 }
@@ -540,6 +585,19 @@ func (b *StatefulSetBuilder) WithPreStopHTTPGet(path string, port int) *Stateful
 	return b
 }
 
+// WithPreStop sets the preStop hook to handler, deep-copied, keeping any postStart hook. It is the
+// general form of the two helpers above, for a hook they cannot express.
+func (b *StatefulSetBuilder) WithPreStop(handler *corev1.LifecycleHandler) *StatefulSetBuilder {
+	if handler == nil {
+		return b
+	}
+	if b.lifecycle == nil {
+		b.lifecycle = &corev1.Lifecycle{}
+	}
+	b.lifecycle.PreStop = handler.DeepCopy()
+	return b
+}
+
 // WithPostStartHook sets a postStart exec hook.
 func (b *StatefulSetBuilder) WithPostStartHook(command []string) *StatefulSetBuilder {
 	if b.lifecycle == nil {
@@ -672,6 +730,10 @@ func (b *StatefulSetBuilder) Build() *appsv1.StatefulSet {
 		sts.Spec.UpdateStrategy = *b.UpdateStrategy.DeepCopy()
 	}
 
+	if b.PersistentVolumeClaimRetentionPolicy != nil {
+		sts.Spec.PersistentVolumeClaimRetentionPolicy = b.PersistentVolumeClaimRetentionPolicy.DeepCopy()
+	}
+
 	// Add volume claim templates if storage is configured
 	if b.StorageConfig != nil {
 		sts.Spec.VolumeClaimTemplates = cloneSlice(b.StorageConfig.VolumeClaimTemplates)
@@ -695,6 +757,9 @@ func (b *StatefulSetBuilder) buildPodSpec() corev1.PodSpec {
 		TerminationGracePeriodSeconds: clonePtr(b.TerminationGracePeriodSeconds),
 		SecurityContext:               b.PodSecurityContext.DeepCopy(),
 		Affinity:                      b.Affinity.DeepCopy(),
+		NodeSelector:                  maps.Clone(b.NodeSelector),
+		Tolerations:                   cloneSlice(b.Tolerations),
+		TopologySpreadConstraints:     cloneSlice(b.TopologySpreadConstraints),
 		Volumes:                       cloneSlice(b.Volumes),
 		InitContainers:                cloneSlice(b.InitContainers),
 		Containers: []corev1.Container{
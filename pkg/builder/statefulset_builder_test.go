@@ -229,6 +229,38 @@ var _ = Describe("StatefulSetBuilder", func() {
 		})
 	})
 
+	Describe("placement", func() {
+		It("should copy the node selector, tolerations and spread constraints onto the pod spec", func() {
+			selector := map[string]string{"disk": "ssd"}
+			spread := []corev1.TopologySpreadConstraint{{
+				MaxSkew: 1, TopologyKey: corev1.LabelTopologyZone, WhenUnsatisfiable: corev1.ScheduleAnyway,
+				LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}},
+			}}
+			sts := stsBuilder.
+				WithNodeSelector(selector).
+				WithTolerations([]corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}).
+				WithTopologySpreadConstraints(spread).
+				Build()
+
+			podSpec := sts.Spec.Template.Spec
+			Expect(podSpec.NodeSelector).To(Equal(selector))
+			Expect(podSpec.Tolerations).To(HaveLen(1))
+			Expect(podSpec.TopologySpreadConstraints).To(Equal(spread))
+
+			podSpec.NodeSelector["disk"] = "hdd"
+			podSpec.TopologySpreadConstraints[0].LabelSelector.MatchLabels["app"] = "other"
+			Expect(selector["disk"]).To(Equal("ssd"), "the built object does not alias the builder's input")
+			Expect(spread[0].LabelSelector.MatchLabels["app"]).To(Equal("test"))
+		})
+
+		It("should leave the fields unset when none is given", func() {
+			podSpec := stsBuilder.Build().Spec.Template.Spec
+			Expect(podSpec.NodeSelector).To(BeNil())
+			Expect(podSpec.Tolerations).To(BeNil())
+			Expect(podSpec.TopologySpreadConstraints).To(BeNil())
+		})
+	})
+
 	Describe("WithTerminationGracePeriod", func() {
 		It("should set the termination grace period", func() {
 			result := stsBuilder.WithTerminationGracePeriod(60)
@@ -238,6 +270,25 @@ var _ = Describe("StatefulSetBuilder", func() {
 		})
 	})
 
+	Describe("WithPersistentVolumeClaimRetentionPolicy", func() {
+		It("sets the native retention policy on the built StatefulSet, and leaves it unset by default", func() {
+			Expect(stsBuilder.Build().Spec.PersistentVolumeClaimRetentionPolicy).To(BeNil())
+
+			policy := &appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy{
+				WhenDeleted: appsv1.DeletePersistentVolumeClaimRetentionPolicyType,
+				WhenScaled:  appsv1.RetainPersistentVolumeClaimRetentionPolicyType,
+			}
+			result := stsBuilder.WithPersistentVolumeClaimRetentionPolicy(policy)
+			Expect(result).To(Equal(stsBuilder))
+
+			sts := stsBuilder.Build()
+			Expect(sts.Spec.PersistentVolumeClaimRetentionPolicy).To(Equal(policy))
+			sts.Spec.PersistentVolumeClaimRetentionPolicy.WhenDeleted = appsv1.RetainPersistentVolumeClaimRetentionPolicyType
+			Expect(stsBuilder.Build().Spec.PersistentVolumeClaimRetentionPolicy.WhenDeleted).
+				To(Equal(appsv1.DeletePersistentVolumeClaimRetentionPolicyType))
+		})
+	})
+
 	Describe("WithPreStopHook", func() {
 		It("should set a preStop exec hook", func() {
 			command := []string{"/bin/sh", "-c", "sleep 10"}
@@ -568,6 +619,28 @@ var _ = Describe("StatefulSetBuilder", func() {
 			Expect(stsBuilder.Resources.Requests).NotTo(HaveKey(corev1.ResourceCPU))
 		})
 
+		It("splits the memory request from the limit and sets ephemeral storage", func() {
+			stsBuilder.WithResources(&v1alpha1.ResourcesSpec{
+				Memory: &v1alpha1.MemoryResource{
+					Limit:   ptr.To(resource.MustParse("4Gi")),
+					Request: ptr.To(resource.MustParse("2Gi")),
+				},
+				EphemeralStorage: &v1alpha1.EphemeralStorageResource{
+					Request: ptr.To(resource.MustParse("1Gi")),
+					Limit:   ptr.To(resource.MustParse("5Gi")),
+				},
+			})
+
+			memReq := stsBuilder.Resources.Requests[corev1.ResourceMemory]
+			memLimit := stsBuilder.Resources.Limits[corev1.ResourceMemory]
+			ephReq := stsBuilder.Resources.Requests[corev1.ResourceEphemeralStorage]
+			ephLimit := stsBuilder.Resources.Limits[corev1.ResourceEphemeralStorage]
+			Expect(memReq.String()).To(Equal("2Gi"))
+			Expect(memLimit.String()).To(Equal("4Gi"))
+			Expect(ephReq.String()).To(Equal("1Gi"))
+			Expect(ephLimit.String()).To(Equal("5Gi"))
+		})
+
 		It("should build StatefulSet with resources", func() {
 			maxCPU := resource.MustParse("500m")
 			minCPU := resource.MustParse("100m")
@@ -1170,6 +1243,26 @@ var _ = Describe("StatefulSetBuilder", func() {
 		})
 	})
 
+	Describe("WithPreStop", func() {
+		It("should set any preStop handler and keep the postStart hook", func() {
+			handler := &corev1.LifecycleHandler{HTTPGet: &corev1.HTTPGetAction{
+				Path: "/decommission", Port: intstr.FromString("http"), Scheme: corev1.URISchemeHTTPS,
+			}}
+			sts := stsBuilder.
+				WithImage(image, corev1.PullIfNotPresent).
+				WithPostStartHook([]string{"/bin/true"}).
+				WithPreStop(handler).
+				Build()
+
+			container := sts.Spec.Template.Spec.Containers[0]
+			Expect(container.Lifecycle.PreStop).To(Equal(handler))
+			Expect(container.Lifecycle.PostStart.Exec.Command).To(Equal([]string{"/bin/true"}))
+
+			handler.HTTPGet.Path = "/changed"
+			Expect(container.Lifecycle.PreStop.HTTPGet.Path).To(Equal("/decommission"))
+		})
+	})
+
 	Describe("WithPostStartHook", func() {
 		It("should set a postStart exec hook", func() {
 			command := []string{"/bin/sh", "-c", "echo started"}
@@ -1856,6 +1949,29 @@ var _ = Describe("WithNamedStorage", func() {
 		Expect(sts.Spec.VolumeClaimTemplates[0].Name).To(Equal(builder.DefaultDataVolumeName))
 	})
 
+	It("accumulates one claim template per name, and replaces a repeated name in place", func() {
+		sts := builder.NewStatefulSetBuilder("test", "default").
+			WithImage("img:1", corev1.PullIfNotPresent).
+			WithNamedStorage("data", storage(), "/kubedoop/data").
+			WithNamedStorage("journal", storage(), "/kubedoop/journal").
+			WithNamedStorage("data", &v1alpha1.StorageResource{Capacity: ptr.To(resource.MustParse("50Gi"))}, "/kubedoop/data").
+			Build()
+
+		Expect(sts.Spec.VolumeClaimTemplates).To(HaveLen(2))
+		Expect(sts.Spec.VolumeClaimTemplates[0].Name).To(Equal("data"))
+		q := sts.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests[corev1.ResourceStorage]
+		Expect(q.String()).To(Equal("50Gi"), "the later call wins")
+		Expect(sts.Spec.VolumeClaimTemplates[1].Name).To(Equal("journal"))
+
+		var dataMounts int
+		for _, m := range sts.Spec.Template.Spec.Containers[0].VolumeMounts {
+			if m.Name == "data" {
+				dataMounts++
+			}
+		}
+		Expect(dataMounts).To(Equal(1), "a repeated name must not mount the volume twice")
+	})
+
 	It("declines to build a claim when the role group states no storage", func() {
 		// A nil storage is a role group that said nothing, not one asking for a zero-sized volume.
 		sts := builder.NewStatefulSetBuilder("test", "default").
@@ -1865,3 +1981,50 @@ var _ = Describe("WithNamedStorage", func() {
 		Expect(sts.Spec.VolumeClaimTemplates).To(BeEmpty())
 	})
 })
+
+var _ = Describe("ApplySidecarResources", func() {
+	It("overlays each stated leaf onto the named container and keeps the provider's others", func() {
+		podSpec := &corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "main"}},
+			InitContainers: []corev1.Container{{
+				Name: "vector",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("50m")},
+					Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+				},
+			}},
+		}
+		builder.ApplySidecarResources(podSpec, map[string]v1alpha1.ContainerResources{
+			"vector": {Memory: &v1alpha1.MemoryResource{Limit: ptr.To(resource.MustParse("256Mi"))}},
+			"absent": {Memory: &v1alpha1.MemoryResource{Limit: ptr.To(resource.MustParse("1Gi"))}},
+		})
+
+		vector := podSpec.InitContainers[0].Resources
+		cpu := vector.Requests[corev1.ResourceCPU]
+		limit := vector.Limits[corev1.ResourceMemory]
+		Expect(cpu.String()).To(Equal("50m"), "the provider's default survives")
+		Expect(limit.String()).To(Equal("256Mi"))
+		Expect(podSpec.Containers[0].Resources.Limits).To(BeEmpty(), "a budget never lands on another container")
+	})
+
+	It("keeps the provider's memory request when a budget states only the limit", func() {
+		podSpec := &corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "vector", Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")},
+				Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")},
+			}},
+			{Name: "jmx-exporter", Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+			}},
+		}}
+		builder.ApplySidecarResources(podSpec, map[string]v1alpha1.ContainerResources{
+			"vector":       {Memory: &v1alpha1.MemoryResource{Limit: ptr.To(resource.MustParse("512Mi"))}},
+			"jmx-exporter": {Memory: &v1alpha1.MemoryResource{Limit: ptr.To(resource.MustParse("128Mi"))}},
+		})
+
+		vector := podSpec.Containers[0].Resources.Requests[corev1.ResourceMemory]
+		Expect(vector.String()).To(Equal("64Mi"), "the unset request is the provider's, not the limit")
+		exporter := podSpec.Containers[1].Resources.Requests[corev1.ResourceMemory]
+		Expect(exporter.String()).To(Equal("128Mi"), "a kept request never exceeds the new limit")
+	})
+})
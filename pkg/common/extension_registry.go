@@ -23,10 +23,16 @@ import (
 	"sort"
 	"sync"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+// tracerName is the instrumentation scope of the extension spans (see executeHooks).
+const tracerName = "github.com/zncdatadev/operator-go/pkg/common"
+
 // extensionEntry wraps a registered extension with its ordering and fault-tolerance metadata.
 type extensionEntry[T Extension] struct {
 	extension T
@@ -193,10 +199,15 @@ func extensionsOf[T Extension](entries []extensionEntry[T]) []T {
 // loop when its (possibly overridden) stop-on-error setting says so; otherwise the failure is
 // logged and the remaining extensions still run. Every failure the policy aggregates is part
 // of the returned error, whether the loop ran to completion or was stopped by a later one.
-func executeHooks[T Extension](ctx context.Context, entries []extensionEntry[T], policy hookPolicy, hook func(T) error) error {
+//
+// Each extension runs in a span of its own, named after the hook (hookName) and carrying the
+// extension's name, so a slow reconcile can be pinned on the hook that made it slow. The span is
+// started from the TracerProvider of the span already in ctx — the reconciler's — so the registry
+// holds no provider and an untraced caller gets no-op spans.
+func executeHooks[T Extension](ctx context.Context, entries []extensionEntry[T], policy hookPolicy, hookName string, hook func(context.Context, T) error) error {
 	var errs []error
 	for _, entry := range entries {
-		err := hook(entry.extension)
+		err := runHook(ctx, entry.extension, hookName, hook)
 		if err == nil {
 			continue
 		}
@@ -239,6 +250,25 @@ func executeHooks[T Extension](ctx context.Context, entries []extensionEntry[T],
 	return errors.Join(errs...)
 }
 
+// runHook runs hook for one extension inside its span. A wait is recorded as an event rather than
+// an error, matching how executeHooks logs it.
+func runHook[T Extension](ctx context.Context, extension T, hookName string, hook func(context.Context, T) error) error {
+	ctx, span := trace.SpanFromContext(ctx).TracerProvider().Tracer(tracerName).Start(ctx, hookName,
+		trace.WithAttributes(attribute.String("kubedoop.extension", extension.Name())))
+	defer span.End()
+
+	err := hook(ctx, extension)
+	if err != nil {
+		if _, waiting := WaitingErrors(err); waiting {
+			span.AddEvent("waiting", trace.WithAttributes(attribute.String("reason", err.Error())))
+		} else {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	return err
+}
+
 // clusterEntries returns a snapshot of the cluster entries in execution order.
 func (r *ExtensionRegistry[CR]) clusterEntries() []extensionEntry[ClusterExtension[CR]] {
 	r.mu.RLock()
@@ -277,16 +307,16 @@ func (r *ExtensionRegistry[CR]) GetRoleGroupExtensions() []RoleGroupExtension[CR
 
 // ExecuteClusterPreReconcile executes all cluster PreReconcile hooks.
 func (r *ExtensionRegistry[CR]) ExecuteClusterPreReconcile(ctx context.Context, client client.Client, cr CR) error {
-	return executeHooks(ctx, r.clusterEntries(), reconcileHookPolicy,
-		func(ext ClusterExtension[CR]) error {
+	return executeHooks(ctx, r.clusterEntries(), reconcileHookPolicy, "ClusterExtension.PreReconcile",
+		func(ctx context.Context, ext ClusterExtension[CR]) error {
 			return ext.PreReconcile(ctx, client, cr)
 		})
 }
 
 // ExecuteClusterPostReconcile executes all cluster PostReconcile hooks.
 func (r *ExtensionRegistry[CR]) ExecuteClusterPostReconcile(ctx context.Context, client client.Client, cr CR) error {
-	return executeHooks(ctx, r.clusterEntries(), reconcileHookPolicy,
-		func(ext ClusterExtension[CR]) error {
+	return executeHooks(ctx, r.clusterEntries(), reconcileHookPolicy, "ClusterExtension.PostReconcile",
+		func(ctx context.Context, ext ClusterExtension[CR]) error {
 			return ext.PostReconcile(ctx, client, cr)
 		})
 }
@@ -296,40 +326,40 @@ func (r *ExtensionRegistry[CR]) ExecuteClusterPostReconcile(ctx context.Context,
 // the original reconcile error nor skips the cleanup of the remaining handlers. A handler
 // registered with WithStopOnError(true) opts out and aborts the loop.
 func (r *ExtensionRegistry[CR]) ExecuteClusterOnError(ctx context.Context, client client.Client, cr CR, reconcileErr error) error {
-	return executeHooks(ctx, r.clusterEntries(), errorHookPolicy,
-		func(ext ClusterExtension[CR]) error {
+	return executeHooks(ctx, r.clusterEntries(), errorHookPolicy, "ClusterExtension.OnReconcileError",
+		func(ctx context.Context, ext ClusterExtension[CR]) error {
 			return ext.OnReconcileError(ctx, client, cr, reconcileErr)
 		})
 }
 
 // ExecuteRolePreReconcile executes all role PreReconcile hooks.
 func (r *ExtensionRegistry[CR]) ExecuteRolePreReconcile(ctx context.Context, client client.Client, cr CR, roleName string) error {
-	return executeHooks(ctx, r.roleEntries(), reconcileHookPolicy,
-		func(ext RoleExtension[CR]) error {
+	return executeHooks(ctx, r.roleEntries(), reconcileHookPolicy, "RoleExtension.PreReconcile",
+		func(ctx context.Context, ext RoleExtension[CR]) error {
 			return ext.PreReconcile(ctx, client, cr, roleName)
 		})
 }
 
 // ExecuteRolePostReconcile executes all role PostReconcile hooks.
 func (r *ExtensionRegistry[CR]) ExecuteRolePostReconcile(ctx context.Context, client client.Client, cr CR, roleName string) error {
-	return executeHooks(ctx, r.roleEntries(), reconcileHookPolicy,
-		func(ext RoleExtension[CR]) error {
+	return executeHooks(ctx, r.roleEntries(), reconcileHookPolicy, "RoleExtension.PostReconcile",
+		func(ctx context.Context, ext RoleExtension[CR]) error {
 			return ext.PostReconcile(ctx, client, cr, roleName)
 		})
 }
 
 // ExecuteRoleGroupPreReconcile executes all role group PreReconcile hooks.
 func (r *ExtensionRegistry[CR]) ExecuteRoleGroupPreReconcile(ctx context.Context, client client.Client, cr CR, roleName, roleGroupName string) error {
-	return executeHooks(ctx, r.roleGroupEntries(), reconcileHookPolicy,
-		func(ext RoleGroupExtension[CR]) error {
+	return executeHooks(ctx, r.roleGroupEntries(), reconcileHookPolicy, "RoleGroupExtension.PreReconcile",
+		func(ctx context.Context, ext RoleGroupExtension[CR]) error {
 			return ext.PreReconcile(ctx, client, cr, roleName, roleGroupName)
 		})
 }
 
 // ExecuteRoleGroupPostReconcile executes all role group PostReconcile hooks.
 func (r *ExtensionRegistry[CR]) ExecuteRoleGroupPostReconcile(ctx context.Context, client client.Client, cr CR, roleName, roleGroupName string) error {
-	return executeHooks(ctx, r.roleGroupEntries(), reconcileHookPolicy,
-		func(ext RoleGroupExtension[CR]) error {
+	return executeHooks(ctx, r.roleGroupEntries(), reconcileHookPolicy, "RoleGroupExtension.PostReconcile",
+		func(ctx context.Context, ext RoleGroupExtension[CR]) error {
 			return ext.PostReconcile(ctx, client, cr, roleName, roleGroupName)
 		})
 }
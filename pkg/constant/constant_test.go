@@ -113,6 +113,7 @@ func TestKubedoopPaths(t *testing.T) {
 		{"KubedoopConfigDirMount", KubedoopConfigDirMount, "/kubedoop/mount/config/", "/"},
 		{"KubedoopLogDirMount", KubedoopLogDirMount, "/kubedoop/mount/log/", "/"},
 		{"KubedoopMountDir", KubedoopMountDir, "/kubedoop/mount/", "/"},
+		{"KubedoopNodeLabelsDir", KubedoopNodeLabelsDir, "/kubedoop/node-labels/", "/"},
 	}
 
 	for _, tt := range tests {
@@ -47,3 +47,8 @@ const (
 	LabelEnrichmentEnableValue = "true"
 	LabelEnrichmentNodeAddress = "enrichment." + KubedoopDomain + "/node-address"
 )
+
+// AnnotationNodeLabels carries the labels of a pod's node that its role declared it needs, as
+// sorted "key=value" lines. Unlike the enrichment annotations above, the operator framework writes
+// it itself once the pod is scheduled (see reconciler.RoleDeclaration.NodeLabels).
+const AnnotationNodeLabels = "enrichment." + KubedoopDomain + "/node-labels"
@@ -30,9 +30,17 @@ const (
 	KubedoopConfigDir      = KubedoopRoot + "config/"
 	KubedoopLogDir         = KubedoopRoot + "log/"
 	KubedoopConfigDirMount = KubedoopRoot + "mount/config/"
-	KubedoopLogDirMount    = KubedoopRoot + "mount/log/"
+
+	// KubedoopLogDirMount is where a role that reloads its logging config
+	// (reconciler.RoleDeclaration.LogConfigReloadInterval) mounts the separate logging ConfigMap:
+	// as a whole directory, since a subPath mount is never updated in place.
+	KubedoopLogDirMount = KubedoopRoot + "mount/log/"
 
 	// KubedoopMountDir is the canonical base directory for all CSI secret volume mounts.
 	// Consumers should use path.Join or the SecretProvisioner API to compose sub-paths.
 	KubedoopMountDir = KubedoopRoot + "mount/"
+
+	// KubedoopNodeLabelsDir is where a role that declares node labels
+	// (reconciler.RoleDeclaration.NodeLabels) reads the labels of the node its pod runs on.
+	KubedoopNodeLabelsDir = KubedoopRoot + "node-labels/"
 )
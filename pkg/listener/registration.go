@@ -47,6 +47,16 @@ func (r *VolumeRegistration) WithListenerName(name string) *VolumeRegistration {
 	return r
 }
 
+// VolumeName returns the name of the pod volume this registration declares.
+func (r *VolumeRegistration) VolumeName() string { return r.volumeName }
+
+// ListenerClass returns the listener class, empty for a by-name registration.
+func (r *VolumeRegistration) ListenerClass() ListenerClass { return r.listenerClass }
+
+// ListenerName returns the pre-created Listener this registration binds to, empty when the
+// listener-operator creates one Listener per pod volume.
+func (r *VolumeRegistration) ListenerName() string { return r.listenerName }
+
 // buildAnnotations constructs the PVC template annotations for this registration.
 // The class annotation is omitted when the listener class is empty (by-name
 // registrations): an empty class would at best be noise and at worst confuse
@@ -21,6 +21,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // boundedFileDefaults applies sensible defaults to the rolling-file bounds so a generated
@@ -216,6 +217,11 @@ type LogbackOptions struct {
 	MaxFileSize  string
 	MaxHistory   int
 	TotalSizeCap string
+
+	// ScanPeriod, when positive, turns on logback's configuration scanning: the file is re-read at
+	// this period and a changed level applies without a restart. Whole seconds; zero leaves
+	// scanning off.
+	ScanPeriod time.Duration
 }
 
 // GenerateLogbackWithOptions generates logback XML with optional file output.
@@ -229,7 +235,12 @@ func GenerateLogbackWithOptions(configs map[string]LoggerConfig, opts LogbackOpt
 	pattern = escapeXML(pattern)
 
 	var sb strings.Builder
-	sb.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<configuration>\n")
+	sb.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	if opts.ScanPeriod > 0 {
+		fmt.Fprintf(&sb, "<configuration scan=\"true\" scanPeriod=\"%d seconds\">\n", int64(opts.ScanPeriod/time.Second))
+	} else {
+		sb.WriteString("<configuration>\n")
+	}
 	fmt.Fprintf(&sb, `  <appender name="STDOUT" class="ch.qos.logback.core.ConsoleAppender">
 %s    <encoder>
       <pattern>%s</pattern>
@@ -436,6 +447,7 @@ func renderLogback(cfg LogConfig, opts RenderOptions) (string, error) {
 		MaxFileSize:    opts.MaxFileSize,
 		MaxHistory:     opts.MaxHistory,
 		TotalSizeCap:   opts.TotalSizeCap,
+		ScanPeriod:     opts.ReloadInterval,
 	})
 }
 
@@ -515,6 +527,11 @@ func renderLog4j2(cfg LogConfig, opts RenderOptions) (string, error) {
 
 	var sb strings.Builder
 	sb.WriteString("# Log4j2 Configuration\n")
+	if opts.ReloadInterval > 0 {
+		// The properties format's spelling of <Configuration monitorInterval="...">: log4j2 checks
+		// the file for changes at this interval, in seconds, and reconfigures in place.
+		fmt.Fprintf(&sb, "monitorInterval=%d\n", int64(opts.ReloadInterval/time.Second))
+	}
 	fmt.Fprintf(&sb, "rootLogger.level=%s\n", escapePropertyValue(string(rootLevel)))
 	// The appenderRefs line only declares reference identifiers; each identifier MUST be bound
 	// to an appender name via "rootLogger.appenderRef.<id>.ref=<AppenderName>", otherwise the
@@ -660,3 +677,210 @@ func renderPython(cfg LogConfig, opts RenderOptions) (string, error) {
 	fmt.Fprintf(&sb, "    'root': {\n        'level': '%s',\n        'handlers': %s,\n    },\n}\n", rootLevel, rootHandlers)
 	return sb.String(), nil
 }
+
+// toJULLevel converts a LogLevel to a java.util.logging level. JUL has no FATAL and names its
+// levels differently; DEBUG maps to FINE, the level JUL-based libraries log debug output at.
+func toJULLevel(level LogLevel) string {
+	switch level {
+	case LogLevelTrace:
+		return "FINEST"
+	case LogLevelDebug:
+		return "FINE"
+	case LogLevelWarn:
+		return "WARNING"
+	case LogLevelError, LogLevelFatal:
+		return "SEVERE"
+	default:
+		return "INFO"
+	}
+}
+
+// julGenerationSuffix is where renderJUL puts the FileHandler's generation number: before the
+// framework suffix, so every generation still ends in it (see renderJUL).
+const julGenerationSuffix = ".%g"
+
+// julFileHandlerPattern turns a log file path into a FileHandler pattern. '%' is the pattern's
+// escape character, so a literal one is doubled; the generation goes in front of the framework
+// suffix, because a FileHandler with more than one generation APPENDS it when the pattern has
+// none — "main.jul.xml.0", which no glob on the suffix matches.
+func julFileHandlerPattern(logFile string) string {
+	escaped := strings.ReplaceAll(logFile, "%", "%%")
+	suffix := LogFileSuffix(LoggingFrameworkJUL)
+	if strings.HasSuffix(escaped, suffix) {
+		return strings.TrimSuffix(escaped, suffix) + julGenerationSuffix + suffix
+	}
+	return escaped + julGenerationSuffix
+}
+
+// renderJUL renders a java.util.logging logging.properties from the framework-neutral model,
+// read by LogManager at startup (-Djava.util.logging.config.file).
+//
+// The console goes through SimpleFormatter, whose format property is the Pattern override. When
+// opts.FileOutputPath is set a FileHandler writes XMLFormatter records, which Vector's files_jul
+// source assembles on "<record>" and edge-parses. The file is the one format in this package whose
+// active file is NOT named exactly FileOutputPath: a FileHandler that rotates always numbers its
+// files, so generation 0 — the one being written — is "<container>.0.jul.xml", and rotation renames
+// it to ".1", ".2" and so on. All of them match the source glob, so the source tracks files by
+// inode rather than by their first line, which XMLFormatter writes identically into every file.
+func renderJUL(cfg LogConfig, opts RenderOptions) (string, error) {
+	pattern := opts.Pattern
+	if pattern == "" {
+		pattern = "%1$tF %1$tT %4$s %3$s - %5$s%6$s%n"
+	}
+	hasFile := opts.FileOutputPath != ""
+
+	var sb strings.Builder
+	sb.WriteString("# java.util.logging Configuration\n")
+	if hasFile {
+		sb.WriteString("handlers=java.util.logging.ConsoleHandler, java.util.logging.FileHandler\n")
+	} else {
+		sb.WriteString("handlers=java.util.logging.ConsoleHandler\n")
+	}
+	fmt.Fprintf(&sb, ".level=%s\n\n", toJULLevel(cfg.RootLevel))
+
+	sb.WriteString("# Handlers\n")
+	// A handler's own level defaults to INFO for the ConsoleHandler, which would cut a DEBUG root
+	// logger's output at the handler; ALL leaves the loggers in charge unless a threshold is set.
+	consoleLevel := "ALL"
+	if cfg.ConsoleLevel != "" {
+		consoleLevel = toJULLevel(cfg.ConsoleLevel)
+	}
+	fmt.Fprintf(&sb, "java.util.logging.ConsoleHandler.level=%s\n", consoleLevel)
+	sb.WriteString("java.util.logging.ConsoleHandler.formatter=java.util.logging.SimpleFormatter\n")
+	fmt.Fprintf(&sb, "java.util.logging.SimpleFormatter.format=%s\n", escapePropertyValue(pattern))
+	if hasFile {
+		fileLevel := "ALL"
+		if cfg.FileLevel != "" {
+			fileLevel = toJULLevel(cfg.FileLevel)
+		}
+		maxFileSize, maxHistory := boundedFileDefaults(opts.MaxFileSize, opts.MaxHistory)
+		sb.WriteString("\n")
+		fmt.Fprintf(&sb, "java.util.logging.FileHandler.level=%s\n", fileLevel)
+		fmt.Fprintf(&sb, "java.util.logging.FileHandler.pattern=%s\n", escapePropertyValue(julFileHandlerPattern(opts.FileOutputPath)))
+		// limit is per file and count includes the active one, so usage is bounded as everywhere
+		// else: limit * (history + 1).
+		fmt.Fprintf(&sb, "java.util.logging.FileHandler.limit=%d\n", parseSizeBytes(maxFileSize, 5*1024*1024))
+		fmt.Fprintf(&sb, "java.util.logging.FileHandler.count=%d\n", maxHistory+1)
+		sb.WriteString("java.util.logging.FileHandler.append=true\n")
+		sb.WriteString("java.util.logging.FileHandler.encoding=UTF-8\n")
+		sb.WriteString("java.util.logging.FileHandler.formatter=java.util.logging.XMLFormatter\n")
+	}
+
+	if len(cfg.Loggers) == 0 {
+		return sb.String(), nil
+	}
+	sb.WriteString("\n# Loggers\n")
+	for _, name := range sortedLoggerNames(cfg.Loggers) {
+		fmt.Fprintf(&sb, "%s.level=%s\n", escapePropertyKey(name), toJULLevel(cfg.Loggers[name]))
+	}
+	return sb.String(), nil
+}
+
+// toAirliftLevel converts a LogLevel to an Airlift level. Airlift knows DEBUG, INFO, WARN and
+// ERROR; the two ends fold onto their neighbours.
+func toAirliftLevel(level LogLevel) string {
+	switch level {
+	case LogLevelTrace, LogLevelDebug:
+		return string(LogLevelDebug)
+	case LogLevelWarn:
+		return string(LogLevelWarn)
+	case LogLevelError, LogLevelFatal:
+		return string(LogLevelError)
+	default:
+		return string(LogLevelInfo)
+	}
+}
+
+// renderAirlift renders an Airlift log.properties (Trino's log.levels-file): one "<logger>=<level>"
+// line per logger, the root logger under the empty name.
+//
+// That is ALL the file can hold. Airlift reads every key as a logger name, so the file output is
+// not configured here but in the product's config.properties (AirliftOutputProperties), and
+// opts.FileOutputPath, the console and file thresholds and the pattern have nothing to render
+// into. Airlift's JSON file format is what Vector's files_airlift source parses.
+func renderAirlift(cfg LogConfig, _ RenderOptions) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("# Airlift log levels\n")
+	fmt.Fprintf(&sb, "=%s\n", toAirliftLevel(cfg.RootLevel))
+	for _, name := range sortedLoggerNames(cfg.Loggers) {
+		fmt.Fprintf(&sb, "%s=%s\n", escapePropertyKey(name), toAirliftLevel(cfg.Loggers[name]))
+	}
+	return sb.String(), nil
+}
+
+// AirliftOutputProperties returns the config.properties entries that send an Airlift product's
+// log to logFile as JSON lines, bounded like every other file appender; nil when logFile is empty,
+// meaning console only. Pass the path the framework decided — RoleGroupBuildContext.LogFileTarget
+// — so the file is written only where the Vector pipeline collects it.
+//
+// Airlift rotates to "<log.path>-<date>.<n>.log", which the source glob does not match.
+func AirliftOutputProperties(logFile string, opts RenderOptions) map[string]string {
+	if logFile == "" {
+		return nil
+	}
+	maxFileSize, maxHistory := boundedFileDefaults(opts.MaxFileSize, opts.MaxHistory)
+	return map[string]string{
+		"log.path":        logFile,
+		"log.format":      "json",
+		"log.max-size":    maxFileSize,
+		"log.max-history": strconv.Itoa(maxHistory),
+	}
+}
+
+// shellQuote renders s as a single-quoted POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// renderJSONEnv renders the settings of a structured JSON logger — Go's slog or zap, Node's pino —
+// as POSIX shell assignments. Such loggers have no config file of their own: they take their level
+// and output from flags or the environment, and how is each product's choice. So the file is the
+// framework's half of a contract whose other half is the product's entrypoint, which sources it
+// ("set -a; . <file>; set +a") and passes the values on to its binary:
+//
+//	LOG_FORMAT             always "json"
+//	LOG_LEVEL              the root level, lowercase (trace, debug, info, warn, error, fatal)
+//	LOG_LEVELS             "<logger>=<level>" pairs, comma-separated; only when loggers are set
+//	LOG_CONSOLE_LEVEL      the console threshold; only when set
+//	LOG_FILE               the rolling log file, one JSON object per line; only with a file appender
+//	LOG_FILE_LEVEL         the file threshold; only when set
+//	LOG_FILE_MAX_BYTES     the size at which the product rotates the file
+//	LOG_FILE_MAX_BACKUPS   how many rotated files the product keeps
+//
+// Rotation is the product's (lumberjack, pino-roll, ...), and a rotated file must not keep the
+// ".log.json" suffix, or the source collects it twice. Vector's files_json source reads the field
+// names these libraries write by default: time/ts/timestamp, level (a name, or pino's number),
+// msg/message, logger/name.
+func renderJSONEnv(cfg LogConfig, opts RenderOptions) (string, error) {
+	level := func(l LogLevel) string {
+		if l == "" {
+			l = LogLevelInfo
+		}
+		return strings.ToLower(string(l))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Structured JSON logging configuration\n")
+	sb.WriteString("LOG_FORMAT='json'\n")
+	fmt.Fprintf(&sb, "LOG_LEVEL=%s\n", shellQuote(level(cfg.RootLevel)))
+	if len(cfg.Loggers) > 0 {
+		pairs := make([]string, 0, len(cfg.Loggers))
+		for _, name := range sortedLoggerNames(cfg.Loggers) {
+			pairs = append(pairs, name+"="+level(cfg.Loggers[name]))
+		}
+		fmt.Fprintf(&sb, "LOG_LEVELS=%s\n", shellQuote(strings.Join(pairs, ",")))
+	}
+	if cfg.ConsoleLevel != "" {
+		fmt.Fprintf(&sb, "LOG_CONSOLE_LEVEL=%s\n", shellQuote(level(cfg.ConsoleLevel)))
+	}
+	if opts.FileOutputPath != "" {
+		maxFileSize, maxHistory := boundedFileDefaults(opts.MaxFileSize, opts.MaxHistory)
+		fmt.Fprintf(&sb, "LOG_FILE=%s\n", shellQuote(opts.FileOutputPath))
+		if cfg.FileLevel != "" {
+			fmt.Fprintf(&sb, "LOG_FILE_LEVEL=%s\n", shellQuote(level(cfg.FileLevel)))
+		}
+		fmt.Fprintf(&sb, "LOG_FILE_MAX_BYTES='%d'\n", parseSizeBytes(maxFileSize, 5*1024*1024))
+		fmt.Fprintf(&sb, "LOG_FILE_MAX_BACKUPS='%d'\n", maxHistory)
+	}
+	return sb.String(), nil
+}
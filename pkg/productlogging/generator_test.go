@@ -876,6 +876,18 @@ var _ = Describe("LoggingFramework constants", func() {
 	It("should have correct LoggingFrameworkPython value", func() {
 		Expect(string(productlogging.LoggingFrameworkPython)).To(Equal("python"))
 	})
+
+	It("should have correct LoggingFrameworkJUL value", func() {
+		Expect(string(productlogging.LoggingFrameworkJUL)).To(Equal("jul"))
+	})
+
+	It("should have correct LoggingFrameworkAirlift value", func() {
+		Expect(string(productlogging.LoggingFrameworkAirlift)).To(Equal("airlift"))
+	})
+
+	It("should have correct LoggingFrameworkJSON value", func() {
+		Expect(string(productlogging.LoggingFrameworkJSON)).To(Equal("json"))
+	})
 })
 
 var _ = Describe("LoggerConfig", func() {
@@ -933,3 +945,127 @@ var _ = Describe("GenerateLogbackWithOptions", func() {
 		Expect(out).To(ContainSubstring(`<logger name="org.apache.zookeeper" level="DEBUG" />`))
 	})
 })
+
+var _ = Describe("java.util.logging generator", func() {
+	render := func(cfg productlogging.LogConfig, opts productlogging.RenderOptions) string {
+		gen, err := productlogging.GeneratorFor(productlogging.LoggingFrameworkJUL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gen.DefaultFileName()).To(Equal("logging.properties"))
+		out, err := gen.Render(cfg, opts)
+		Expect(err).NotTo(HaveOccurred())
+		return out
+	}
+
+	It("renders a console-only configuration with JUL level names", func() {
+		out := render(productlogging.LogConfig{
+			RootLevel: productlogging.LogLevelWarn,
+			Loggers: map[string]productlogging.LogLevel{
+				"org.example.noisy": productlogging.LogLevelTrace,
+				"org.example.app":   productlogging.LogLevelDebug,
+			},
+			ConsoleLevel: productlogging.LogLevelError,
+		}, productlogging.RenderOptions{})
+		Expect(out).To(ContainSubstring("handlers=java.util.logging.ConsoleHandler\n"))
+		Expect(out).To(ContainSubstring(".level=WARNING\n"))
+		Expect(out).To(ContainSubstring("java.util.logging.ConsoleHandler.level=SEVERE\n"))
+		Expect(out).To(ContainSubstring("org.example.app.level=FINE\n"))
+		Expect(out).To(ContainSubstring("org.example.noisy.level=FINEST\n"))
+		Expect(out).NotTo(ContainSubstring("FileHandler"))
+	})
+
+	It("adds a bounded XMLFormatter file handler whose generations keep the collected suffix", func() {
+		// Without "%g" in the pattern a rotating FileHandler appends the generation after the
+		// suffix ("main.jul.xml.0"), which the files_jul glob does not match.
+		out := render(productlogging.LogConfig{FileLevel: productlogging.LogLevelInfo}, productlogging.RenderOptions{
+			FileOutputPath: "/kubedoop/log/main/main.jul.xml",
+		})
+		Expect(out).To(ContainSubstring("handlers=java.util.logging.ConsoleHandler, java.util.logging.FileHandler\n"))
+		Expect(out).To(ContainSubstring("java.util.logging.FileHandler.pattern=/kubedoop/log/main/main.%g.jul.xml\n"))
+		Expect(out).To(ContainSubstring("java.util.logging.FileHandler.limit=5242880\n"))
+		Expect(out).To(ContainSubstring("java.util.logging.FileHandler.count=2\n"))
+		Expect(out).To(ContainSubstring("java.util.logging.FileHandler.formatter=java.util.logging.XMLFormatter\n"))
+		Expect(out).To(ContainSubstring("java.util.logging.FileHandler.level=INFO\n"))
+		Expect(out).To(ContainSubstring("java.util.logging.ConsoleHandler.level=ALL\n"))
+	})
+
+	It("escapes a literal percent sign in the file path", func() {
+		out := render(productlogging.LogConfig{}, productlogging.RenderOptions{
+			FileOutputPath: "/kubedoop/log/main/50%.jul.xml",
+		})
+		Expect(out).To(ContainSubstring("java.util.logging.FileHandler.pattern=/kubedoop/log/main/50%%.%g.jul.xml\n"))
+	})
+})
+
+var _ = Describe("Airlift generator", func() {
+	It("renders only logger levels, with the root logger under the empty name", func() {
+		gen, err := productlogging.GeneratorFor(productlogging.LoggingFrameworkAirlift)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gen.DefaultFileName()).To(Equal("log.properties"))
+		out, err := gen.Render(productlogging.LogConfig{
+			RootLevel: productlogging.LogLevelFatal,
+			Loggers: map[string]productlogging.LogLevel{
+				"io.trino":          productlogging.LogLevelTrace,
+				"io.trino.security": productlogging.LogLevelWarn,
+			},
+		}, productlogging.RenderOptions{FileOutputPath: "/kubedoop/log/trino/trino.airlift.json"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out).To(Equal("# Airlift log levels\n=ERROR\nio.trino=DEBUG\nio.trino.security=WARN\n"))
+	})
+
+	It("puts the bounded JSON file output in the product's config.properties entries", func() {
+		Expect(productlogging.AirliftOutputProperties("", productlogging.RenderOptions{})).To(BeNil())
+		Expect(productlogging.AirliftOutputProperties("/kubedoop/log/trino/trino.airlift.json", productlogging.RenderOptions{
+			MaxFileSize: "10MB",
+			MaxHistory:  3,
+		})).To(Equal(map[string]string{
+			"log.path":        "/kubedoop/log/trino/trino.airlift.json",
+			"log.format":      "json",
+			"log.max-size":    "10MB",
+			"log.max-history": "3",
+		}))
+	})
+})
+
+var _ = Describe("structured JSON generator", func() {
+	render := func(cfg productlogging.LogConfig, opts productlogging.RenderOptions) string {
+		gen, err := productlogging.GeneratorFor(productlogging.LoggingFrameworkJSON)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gen.DefaultFileName()).To(Equal("logging.env"))
+		out, err := gen.Render(cfg, opts)
+		Expect(err).NotTo(HaveOccurred())
+		return out
+	}
+
+	It("renders lowercase levels as shell assignments", func() {
+		out := render(productlogging.LogConfig{
+			Loggers: map[string]productlogging.LogLevel{
+				"server": productlogging.LogLevelWarn,
+				"db":     productlogging.LogLevelDebug,
+			},
+			ConsoleLevel: productlogging.LogLevelInfo,
+		}, productlogging.RenderOptions{})
+		Expect(out).To(ContainSubstring("LOG_FORMAT='json'\n"))
+		Expect(out).To(ContainSubstring("LOG_LEVEL='info'\n"))
+		Expect(out).To(ContainSubstring("LOG_LEVELS='db=debug,server=warn'\n"))
+		Expect(out).To(ContainSubstring("LOG_CONSOLE_LEVEL='info'\n"))
+		Expect(out).NotTo(ContainSubstring("LOG_FILE"))
+	})
+
+	It("adds the bounded file output when a file appender is requested", func() {
+		out := render(productlogging.LogConfig{FileLevel: productlogging.LogLevelError}, productlogging.RenderOptions{
+			FileOutputPath: "/kubedoop/log/app/app.log.json",
+			MaxHistory:     4,
+		})
+		Expect(out).To(ContainSubstring("LOG_FILE='/kubedoop/log/app/app.log.json'\n"))
+		Expect(out).To(ContainSubstring("LOG_FILE_LEVEL='error'\n"))
+		Expect(out).To(ContainSubstring("LOG_FILE_MAX_BYTES='5242880'\n"))
+		Expect(out).To(ContainSubstring("LOG_FILE_MAX_BACKUPS='4'\n"))
+	})
+
+	It("quotes a value carrying a single quote so the file stays sourceable", func() {
+		out := render(productlogging.LogConfig{}, productlogging.RenderOptions{
+			FileOutputPath: "/kubedoop/log/app/it's.log.json",
+		})
+		Expect(out).To(ContainSubstring(`LOG_FILE='/kubedoop/log/app/it'"'"'s.log.json'`))
+	})
+})
@@ -15,7 +15,8 @@ limitations under the License.
 */
 
 // Package productlogging provides a product-agnostic logging engine: it converts a CRD
-// LoggingConfigSpec into framework-specific logging config files (logback / log4j / log4j2 / python),
+// LoggingConfigSpec into framework-specific logging config files (logback / log4j / log4j2 / python /
+// java.util.logging / airlift / structured JSON),
 // deep-merges role/role-group logging, and exposes a generator registry. It depends only on
 // the commons API types, so both pkg/config and pkg/reconciler (and product operators) can
 // build on it without import cycles.
@@ -27,6 +28,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
 	"github.com/zncdatadev/operator-go/pkg/constant"
@@ -39,8 +41,8 @@ type ContainerLogging struct {
 	// Container is the container name; its merged logging spec (CRD logging.containers.<name>)
 	// drives the generated file.
 	Container string
-	// Framework selects the output format (logback / log4j / log4j2 / python), and by being set at
-	// all it says the FRAMEWORK renders this container's config file.
+	// Framework selects the output format (logback / log4j / log4j2 / python / jul / airlift /
+	// json), and by being set at all it says the FRAMEWORK renders this container's config file.
 	//
 	// EMPTY means the product writes the file itself, and then LogFileName is required. The
 	// container still joins the Vector pipeline in every other respect — the shared log volume, its
@@ -221,15 +223,15 @@ func ValidateProducers(decls []ContainerLogging) error {
 //
 // They are the reason this list is not simply the generator registry's. The pipeline carries a
 // source and an edge parser for each of them: `.stdout.log` and `.stderr.log` are the redirect
-// convention a product's entrypoint uses, and `.airlift.json` is Trino's own logger. Deriving the
-// allow-list from the four frameworks this package can RENDER answered a different question than the
-// one being asked — "can we render it" rather than "does anything collect it" — and rejected exactly
-// the formats the product-renders-its-own-file seam exists to serve.
+// convention a product's entrypoint uses. Deriving the allow-list from the frameworks this package
+// can RENDER answered a different question than the one being asked — "can we render it" rather
+// than "does anything collect it" — and rejected exactly the formats the product-renders-its-own-file
+// seam exists to serve. (`.airlift.json` was the third until Airlift became a framework.)
 //
 // pkg/vector cannot be imported here (it imports this package), so the coupling is pinned from the
 // other side: a test in pkg/vector asserts the rendered vector.yaml's source globs are exactly
 // KnownLogFileSuffixes(), and fails if either list moves without the other.
-var ProductRenderedLogFileSuffixes = []string{".airlift.json", ".stderr.log", ".stdout.log"}
+var ProductRenderedLogFileSuffixes = []string{".stderr.log", ".stdout.log"}
 
 // KnownLogFileSuffixes returns every rolling log-file suffix the Vector pipeline globs on, sorted
 // so the set is stable in an error message. A product writing its own log file must use one of
@@ -275,6 +277,14 @@ const (
 	LoggingFrameworkLog4j2  LoggingFramework = "log4j2"
 	LoggingFrameworkLogback LoggingFramework = "logback"
 	LoggingFrameworkPython  LoggingFramework = "python"
+	// LoggingFrameworkJUL is java.util.logging (logging.properties).
+	LoggingFrameworkJUL LoggingFramework = "jul"
+	// LoggingFrameworkAirlift is Airlift's logger (Trino's log.properties); see
+	// AirliftOutputProperties for the half of its configuration that lives elsewhere.
+	LoggingFrameworkAirlift LoggingFramework = "airlift"
+	// LoggingFrameworkJSON is a structured JSON logger configured through the environment (Go's
+	// slog or zap, Node's pino); see renderJSONEnv for the contract.
+	LoggingFrameworkJSON LoggingFramework = "json"
 )
 
 // loggingFrameworkSpec holds everything the framework layer derives from one logging framework.
@@ -286,13 +296,33 @@ type loggingFrameworkSpec struct {
 	logFileSuffix string
 	// generator renders the framework's config file.
 	generator LogFileGenerator
+	// reloads reports whether the framework re-reads its config file by itself once the file says
+	// so (see SupportsReload).
+	reloads bool
 }
 
 var loggingFrameworks = map[LoggingFramework]loggingFrameworkSpec{
 	LoggingFrameworkLog4j:   {logFileSuffix: ".log4j.xml", generator: log4jGenerator{}},
-	LoggingFrameworkLogback: {logFileSuffix: ".log4j.xml", generator: logbackGenerator{}},
-	LoggingFrameworkLog4j2:  {logFileSuffix: ".log4j2.xml", generator: log4j2Generator{}},
+	LoggingFrameworkLogback: {logFileSuffix: ".log4j.xml", generator: logbackGenerator{}, reloads: true},
+	LoggingFrameworkLog4j2:  {logFileSuffix: ".log4j2.xml", generator: log4j2Generator{}, reloads: true},
 	LoggingFrameworkPython:  {logFileSuffix: ".py.json", generator: pythonGenerator{}},
+	LoggingFrameworkJUL:     {logFileSuffix: ".jul.xml", generator: julGenerator{}},
+	LoggingFrameworkAirlift: {logFileSuffix: ".airlift.json", generator: airliftGenerator{}},
+	LoggingFrameworkJSON:    {logFileSuffix: ".log.json", generator: jsonEnvGenerator{}},
+}
+
+// SupportsReload reports whether a framework's generated config file can switch on the
+// framework's own reloading, so a rewritten file takes effect without a restart: logback's `scan`
+// and log4j2's `monitorInterval`.
+//
+// The others cannot. log4j's equivalent, PropertyConfigurator.configureAndWatch, is an API the
+// application has to call — nothing in log4j.properties turns it on — and Python's
+// logging.config.listen is a socket the application has to open; JUL re-reads only when the
+// application calls LogManager.readConfiguration, Airlift reads its levels file once at startup,
+// and a structured JSON logger's environment is fixed for the life of the process. A product whose
+// code reloads regardless of what this says; the framework cannot know, so it does not promise.
+func SupportsReload(framework LoggingFramework) bool {
+	return loggingFrameworks[framework].reloads
 }
 
 // SupportedLoggingFrameworks returns every framework this package can generate a config file for,
@@ -316,7 +346,11 @@ func SupportedLoggingFrameworks() []LoggingFramework {
 //
 //   - log4j and logback write log4j 1.x XMLLayout events -> ".log4j.xml" (files_log4j),
 //   - log4j2 writes log4j2 XMLLayout events -> ".log4j2.xml" (files_log4j2),
-//   - python writes JSON lines -> ".py.json" (files_py).
+//   - python writes JSON lines -> ".py.json" (files_py),
+//   - jul writes XMLFormatter records -> ".jul.xml" (files_jul; the active file is
+//     "<container>.0.jul.xml", see renderJUL),
+//   - airlift writes JSON lines -> ".airlift.json" (files_airlift),
+//   - json (slog / zap / pino) writes JSON lines -> ".log.json" (files_json).
 //
 // Unknown frameworks return "" (RenderConfigFile rejects them via GeneratorFor first).
 func LogFileSuffix(framework LoggingFramework) string {
@@ -359,21 +393,36 @@ func ContainerLogDir(container string) string {
 // path. The path convention is framework-owned (ContainerLogDir + ContainerLogFileName),
 // not product-supplied.
 func RenderConfigFile(spec *v1alpha1.LoggingConfigSpec, decl ContainerLogging, withFileAppender bool) (string, string, error) {
+	return RenderConfigFileWithOptions(spec, decl, ConfigFileOptions{FileAppender: withFileAppender})
+}
+
+// ConfigFileOptions are the parts of a rendered config file the framework decides rather than the
+// CRD or the product's declaration.
+type ConfigFileOptions struct {
+	// FileAppender adds the rolling file appender; see RenderConfigFile.
+	FileAppender bool
+	// ReloadInterval, when positive, has the framework re-read the file at this interval (whole
+	// seconds). Only frameworks for which SupportsReload is true accept it.
+	ReloadInterval time.Duration
+}
+
+// RenderConfigFileWithOptions is RenderConfigFile with every framework-decided option.
+func RenderConfigFileWithOptions(spec *v1alpha1.LoggingConfigSpec, decl ContainerLogging, options ConfigFileOptions) (string, string, error) {
 	gen, err := GeneratorFor(decl.Framework)
 	if err != nil {
 		return "", "", err
 	}
+	if err := validateReloadInterval(decl.Framework, options.ReloadInterval); err != nil {
+		return "", "", fmt.Errorf("container %q: %w", decl.Container, err)
+	}
 	// Outside the withFileAppender branch on purpose: an unusable logDirName is a mistake in the
 	// declaration, and whether it is caught should not depend on whether Vector is on this cycle.
 	if err := validateLogDirName(decl); err != nil {
 		return "", "", err
 	}
-	fileName := decl.FileName
-	if fileName == "" {
-		fileName = gen.DefaultFileName()
-	}
-	opts := RenderOptions{Pattern: decl.Pattern}
-	if withFileAppender {
+	fileName := configFileName(decl, gen)
+	opts := RenderOptions{Pattern: decl.Pattern, ReloadInterval: options.ReloadInterval}
+	if options.FileAppender {
 		logFileName := ContainerLogFileName(decl.Framework, decl.Container)
 		if decl.LogFileName != "" {
 			if !strings.HasSuffix(decl.LogFileName, LogFileSuffix(decl.Framework)) {
@@ -399,6 +448,40 @@ func RenderConfigFile(spec *v1alpha1.LoggingConfigSpec, decl ContainerLogging, w
 	return fileName, content, nil
 }
 
+// ConfigFileName returns the name of the config file the framework renders for a producer: its
+// FileName, or the generator's default (e.g. "logback.xml"). A product pointing its process at the
+// file — a -Dlogback.configurationFile flag, a LOG4J_CONFIGURATION_FILE variable — derives the
+// name here rather than repeating the default.
+func ConfigFileName(decl ContainerLogging) (string, error) {
+	gen, err := GeneratorFor(decl.Framework)
+	if err != nil {
+		return "", err
+	}
+	return configFileName(decl, gen), nil
+}
+
+func configFileName(decl ContainerLogging, gen LogFileGenerator) string {
+	if decl.FileName != "" {
+		return decl.FileName
+	}
+	return gen.DefaultFileName()
+}
+
+// validateReloadInterval rejects an interval the framework's config file cannot express. A config
+// that silently does not reload is the failure to avoid: the level change would wait for a
+// restart that, once reloading is promised, nothing schedules.
+func validateReloadInterval(framework LoggingFramework, interval time.Duration) error {
+	switch {
+	case interval == 0:
+		return nil
+	case interval < 0 || interval%time.Second != 0:
+		return fmt.Errorf("reload interval %s must be a positive number of whole seconds", interval)
+	case !SupportsReload(framework):
+		return fmt.Errorf("logging framework %q cannot reload its config file by itself", framework)
+	}
+	return nil
+}
+
 // RootLoggerName is the reserved logger key in LoggingConfigSpec.Loggers that sets the
 // root logger level. All other keys become named loggers.
 const RootLoggerName = "ROOT"
@@ -476,6 +559,10 @@ type RenderOptions struct {
 	MaxFileSize  string
 	MaxHistory   int
 	TotalSizeCap string
+	// ReloadInterval, when positive, switches on the framework's own config reloading (logback
+	// scan, log4j2 monitorInterval) at this interval. Generators of frameworks that have no such
+	// switch ignore it; RenderConfigFileWithOptions rejects it for them before rendering.
+	ReloadInterval time.Duration
 }
 
 // LogFileGenerator renders a logging configuration file for one logging framework from the
@@ -529,6 +616,28 @@ func (pythonGenerator) Render(cfg LogConfig, opts RenderOptions) (string, error)
 	return renderPython(cfg, opts)
 }
 
+type julGenerator struct{}
+
+func (julGenerator) DefaultFileName() string { return "logging.properties" }
+func (julGenerator) Render(cfg LogConfig, opts RenderOptions) (string, error) {
+	return renderJUL(cfg, opts)
+}
+
+type airliftGenerator struct{}
+
+func (airliftGenerator) DefaultFileName() string { return "log.properties" }
+func (airliftGenerator) Render(cfg LogConfig, opts RenderOptions) (string, error) {
+	return renderAirlift(cfg, opts)
+}
+
+// The file is sourced by the product's entrypoint, not read by a library, hence the ".env" name.
+type jsonEnvGenerator struct{}
+
+func (jsonEnvGenerator) DefaultFileName() string { return "logging.env" }
+func (jsonEnvGenerator) Render(cfg LogConfig, opts RenderOptions) (string, error) {
+	return renderJSONEnv(cfg, opts)
+}
+
 // MergeLoggingSpec deep-merges role-level and roleGroup-level logging specs. RoleGroup values
 // win at the leaf: containers are unioned by name, loggers within a container are unioned by
 // name (group overrides per key), and Console / File / EnableVectorAgent override only when
@@ -569,6 +678,13 @@ func mergeContainerLogging(role, group v1alpha1.LoggingConfigSpec) v1alpha1.Logg
 	merged := v1alpha1.LoggingConfigSpec{
 		Console: role.Console,
 		File:    role.File,
+		Filter:  role.Filter,
+	}
+	// The filter is a rule set, not a leaf: merging two include lists, or a role's redactions with
+	// a group's, has no answer that is right for every case, so the group's rules replace the
+	// role's whole — and `filter: {}` is how a group turns them off.
+	if group.Filter != nil {
+		merged.Filter = group.Filter
 	}
 	// Only override when the group actually sets a level, so a role group supplying an empty
 	// console/file (e.g. `console: {}`) does not silently wipe the role-level threshold.
@@ -17,6 +17,8 @@ limitations under the License.
 package productlogging_test
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -62,6 +64,9 @@ var _ = Describe("SupportedLoggingFrameworks", func() {
 			productlogging.LoggingFrameworkLog4j2,
 			productlogging.LoggingFrameworkLogback,
 			productlogging.LoggingFrameworkPython,
+			productlogging.LoggingFrameworkJUL,
+			productlogging.LoggingFrameworkAirlift,
+			productlogging.LoggingFrameworkJSON,
 		))
 	})
 
@@ -102,6 +107,16 @@ var _ = Describe("RenderConfigFile file path", func() {
 			}, true)
 			Expect(err).NotTo(HaveOccurred(), "framework %s", fw)
 			expected := "/kubedoop/log/main/Main" + productlogging.LogFileSuffix(fw)
+			switch fw {
+			case productlogging.LoggingFrameworkJUL:
+				// The FileHandler numbers its generations; the active one is generation 0.
+				expected = "/kubedoop/log/main/Main.%g" + productlogging.LogFileSuffix(fw)
+			case productlogging.LoggingFrameworkAirlift:
+				// log.properties holds levels only; the path goes to config.properties.
+				Expect(productlogging.AirliftOutputProperties(expected, productlogging.RenderOptions{})).To(
+					HaveKeyWithValue("log.path", expected))
+				continue
+			}
 			Expect(content).To(ContainSubstring(expected), "framework %s", fw)
 			Expect(content).NotTo(ContainSubstring("//main"), "framework %s", fw)
 		}
@@ -334,6 +349,24 @@ var _ = Describe("MergeLoggingSpec", func() {
 		c := productlogging.MergeLoggingSpec(role, group).Containers["main"]
 		Expect(c.Loggers["ROOT"].Level).To(Equal("WARN"))
 	})
+
+	It("replaces the role's filter whole, and inherits it when the group sets none", func() {
+		role := &v1alpha1.LoggingSpec{
+			Containers: map[string]v1alpha1.LoggingConfigSpec{
+				"main":  {Filter: &v1alpha1.LogFilterSpec{MinLevel: "INFO", ExcludeLoggers: []string{"noisy"}}},
+				"other": {Filter: &v1alpha1.LogFilterSpec{MinLevel: "WARN"}},
+			},
+		}
+		group := &v1alpha1.LoggingSpec{
+			Containers: map[string]v1alpha1.LoggingConfigSpec{
+				"main":  {Filter: &v1alpha1.LogFilterSpec{SampleRate: 10}},
+				"other": {Console: &v1alpha1.LogLevelSpec{Level: "DEBUG"}},
+			},
+		}
+		merged := productlogging.MergeLoggingSpec(role, group)
+		Expect(merged.Containers["main"].Filter).To(Equal(&v1alpha1.LogFilterSpec{SampleRate: 10}))
+		Expect(merged.Containers["other"].Filter).To(Equal(&v1alpha1.LogFilterSpec{MinLevel: "WARN"}))
+	})
 })
 
 var _ = Describe("Render with appender thresholds", func() {
@@ -469,3 +502,55 @@ var _ = Describe("A producer whose config file the product writes", func() {
 		Expect(err.Error()).To(ContainSubstring("both write their log file to"))
 	})
 })
+
+var _ = Describe("RenderConfigFileWithOptions reload", func() {
+	render := func(framework productlogging.LoggingFramework, interval time.Duration) (string, error) {
+		_, content, err := productlogging.RenderConfigFileWithOptions(nil, productlogging.ContainerLogging{
+			Framework: framework,
+			Container: "main",
+		}, productlogging.ConfigFileOptions{ReloadInterval: interval})
+		return content, err
+	}
+
+	It("turns on logback scanning at the interval", func() {
+		content, err := render(productlogging.LoggingFrameworkLogback, 30*time.Second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content).To(ContainSubstring(`<configuration scan="true" scanPeriod="30 seconds">`))
+	})
+
+	It("sets the log4j2 monitor interval", func() {
+		content, err := render(productlogging.LoggingFrameworkLog4j2, time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(content).To(ContainSubstring("monitorInterval=60\n"))
+	})
+
+	It("renders exactly as before without an interval", func() {
+		for _, fw := range productlogging.SupportedLoggingFrameworks() {
+			withOptions, err := render(fw, 0)
+			Expect(err).NotTo(HaveOccurred(), "framework %s", fw)
+			_, plain, err := productlogging.RenderConfigFile(nil, productlogging.ContainerLogging{
+				Framework: fw,
+				Container: "main",
+			}, false)
+			Expect(err).NotTo(HaveOccurred(), "framework %s", fw)
+			Expect(withOptions).To(Equal(plain), "framework %s", fw)
+		}
+	})
+
+	It("rejects an interval for a framework that cannot reload by itself", func() {
+		for _, fw := range []productlogging.LoggingFramework{
+			productlogging.LoggingFrameworkLog4j, productlogging.LoggingFrameworkPython,
+		} {
+			Expect(productlogging.SupportsReload(fw)).To(BeFalse(), "framework %s", fw)
+			_, err := render(fw, 30*time.Second)
+			Expect(err).To(HaveOccurred(), "framework %s", fw)
+		}
+	})
+
+	It("rejects an interval that is not whole seconds", func() {
+		_, err := render(productlogging.LoggingFrameworkLogback, 1500*time.Millisecond)
+		Expect(err).To(HaveOccurred())
+		_, err = render(productlogging.LoggingFrameworkLogback, -time.Second)
+		Expect(err).To(HaveOccurred())
+	})
+})
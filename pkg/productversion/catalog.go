@@ -0,0 +1,171 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package productversion holds a product's version catalog: the product versions its operator
+// supports, the ones it is retiring, the upgrades between them that are safe, and the config
+// defaults that differ from one version to the next. It depends only on the commons API types, so
+// both pkg/webhook, which refuses an unsafe upgrade at admission, and pkg/reconciler, which refuses
+// it again for a cluster the webhook never saw, read the same catalog without an import cycle.
+package productversion
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+)
+
+// Catalog is everything a product's operator knows about the product versions it can run. The zero
+// value, and a nil *Catalog, accept every version and every upgrade — the behaviour before there
+// was a catalog — so a product opts in by declaring one.
+//
+// Versions are compared as strings, exactly as spec.image.productVersion states them. The catalog
+// does not parse them as semver: product versions are not reliably semver ("3.9.2", "24.11.1",
+// "1.16.3-hotfix1"), and an ordering the catalog inferred would be the rule "no skipping major
+// versions" guessed at rather than stated. The product states its edges instead.
+type Catalog struct {
+	// Versions are the supported product versions, keyed by version. A version not in the map is
+	// unsupported and is refused. An empty map supports every version, and then only Upgrades
+	// constrains anything.
+	Versions map[string]Version
+
+	// Upgrades lists, for each version, the versions a running cluster may move to DIRECTLY. Staying
+	// on a version is always allowed and need not be listed. A version absent from the map may not
+	// be left at all, downgrades included; a nil map allows every move between supported versions.
+	//
+	// Edges are not followed transitively, which is the point: with 3.7 → 3.8 and 3.8 → 3.9 listed,
+	// 3.7 → 3.9 is refused, and the user upgrades through 3.8.
+	//
+	// An edge may start at a version no longer in Versions. That is how a product retires a
+	// version without stranding the clusters still on it: dropping 3.7 from Versions refuses it to
+	// new clusters and fails the reconcile of running ones, and keeping 3.7 → 3.8 here is the way
+	// out. Without such an edge a cluster on a retired version cannot be moved at all.
+	Upgrades map[string][]string
+}
+
+// Version describes one supported product version.
+type Version struct {
+	// Deprecated marks a version that still runs but is on its way out. Choosing it, or staying on
+	// it, is allowed with a warning.
+	Deprecated bool
+
+	// DeprecationMessage, when set, replaces the generic warning text — typically naming the
+	// version to move to.
+	DeprecationMessage string
+
+	// ConfigDefaults are per-role config defaults for this version, keyed by role name. They fold
+	// BENEATH RoleDeclaration.ConfigDefaults, so the declaration stays the product's general answer
+	// and this is only what differs for one version: a heap default a release raised, a data volume
+	// a release started to need. A role absent from the map has no version-specific defaults.
+	ConfigDefaults map[string]*v1alpha1.RoleGroupConfigSpec
+}
+
+// Validate checks the catalog is self-consistent: every upgrade edge must end at a supported
+// version. A dangling edge is a typo in a catalog a product ships, and left alone it would allow an
+// upgrade to a version the catalog refuses. An edge may start anywhere: one from a version not in
+// Versions is the way off a retired version (see Upgrades). Call it once, at operator start.
+func (c *Catalog) Validate() error {
+	if c == nil || len(c.Versions) == 0 {
+		return nil
+	}
+	var problems []string
+	for _, from := range sortedKeys(c.Upgrades) {
+		for _, to := range c.Upgrades[from] {
+			if !c.supports(to) {
+				problems = append(problems, fmt.Sprintf("upgrade %q -> %q ends at an unsupported version", from, to))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Check reports whether version may run: an error for an unsupported version, a warning for a
+// deprecated one. An empty version is accepted silently — it means the framework did not resolve
+// one, so there is nothing to judge.
+func (c *Catalog) Check(version string) (warning string, err error) {
+	if c == nil || version == "" || len(c.Versions) == 0 {
+		return "", nil
+	}
+	v, ok := c.Versions[version]
+	if !ok {
+		return "", fmt.Errorf("product version %q is not supported; supported versions are %s",
+			version, strings.Join(c.SupportedVersions(), ", "))
+	}
+	if !v.Deprecated {
+		return "", nil
+	}
+	if v.DeprecationMessage != "" {
+		return fmt.Sprintf("product version %q is deprecated: %s", version, v.DeprecationMessage), nil
+	}
+	return fmt.Sprintf("product version %q is deprecated", version), nil
+}
+
+// CheckUpgrade reports whether a running cluster may move from one version to another. Either
+// side being empty — a cluster not yet deployed, or a version the framework did not resolve — is
+// not an upgrade and is accepted. Whether `to` itself is supported is Check's question.
+func (c *Catalog) CheckUpgrade(from, to string) error {
+	if c == nil || c.Upgrades == nil || from == "" || to == "" || from == to {
+		return nil
+	}
+	allowed := c.Upgrades[from]
+	if slices.Contains(allowed, to) {
+		return nil
+	}
+	if len(allowed) == 0 {
+		return fmt.Errorf("product version %q cannot be changed to %q: no upgrade from %q is supported",
+			from, to, from)
+	}
+	targets := slices.Clone(allowed)
+	sort.Strings(targets)
+	return fmt.Errorf("product version %q cannot be changed to %q: supported upgrades from %q are to %s",
+		from, to, from, strings.Join(targets, ", "))
+}
+
+// ConfigDefaults returns version's config defaults for a role, or nil.
+func (c *Catalog) ConfigDefaults(version, roleName string) *v1alpha1.RoleGroupConfigSpec {
+	if c == nil || version == "" {
+		return nil
+	}
+	return c.Versions[version].ConfigDefaults[roleName]
+}
+
+// SupportedVersions returns the supported versions, sorted, for messages.
+func (c *Catalog) SupportedVersions() []string {
+	if c == nil {
+		return nil
+	}
+	return sortedKeys(c.Versions)
+}
+
+func (c *Catalog) supports(version string) bool {
+	_, ok := c.Versions[version]
+	return ok
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package productversion_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/productversion"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("Catalog", func() {
+	var catalog *productversion.Catalog
+
+	BeforeEach(func() {
+		catalog = &productversion.Catalog{
+			Versions: map[string]productversion.Version{
+				"3.7.1": {Deprecated: true, DeprecationMessage: "upgrade to 3.8.0"},
+				"3.8.0": {},
+				"3.9.0": {ConfigDefaults: map[string]*v1alpha1.RoleGroupConfigSpec{
+					"broker": {GracefulShutdownTimeout: ptr.To("5m")},
+				}},
+			},
+			Upgrades: map[string][]string{
+				"3.7.1": {"3.8.0"},
+				"3.8.0": {"3.9.0"},
+			},
+		}
+	})
+
+	Describe("Validate", func() {
+		It("accepts a consistent catalog", func() {
+			Expect(catalog.Validate()).To(Succeed())
+		})
+
+		It("names every edge ending at an unsupported version", func() {
+			catalog.Upgrades["3.8.0"] = []string{"3.9.0", "3.9.9"}
+			catalog.Upgrades["3.9.0"] = []string{"4.0.0"}
+			err := catalog.Validate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal(`upgrade "3.8.0" -> "3.9.9" ends at an unsupported version; ` +
+				`upgrade "3.9.0" -> "4.0.0" ends at an unsupported version`))
+		})
+
+		It("accepts an edge leaving a retired version", func() {
+			catalog.Upgrades["3.6.0"] = []string{"3.7.1"}
+			Expect(catalog.Validate()).To(Succeed())
+		})
+
+		It("accepts a nil catalog", func() {
+			var nilCatalog *productversion.Catalog
+			Expect(nilCatalog.Validate()).To(Succeed())
+		})
+	})
+
+	Describe("Check", func() {
+		It("accepts a supported version silently", func() {
+			warning, err := catalog.Check("3.8.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warning).To(BeEmpty())
+		})
+
+		It("warns on a deprecated version", func() {
+			warning, err := catalog.Check("3.7.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warning).To(Equal(`product version "3.7.1" is deprecated: upgrade to 3.8.0`))
+		})
+
+		It("refuses an unsupported version and lists the supported ones", func() {
+			_, err := catalog.Check("3.6.0")
+			Expect(err).To(MatchError(`product version "3.6.0" is not supported; ` +
+				`supported versions are 3.7.1, 3.8.0, 3.9.0`))
+		})
+
+		It("accepts an unresolved version", func() {
+			_, err := catalog.Check("")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("CheckUpgrade", func() {
+		It("allows a listed edge and staying put", func() {
+			Expect(catalog.CheckUpgrade("3.7.1", "3.8.0")).To(Succeed())
+			Expect(catalog.CheckUpgrade("3.9.0", "3.9.0")).To(Succeed())
+		})
+
+		It("does not follow edges transitively", func() {
+			Expect(catalog.CheckUpgrade("3.7.1", "3.9.0")).To(MatchError(
+				`product version "3.7.1" cannot be changed to "3.9.0": supported upgrades from "3.7.1" are to 3.8.0`))
+		})
+
+		It("refuses a downgrade", func() {
+			Expect(catalog.CheckUpgrade("3.9.0", "3.8.0")).To(MatchError(
+				`product version "3.9.0" cannot be changed to "3.8.0": no upgrade from "3.9.0" is supported`))
+		})
+
+		It("allows any move when no edges are declared", func() {
+			catalog.Upgrades = nil
+			Expect(catalog.CheckUpgrade("3.9.0", "3.7.1")).To(Succeed())
+		})
+
+		It("follows the edges out of a retired version", func() {
+			delete(catalog.Versions, "3.7.1")
+			Expect(catalog.Validate()).To(Succeed())
+			Expect(catalog.CheckUpgrade("3.7.1", "3.8.0")).To(Succeed())
+			Expect(catalog.CheckUpgrade("3.7.1", "3.9.0")).To(HaveOccurred())
+		})
+
+		It("treats an undeployed cluster as no upgrade", func() {
+			Expect(catalog.CheckUpgrade("", "3.9.0")).To(Succeed())
+		})
+	})
+
+	Describe("ConfigDefaults", func() {
+		It("returns the role's defaults for the version", func() {
+			Expect(catalog.ConfigDefaults("3.9.0", "broker").GracefulShutdownTimeout).To(HaveValue(Equal("5m")))
+			Expect(catalog.ConfigDefaults("3.9.0", "controller")).To(BeNil())
+			Expect(catalog.ConfigDefaults("3.8.0", "broker")).To(BeNil())
+		})
+	})
+})
@@ -97,6 +97,9 @@ func EncodeAffinity(affinity *corev1.Affinity) (*k8sruntime.RawExtension, error)
 // wants by default, and is named here so a product does not re-type the string.
 const TopologyKeyHostname = "kubernetes.io/hostname"
 
+// TopologyKeyZone spreads across zones, the failure domain a quorum most often has to survive.
+const TopologyKeyZone = corev1.LabelTopologyZone
+
 // ClusterSelectorLabels is the framework's identity selector for every pod of one cluster
 // (`app.kubernetes.io/instance`).
 //
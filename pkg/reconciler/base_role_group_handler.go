@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
 	"github.com/zncdatadev/operator-go/pkg/builder"
 	"github.com/zncdatadev/operator-go/pkg/common"
 	"github.com/zncdatadev/operator-go/pkg/config"
@@ -35,6 +36,7 @@ import (
 	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -174,14 +176,17 @@ func (h *BaseRoleGroupHandler[CR]) resolveSecurityContext() (*corev1.SecurityCon
 // BuildResources builds the default Kubernetes resources for a role group.
 // This implementation creates:
 // - ConfigMap from merged configuration
+// - the logging ConfigMap, when the role reloads its logging config
 // - Headless Service for StatefulSet
 // - Service (if ports are defined)
 // - StatefulSet with standard configuration
 //
-// The PodDisruptionBudget is intentionally NOT built here: it is a role-level resource
+// The role's PodDisruptionBudget is NOT built here: by default it is a role-level resource
 // (roleConfig.podDisruptionBudget covers all pods of a role across every role group), so the
 // generic reconciler builds exactly one per role via BuildRolePodDisruptionBudget. Building it
-// per role group here would emit one PDB per group and split the role's disruption budget.
+// per role group here would emit one PDB per group and split the role's disruption budget. Only
+// a role whose podDisruptionBudget.scope is RoleGroup asked for that split, and then the group's
+// PDB is built here, by BuildRoleGroupPodDisruptionBudget.
 func (h *BaseRoleGroupHandler[CR]) BuildResources(
 	ctx context.Context,
 	k8sClient client.Client,
@@ -230,11 +235,12 @@ func (h *BaseRoleGroupHandler[CR]) BuildResources(
 	labels := h.buildLabels(buildCtx)
 
 	// Build ConfigMap
-	configMap, err := h.buildConfigMap(buildCtx, labels)
+	configMap, logConfigMap, err := h.buildConfigMap(buildCtx, labels)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build ConfigMap: %w", err)
 	}
 	resources.ConfigMap = configMap
+	resources.LogConfigMap = logConfigMap
 
 	// Build Headless Service
 	headlessSvc := h.buildHeadlessService(buildCtx, labels)
@@ -253,6 +259,8 @@ func (h *BaseRoleGroupHandler[CR]) BuildResources(
 	}
 	resources.StatefulSet = sts
 
+	resources.PodDisruptionBudget = h.BuildRoleGroupPodDisruptionBudget(buildCtx)
+
 	logger.V(1).Info("Built role group resources",
 		"role", buildCtx.RoleName,
 		"group", buildCtx.RoleGroupName,
@@ -452,8 +460,11 @@ func (h *BaseRoleGroupHandler[CR]) configMountPath() string {
 	return constant.KubedoopConfigDirMount
 }
 
-// buildConfigMap creates the ConfigMap for the role group.
-func (h *BaseRoleGroupHandler[CR]) buildConfigMap(buildCtx *RoleGroupBuildContext, labels map[string]string) (*corev1.ConfigMap, error) {
+// buildConfigMap creates the ConfigMap for the role group, and — when the role reloads its logging
+// config — the logging ConfigMap holding the framework-rendered logging files instead.
+func (h *BaseRoleGroupHandler[CR]) buildConfigMap(
+	buildCtx *RoleGroupBuildContext, labels map[string]string,
+) (*corev1.ConfigMap, *corev1.ConfigMap, error) {
 	// Build config data. The ConfigGenerator, when set, owns the rendering of every file it
 	// recognizes; the fallback below only fills the gaps, so the two paths can never disagree
 	// about the same filename.
@@ -462,7 +473,7 @@ func (h *BaseRoleGroupHandler[CR]) buildConfigMap(buildCtx *RoleGroupBuildContex
 	if h.ConfigGenerator != nil && len(buildCtx.MergedConfig.ConfigFiles) > 0 {
 		generatedData, err := h.ConfigGenerator.GenerateFiles(buildCtx.MergedConfig.ConfigFiles)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		for filename, content := range generatedData {
 			data[filename] = content
@@ -481,7 +492,7 @@ func (h *BaseRoleGroupHandler[CR]) buildConfigMap(buildCtx *RoleGroupBuildContex
 		}
 		content, err := propertiesAdapter.Marshal(cfg)
 		if err != nil {
-			return nil, fmt.Errorf("failed to render config file %q: %w", filename, err)
+			return nil, nil, fmt.Errorf("failed to render config file %q: %w", filename, err)
 		}
 		data[filename] = content
 	}
@@ -492,15 +503,42 @@ func (h *BaseRoleGroupHandler[CR]) buildConfigMap(buildCtx *RoleGroupBuildContex
 	// overwriting a file the product already produced (e.g. via MergedConfig.ConfigFiles).
 	loggingData, err := RenderLoggingConfigMapData(buildCtx, buildCtx.Declaration.LogProducers)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	// A role that reloads its logging config gets the logging files in a ConfigMap of their own, so
+	// a level change rewrites that one and nothing else. vector.yaml stays: the agent does not
+	// reload it, and it is not what a level change touches. The collision check still runs against
+	// the role group ConfigMap, so a file name means one file whichever ConfigMap holds it.
+	reloads := buildCtx.Declaration.LogConfigReloadInterval > 0
+	logData := make(map[string]string)
 	for filename, content := range loggingData {
 		if _, exists := data[filename]; exists {
-			return nil, fmt.Errorf("logging config file %q collides with an existing ConfigMap key", filename)
+			return nil, nil, fmt.Errorf("logging config file %q collides with an existing ConfigMap key", filename)
+		}
+		if reloads && filename != vector.VectorConfigFileName {
+			logData[filename] = content
+			continue
 		}
 		data[filename] = content
 	}
 
+	// Sidecar configuration the framework renders (sidecar.ConfigDataProvider, e.g. a JMX exporter
+	// built from rules) lives in this ConfigMap too, under the same collision rule. BuildResources
+	// has already settled buildCtx.SidecarManager, so this reads the manager injection uses.
+	if buildCtx.SidecarManager != nil {
+		sidecarData, err := buildCtx.SidecarManager.ConfigMapData()
+		if err != nil {
+			return nil, nil, err
+		}
+		for filename, content := range sidecarData {
+			_, inLogData := logData[filename]
+			if _, exists := data[filename]; exists || inLogData {
+				return nil, nil, fmt.Errorf("sidecar config file %q collides with an existing ConfigMap key", filename)
+			}
+			data[filename] = content
+		}
+	}
+
 	cm := builder.NewConfigMapBuilder(buildCtx.ResourceName, buildCtx.ClusterNamespace).
 		WithLabels(labels).
 		WithConfigFiles(data).
@@ -513,7 +551,20 @@ func (h *BaseRoleGroupHandler[CR]) buildConfigMap(buildCtx *RoleGroupBuildContex
 	if cm.Data == nil {
 		cm.Data = map[string]string{}
 	}
-	return cm, nil
+	if !reloads {
+		return cm, nil, nil
+	}
+
+	// The restarter watches every ConfigMap the pod template references, this one included, so on
+	// a workload labelled for it a logging change still rolls the pods.
+	logCM := builder.NewConfigMapBuilder(logConfigMapName(buildCtx.ResourceName), buildCtx.ClusterNamespace).
+		WithLabels(labels).
+		WithConfigFiles(logData).
+		Build()
+	if logCM.Data == nil {
+		logCM.Data = map[string]string{}
+	}
+	return cm, logCM, nil
 }
 
 // buildHeadlessService creates the headless service for StatefulSet.
@@ -574,6 +625,38 @@ func (h *BaseRoleGroupHandler[CR]) wireVolumes(
 		ReadOnly:  true,
 	})
 
+	// The logging ConfigMap, mounted as a directory and never through subPath: the kubelet swaps a
+	// directory mount's contents when the ConfigMap changes, and never touches a subPath mount.
+	if buildCtx.Declaration.LogConfigReloadInterval > 0 {
+		stsBuilder.AddVolume(corev1.Volume{
+			Name: LogConfigVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: logConfigMapName(buildCtx.ResourceName),
+					},
+				},
+			},
+		})
+		stsBuilder.AddVolumeMount(corev1.VolumeMount{
+			Name:      LogConfigVolumeName,
+			MountPath: constant.KubedoopLogDirMount,
+			ReadOnly:  true,
+		})
+	}
+
+	// The cluster-wide spec.tls volumes, resolved by the reconciler. They go first so a product
+	// that registers a volume of the same name fails on a duplicate rather than silently shadowing
+	// the certificate the probes and the build context point at.
+	if buildCtx.TLS != nil {
+		for _, v := range buildCtx.TLS.Volumes() {
+			stsBuilder.AddVolume(v)
+		}
+		for _, m := range buildCtx.TLS.VolumeMounts() {
+			stsBuilder.AddVolumeMount(m)
+		}
+	}
+
 	// Inject product-registered CSI volumes (secret/TLS certificates, listener address
 	// volumes). These flow through the same builder path as the config volume (volumes on the
 	// pod, mounts on the primary container), before the container rename and sidecar injection.
@@ -651,6 +734,7 @@ func (h *BaseRoleGroupHandler[CR]) buildStatefulSet(
 	// defaults, and nothing derived from the effective config could reach the ConfigMap because
 	// that had already been built.
 	roleGroupConfig := buildCtx.RoleGroupSpec.GetConfig()
+	var gracePeriod time.Duration
 	if roleGroupConfig != nil {
 		if roleGroupConfig.Resources != nil {
 			stsBuilder.WithResources(roleGroupConfig.Resources)
@@ -660,6 +744,12 @@ func (h *BaseRoleGroupHandler[CR]) buildStatefulSet(
 			if dv := buildCtx.Declaration.DataVolume; dv != nil && roleGroupConfig.Resources.Storage != nil {
 				stsBuilder.WithNamedStorage(dv.Name, roleGroupConfig.Resources.Storage, dv.MountPath)
 			}
+			// The named data volumes, one claim template each, after the primary one so an existing
+			// StatefulSet's first template keeps its place. Their names were validated with the
+			// build context (resolveDataVolumeMountPaths).
+			for _, v := range expandDataVolumes(roleGroupConfig.Resources.Volumes) {
+				stsBuilder.WithNamedStorage(v.Name, &v.Storage, v.MountPath)
+			}
 		}
 
 		// Affinity: the CRD carries it as a schema-free RawExtension holding a corev1.Affinity.
@@ -677,27 +767,43 @@ func (h *BaseRoleGroupHandler[CR]) buildStatefulSet(
 			stsBuilder.WithAffinity(affinity)
 		}
 
+		// Placement: folded wholesale like affinity, and set only when stated for the same
+		// reason. A spread constraint without a selector spreads the ROLE (SpreadConstraintsForRole).
+		if placement := roleGroupConfig.Placement; placement != nil {
+			stsBuilder.
+				WithNodeSelector(placement.NodeSelector).
+				WithTolerations(placement.Tolerations).
+				WithTopologySpreadConstraints(SpreadConstraintsForRole(
+					placement.TopologySpreadConstraints, buildCtx.ClusterName, buildCtx.RoleName))
+		}
+
 		// GracefulShutdownTimeout maps to the pod's terminationGracePeriodSeconds (see
-		// docs/architecture.md §4.11.2 Graceful Shutdown). An unparsable or non-positive
-		// duration fails the build loudly rather than silently falling back. A positive
-		// sub-second duration rounds up to 1s so it never truncates to 0 (which would mean
-		// immediate SIGKILL).
+		// docs/architecture.md §4.11.2 Graceful Shutdown) and bounds the declared ShutdownAction
+		// below. An unparsable or non-positive duration fails the build loudly rather than
+		// silently falling back (GracefulShutdownPeriod).
 		//
 		// GetGracefulShutdownTimeout supplies DefaultGracefulShutdownTimeout when neither the role
 		// nor the group set one, so the value is always written explicitly rather than relying on
 		// the Kubernetes default — the field used to carry a CRD default, and removing it must not
 		// silently change the effective grace period of an existing cluster.
-		timeout := roleGroupConfig.GetGracefulShutdownTimeout()
-		d, err := time.ParseDuration(timeout)
+		var err error
+		if gracePeriod, err = GracefulShutdownPeriod(roleGroupConfig); err != nil {
+			return nil, fmt.Errorf("%w in role group config (role %q, group %q)",
+				err, buildCtx.RoleName, buildCtx.RoleGroupName)
+		}
+		stsBuilder.WithTerminationGracePeriod(int64(gracePeriod / time.Second))
+
+		// The PVC retention policy goes onto the StatefulSet's native field, which the StatefulSet
+		// controller enforces on scale-down and on deletion — including the garbage collection that
+		// follows a deleted cluster, which no code of this framework sees. The orphan cleaner reads
+		// the same field back when it deletes a removed role group.
+		pvcRetention, err := statefulSetPVCRetentionPolicy(roleGroupConfig.PersistentVolumeClaimRetentionPolicy)
 		if err != nil {
-			return nil, fmt.Errorf("invalid gracefulShutdownTimeout %q in role group config (role %q, group %q): %w",
-				timeout, buildCtx.RoleName, buildCtx.RoleGroupName, err)
+			return nil, fmt.Errorf("%w (role %q, group %q)", err, buildCtx.RoleName, buildCtx.RoleGroupName)
 		}
-		if d <= 0 {
-			return nil, fmt.Errorf("invalid gracefulShutdownTimeout %q in role group config (role %q, group %q): must be a positive duration",
-				timeout, buildCtx.RoleName, buildCtx.RoleGroupName)
+		if pvcRetention != nil {
+			stsBuilder.WithPersistentVolumeClaimRetentionPolicy(pvcRetention)
 		}
-		stsBuilder.WithTerminationGracePeriod(int64((d + time.Second - 1) / time.Second))
 	}
 
 	// Apply the security context (framework canonical default unless the product overrode it).
@@ -732,7 +838,12 @@ func (h *BaseRoleGroupHandler[CR]) buildStatefulSet(
 		stsBuilder.WithMainContainerName(mainName)
 	}
 
-	applyDeclaredContainerFields(stsBuilder, buildCtx.Declaration)
+	applyDeclaredContainerFields(stsBuilder, buildCtx.Declaration, buildCtx.TLS.ServerEnabled())
+	// After the declared lifecycle, which WithLifecycle sets wholesale: the shutdown action is its
+	// preStop, and a declared postStart survives.
+	if shutdown := buildCtx.Declaration.Shutdown; shutdown != nil {
+		stsBuilder.WithPreStop(shutdown.PreStopHandler(gracePeriod))
+	}
 
 	// Build the StatefulSet
 	sts := stsBuilder.Build()
@@ -761,6 +872,19 @@ func (h *BaseRoleGroupHandler[CR]) buildStatefulSet(
 		}
 	}
 
+	// Mirror and pin every image that reached the pod without passing through the resolver: a
+	// sidecar provider's own image, a podOverrides container. The product image, and the sidecars
+	// SetProductImage gave it to, are final already and are left alone.
+	if buildCtx.imageResolution != nil {
+		buildCtx.imageResolution.rewritePodImages(&sts.Spec.Template.Spec, buildCtx.ResolvedImage.Reference)
+	}
+
+	// The sidecars' budgets from resources.sidecars. Only now: the containers they name did not
+	// exist until InjectAll.
+	if roleGroupConfig != nil && roleGroupConfig.Resources != nil {
+		builder.ApplySidecarResources(&sts.Spec.Template.Spec, roleGroupConfig.Resources.Sidecars)
+	}
+
 	// Fail loudly on image-less containers instead of shipping a StatefulSet the API server
 	// rejects (a silent Degraded loop). The typical cause: a podOverride container whose name
 	// matches nothing — a typo, or a sidecar name; sidecars are injected AFTER the overrides
@@ -797,16 +921,30 @@ func (h *BaseRoleGroupHandler[CR]) BuildRolePodDisruptionBudget(
 	if buildCtx == nil || buildCtx.RoleSpec == nil {
 		return nil
 	}
-	roleConfig := buildCtx.RoleSpec.GetRoleConfig()
-	if roleConfig == nil || roleConfig.PodDisruptionBudget == nil {
+	var spec *v1alpha1.PodDisruptionBudgetSpec
+	if roleConfig := buildCtx.RoleSpec.GetRoleConfig(); roleConfig != nil {
+		spec = roleConfig.PodDisruptionBudget
+	}
+	quorum := buildCtx.Declaration.Quorum
+	// A quorum role gets its PDB without asking: losing quorum to a node drain is an outage.
+	if spec == nil && quorum == nil {
+		return nil
+	}
+	// A quorum spans the role, so a quorum role keeps its role-level PDB whatever the scope says;
+	// the reconciler rejects that combination before it gets here.
+	if quorum == nil && spec.GetScope() == v1alpha1.PodDisruptionBudgetScopeRoleGroup {
 		return nil
 	}
 
 	b := builder.NewPDBBuilder(
 		RoleResourceName(buildCtx.ClusterName, buildCtx.RoleName), buildCtx.ClusterNamespace).
 		WithLabels(h.buildRoleLabels(buildCtx)).
-		WithSelector(h.buildRoleSelectorLabels(buildCtx.ClusterName, buildCtx.RoleName)).
-		WithSpec(roleConfig.PodDisruptionBudget)
+		WithSelector(h.buildRoleSelectorLabels(buildCtx.ClusterName, buildCtx.RoleName))
+	// The quorum is the product's default; a budget the user states replaces it.
+	if quorum != nil {
+		b.WithMinAvailable(intstr.FromInt32(quorum.MinAvailable(buildCtx.Replicas)))
+	}
+	b.WithSpec(spec)
 
 	// Enabled defaults to true in the CRD; honor an explicit disable.
 	if !b.IsEnabled() {
@@ -816,6 +954,31 @@ func (h *BaseRoleGroupHandler[CR]) BuildRolePodDisruptionBudget(
 	return b.Build()
 }
 
+// BuildRoleGroupPodDisruptionBudget builds a role group's own PodDisruptionBudget when the role's
+// podDisruptionBudget.scope is RoleGroup: the role's policy, applied to this group's pods alone,
+// under the group's resource name. It returns nil for every other role — the role-level PDB covers
+// those — and for a role that declares a quorum, which per-group budgets cannot protect.
+func (h *BaseRoleGroupHandler[CR]) BuildRoleGroupPodDisruptionBudget(
+	buildCtx *RoleGroupBuildContext,
+) *policyv1.PodDisruptionBudget {
+	if buildCtx == nil || buildCtx.RoleSpec == nil || buildCtx.Declaration.Quorum != nil {
+		return nil
+	}
+	roleConfig := buildCtx.RoleSpec.GetRoleConfig()
+	if roleConfig == nil || roleConfig.PodDisruptionBudget.GetScope() != v1alpha1.PodDisruptionBudgetScopeRoleGroup {
+		return nil
+	}
+
+	b := builder.NewPDBBuilder(buildCtx.ResourceName, buildCtx.ClusterNamespace).
+		WithLabels(h.buildLabels(buildCtx)).
+		WithSelector(h.buildSelectorLabels(buildCtx)).
+		WithSpec(roleConfig.PodDisruptionBudget)
+	if !b.IsEnabled() {
+		return nil
+	}
+	return b.Build()
+}
+
 // roleIdentityLabels are the product-owned labels that identify a role (cluster + role, without
 // a role group). They are a subset of every pod's identity labels, so a selector built from them
 // matches all of a role's pods across role groups. Empty when the handler has no LabelDomain.
@@ -901,7 +1064,16 @@ var _ RoleGroupHandler[common.ClusterInterface] = &BaseRoleGroupHandler[common.C
 //
 // Every field here has NO user layer, which is why declaring them beats nobody. Args are absent on
 // purpose — they reach the container through cliOverrides, which the user can state.
-func applyDeclaredContainerFields(b *builder.StatefulSetBuilder, decl RoleDeclaration) {
+//
+// serverTLS switches the declared HTTP probes to HTTPS. The product declares one probe per role and
+// the cluster decides whether it is served over TLS, so the switch is the framework's: a product
+// declaring the scheme itself would have to re-declare the role every time spec.tls changes.
+func applyDeclaredContainerFields(b *builder.StatefulSetBuilder, decl RoleDeclaration, serverTLS bool) {
+	if serverTLS {
+		decl.ReadinessProbe = httpsProbe(decl.ReadinessProbe)
+		decl.LivenessProbe = httpsProbe(decl.LivenessProbe)
+		decl.StartupProbe = httpsProbe(decl.StartupProbe)
+	}
 	if len(decl.Command) > 0 {
 		b.WithCommand(decl.Command)
 	}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -150,7 +151,7 @@ var _ = Describe("BaseRoleGroupHandler", func() {
 			// Even with roleConfig.podDisruptionBudget configured, BuildResources must not emit
 			// a per-group PDB: the framework builds exactly one PDB per role via
 			// BuildRolePodDisruptionBudget (covered in the "PodDisruptionBudget building" suite).
-			maxUnavailable := int32(1)
+			maxUnavailable := intstr.FromInt32(1)
 			buildCtx.RoleSpec = &v1alpha1.RoleSpec{
 				RoleConfig: &v1alpha1.RoleConfigSpec{
 					PodDisruptionBudget: &v1alpha1.PodDisruptionBudgetSpec{
@@ -366,7 +367,7 @@ var _ = Describe("PodDisruptionBudget building", func() {
 	})
 
 	It("should set maxUnavailable correctly", func() {
-		maxUnavailable := int32(2)
+		maxUnavailable := intstr.FromInt32(2)
 		roleSpec := roleWithPDB(&v1alpha1.PodDisruptionBudgetSpec{Enabled: ptr.To(true), MaxUnavailable: &maxUnavailable})
 
 		pdb := handler.BuildRolePodDisruptionBudget(roleCtx(roleSpec, nil))
@@ -1006,6 +1007,33 @@ var _ = Describe("RoleGroupConfig affinity and gracefulShutdownTimeout consumpti
 		Expect(resources.StatefulSet.Spec.Template.Spec.Affinity).To(Equal(configAffinity))
 	})
 
+	It("applies the config placement to the pod spec, spreading the role when a constraint has no selector", func() {
+		own := &metav1.LabelSelector{MatchLabels: map[string]string{"custom": "yes"}}
+		buildCtx.RoleGroupSpec.Config = &v1alpha1.RoleGroupConfigSpec{
+			Placement: &v1alpha1.PlacementSpec{
+				NodeSelector: map[string]string{"disk": "ssd"},
+				Tolerations:  []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+				TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+					reconciler.PreferredTopologySpread(1, reconciler.TopologyKeyZone),
+					{MaxSkew: 2, TopologyKey: corev1.LabelHostname, WhenUnsatisfiable: corev1.DoNotSchedule,
+						LabelSelector: own},
+				},
+			},
+		}
+
+		resources, err := handler.BuildResources(context.Background(), nil, nil, buildCtx)
+		Expect(err).NotTo(HaveOccurred())
+		podSpec := resources.StatefulSet.Spec.Template.Spec
+		Expect(podSpec.NodeSelector).To(Equal(map[string]string{"disk": "ssd"}))
+		Expect(podSpec.Tolerations).To(HaveLen(1))
+		Expect(podSpec.TopologySpreadConstraints).To(HaveLen(2))
+		Expect(podSpec.TopologySpreadConstraints[0].LabelSelector.MatchLabels).To(Equal(
+			reconciler.RoleSelectorLabels("test-cluster", "test-role")))
+		Expect(podSpec.TopologySpreadConstraints[1].LabelSelector).To(Equal(own), "a stated selector is kept")
+		Expect(buildCtx.RoleGroupSpec.Config.Placement.TopologySpreadConstraints[0].LabelSelector).To(BeNil(),
+			"the config is not written back into")
+	})
+
 	It("fails the build loudly on invalid affinity JSON", func() {
 		buildCtx.RoleGroupSpec.Config = &v1alpha1.RoleGroupConfigSpec{
 			Affinity: &k8sruntime.RawExtension{Raw: []byte(`{"podAntiAffinity": [`)},
@@ -1054,6 +1082,59 @@ var _ = Describe("RoleGroupConfig affinity and gracefulShutdownTimeout consumpti
 		}
 	})
 
+	It("runs the declared shutdown action as the preStop hook, bounded by gracefulShutdownTimeout", func() {
+		buildCtx.RoleGroupSpec.Config = &v1alpha1.RoleGroupConfigSpec{GracefulShutdownTimeout: ptr.To("2m")}
+		buildCtx.Declaration.Shutdown = &reconciler.ShutdownAction{Exec: []string{"/kubedoop/bin/decommission.sh"}}
+		buildCtx.Declaration.Lifecycle = &corev1.Lifecycle{PostStart: &corev1.LifecycleHandler{
+			Exec: &corev1.ExecAction{Command: []string{"/bin/true"}},
+		}}
+
+		resources, err := handler.BuildResources(context.Background(), nil, nil, buildCtx)
+		Expect(err).NotTo(HaveOccurred())
+		lifecycle := resources.StatefulSet.Spec.Template.Spec.Containers[0].Lifecycle
+		Expect(lifecycle.PreStop).To(Equal(buildCtx.Declaration.Shutdown.PreStopHandler(2 * time.Minute)))
+		Expect(lifecycle.PreStop.Exec.Command).To(ContainElement(ContainSubstring("sleep 115;")))
+		Expect(lifecycle.PostStart).NotTo(BeNil(), "a declared postStart survives")
+	})
+
+	It("maps persistentVolumeClaimRetentionPolicy onto the StatefulSet, Retain filling an unset field", func() {
+		buildCtx.RoleGroupSpec.Config = &v1alpha1.RoleGroupConfigSpec{
+			PersistentVolumeClaimRetentionPolicy: &v1alpha1.PersistentVolumeClaimRetentionPolicySpec{
+				WhenScaled: v1alpha1.PersistentVolumeClaimDelete,
+			},
+		}
+
+		resources, err := handler.BuildResources(context.Background(), nil, nil, buildCtx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resources.StatefulSet.Spec.PersistentVolumeClaimRetentionPolicy).To(Equal(
+			&appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy{
+				WhenDeleted: appsv1.RetainPersistentVolumeClaimRetentionPolicyType,
+				WhenScaled:  appsv1.DeletePersistentVolumeClaimRetentionPolicyType,
+			}))
+	})
+
+	It("leaves the PVC retention policy to the Kubernetes default when none is stated", func() {
+		buildCtx.RoleGroupSpec.Config = &v1alpha1.RoleGroupConfigSpec{}
+
+		resources, err := handler.BuildResources(context.Background(), nil, nil, buildCtx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resources.StatefulSet.Spec.PersistentVolumeClaimRetentionPolicy).To(BeNil())
+	})
+
+	It("fails the build loudly on an unknown PVC retention policy value", func() {
+		// The CRD enum rejects it; a spec built in Go never passed the schema.
+		buildCtx.RoleGroupSpec.Config = &v1alpha1.RoleGroupConfigSpec{
+			PersistentVolumeClaimRetentionPolicy: &v1alpha1.PersistentVolumeClaimRetentionPolicySpec{
+				WhenDeleted: "Keep",
+			},
+		}
+
+		_, err := handler.BuildResources(context.Background(), nil, nil, buildCtx)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("whenDeleted"))
+		Expect(err.Error()).To(ContainSubstring("Keep"))
+	})
+
 	It("leaves affinity unset but writes the default grace period when the config fields are empty", func() {
 		// Config present but with neither affinity nor gracefulShutdownTimeout set. Affinity stays
 		// nil for backward compatibility: products that post-process the built StatefulSet with
@@ -1064,6 +1145,9 @@ var _ = Describe("RoleGroupConfig affinity and gracefulShutdownTimeout consumpti
 		Expect(err).NotTo(HaveOccurred())
 		podSpec := resources.StatefulSet.Spec.Template.Spec
 		Expect(podSpec.Affinity).To(BeNil())
+		Expect(podSpec.NodeSelector).To(BeNil())
+		Expect(podSpec.Tolerations).To(BeNil())
+		Expect(podSpec.TopologySpreadConstraints).To(BeNil())
 		// The grace period IS written, explicitly. It used to come from the CRD default the API
 		// server stamped into every config block; removing that default (so a role-level value can
 		// reach its groups) would otherwise have silently changed the effective grace period of
@@ -1269,14 +1353,14 @@ var _ = Describe("BaseRoleGroupHandler with PDB", func() {
 	}
 
 	It("should create PDB when MaxUnavailable is set and Enabled is true", func() {
-		maxUnavailable := int32(1)
+		maxUnavailable := intstr.FromInt32(1)
 		pdb := buildRolePDB(&v1alpha1.PodDisruptionBudgetSpec{Enabled: ptr.To(true), MaxUnavailable: &maxUnavailable})
 		Expect(pdb).NotTo(BeNil())
 		Expect(pdb.Spec.MaxUnavailable).NotTo(BeNil())
 	})
 
 	It("should not create PDB when Enabled is false", func() {
-		maxUnavailable := int32(1)
+		maxUnavailable := intstr.FromInt32(1)
 		Expect(buildRolePDB(&v1alpha1.PodDisruptionBudgetSpec{Enabled: ptr.To(false), MaxUnavailable: &maxUnavailable})).To(BeNil())
 	})
 
@@ -1292,6 +1376,85 @@ var _ = Describe("BaseRoleGroupHandler with PDB", func() {
 			RoleSpec:         &v1alpha1.RoleSpec{},
 		})).To(BeNil())
 	})
+
+	It("should carry minAvailable, percentages and the unhealthy pod eviction policy", func() {
+		pdb := buildRolePDB(&v1alpha1.PodDisruptionBudgetSpec{
+			MinAvailable:               ptr.To(intstr.FromString("50%")),
+			UnhealthyPodEvictionPolicy: ptr.To(v1alpha1.UnhealthyPodEvictionAlwaysAllow),
+		})
+		Expect(pdb.Spec.MinAvailable).To(HaveValue(Equal(intstr.FromString("50%"))))
+		Expect(pdb.Spec.MaxUnavailable).To(BeNil())
+		Expect(pdb.Spec.UnhealthyPodEvictionPolicy).To(HaveValue(Equal(policyv1.AlwaysAllow)))
+	})
+
+	It("should leave the role-level PDB to the role groups when the scope is RoleGroup", func() {
+		Expect(buildRolePDB(&v1alpha1.PodDisruptionBudgetSpec{
+			Scope: v1alpha1.PodDisruptionBudgetScopeRoleGroup,
+		})).To(BeNil())
+	})
+
+	Context("for a quorum role", func() {
+		buildQuorumPDB := func(spec *v1alpha1.PodDisruptionBudgetSpec, quorum reconciler.Quorum, replicas int32) *policyv1.PodDisruptionBudget {
+			return handler.BuildRolePodDisruptionBudget(&reconciler.RoleBuildContext{
+				ClusterName:      "test-cluster",
+				ClusterNamespace: "default",
+				RoleName:         "server",
+				RoleSpec:         &v1alpha1.RoleSpec{RoleConfig: &v1alpha1.RoleConfigSpec{PodDisruptionBudget: spec}},
+				Declaration:      reconciler.RoleDeclaration{Quorum: &quorum},
+				Replicas:         replicas,
+			})
+		}
+
+		It("should keep a majority of the live replicas available without being asked", func() {
+			pdb := buildQuorumPDB(nil, reconciler.Quorum{}, 5)
+			Expect(pdb).NotTo(BeNil())
+			Expect(pdb.Spec.MinAvailable).To(HaveValue(Equal(intstr.FromInt32(3))))
+			Expect(pdb.Spec.MaxUnavailable).To(BeNil())
+
+			Expect(buildQuorumPDB(nil, reconciler.Quorum{}, 4).Spec.MinAvailable).To(HaveValue(Equal(intstr.FromInt32(3))))
+			Expect(buildQuorumPDB(nil, reconciler.Quorum{Size: 2}, 5).Spec.MinAvailable).To(HaveValue(Equal(intstr.FromInt32(2))))
+		})
+
+		It("should let a budget the user states replace the quorum", func() {
+			pdb := buildQuorumPDB(&v1alpha1.PodDisruptionBudgetSpec{MaxUnavailable: ptr.To(intstr.FromInt32(2))}, reconciler.Quorum{}, 5)
+			Expect(pdb.Spec.MaxUnavailable).To(HaveValue(Equal(intstr.FromInt32(2))))
+			Expect(pdb.Spec.MinAvailable).To(BeNil())
+		})
+
+		It("should honour an explicit disable", func() {
+			Expect(buildQuorumPDB(&v1alpha1.PodDisruptionBudgetSpec{Enabled: ptr.To(false)}, reconciler.Quorum{}, 3)).To(BeNil())
+		})
+	})
+
+	Describe("BuildRoleGroupPodDisruptionBudget", func() {
+		newGroupCtx := func(spec *v1alpha1.PodDisruptionBudgetSpec) *reconciler.RoleGroupBuildContext {
+			return &reconciler.RoleGroupBuildContext{
+				ClusterName:      "test-cluster",
+				ClusterNamespace: "default",
+				RoleName:         "worker",
+				RoleSpec:         &v1alpha1.RoleSpec{RoleConfig: &v1alpha1.RoleConfigSpec{PodDisruptionBudget: spec}},
+				RoleGroupName:    "large",
+				ResourceName:     reconciler.RoleGroupResourceName("test-cluster", "worker", "large"),
+			}
+		}
+
+		It("should build the role's policy for the group's pods alone when the scope is RoleGroup", func() {
+			pdb := handler.BuildRoleGroupPodDisruptionBudget(newGroupCtx(&v1alpha1.PodDisruptionBudgetSpec{
+				Scope:          v1alpha1.PodDisruptionBudgetScopeRoleGroup,
+				MaxUnavailable: ptr.To(intstr.FromString("25%")),
+			}))
+			Expect(pdb).NotTo(BeNil())
+			Expect(pdb.Name).To(Equal("test-cluster-worker-large"))
+			Expect(pdb.Spec.MaxUnavailable).To(HaveValue(Equal(intstr.FromString("25%"))))
+			Expect(pdb.Spec.Selector.MatchLabels).To(HaveKeyWithValue(
+				reconciler.RoleGroupMarkerLabelKey("test-cluster", "worker", "large"), "true"))
+		})
+
+		It("should build nothing for a role-scoped budget", func() {
+			Expect(handler.BuildRoleGroupPodDisruptionBudget(newGroupCtx(&v1alpha1.PodDisruptionBudgetSpec{}))).To(BeNil())
+			Expect(handler.BuildRoleGroupPodDisruptionBudget(newGroupCtx(nil))).To(BeNil())
+		})
+	})
 })
 
 var _ = Describe("BaseRoleGroupHandler enhancements", func() {
@@ -1345,6 +1508,29 @@ var _ = Describe("BaseRoleGroupHandler enhancements", func() {
 		Expect(resources.StatefulSet.Spec.VolumeClaimTemplates).To(BeEmpty())
 	})
 
+	It("adds one claim template and mount per named data volume, after the primary one", func() {
+		handler := reconciler.NewBaseRoleGroupHandler[common.ClusterInterface](testScheme)
+		buildCtx.Declaration.DataVolume = &reconciler.DataVolume{MountPath: "/kubedoop/data"}
+		buildCtx.RoleGroupSpec.Config.Resources.Volumes = map[string]v1alpha1.VolumeResource{
+			"journal": {StorageResource: v1alpha1.StorageResource{StorageClass: ptr.To("fast")}, MountPath: "/kubedoop/journal"},
+			"disk":    {MountPath: "/kubedoop/disk", Count: ptr.To(int32(2))},
+		}
+
+		resources, err := handler.BuildResources(ctx, k8sClient, mockCR, buildCtx)
+		Expect(err).NotTo(HaveOccurred())
+		templates := resources.StatefulSet.Spec.VolumeClaimTemplates
+		Expect(templates).To(HaveLen(4))
+		Expect([]string{templates[0].Name, templates[1].Name, templates[2].Name, templates[3].Name}).To(
+			Equal([]string{"data", "disk-0", "disk-1", "journal"}))
+		Expect(templates[3].Spec.StorageClassName).To(HaveValue(Equal("fast")))
+		mounts := resources.StatefulSet.Spec.Template.Spec.Containers[0].VolumeMounts
+		Expect(mounts).To(ContainElements(
+			corev1.VolumeMount{Name: "disk-0", MountPath: "/kubedoop/disk/0"},
+			corev1.VolumeMount{Name: "disk-1", MountPath: "/kubedoop/disk/1"},
+			corev1.VolumeMount{Name: "journal", MountPath: "/kubedoop/journal"},
+		))
+	})
+
 	It("sets PublishNotReadyAddresses on the headless service when enabled", func() {
 		handler := reconciler.NewBaseRoleGroupHandler[common.ClusterInterface](testScheme)
 		buildCtx.Declaration.PublishNotReadyAddresses = true
@@ -1467,6 +1653,86 @@ var _ = Describe("BaseRoleGroupHandler declarative logging", func() {
 		Expect(resources.ConfigMap.Data["vector.yaml"]).To(ContainSubstring("vector-aggregator.default.svc:6123"))
 	})
 
+	It("moves the logging files into a restarter-ignored ConfigMap mounted as a directory when the role reloads them", func() {
+		handler := reconciler.NewBaseRoleGroupHandler[common.ClusterInterface](testScheme)
+
+		mockCR := testutil.NewMockCluster("test-cluster", "default")
+		buildCtx := &reconciler.RoleGroupBuildContext{
+			ResolvedImage:           reconciler.ResolvedImage{Reference: "test-image:latest"},
+			ClusterName:             "test-cluster",
+			ClusterNamespace:        "default",
+			RoleName:                "test-role",
+			RoleSpec:                &v1alpha1.RoleSpec{},
+			RoleGroupName:           "default",
+			RoleGroupSpec:           v1alpha1.RoleGroupSpec{Replicas: ptr.To(int32(1))},
+			ResourceName:            "test-cluster-default",
+			VectorAggregatorAddress: "vector-aggregator.default.svc:6123",
+			MergedConfig: &config.MergedConfig{
+				Logging: &v1alpha1.LoggingSpec{
+					EnableVectorAgent: ptr.To(true),
+					Containers:        map[string]v1alpha1.LoggingConfigSpec{"main": {}},
+				},
+			},
+		}
+		buildCtx.Declaration.LogProducers = []productlogging.ContainerLogging{{
+			Container: "main",
+			Framework: productlogging.LoggingFrameworkLogback,
+		}}
+		buildCtx.Declaration.LogConfigReloadInterval = 30 * time.Second
+
+		resources, err := handler.BuildResources(context.Background(), k8sClient, mockCR, buildCtx)
+		Expect(err).NotTo(HaveOccurred())
+
+		logCM := resources.LogConfigMap
+		Expect(logCM).NotTo(BeNil())
+		Expect(logCM.Name).To(Equal("test-cluster-default-log-config"))
+		Expect(logCM.Data["logback.xml"]).To(ContainSubstring(`scan="true" scanPeriod="30 seconds"`))
+		// Only the logging files move: the agent does not reload vector.yaml.
+		Expect(resources.ConfigMap.Data).NotTo(HaveKey("logback.xml"))
+		Expect(resources.ConfigMap.Data).To(HaveKey("vector.yaml"))
+
+		Expect(resources.StatefulSet.Spec.Template.Spec.Volumes).To(ContainElement(SatisfyAll(
+			HaveField("Name", reconciler.LogConfigVolumeName),
+			HaveField("ConfigMap.Name", "test-cluster-default-log-config"),
+		)))
+		// A subPath mount is never refreshed by the kubelet, which would defeat the reload.
+		Expect(resources.StatefulSet.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(SatisfyAll(
+			HaveField("Name", reconciler.LogConfigVolumeName),
+			HaveField("MountPath", constant.KubedoopLogDirMount),
+			HaveField("SubPath", ""),
+		)))
+
+		path, err := reconciler.LogConfigPath(buildCtx, buildCtx.Declaration.LogProducers[0], constant.KubedoopConfigDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(path).To(Equal("/kubedoop/mount/log/logback.xml"))
+	})
+
+	It("builds no logging ConfigMap when the role does not reload its logging config", func() {
+		handler := reconciler.NewBaseRoleGroupHandler[common.ClusterInterface](testScheme)
+
+		buildCtx := &reconciler.RoleGroupBuildContext{
+			ResolvedImage:    reconciler.ResolvedImage{Reference: "test-image:latest"},
+			ClusterName:      "test-cluster",
+			ClusterNamespace: "default",
+			RoleName:         "test-role",
+			RoleSpec:         &v1alpha1.RoleSpec{},
+			RoleGroupName:    "default",
+			RoleGroupSpec:    v1alpha1.RoleGroupSpec{Replicas: ptr.To(int32(1))},
+			ResourceName:     "test-cluster-default",
+			MergedConfig:     &config.MergedConfig{},
+		}
+		buildCtx.Declaration.LogProducers = []productlogging.ContainerLogging{{
+			Container: "main",
+			Framework: productlogging.LoggingFrameworkLogback,
+		}}
+
+		resources, err := handler.BuildResources(context.Background(), k8sClient, testutil.NewMockCluster("test-cluster", "default"), buildCtx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resources.LogConfigMap).To(BeNil())
+		Expect(resources.ConfigMap.Data).To(HaveKey("logback.xml"))
+		Expect(resources.StatefulSet.Spec.Template.Spec.Volumes).NotTo(ContainElement(HaveField("Name", reconciler.LogConfigVolumeName)))
+	})
+
 	It("Option A: omits the file appender (console-only) when Vector is disabled", func() {
 		handler := reconciler.NewBaseRoleGroupHandler[common.ClusterInterface](testScheme)
 
@@ -1892,6 +2158,31 @@ var _ = Describe("VolumeProvider injection", func() {
 		Expect(sts.Spec.Template.Spec.InitContainers).To(ContainElement(HaveField("Name", "init-config")))
 	})
 
+	It("renders a generated JMX exporter config into the role group ConfigMap and wires the java agent", func() {
+		buildCtx.Declaration.MainContainerName = "kafka"
+		sidecarMgr := sidecar.NewSidecarManager()
+		sidecarMgr.Register(
+			sidecar.NewJMXExporterSidecarProvider().
+				WithGeneratedConfig(buildCtx.ResourceName, &sidecar.JMXExporterConfig{
+					DefaultRules: []sidecar.JMXExporterRule{sidecar.JMXExporterCatchAllRule()},
+				}).
+				AsJavaAgent("kafka", "KAFKA_OPTS"),
+			&sidecar.SidecarConfig{Enabled: true},
+		)
+		buildCtx.SidecarManager = sidecarMgr
+
+		resources, err := handler.BuildResources(context.Background(), nil, nil, buildCtx)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(resources.ConfigMap.Data).To(HaveKeyWithValue(sidecar.JMXExporterGeneratedConfigFileName, ContainSubstring("pattern: .*")))
+		main := resources.StatefulSet.Spec.Template.Spec.Containers[0]
+		Expect(main.Env).To(ContainElement(And(
+			HaveField("Name", "KAFKA_OPTS"),
+			HaveField("Value", ContainSubstring("-javaagent:")),
+		)))
+		Expect(resources.StatefulSet.Spec.Template.Spec.InitContainers).To(BeEmpty())
+	})
+
 	It("applies the per-role MainContainerName over the global one when building the StatefulSet", func() {
 		// buildCtx.RoleName is "test-role"; the per-role override must win over the global name in
 		// the actual built StatefulSet, exercising the mainContainerNameFor wiring end-to-end.
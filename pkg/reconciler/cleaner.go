@@ -26,14 +26,17 @@ import (
 	"time"
 
 	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	listenersv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/listeners/v1alpha1"
 	"github.com/zncdatadev/operator-go/pkg/constant"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/utils/ptr"
@@ -48,13 +51,20 @@ const (
 	AnnotationPendingDeletion = "orphan.zncdata.dev/pending-deletion"
 
 	// AnnotationDeletePVCs when set to "true" on the cluster CR, causes the cleaner to also
-	// delete PVCs associated with orphaned StatefulSets.
+	// delete PVCs associated with orphaned StatefulSets, whatever their retention policy says.
 	AnnotationDeletePVCs = "operator.zncdata.dev/delete-pvcs"
 
 	// AnnotationDrainStarted records, as an RFC3339 timestamp, when an orphaned StatefulSet was
 	// first observed draining. It lives on the object rather than in memory so the drain deadline
 	// survives operator restarts and leader changes.
 	AnnotationDrainStarted = "orphan.zncdata.dev/drain-started"
+
+	// AnnotationDrainBudget records, as a Go duration, how long the drain stamped by
+	// AnnotationDrainStarted may take when no DrainTimeout is configured: the shutdown budget of
+	// the pods it is waiting for (see drainBudget). It is stamped by the scale-to-zero write, from
+	// the replica count before it: an ordered drain starts retiring pods as soon as that write
+	// lands, so a count read on any later pass is already short.
+	AnnotationDrainBudget = "orphan.zncdata.dev/drain-budget"
 )
 
 // ConditionOrphanCleanupPending reports that the framework has not finished reclaiming the
@@ -91,14 +101,20 @@ const LabelRoleGroupPodDisruptionBudget = "pdb." + constant.KubedoopDomain + "/r
 // short: the cycle it schedules only re-reads the resources it is waiting on.
 const DefaultDrainPollInterval = 5 * time.Second
 
-// DefaultDrainTimeout bounds the ordered drain of an orphaned StatefulSet. The drain is a
-// courtesy — it lets a stateful product shut down the way its own rolling update would — but it
-// gates every following step, so a pod that can never terminate (a stuck finalizer, an
-// unreachable node) would otherwise strand the role group's ConfigMap, Services and status entry
-// forever. Once this elapses the StatefulSet is deleted anyway and the remaining pods are left to
-// cascade garbage collection, which is what deleting it outright always did.
+// DefaultDrainTimeout bounds the ordered drain of an orphaned StatefulSet whose pods state no
+// termination grace period, and of one whose drain started before the budget was recorded
+// (AnnotationDrainBudget). The drain is a courtesy — it lets a stateful product shut down the way
+// its own rolling update would — but it gates every following step, so a pod that can never
+// terminate (a stuck finalizer, an unreachable node) would otherwise strand the role group's
+// ConfigMap, Services and status entry forever. Once the deadline elapses the StatefulSet is
+// deleted anyway and the remaining pods are left to cascade garbage collection, which is what
+// deleting it outright always did.
 const DefaultDrainTimeout = 10 * time.Minute
 
+// drainSlack is added to a drain budget derived from the pods' grace period, for what follows the
+// grace period: the kubelet's SIGKILL, the pod's removal, and the StatefulSet status catching up.
+const drainSlack = 30 * time.Second
+
 // defaultCleanupRateLimitRetryAfter mirrors GenericReconcilerConfig.RateLimitRetryAfter for a
 // cleaner a product builds directly.
 const defaultCleanupRateLimitRetryAfter = 10 * time.Second
@@ -117,6 +133,13 @@ type RoleGroupCleaner struct {
 	// RoleGroupResources.ExtraResources; only their GVK is read. Empty means the cleaner deletes
 	// only the framework's fixed kinds, which is what it did before WithExtraResourceKinds existed.
 	extraResourceKinds []client.Object
+	// networkPolicies adds the role group's NetworkPolicy to the teardown. Off by default, so a
+	// cleaner in an operator without NetworkPolicy RBAC never reads one.
+	networkPolicies bool
+	// listenerFallback adds the built-in listener fallback's Services and Listeners to the teardown.
+	listenerFallback bool
+	// serviceMonitors adds the role group's ServiceMonitor to the teardown.
+	serviceMonitors bool
 }
 
 // NewRoleGroupCleaner creates a new RoleGroupCleaner.
@@ -127,6 +150,27 @@ func NewRoleGroupCleaner(client client.Client, scheme *runtime.Scheme) *RoleGrou
 	}
 }
 
+// WithNetworkPolicies makes the teardown delete the role group's NetworkPolicy slot too (see
+// GenericReconcilerConfig.NetworkPolicies).
+func (c *RoleGroupCleaner) WithNetworkPolicies() *RoleGroupCleaner {
+	c.networkPolicies = true
+	return c
+}
+
+// WithListenerFallback makes the teardown delete the per-pod Services and Listeners the built-in
+// listener fallback created for the role group (see GenericReconcilerConfig.ListenerFallback).
+func (c *RoleGroupCleaner) WithListenerFallback() *RoleGroupCleaner {
+	c.listenerFallback = true
+	return c
+}
+
+// WithServiceMonitors makes the teardown delete the role group's ServiceMonitor (see
+// GenericReconcilerConfig.ServiceMonitors).
+func (c *RoleGroupCleaner) WithServiceMonitors() *RoleGroupCleaner {
+	c.serviceMonitors = true
+	return c
+}
+
 // WithGrayDeleteGracePeriod sets the grace period for gray deletion.
 // When > 0, orphaned resources are first annotated and only deleted after the grace period.
 // When 0 (default), resources are deleted immediately.
@@ -146,9 +190,10 @@ func (c *RoleGroupCleaner) WithDrainPollInterval(d time.Duration) *RoleGroupClea
 
 // WithDrainTimeout bounds how long an orphaned StatefulSet's ordered drain may block the rest of
 // its role group's teardown; once it elapses the StatefulSet is deleted with pods still
-// terminating. A non-positive value keeps DefaultDrainTimeout — the wait is always bounded,
-// because everything that follows it (the ConfigMap, the Services, the status entry) is blocked
-// until it ends.
+// terminating. A non-positive value derives the bound from the StatefulSet's own shutdown budget
+// (drainBudget), falling back to DefaultDrainTimeout — the wait is always bounded, because
+// everything that follows it (the ConfigMap, the Services, the status entry) is blocked until it
+// ends.
 func (c *RoleGroupCleaner) WithDrainTimeout(d time.Duration) *RoleGroupCleaner {
 	c.drainTimeout = d
 	return c
@@ -180,14 +225,55 @@ func (c *RoleGroupCleaner) pollInterval() time.Duration {
 	return DefaultDrainPollInterval
 }
 
-// drainDeadline is how long an orphaned StatefulSet may keep the rest of its role group waiting.
-func (c *RoleGroupCleaner) drainDeadline() time.Duration {
+// drainDeadline is how long an orphaned StatefulSet may keep the rest of its role group waiting:
+// the configured timeout, else the budget stamped when its drain started, else
+// DefaultDrainTimeout.
+func (c *RoleGroupCleaner) drainDeadline(sts *appsv1.StatefulSet) time.Duration {
 	if c.drainTimeout > 0 {
 		return c.drainTimeout
 	}
+	if budget, err := time.ParseDuration(sts.GetAnnotations()[AnnotationDrainBudget]); err == nil && budget > 0 {
+		return budget
+	}
 	return DefaultDrainTimeout
 }
 
+// drainBudget is how long draining replicas pods of sts should take when every pod uses its whole
+// grace period — the gracefulShutdownTimeout the role group was built with: one grace period when
+// the pods stop in parallel (the framework's default pod management), one per pod when they stop
+// in order, plus drainSlack. Zero means the pods state no grace period to derive it from.
+//
+// Deriving it keeps the two budgets consistent in both directions. A fixed deadline shorter than a
+// long grace period — a DataNode given 30 minutes to decommission — cut the ordered shutdown short;
+// one far longer than a short grace period kept the teardown waiting on a pod that could only be
+// stuck.
+func drainBudget(sts *appsv1.StatefulSet, replicas int32) time.Duration {
+	grace := sts.Spec.Template.Spec.TerminationGracePeriodSeconds
+	if grace == nil {
+		return 0
+	}
+	pods := int64(1)
+	if sts.Spec.PodManagementPolicy != appsv1.ParallelPodManagement && replicas > 1 {
+		pods = int64(replicas)
+	}
+	return time.Duration(*grace*pods)*time.Second + drainSlack
+}
+
+// setDrainBudget records drainBudget(sts, replicas) on sts, or removes a stale record when the pods
+// state no grace period.
+func setDrainBudget(sts *appsv1.StatefulSet, replicas int32) {
+	annotations := sts.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	if budget := drainBudget(sts, replicas); budget > 0 {
+		annotations[AnnotationDrainBudget] = budget.String()
+	} else {
+		delete(annotations, AnnotationDrainBudget)
+	}
+	sts.SetAnnotations(annotations)
+}
+
 // confirmReader is the reader used for the terminal "nothing of this role group is left" check.
 // It is the uncached APIReader when one is wired, falling back to the regular client.
 func (c *RoleGroupCleaner) confirmReader() client.Reader {
@@ -259,7 +345,9 @@ func (c *RoleGroupCleaner) emitDeleted(clusterName string, obj client.Object) {
 // Cleanup removes orphaned resources for a cluster.
 // Resources are deleted in order: PDB → StatefulSet → ConfigMap → Service → headless Service →
 // metrics Service, and the role-level PDB of any role that disappeared from the spec entirely.
-// PVCs are intentionally preserved to protect data unless AnnotationDeletePVCs is set in crAnnotations.
+// PVCs are intentionally preserved to protect data unless the orphaned StatefulSet's retention
+// policy says whenDeleted: Delete or AnnotationDeletePVCs is set in crAnnotations; preserved PVCs
+// are labelled LabelRetainedPVC, and re-adopted when their role group comes back.
 // Only resources with an ownerReference pointing to ownerUID (with controller=true) are deleted.
 // If GrayDeleteGracePeriod > 0, resources are annotated on first detection and only deleted
 // after the grace period has elapsed. Resources that are no longer orphaned have the annotation cleared.
@@ -288,7 +376,10 @@ func (c *RoleGroupCleaner) Cleanup(
 	status *v1alpha1.GenericClusterStatus,
 	ownerUID types.UID,
 	crAnnotations map[string]string,
-) (time.Duration, error) {
+) (requeueAfter time.Duration, err error) {
+	ctx, span := startSpan(ctx, "RoleGroupCleaner.Cleanup", attrNamespace.String(namespace), attrCluster.String(clusterName))
+	defer func() { endSpan(span, err) }()
+
 	logger := log.FromContext(ctx)
 
 	deletePVCs := crAnnotations[AnnotationDeletePVCs] == valueTrue
@@ -311,6 +402,10 @@ func (c *RoleGroupCleaner) Cleanup(
 				logger.V(1).Info("Failed to reset teardown progress on an active resource",
 					"resource", resourceName, "error", err)
 			}
+			if err := c.readoptRetainedPVCs(ctx, namespace, clusterName, roleName, groupName); err != nil {
+				logger.V(1).Info("Failed to re-adopt the retained PVCs of an active role group",
+					"resource", resourceName, "error", err)
+			}
 		}
 	}
 
@@ -697,7 +792,8 @@ func (c *RoleGroupCleaner) cleanupRoleGroup(
 		}
 	}
 
-	// Delete in order: PDB → StatefulSet → ConfigMap → Service → headless Service → metrics Service.
+	// Delete in order: PDB → StatefulSet → ConfigMap → logging ConfigMap → Service → ServiceMonitor →
+	// NetworkPolicy → headless Service → metrics Service.
 	// The order only means something because each step is confirmed gone before the next is issued:
 	// the PDB goes first so it cannot block the eviction of the pods that follow, and the Services
 	// go last so the pods still resolve each other while they terminate.
@@ -706,7 +802,7 @@ func (c *RoleGroupCleaner) cleanupRoleGroup(
 			return deleteOwned[policyv1.PodDisruptionBudget](ctx, c, namespace, resourceName, ownerUID, clusterName)
 		},
 		func() (deletionState, error) {
-			return c.deleteStatefulSet(ctx, namespace, resourceName, ownerUID, deletePVCs, clusterName)
+			return c.deleteStatefulSet(ctx, namespace, orphan, ownerUID, deletePVCs, clusterName)
 		},
 		// The product's own extras go after the workload, mirroring the apply path that creates
 		// them BEFORE it: they are typically pod-scheduling prerequisites (a Listener CR the pods
@@ -714,13 +810,42 @@ func (c *RoleGroupCleaner) cleanupRoleGroup(
 		func() (deletionState, error) {
 			return c.deleteRoleGroupExtras(ctx, namespace, clusterName, orphan.roleName, orphan.groupName, ownerUID)
 		},
+		// The listener fallback's objects stand in for a Listener the pods mount, so they go where
+		// the extras that usually carry one go.
 		func() (deletionState, error) {
-			return deleteOwned[corev1.ConfigMap](ctx, c, namespace, resourceName, ownerUID, clusterName)
+			return c.deleteListenerFallback(ctx, namespace, clusterName, orphan.roleName, orphan.groupName, ownerUID)
 		},
 		func() (deletionState, error) {
-			return deleteOwned[corev1.Service](ctx, c, namespace, resourceName, ownerUID, clusterName)
+			return deleteOwned[corev1.ConfigMap](ctx, c, namespace, resourceName, ownerUID, clusterName)
 		},
 	}
+	// The logging ConfigMap of a role group that reloads its logging config. Its name is derived by
+	// suffix too, so it is skipped for the same reason the derived Services below are: a live role
+	// group may be named "<group>-log-config", and its own ConfigMap carries the same owner.
+	var derivedConfigMaps []string
+	if logConfig := logConfigMapName(resourceName); !isLiveResourceName(ctx, liveResourceNames, logConfig) {
+		derivedConfigMaps = append(derivedConfigMaps, logConfig)
+		steps = append(steps, func() (deletionState, error) {
+			return deleteOwned[corev1.ConfigMap](ctx, c, namespace, logConfig, ownerUID, clusterName)
+		})
+	}
+	steps = append(steps, func() (deletionState, error) {
+		return deleteOwned[corev1.Service](ctx, c, namespace, resourceName, ownerUID, clusterName)
+	})
+	// The ServiceMonitor goes before the metrics Service it scrapes, so Prometheus never holds a
+	// monitor whose target has already vanished.
+	if c.serviceMonitors {
+		steps = append(steps, func() (deletionState, error) {
+			return c.deleteServiceMonitors(ctx, namespace, clusterName, orphan.roleName, orphan.groupName, ownerUID)
+		})
+	}
+	// After the workload: in a default-deny namespace, deleting the policy first would cut the
+	// terminating pods off from the peers they hand their data to.
+	if c.networkPolicies {
+		steps = append(steps, func() (deletionState, error) {
+			return deleteOwned[networkingv1.NetworkPolicy](ctx, c, namespace, resourceName, ownerUID, clusterName)
+		})
+	}
 
 	// The headless and metrics names are derived by suffix, and a role group may legitimately be
 	// named "<group>-headless" or "<group>-metrics", making its own client Service collide with
@@ -730,9 +855,7 @@ func (c *RoleGroupCleaner) cleanupRoleGroup(
 	derivedServices := make([]string, 0, 2)
 	for _, suffix := range []string{"-headless", "-metrics"} {
 		derived := resourceName + suffix
-		if _, live := liveResourceNames[derived]; live {
-			log.FromContext(ctx).V(1).Info("Skipping derived Service deletion: name belongs to a live role group",
-				"name", derived)
+		if isLiveResourceName(ctx, liveResourceNames, derived) {
 			continue
 		}
 		derivedServices = append(derivedServices, derived)
@@ -756,7 +879,7 @@ func (c *RoleGroupCleaner) cleanupRoleGroup(
 	// prunes the role group from Status.RoleGroups on the strength of this verdict, and that
 	// snapshot is the ONLY record the orphan detector has: a wrong "nothing left" answer leaves
 	// live resources that nothing will ever look at again. Confirm it against the API server.
-	state, err := c.confirmRoleGroupReclaimed(ctx, namespace, resourceName, ownerUID, derivedServices)
+	state, err := c.confirmRoleGroupReclaimed(ctx, namespace, resourceName, ownerUID, derivedConfigMaps, derivedServices)
 	if err != nil {
 		return false, 0, err
 	}
@@ -776,17 +899,26 @@ func (c *RoleGroupCleaner) confirmRoleGroupReclaimed(
 	ctx context.Context,
 	namespace, resourceName string,
 	ownerUID types.UID,
-	derivedServices []string,
+	derivedConfigMaps, derivedServices []string,
 ) (deletionState, error) {
 	key := types.NamespacedName{Namespace: namespace, Name: resourceName}
 
-	checks := make([]func() (bool, error), 0, 4+len(derivedServices))
+	checks := make([]func() (bool, error), 0, 5+len(derivedConfigMaps)+len(derivedServices))
 	checks = append(checks,
 		func() (bool, error) { return stillOwned[policyv1.PodDisruptionBudget](ctx, c, key, ownerUID) },
 		func() (bool, error) { return stillOwned[appsv1.StatefulSet](ctx, c, key, ownerUID) },
 		func() (bool, error) { return stillOwned[corev1.ConfigMap](ctx, c, key, ownerUID) },
 		func() (bool, error) { return stillOwned[corev1.Service](ctx, c, key, ownerUID) },
 	)
+	if c.networkPolicies {
+		checks = append(checks, func() (bool, error) { return stillOwned[networkingv1.NetworkPolicy](ctx, c, key, ownerUID) })
+	}
+	for _, derived := range derivedConfigMaps {
+		derivedKey := types.NamespacedName{Namespace: namespace, Name: derived}
+		checks = append(checks, func() (bool, error) {
+			return stillOwned[corev1.ConfigMap](ctx, c, derivedKey, ownerUID)
+		})
+	}
 	for _, derived := range derivedServices {
 		derivedKey := types.NamespacedName{Namespace: namespace, Name: derived}
 		checks = append(checks, func() (bool, error) {
@@ -809,6 +941,18 @@ func (c *RoleGroupCleaner) confirmRoleGroupReclaimed(
 	return deletionSettled, nil
 }
 
+// isLiveResourceName reports whether a name derived from an orphan's resource name is the resource
+// name of a role group the spec still declares, in which case the orphan's teardown must not touch
+// it.
+func isLiveResourceName(ctx context.Context, liveResourceNames map[string]struct{}, derived string) bool {
+	if _, live := liveResourceNames[derived]; !live {
+		return false
+	}
+	log.FromContext(ctx).V(1).Info("Skipping derived resource deletion: name belongs to a live role group",
+		"name", derived)
+	return true
+}
+
 // stillOwned reports whether a resource this cluster owns is still present, read through the
 // uncached confirmation reader.
 func stillOwned[T any, PT ptrObject[T]](ctx context.Context, c *RoleGroupCleaner, key types.NamespacedName, ownerUID types.UID) (bool, error) {
@@ -1038,20 +1182,23 @@ func (c *RoleGroupCleaner) deletePDB(ctx context.Context, namespace, name string
 // Each phase returns deletionInFlight so the caller requeues instead of blocking a reconcile
 // worker: a drain outlives many reconcile cycles.
 //
-// When deletePVCs is true the PVCs are deleted at the END of that sequence — after the drain,
-// immediately before the StatefulSet — and not at the start. See the comment at that call site for
-// why the irreversible step goes last, why it nevertheless precedes the StatefulSet, and why the
-// drain-timeout path falls through to it. Without the annotation they are preserved.
+// When deletePVCs is true, or the StatefulSet's own retention policy says whenDeleted: Delete, the
+// PVCs are deleted at the END of that sequence — after the drain, immediately before the
+// StatefulSet — and not at the start. See the comment at that call site for why the irreversible
+// step goes last, why it nevertheless precedes the StatefulSet, and why the drain-timeout path falls
+// through to it. Otherwise they are preserved, and labelled as retained at the same point.
 //
 // clusterName names the owning cluster in the emitted Deleted event.
 func (c *RoleGroupCleaner) deleteStatefulSet(
 	ctx context.Context,
-	namespace, name string,
+	namespace string,
+	orphan orphanRef,
 	ownerUID types.UID,
 	deletePVCs bool,
 	clusterName string,
 ) (deletionState, error) {
 	logger := log.FromContext(ctx)
+	name := orphan.resourceName
 	sts := &appsv1.StatefulSet{}
 	key := types.NamespacedName{Namespace: namespace, Name: name}
 
@@ -1067,9 +1214,11 @@ func (c *RoleGroupCleaner) deleteStatefulSet(
 		return deletionSettled, nil
 	}
 
+	deleteClaims := deletePVCs || claimsDeletedWith(sts)
+
 	// A nil replica count means the API server default of 1, so it is a scale-down like any other.
 	if sts.Spec.Replicas == nil || *sts.Spec.Replicas > 0 {
-		if err := c.scaleToZero(ctx, key); err != nil {
+		if err := c.scaleToZero(ctx, key, !deleteClaims); err != nil {
 			return deletionInFlight, err
 		}
 		logger.Info("Scaled orphaned StatefulSet to zero, waiting for the drain", "name", name)
@@ -1092,7 +1241,7 @@ func (c *RoleGroupCleaner) deleteStatefulSet(
 			return deletionInFlight, nil
 		}
 		logger.Info("Orphaned StatefulSet drain timed out; deleting it with pods still terminating",
-			"name", name, "remainingReplicas", sts.Status.Replicas, "drainTimeout", c.drainDeadline())
+			"name", name, "remainingReplicas", sts.Status.Replicas, "drainTimeout", c.drainDeadline(sts))
 		OrphanDrainTimeouts.WithLabelValues(namespace, clusterName).Inc()
 	}
 
@@ -1118,10 +1267,15 @@ func (c *RoleGroupCleaner) deleteStatefulSet(
 	//
 	// The drain-timeout path above falls through to here deliberately: the user asked for the PVCs
 	// to go, and skipping them because a pod would not terminate would leak the volumes silently.
-	if deletePVCs {
+	//
+	// Retained PVCs are labelled here for the same reason they would be deleted here: the
+	// StatefulSet's selector is how the cleaner finds them, so it is the last chance to.
+	if deleteClaims {
 		if err := c.deletePVCsForStatefulSet(ctx, sts); err != nil {
 			return deletionInFlight, err
 		}
+	} else if err := c.retainPVCsForStatefulSet(ctx, sts, clusterName, orphan); err != nil {
+		return deletionInFlight, err
 	}
 
 	if err := c.Client.Delete(ctx, sts); err != nil {
@@ -1143,7 +1297,7 @@ func (c *RoleGroupCleaner) deleteStatefulSet(
 func (c *RoleGroupCleaner) drainDeadlineExpired(ctx context.Context, sts *appsv1.StatefulSet, key types.NamespacedName) (bool, error) {
 	if startedAt, ok := sts.GetAnnotations()[AnnotationDrainStarted]; ok {
 		if started, err := time.Parse(time.RFC3339, startedAt); err == nil {
-			return time.Since(started) >= c.drainDeadline(), nil
+			return time.Since(started) >= c.drainDeadline(sts), nil
 		}
 		// An unparsable timestamp is re-stamped below: it carries no deadline, and treating it as
 		// expired would delete a StatefulSet that may have started draining seconds ago.
@@ -1160,6 +1314,11 @@ func (c *RoleGroupCleaner) drainDeadlineExpired(ctx context.Context, sts *appsv1
 		}
 		annotations[AnnotationDrainStarted] = time.Now().UTC().Format(time.RFC3339)
 		live.SetAnnotations(annotations)
+		// The scale-to-zero write normally stamped the budget already. One scaled to zero by
+		// something else, or before budgets were recorded, gets the best count still left.
+		if _, ok := annotations[AnnotationDrainBudget]; !ok {
+			setDrainBudget(live, live.Status.Replicas)
+		}
 		return c.Client.Update(ctx, live)
 	})
 	// The StatefulSet disappeared while the drain was being timestamped: the next step re-reads it.
@@ -1172,15 +1331,32 @@ func (c *RoleGroupCleaner) drainDeadlineExpired(ctx context.Context, sts *appsv1
 // scaleToZero sets .spec.replicas to 0 on a live StatefulSet, re-reading it on conflict. The same
 // object is written by the apply path and by any autoscaler pointed at it, so a routine 409 must
 // not turn into a failed cleanup pass that leaves the role group half-deleted.
-func (c *RoleGroupCleaner) scaleToZero(ctx context.Context, key types.NamespacedName) error {
+//
+// When retainClaims is set, a whenScaled: Delete retention policy is turned to Retain in the same
+// write. Scaling to zero condemns every pod, so under that policy the StatefulSet controller itself
+// would delete every PVC on the way down — a role group whose whenDeleted says Retain would lose
+// its data to the drain that precedes the deletion.
+//
+// The drain budget (AnnotationDrainBudget) is stamped in the same write, from the larger of the
+// desired and current replica counts, while every pod the drain will retire is still counted.
+func (c *RoleGroupCleaner) scaleToZero(ctx context.Context, key types.NamespacedName, retainClaims bool) error {
 	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
 		live := &appsv1.StatefulSet{}
 		if err := c.Client.Get(ctx, key, live); err != nil {
 			return err
 		}
-		if live.Spec.Replicas != nil && *live.Spec.Replicas == 0 {
+		policy := live.Spec.PersistentVolumeClaimRetentionPolicy
+		keepScaled := retainClaims && policy != nil &&
+			policy.WhenScaled == appsv1.DeletePersistentVolumeClaimRetentionPolicyType
+		if live.Spec.Replicas != nil && *live.Spec.Replicas == 0 && !keepScaled {
 			return nil
 		}
+		if keepScaled {
+			policy.WhenScaled = appsv1.RetainPersistentVolumeClaimRetentionPolicyType
+		}
+		if replicas := ptr.Deref(live.Spec.Replicas, 1); replicas > 0 {
+			setDrainBudget(live, max(replicas, live.Status.Replicas))
+		}
 		live.Spec.Replicas = ptr.To(int32(0))
 		return c.Client.Update(ctx, live)
 	})
@@ -1275,7 +1451,7 @@ func (c *RoleGroupCleaner) clearTeardownProgress(ctx context.Context, namespace,
 
 		annotations := primary.GetAnnotations()
 		changed := false
-		for _, a := range []string{AnnotationPendingDeletion, AnnotationDrainStarted} {
+		for _, a := range []string{AnnotationPendingDeletion, AnnotationDrainStarted, AnnotationDrainBudget} {
 			if _, ok := annotations[a]; ok {
 				delete(annotations, a)
 				changed = true
@@ -1343,46 +1519,101 @@ func (c *RoleGroupCleaner) deleteRoleGroupExtras(
 				fmt.Sprintf("%T", prototype))
 			continue
 		}
+		kindState, err := c.deleteLabelled(ctx, gvk, namespace, clusterName, roleName, groupName, ownerUID, selector, "extra")
+		if err != nil {
+			return deletionInFlight, err
+		}
+		if kindState == deletionInFlight {
+			state = deletionInFlight
+		}
+	}
 
-		// Read through the confirmation reader (the uncached APIReader when one is wired), for the
-		// reason confirmRoleGroupReclaimed already gives: the caller prunes the role group from
-		// Status.RoleGroups on the strength of this pass settling, and a cached List that has not
-		// caught up answers "nothing here" for an extra that exists.
-		//
-		// For extras that answer is TERMINAL rather than merely early. The framework's own kinds are
-		// re-found by discoverLiveOrphans on a later pass, which is exactly the safety net #556
-		// added; extras are not in that inventory and have no derived name to look them up by, so a
-		// missed one is the leak this function exists to close, arriving through a stale cache
-		// instead of through the missing feature.
-		list := &unstructured.UnstructuredList{}
-		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
-		if err := c.confirmReader().List(ctx, list, client.InNamespace(namespace), selector); err != nil {
-			return deletionInFlight, fmt.Errorf("failed to list extra %s resources of role group %s/%s: %w",
-				gvk.Kind, roleName, groupName, c.apiError(err))
-		}
-
-		for i := range list.Items {
-			item := &list.Items[i]
-			if !isOwnedByCluster(item, ownerUID) {
-				continue
-			}
-			if !item.GetDeletionTimestamp().IsZero() {
-				// Already going; the next pass confirms it is gone.
-				state = deletionInFlight
+	return state, nil
+}
+
+// deleteListenerFallback deletes the Services and Listeners the built-in listener fallback created
+// for a role group: those carrying LabelListenerFallback for it and controlled by this cluster. The
+// role group's own Service never carries the label, so it is left to its own step.
+func (c *RoleGroupCleaner) deleteListenerFallback(
+	ctx context.Context,
+	namespace, clusterName, roleName, groupName string,
+	ownerUID types.UID,
+) (deletionState, error) {
+	if !c.listenerFallback || ownerUID == "" || roleName == "" || groupName == "" {
+		return deletionSettled, nil
+	}
+	selector := client.MatchingLabels{
+		constant.LabelKubernetesInstance:  clusterName,
+		constant.LabelKubernetesComponent: roleName,
+		LabelListenerFallback:             groupName,
+	}
+	state := deletionSettled
+	for _, gvk := range []schema.GroupVersionKind{
+		corev1.SchemeGroupVersion.WithKind("Service"),
+		listenersv1alpha1.GroupVersion.WithKind("Listener"),
+	} {
+		kindState, err := c.deleteLabelled(ctx, gvk, namespace, clusterName, roleName, groupName, ownerUID, selector, "listener fallback")
+		if err != nil {
+			return deletionInFlight, err
+		}
+		if kindState == deletionInFlight {
+			state = deletionInFlight
+		}
+	}
+	return state, nil
+}
+
+// deleteLabelled deletes every object of kind gvk that matches selector and is controlled by this
+// cluster. what names the objects in logs and errors.
+func (c *RoleGroupCleaner) deleteLabelled(
+	ctx context.Context,
+	gvk schema.GroupVersionKind,
+	namespace, clusterName, roleName, groupName string,
+	ownerUID types.UID,
+	selector client.MatchingLabels,
+	what string,
+) (deletionState, error) {
+	logger := log.FromContext(ctx)
+
+	// Read through the confirmation reader (the uncached APIReader when one is wired), for the
+	// reason confirmRoleGroupReclaimed already gives: the caller prunes the role group from
+	// Status.RoleGroups on the strength of this pass settling, and a cached List that has not
+	// caught up answers "nothing here" for an object that exists.
+	//
+	// For these objects that answer is TERMINAL rather than merely early. The framework's own kinds
+	// are re-found by discoverLiveOrphans on a later pass, which is exactly the safety net #556
+	// added; labelled objects are not in that inventory and have no derived name to look them up
+	// by, so a missed one is the leak this function exists to close, arriving through a stale cache
+	// instead of through the missing feature.
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := c.confirmReader().List(ctx, list, client.InNamespace(namespace), selector); err != nil {
+		return deletionInFlight, fmt.Errorf("failed to list %s %s resources of role group %s/%s: %w",
+			what, gvk.Kind, roleName, groupName, c.apiError(err))
+	}
+
+	state := deletionSettled
+	for i := range list.Items {
+		item := &list.Items[i]
+		if !isOwnedByCluster(item, ownerUID) {
+			continue
+		}
+		if !item.GetDeletionTimestamp().IsZero() {
+			// Already going; the next pass confirms it is gone.
+			state = deletionInFlight
+			continue
+		}
+		if err := c.Client.Delete(ctx, item); err != nil {
+			if errors.IsNotFound(err) {
 				continue
 			}
-			if err := c.Client.Delete(ctx, item); err != nil {
-				if errors.IsNotFound(err) {
-					continue
-				}
-				return deletionInFlight, fmt.Errorf("failed to delete extra %s %s/%s: %w",
-					gvk.Kind, namespace, item.GetName(), c.apiError(err))
-			}
-			logger.Info("Deleted orphaned extra resource",
-				"kind", gvk.Kind, "name", item.GetName(), "role", roleName, "group", groupName)
-			c.emitDeleted(clusterName, item)
-			state = deletionInFlight
+			return deletionInFlight, fmt.Errorf("failed to delete %s %s %s/%s: %w",
+				what, gvk.Kind, namespace, item.GetName(), c.apiError(err))
 		}
+		logger.Info("Deleted orphaned "+what+" resource",
+			"kind", gvk.Kind, "name", item.GetName(), "role", roleName, "group", groupName)
+		c.emitDeleted(clusterName, item)
+		state = deletionInFlight
 	}
 
 	return state, nil
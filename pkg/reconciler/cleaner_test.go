@@ -1461,6 +1461,20 @@ func (c *failingDeleteClient) Delete(ctx context.Context, obj client.Object, opt
 	return c.Client.Delete(ctx, obj, opts...)
 }
 
+// forbiddenPVCPatchClient answers every PVC Patch with a 403, as the API server does under the
+// baseline RBAC, which grants PVCs no patch.
+type forbiddenPVCPatchClient struct {
+	client.Client
+}
+
+func (c *forbiddenPVCPatchClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if _, ok := obj.(*corev1.PersistentVolumeClaim); ok {
+		return k8serrors.NewForbidden(schema.GroupResource{Resource: "persistentvolumeclaims"}, obj.GetName(),
+			fmt.Errorf("simulated missing patch grant"))
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
 var _ = Describe("RoleGroupCleaner API failure handling", func() {
 	var ctx context.Context
 
@@ -1754,6 +1768,95 @@ var _ = Describe("RoleGroupCleaner bounded drain", func() {
 	})
 })
 
+var _ = Describe("RoleGroupCleaner drain budget", func() {
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.Background()
+	})
+
+	It("waits as long as the pods' grace period allows when no drain timeout is configured", func() {
+		clusterName := "drain-budget"
+		resourceName := reconciler.RoleGroupResourceName(clusterName, "role", "gone")
+
+		// A DataNode given 30 minutes to decommission: the 10-minute default would cut it short.
+		sts := orphanTestStatefulSet(resourceName, 0)
+		sts.Spec.PodManagementPolicy = appsv1.ParallelPodManagement
+		sts.Spec.Template.Spec.TerminationGracePeriodSeconds = ptr.To(int64(1800))
+		Expect(k8sClient.Create(ctx, sts)).To(Succeed())
+		DeferCleanup(func() {
+			_ = k8sClient.Delete(ctx, sts)
+		})
+		sts.Status.Replicas = 3
+		Expect(k8sClient.Status().Update(ctx, sts)).To(Succeed())
+
+		status := &v1alpha1.GenericClusterStatus{}
+		status.SetRoleGroup("role", "gone")
+		cleaner := reconciler.NewRoleGroupCleaner(k8sClient, testScheme)
+		cleanup := func() {
+			GinkgoHelper()
+			_, err := cleaner.Cleanup(ctx, cleanerTestNamespace, clusterName, orphanedGroupSpec(), status, "", nil)
+			Expect(err).To(Succeed())
+		}
+		startedAgo := func(d time.Duration) {
+			GinkgoHelper()
+			live := &appsv1.StatefulSet{}
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(sts), live)).To(Succeed())
+			live.Annotations[reconciler.AnnotationDrainStarted] = time.Now().Add(-d).UTC().Format(time.RFC3339)
+			Expect(k8sClient.Update(ctx, live)).To(Succeed())
+		}
+
+		cleanup()
+		live := &appsv1.StatefulSet{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(sts), live)).To(Succeed())
+		// The pods stop in parallel, so one grace period covers all three.
+		Expect(live.Annotations).To(HaveKeyWithValue(reconciler.AnnotationDrainBudget, "30m30s"))
+
+		startedAgo(20 * time.Minute)
+		cleanup()
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(sts), &appsv1.StatefulSet{})).To(Succeed())
+
+		startedAgo(31 * time.Minute)
+		cleanup()
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(sts), &appsv1.StatefulSet{})).
+			To(MatchError(k8serrors.IsNotFound, "IsNotFound"))
+	})
+
+	It("budgets an ordered drain for every pod it will retire, not the ones left when it is first seen", func() {
+		clusterName := "drain-budget-ordered"
+		resourceName := reconciler.RoleGroupResourceName(clusterName, "role", "gone")
+
+		sts := orphanTestStatefulSet(resourceName, 3)
+		sts.Spec.PodManagementPolicy = appsv1.OrderedReadyPodManagement
+		sts.Spec.Template.Spec.TerminationGracePeriodSeconds = ptr.To(int64(60))
+		Expect(k8sClient.Create(ctx, sts)).To(Succeed())
+		DeferCleanup(func() {
+			_ = k8sClient.Delete(ctx, sts)
+		})
+		sts.Status.Replicas = 3
+		Expect(k8sClient.Status().Update(ctx, sts)).To(Succeed())
+
+		status := &v1alpha1.GenericClusterStatus{}
+		status.SetRoleGroup("role", "gone")
+		cleaner := reconciler.NewRoleGroupCleaner(k8sClient, testScheme)
+		_, err := cleaner.Cleanup(ctx, cleanerTestNamespace, clusterName, orphanedGroupSpec(), status, "", nil)
+		Expect(err).To(Succeed())
+
+		// By the next pass the StatefulSet controller has already retired two of the three pods.
+		live := &appsv1.StatefulSet{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(sts), live)).To(Succeed())
+		live.Status.Replicas = 1
+		Expect(k8sClient.Status().Update(ctx, live)).To(Succeed())
+		_, err = cleaner.Cleanup(ctx, cleanerTestNamespace, clusterName, orphanedGroupSpec(), status, "", nil)
+		Expect(err).To(Succeed())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(sts), live)).To(Succeed())
+		Expect(live.Annotations).To(HaveKey(reconciler.AnnotationDrainStarted))
+		Expect(live.Annotations).To(HaveKeyWithValue(reconciler.AnnotationDrainBudget, "3m30s"),
+			"three grace periods, one per pod, stamped with the scale-down")
+	})
+})
+
 var _ = Describe("RoleGroupCleaner gray deletion across the teardown", func() {
 	var ctx context.Context
 
@@ -2321,7 +2424,10 @@ var _ = Describe("RoleGroupCleaner PVC deletion order", func() {
 
 	// stsWithPVC creates an orphaned StatefulSet declaring a volumeClaimTemplate, plus a PVC
 	// carrying the selector labels the StatefulSet controller copies onto the real ones.
-	stsWithPVC := func(clusterName string, replicas, statusReplicas int32) (string, *corev1.PersistentVolumeClaim) {
+	stsWithPVC := func(
+		clusterName string, replicas, statusReplicas int32,
+		policy ...*appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy,
+	) (string, *corev1.PersistentVolumeClaim) {
 		resourceName := reconciler.RoleGroupResourceName(clusterName, "role", "gone")
 		sts := orphanTestStatefulSet(resourceName, replicas)
 		sts.Spec.VolumeClaimTemplates = []corev1.PersistentVolumeClaim{{
@@ -2333,6 +2439,9 @@ var _ = Describe("RoleGroupCleaner PVC deletion order", func() {
 				},
 			},
 		}}
+		if len(policy) > 0 {
+			sts.Spec.PersistentVolumeClaimRetentionPolicy = policy[0]
+		}
 		Expect(k8sClient.Create(ctx, sts)).To(Succeed())
 		DeferCleanup(func() { _ = k8sClient.Delete(ctx, sts) })
 
@@ -2435,6 +2544,127 @@ var _ = Describe("RoleGroupCleaner PVC deletion order", func() {
 		Expect(pvcExists(pvc)).To(BeTrue())
 	})
 
+	It("deletes the PVCs without the annotation when the StatefulSet's policy says whenDeleted: Delete", func() {
+		// The role group is gone from the spec, so the policy is read from the StatefulSet it left.
+		clusterName := "pvc-policy-delete"
+		_, pvc := stsWithPVC(clusterName, 0, 0, &appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy{
+			WhenDeleted: appsv1.DeletePersistentVolumeClaimRetentionPolicyType,
+			WhenScaled:  appsv1.RetainPersistentVolumeClaimRetentionPolicyType,
+		})
+
+		status := &v1alpha1.GenericClusterStatus{}
+		status.SetRoleGroup("role", "gone")
+		cleaner := reconciler.NewRoleGroupCleaner(k8sClient, testScheme)
+
+		_, err := cleaner.Cleanup(ctx, cleanerTestNamespace, clusterName, orphanedGroupSpec(), status, "", nil)
+		Expect(err).To(Succeed())
+		Expect(pvcExists(pvc)).To(BeFalse())
+	})
+
+	It("labels the retained PVCs with the cluster, role and role group they came from", func() {
+		clusterName := "pvc-retain-label"
+		_, pvc := stsWithPVC(clusterName, 0, 0)
+
+		status := &v1alpha1.GenericClusterStatus{}
+		status.SetRoleGroup("role", "gone")
+		cleaner := reconciler.NewRoleGroupCleaner(k8sClient, testScheme)
+
+		_, err := cleaner.Cleanup(ctx, cleanerTestNamespace, clusterName, orphanedGroupSpec(), status, "", nil)
+		Expect(err).To(Succeed())
+
+		live := &corev1.PersistentVolumeClaim{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pvc), live)).To(Succeed())
+		Expect(live.DeletionTimestamp).To(BeNil())
+		Expect(live.Labels).To(HaveKeyWithValue(reconciler.LabelRetainedPVC, "true"))
+		Expect(live.Labels).To(HaveKeyWithValue(constant.LabelKubernetesInstance, clusterName))
+		Expect(live.Labels).To(HaveKeyWithValue(constant.LabelKubernetesComponent, "role"))
+		Expect(live.Labels).To(HaveKeyWithValue(constant.LabelKubernetesRoleGroup, "gone"))
+	})
+
+	It("deletes the StatefulSet with its PVCs unlabelled when the operator may not patch PVCs", func() {
+		// The baseline RBAC grants PVCs no patch. The label is a courtesy; the teardown is not.
+		clusterName := "pvc-retain-forbidden"
+		resourceName, pvc := stsWithPVC(clusterName, 0, 0)
+
+		status := &v1alpha1.GenericClusterStatus{}
+		status.SetRoleGroup("role", "gone")
+		cleaner := reconciler.NewRoleGroupCleaner(&forbiddenPVCPatchClient{Client: k8sClient}, testScheme)
+
+		_, err := cleaner.Cleanup(ctx, cleanerTestNamespace, clusterName, orphanedGroupSpec(), status, "", nil)
+		Expect(err).To(Succeed())
+
+		Expect(k8sClient.Get(ctx, types.NamespacedName{
+			Namespace: cleanerTestNamespace, Name: resourceName}, &appsv1.StatefulSet{})).
+			To(MatchError(k8serrors.IsNotFound, "IsNotFound"))
+		live := &corev1.PersistentVolumeClaim{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pvc), live)).To(Succeed())
+		Expect(live.DeletionTimestamp).To(BeNil())
+		Expect(live.Labels).NotTo(HaveKey(reconciler.LabelRetainedPVC))
+	})
+
+	It("turns whenScaled to Retain in the scale-down when the PVCs are to be retained", func() {
+		// Scaling to zero condemns every pod; under whenScaled: Delete the StatefulSet controller
+		// would delete every claim on the way down, whatever whenDeleted says.
+		clusterName := "pvc-retain-scaled"
+		resourceName, pvc := stsWithPVC(clusterName, 3, 0, &appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy{
+			WhenDeleted: appsv1.RetainPersistentVolumeClaimRetentionPolicyType,
+			WhenScaled:  appsv1.DeletePersistentVolumeClaimRetentionPolicyType,
+		})
+
+		status := &v1alpha1.GenericClusterStatus{}
+		status.SetRoleGroup("role", "gone")
+		cleaner := reconciler.NewRoleGroupCleaner(k8sClient, testScheme).WithDrainPollInterval(pollInterval)
+
+		_, err := cleaner.Cleanup(ctx, cleanerTestNamespace, clusterName, orphanedGroupSpec(), status, "", nil)
+		Expect(err).To(Succeed())
+
+		live := &appsv1.StatefulSet{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: cleanerTestNamespace, Name: resourceName}, live)).
+			To(Succeed())
+		Expect(live.Spec.Replicas).To(HaveValue(BeZero()))
+		Expect(live.Spec.PersistentVolumeClaimRetentionPolicy.WhenScaled).
+			To(Equal(appsv1.RetainPersistentVolumeClaimRetentionPolicyType))
+		Expect(pvcExists(pvc)).To(BeTrue())
+	})
+
+	It("re-adopts the retained PVCs when the role group comes back", func() {
+		clusterName := "pvc-readopt"
+		resourceName := reconciler.RoleGroupResourceName(clusterName, "role", "back")
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "data-" + resourceName + "-0",
+				Namespace: cleanerTestNamespace,
+				Labels: map[string]string{
+					reconciler.LabelRetainedPVC:       "true",
+					constant.LabelKubernetesInstance:  clusterName,
+					constant.LabelKubernetesComponent: "role",
+					constant.LabelKubernetesRoleGroup: "back",
+				},
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pvc)).To(Succeed())
+		DeferCleanup(func() { _ = k8sClient.Delete(ctx, pvc) })
+
+		spec := &v1alpha1.GenericClusterSpec{
+			Roles: map[string]v1alpha1.RoleSpec{"role": {RoleGroups: map[string]v1alpha1.RoleGroupSpec{"back": {}}}},
+		}
+		cleaner := reconciler.NewRoleGroupCleaner(k8sClient, testScheme)
+		_, err := cleaner.Cleanup(ctx, cleanerTestNamespace, clusterName, spec, &v1alpha1.GenericClusterStatus{}, "", nil)
+		Expect(err).To(Succeed())
+
+		live := &corev1.PersistentVolumeClaim{}
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(pvc), live)).To(Succeed())
+		Expect(live.Labels).NotTo(HaveKey(reconciler.LabelRetainedPVC))
+		Expect(live.Labels).To(HaveKeyWithValue(constant.LabelKubernetesRoleGroup, "back"),
+			"the provenance stays; only the marker goes")
+	})
+
 	It("still deletes the PVCs when the drain times out", func() {
 		// A pod that will not terminate must not leak the volumes the user asked to reclaim: the
 		// timeout path falls through to the same deletion rather than skipping it.
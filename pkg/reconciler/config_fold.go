@@ -17,14 +17,18 @@ limitations under the License.
 package reconciler
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
 
 	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
 	"github.com/zncdatadev/operator-go/pkg/productlogging"
@@ -104,12 +108,18 @@ var opaqueLeafTypes = map[reflect.Type]struct{}{
 //
 //   - resources folds per LEAF. Overriding one knob (cpu.max, a storageClass) and keeping its
 //     siblings is the normal way to use this API, and a struct-level fold silently dropped the rest.
+//     resources.volumes and resources.sidecars fold per name and then per field, by the same argument.
 //   - affinity is REPLACED WHOLESALE by any layer that states one, and an empty value CLEARS. Each
 //     layer is decoded STRICTLY (DecodeAffinity rejects an unknown field, so `nodeAffinty` is a
 //     build failure rather than pods scheduled anywhere). The result is normalized, so a cleared
 //     member renders byte-identically to one that never existed and produces no churn in the apply
 //     path's diff. What a replacement discarded is REPORTED — see the second return value.
+//   - placement is REPLACED WHOLESALE too, by any layer that states one of its members, and what
+//     that discarded is reported the same way. Its schema is structural, so an empty block
+//     inherits; a member stated empty clears that member.
 //   - gracefulShutdownTimeout is atomic: a non-nil upper value wins.
+//   - persistentVolumeClaimRetentionPolicy folds per field, so a role's whenDeleted survives a
+//     group that only states whenScaled; an empty field inherits.
 //   - logging folds through productlogging.MergeLoggingSpec — on THIS path and no other.
 //
 // CLEARING IS PER FIELD, and the rule follows the SCHEMA rather than taste.
@@ -146,6 +156,7 @@ func FoldCommonConfig(layers ...*v1alpha1.RoleGroupConfigSpec) (
 	*v1alpha1.RoleGroupConfigSpec, []AffinityReplacement, error) {
 	out := &v1alpha1.RoleGroupConfigSpec{}
 	var affinity *corev1.Affinity
+	var placement *v1alpha1.PlacementSpec
 	var replaced []AffinityReplacement
 
 	for i, layer := range layers {
@@ -157,6 +168,8 @@ func FoldCommonConfig(layers ...*v1alpha1.RoleGroupConfigSpec) (
 			out.GracefulShutdownTimeout = copyString(layer.GracefulShutdownTimeout)
 		}
 		out.Resources = foldResources(out.Resources, layer.Resources)
+		out.PersistentVolumeClaimRetentionPolicy = foldPVCRetentionPolicy(
+			out.PersistentVolumeClaimRetentionPolicy, layer.PersistentVolumeClaimRetentionPolicy)
 		out.Logging = productlogging.MergeLoggingSpec(out.Logging, layer.Logging).DeepCopy()
 
 		decoded, err := DecodeAffinity(layer.Affinity)
@@ -165,9 +178,20 @@ func FoldCommonConfig(layers ...*v1alpha1.RoleGroupConfigSpec) (
 		}
 		next, dropped := foldAffinity(affinity, decoded)
 		if len(dropped) > 0 {
-			replaced = append(replaced, AffinityReplacement{Layer: i, Dropped: dropped})
+			replaced = append(replaced, AffinityReplacement{Field: ReplacedAffinity, Layer: i, Dropped: dropped})
 		}
 		affinity = next
+
+		var droppedPlacement []string
+		placement, droppedPlacement = foldPlacement(placement, layer.Placement)
+		if len(droppedPlacement) > 0 {
+			replaced = append(replaced, AffinityReplacement{Field: ReplacedPlacement, Layer: i, Dropped: droppedPlacement})
+		}
+	}
+	out.Placement = normalizePlacement(placement)
+
+	if err := validateFoldedResources(out.Resources); err != nil {
+		return nil, nil, err
 	}
 
 	encoded, err := EncodeAffinity(NormalizeAffinity(affinity))
@@ -178,16 +202,93 @@ func FoldCommonConfig(layers ...*v1alpha1.RoleGroupConfigSpec) (
 	return out, replaced, nil
 }
 
-// AffinityReplacement records one layer's affinity value discarding members that a layer beneath it
-// had declared. It exists so wholesale replacement is loud rather than silent; it is never an error,
-// because the upper layer getting its way IS the rule.
+// validateFoldedResources rejects a folded budget the API server would refuse, or that cannot mean
+// what it says. It runs on the FOLDED result because that is the only place the question has an
+// answer: a role group's memory request is compared with a limit that may be the role's, and each
+// layer alone is legal.
+//
+// A request above its limit is rejected by the API server too, but as a StatefulSet pod-template
+// error naming `spec.template.spec.containers[0].resources.requests` — a field the user never wrote.
+func validateFoldedResources(res *v1alpha1.ResourcesSpec) error {
+	if res == nil {
+		return nil
+	}
+	var problems []string
+	check := func(path string, request, limit *resource.Quantity) {
+		if request != nil && limit != nil && request.Cmp(*limit) > 0 {
+			problems = append(problems, fmt.Sprintf("%s.request %s exceeds %s.limit %s",
+				path, request.String(), path, limit.String()))
+		}
+	}
+	if res.Memory != nil {
+		check("resources.memory", res.Memory.Request, res.Memory.Limit)
+	}
+	if res.EphemeralStorage != nil {
+		check("resources.ephemeralStorage", res.EphemeralStorage.Request, res.EphemeralStorage.Limit)
+	}
+	names := make([]string, 0, len(res.Sidecars))
+	for name := range res.Sidecars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sc := res.Sidecars[name]
+		if sc.Memory != nil {
+			check("resources.sidecars."+name+".memory", sc.Memory.Request, sc.Memory.Limit)
+		}
+		if sc.EphemeralStorage != nil {
+			check("resources.sidecars."+name+".ephemeralStorage", sc.EphemeralStorage.Request, sc.EphemeralStorage.Limit)
+		}
+	}
+	if h := res.Heap; h != nil {
+		if h.Fraction != nil {
+			if f, err := strconv.ParseFloat(*h.Fraction, 64); err != nil || f < 0 || f > 1 {
+				problems = append(problems, fmt.Sprintf(
+					"resources.heap.fraction %q is not a decimal between 0 and 1", *h.Fraction))
+			}
+		}
+		if h.Min != nil && h.Max != nil && h.Min.Cmp(*h.Max) > 0 {
+			problems = append(problems, fmt.Sprintf("resources.heap.min %s exceeds resources.heap.max %s",
+				h.Min.String(), h.Max.String()))
+		}
+		if h.ReservedOffHeap != nil && res.Memory != nil && res.Memory.Limit != nil &&
+			h.ReservedOffHeap.Cmp(*res.Memory.Limit) >= 0 {
+			problems = append(problems, fmt.Sprintf(
+				"resources.heap.reservedOffHeap %s leaves no heap within resources.memory.limit %s",
+				h.ReservedOffHeap.String(), res.Memory.Limit.String()))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ReplacedConfigField names a config field FoldCommonConfig replaces wholesale and reports.
+type ReplacedConfigField string
+
+// The fields an AffinityReplacement can name. They are typed so that the fold and the reporter
+// agree through the compiler: a misspelt field is a build error, not a placement replacement that
+// reaches the user worded as an affinity one.
+const (
+	ReplacedAffinity  ReplacedConfigField = "affinity"
+	ReplacedPlacement ReplacedConfigField = "placement"
+)
+
+// AffinityReplacement records one layer's affinity or placement value discarding members that a
+// layer beneath it had declared. It exists so wholesale replacement is loud rather than silent; it is
+// never an error, because the upper layer getting its way IS the rule.
 type AffinityReplacement struct {
+	// Field is the config field that was replaced.
+	Field ReplacedConfigField
+
 	// Layer indexes the layers passed to FoldCommonConfig, so the caller names it in the words its
 	// own users use ("the role group config", not "layer 2").
 	Layer int
 
 	// Dropped names the members the replacement discarded, in a fixed order — nodeAffinity,
-	// podAffinity, podAntiAffinity — so a message built from it is byte-stable across reconciles.
+	// podAffinity, podAntiAffinity; nodeSelector, tolerations, topologySpreadConstraints — so a
+	// message built from it is byte-stable across reconciles.
 	// An unstable message would defeat the no-op guard in updateStatus.
 	Dropped []string
 }
@@ -233,6 +334,74 @@ func droppedAffinityMembers(lower, upper *corev1.Affinity) []string {
 	return dropped
 }
 
+// foldPlacement replaces the accumulated placement with the upper layer's, wholesale, and reports
+// the members that replacement discarded. A layer stating no member inherits: the schema is
+// structural, so an empty block may be what the API server pruned a misspelt member to, and reading
+// it as a clear would let a typo delete a product's zone spread.
+func foldPlacement(lower, upper *v1alpha1.PlacementSpec) (*v1alpha1.PlacementSpec, []string) {
+	if !upper.IsStated() {
+		return lower, nil
+	}
+	lower = normalizePlacement(lower)
+	if lower == nil {
+		return upper.DeepCopy(), nil
+	}
+	var dropped []string
+	if lower.NodeSelector != nil && upper.NodeSelector == nil {
+		dropped = append(dropped, "nodeSelector")
+	}
+	if lower.Tolerations != nil && upper.Tolerations == nil {
+		dropped = append(dropped, "tolerations")
+	}
+	if lower.TopologySpreadConstraints != nil && upper.TopologySpreadConstraints == nil {
+		dropped = append(dropped, "topologySpreadConstraints")
+	}
+	return upper.DeepCopy(), dropped
+}
+
+// normalizePlacement drops the members that are stated but empty, and returns nil when none is
+// left, so a cleared member renders exactly like one never stated and produces no diff in the
+// apply path.
+func normalizePlacement(p *v1alpha1.PlacementSpec) *v1alpha1.PlacementSpec {
+	if p == nil {
+		return nil
+	}
+	out := p.DeepCopy()
+	if len(out.NodeSelector) == 0 {
+		out.NodeSelector = nil
+	}
+	if len(out.Tolerations) == 0 {
+		out.Tolerations = nil
+	}
+	if len(out.TopologySpreadConstraints) == 0 {
+		out.TopologySpreadConstraints = nil
+	}
+	if !out.IsStated() {
+		return nil
+	}
+	return out
+}
+
+// foldPVCRetentionPolicy folds the upper layer's PVC retention policy into the lower's, field by
+// field.
+func foldPVCRetentionPolicy(
+	lower, upper *v1alpha1.PersistentVolumeClaimRetentionPolicySpec,
+) *v1alpha1.PersistentVolumeClaimRetentionPolicySpec {
+	if upper == nil {
+		return lower.DeepCopy()
+	}
+	merged := upper.DeepCopy()
+	if lower != nil {
+		if merged.WhenDeleted == "" {
+			merged.WhenDeleted = lower.WhenDeleted
+		}
+		if merged.WhenScaled == "" {
+			merged.WhenScaled = lower.WhenScaled
+		}
+	}
+	return merged
+}
+
 // foldResources folds the upper layer's resources into the lower's, leaf by leaf.
 func foldResources(lower, upper *v1alpha1.ResourcesSpec) *v1alpha1.ResourcesSpec {
 	switch {
@@ -246,39 +415,154 @@ func foldResources(lower, upper *v1alpha1.ResourcesSpec) *v1alpha1.ResourcesSpec
 
 	merged := upper.DeepCopy()
 
+	merged.CPU = foldCPU(lower.CPU, merged.CPU)
+	merged.Memory = foldMemory(lower.Memory, merged.Memory)
+	merged.EphemeralStorage = foldEphemeralStorage(lower.EphemeralStorage, merged.EphemeralStorage)
+
 	switch {
-	case merged.CPU == nil:
-		merged.CPU = lower.CPU.DeepCopy()
-	case lower.CPU != nil:
-		if merged.CPU.Min == nil {
-			merged.CPU.Min = copyQuantity(lower.CPU.Min)
+	case merged.Storage == nil:
+		merged.Storage = lower.Storage.DeepCopy()
+	case lower.Storage != nil:
+		if merged.Storage.Capacity == nil {
+			merged.Storage.Capacity = copyQuantity(lower.Storage.Capacity)
 		}
-		if merged.CPU.Max == nil {
-			merged.CPU.Max = copyQuantity(lower.CPU.Max)
+		if merged.Storage.StorageClass == nil {
+			merged.Storage.StorageClass = copyString(lower.Storage.StorageClass)
 		}
 	}
 
+	merged.Volumes = foldVolumes(lower.Volumes, merged.Volumes)
+	merged.Sidecars = foldSidecarResources(lower.Sidecars, merged.Sidecars)
+	merged.Heap = foldHeap(lower.Heap, merged.Heap)
+
+	return merged
+}
+
+// foldCPU, foldMemory and foldEphemeralStorage fold one budget per leaf. upper is owned by the
+// caller (a deep copy) and is filled in place; lower is only read.
+func foldCPU(lower, upper *v1alpha1.CPUResource) *v1alpha1.CPUResource {
 	switch {
-	case merged.Memory == nil:
-		merged.Memory = lower.Memory.DeepCopy()
-	case lower.Memory != nil:
-		if merged.Memory.Limit == nil {
-			merged.Memory.Limit = copyQuantity(lower.Memory.Limit)
+	case upper == nil:
+		return lower.DeepCopy()
+	case lower != nil:
+		if upper.Min == nil {
+			upper.Min = copyQuantity(lower.Min)
+		}
+		if upper.Max == nil {
+			upper.Max = copyQuantity(lower.Max)
 		}
 	}
+	return upper
+}
 
+func foldMemory(lower, upper *v1alpha1.MemoryResource) *v1alpha1.MemoryResource {
 	switch {
-	case merged.Storage == nil:
-		merged.Storage = lower.Storage.DeepCopy()
-	case lower.Storage != nil:
-		if merged.Storage.Capacity == nil {
-			merged.Storage.Capacity = copyQuantity(lower.Storage.Capacity)
+	case upper == nil:
+		return lower.DeepCopy()
+	case lower != nil:
+		if upper.Limit == nil {
+			upper.Limit = copyQuantity(lower.Limit)
 		}
-		if merged.Storage.StorageClass == nil {
-			merged.Storage.StorageClass = copyString(lower.Storage.StorageClass)
+		if upper.Request == nil {
+			upper.Request = copyQuantity(lower.Request)
+		}
+	}
+	return upper
+}
+
+func foldEphemeralStorage(lower, upper *v1alpha1.EphemeralStorageResource) *v1alpha1.EphemeralStorageResource {
+	switch {
+	case upper == nil:
+		return lower.DeepCopy()
+	case lower != nil:
+		if upper.Request == nil {
+			upper.Request = copyQuantity(lower.Request)
+		}
+		if upper.Limit == nil {
+			upper.Limit = copyQuantity(lower.Limit)
+		}
+	}
+	return upper
+}
+
+// foldSidecarResources folds the per-sidecar budgets per container name, then per leaf, for the
+// same reason foldVolumes folds per name: a role group raising the Vector agent's memory keeps the
+// role's budget for the JMX exporter.
+func foldSidecarResources(
+	lower, upper map[string]v1alpha1.ContainerResources,
+) map[string]v1alpha1.ContainerResources {
+	if len(lower) == 0 {
+		return upper
+	}
+	merged := make(map[string]v1alpha1.ContainerResources, len(lower)+len(upper))
+	for name, res := range lower {
+		merged[name] = *res.DeepCopy()
+	}
+	for name, res := range upper {
+		base := lower[name]
+		res.CPU = foldCPU(base.CPU, res.CPU)
+		res.Memory = foldMemory(base.Memory, res.Memory)
+		res.EphemeralStorage = foldEphemeralStorage(base.EphemeralStorage, res.EphemeralStorage)
+		merged[name] = res
+	}
+	return merged
+}
+
+// foldHeap folds the heap policy per field: a role group lowering heap.max keeps the product's
+// fraction and its off-heap reserve.
+func foldHeap(lower, upper *v1alpha1.HeapPolicy) *v1alpha1.HeapPolicy {
+	switch {
+	case upper == nil:
+		return lower.DeepCopy()
+	case lower != nil:
+		if upper.Fraction == nil {
+			upper.Fraction = copyString(lower.Fraction)
+		}
+		if upper.Min == nil {
+			upper.Min = copyQuantity(lower.Min)
+		}
+		if upper.Max == nil {
+			upper.Max = copyQuantity(lower.Max)
+		}
+		if upper.ReservedOffHeap == nil {
+			upper.ReservedOffHeap = copyQuantity(lower.ReservedOffHeap)
 		}
 	}
+	return upper
+}
 
+// foldVolumes folds the named data volumes per name, then per field: a volume only one layer
+// names is taken whole, and a volume both name inherits each field the upper layer leaves unset.
+// Merging by name is what lets a role group resize one JBOD disk of the role's without restating —
+// and so silently dropping — the others.
+func foldVolumes(lower, upper map[string]v1alpha1.VolumeResource) map[string]v1alpha1.VolumeResource {
+	if len(lower) == 0 {
+		return upper
+	}
+	merged := make(map[string]v1alpha1.VolumeResource, len(lower)+len(upper))
+	for name, vol := range lower {
+		merged[name] = *vol.DeepCopy()
+	}
+	for name, vol := range upper {
+		base, ok := lower[name]
+		if !ok {
+			merged[name] = vol
+			continue
+		}
+		if vol.Capacity == nil {
+			vol.Capacity = copyQuantity(base.Capacity)
+		}
+		if vol.StorageClass == nil {
+			vol.StorageClass = copyString(base.StorageClass)
+		}
+		if vol.MountPath == "" {
+			vol.MountPath = base.MountPath
+		}
+		if vol.Count == nil && base.Count != nil {
+			vol.Count = ptr.To(*base.Count)
+		}
+		merged[name] = vol
+	}
 	return merged
 }
 
@@ -125,6 +125,78 @@ var _ = Describe("FoldCommonConfig (the framework's half)", func() {
 		Expect(roleLayer.Resources.Storage.StorageClass).To(HaveValue(Equal("fast-ssd")))
 	})
 
+	It("folds resources.volumes per NAME, so a group resizing one disk keeps the role's others", func() {
+		out, _, err := reconciler.FoldCommonConfig(
+			&commonsv1alpha1.RoleGroupConfigSpec{Resources: &commonsv1alpha1.ResourcesSpec{
+				Volumes: map[string]commonsv1alpha1.VolumeResource{
+					"disk":    {StorageResource: commonsv1alpha1.StorageResource{Capacity: foldQ("100Gi")}, MountPath: "/kubedoop/disk", Count: ptr.To(int32(2))},
+					"journal": {StorageResource: commonsv1alpha1.StorageResource{Capacity: foldQ("5Gi")}, MountPath: "/kubedoop/journal"},
+				},
+			}},
+			&commonsv1alpha1.RoleGroupConfigSpec{Resources: &commonsv1alpha1.ResourcesSpec{
+				Volumes: map[string]commonsv1alpha1.VolumeResource{
+					"disk": {StorageResource: commonsv1alpha1.StorageResource{Capacity: foldQ("500Gi")}},
+				},
+			}},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.Resources.Volumes).To(HaveLen(2), "the volume the group did not name survives")
+		disk := out.Resources.Volumes["disk"]
+		Expect(disk.Capacity.String()).To(Equal("500Gi"), "user wins")
+		Expect(disk.MountPath).To(Equal("/kubedoop/disk"), "sibling field survives")
+		Expect(disk.GetCount()).To(Equal(int32(2)))
+		Expect(out.Resources.Volumes["journal"].MountPath).To(Equal("/kubedoop/journal"))
+	})
+
+	It("folds the memory request, ephemeral storage, sidecar budgets and heap policy per leaf", func() {
+		out, _, err := reconciler.FoldCommonConfig(
+			&commonsv1alpha1.RoleGroupConfigSpec{Resources: &commonsv1alpha1.ResourcesSpec{
+				Memory:           &commonsv1alpha1.MemoryResource{Limit: foldQ("4Gi"), Request: foldQ("2Gi")},
+				EphemeralStorage: &commonsv1alpha1.EphemeralStorageResource{Request: foldQ("1Gi"), Limit: foldQ("2Gi")},
+				Sidecars: map[string]commonsv1alpha1.ContainerResources{
+					"vector":       {CPU: &commonsv1alpha1.CPUResource{Min: foldQ("50m")}, Memory: &commonsv1alpha1.MemoryResource{Limit: foldQ("128Mi")}},
+					"jmx-exporter": {Memory: &commonsv1alpha1.MemoryResource{Limit: foldQ("64Mi")}},
+				},
+				Heap: &commonsv1alpha1.HeapPolicy{Fraction: ptr.To("0.7"), ReservedOffHeap: foldQ("512Mi")},
+			}},
+			&commonsv1alpha1.RoleGroupConfigSpec{Resources: &commonsv1alpha1.ResourcesSpec{
+				Memory:           &commonsv1alpha1.MemoryResource{Limit: foldQ("8Gi")},
+				EphemeralStorage: &commonsv1alpha1.EphemeralStorageResource{Limit: foldQ("5Gi")},
+				Sidecars: map[string]commonsv1alpha1.ContainerResources{
+					"vector": {Memory: &commonsv1alpha1.MemoryResource{Limit: foldQ("256Mi")}},
+				},
+				Heap: &commonsv1alpha1.HeapPolicy{Max: foldQ("6Gi")},
+			}},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		res := out.Resources
+		Expect(res.Memory.Limit.String()).To(Equal("8Gi"))
+		Expect(res.Memory.Request.String()).To(Equal("2Gi"), "sibling leaf survives")
+		Expect(res.EphemeralStorage.Request.String()).To(Equal("1Gi"))
+		Expect(res.EphemeralStorage.Limit.String()).To(Equal("5Gi"))
+		Expect(res.Sidecars).To(HaveLen(2), "the sidecar the group did not name survives")
+		Expect(res.Sidecars["vector"].Memory.Limit.String()).To(Equal("256Mi"))
+		Expect(res.Sidecars["vector"].CPU.Min.String()).To(Equal("50m"), "sibling budget survives")
+		Expect(res.Heap.Fraction).To(HaveValue(Equal("0.7")))
+		Expect(res.Heap.ReservedOffHeap.String()).To(Equal("512Mi"))
+		Expect(res.Heap.Max.String()).To(Equal("6Gi"))
+	})
+
+	It("rejects a folded budget whose request exceeds its limit, or a heap policy that cannot hold", func() {
+		// Each layer is legal alone: the request is the role's and the limit the role group's.
+		_, _, err := reconciler.FoldCommonConfig(
+			&commonsv1alpha1.RoleGroupConfigSpec{Resources: &commonsv1alpha1.ResourcesSpec{
+				Memory: &commonsv1alpha1.MemoryResource{Request: foldQ("4Gi")},
+				Heap:   &commonsv1alpha1.HeapPolicy{Fraction: ptr.To("1.5")},
+			}},
+			&commonsv1alpha1.RoleGroupConfigSpec{Resources: &commonsv1alpha1.ResourcesSpec{
+				Memory: &commonsv1alpha1.MemoryResource{Limit: foldQ("2Gi")},
+			}},
+		)
+		Expect(err).To(MatchError(ContainSubstring("resources.memory.request 4Gi exceeds resources.memory.limit 2Gi")))
+		Expect(err).To(MatchError(ContainSubstring(`resources.heap.fraction "1.5" is not a decimal between 0 and 1`)))
+	})
+
 	It("lets the UPPER gracefulShutdownTimeout win, so a user beats the product's default", func() {
 		out, _, err := reconciler.FoldCommonConfig(
 			&commonsv1alpha1.RoleGroupConfigSpec{GracefulShutdownTimeout: ptr.To("5m")},
@@ -134,6 +206,27 @@ var _ = Describe("FoldCommonConfig (the framework's half)", func() {
 		Expect(out.GracefulShutdownTimeout).To(HaveValue(Equal("90s")))
 	})
 
+	It("folds the PVC retention policy per field, so a group's whenScaled keeps the role's whenDeleted", func() {
+		roleLayer := &commonsv1alpha1.RoleGroupConfigSpec{
+			PersistentVolumeClaimRetentionPolicy: &commonsv1alpha1.PersistentVolumeClaimRetentionPolicySpec{
+				WhenDeleted: commonsv1alpha1.PersistentVolumeClaimDelete,
+			},
+		}
+		out, _, err := reconciler.FoldCommonConfig(roleLayer, &commonsv1alpha1.RoleGroupConfigSpec{
+			PersistentVolumeClaimRetentionPolicy: &commonsv1alpha1.PersistentVolumeClaimRetentionPolicySpec{
+				WhenScaled: commonsv1alpha1.PersistentVolumeClaimRetain,
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.PersistentVolumeClaimRetentionPolicy).To(Equal(&commonsv1alpha1.PersistentVolumeClaimRetentionPolicySpec{
+			WhenDeleted: commonsv1alpha1.PersistentVolumeClaimDelete,
+			WhenScaled:  commonsv1alpha1.PersistentVolumeClaimRetain,
+		}))
+
+		out.PersistentVolumeClaimRetentionPolicy.WhenDeleted = commonsv1alpha1.PersistentVolumeClaimRetain
+		Expect(roleLayer.PersistentVolumeClaimRetentionPolicy.WhenDeleted).To(Equal(commonsv1alpha1.PersistentVolumeClaimDelete))
+	})
+
 	It("replaces affinity WHOLESALE, and names what the replacement discarded", func() {
 		// The Kubernetes rule for this field, kept deliberately: PodSpec.affinity, a Helm value and
 		// a Kustomize patch all replace wholesale, and folding per member would oblige a user to
@@ -172,6 +265,7 @@ var _ = Describe("FoldCommonConfig (the framework's half)", func() {
 		Expect(decoded.PodAntiAffinity).To(BeNil())
 
 		Expect(replaced).To(HaveLen(1))
+		Expect(replaced[0].Field).To(Equal(reconciler.ReplacedAffinity))
 		Expect(replaced[0].Layer).To(Equal(1))
 		Expect(replaced[0].Dropped).To(Equal([]string{"podAffinity", "podAntiAffinity"}),
 			"a fixed order, so the event message is byte-stable across passes")
@@ -195,6 +289,55 @@ var _ = Describe("FoldCommonConfig (the framework's half)", func() {
 		Expect(decoded).To(BeNil(), "an explicitly empty affinity must not inherit the product default")
 	})
 
+	It("replaces placement WHOLESALE like affinity, and names what the replacement discarded", func() {
+		// A product spreads its role across zones and keeps it off GPU nodes; the user adds a
+		// toleration for a dedicated pool and says nothing about the rest. The block is taken over,
+		// and the fold names what went with it.
+		productDefault := &commonsv1alpha1.RoleGroupConfigSpec{Placement: &commonsv1alpha1.PlacementSpec{
+			NodeSelector: map[string]string{"gpu": "false"},
+			TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+				reconciler.PreferredTopologySpread(1, reconciler.TopologyKeyZone),
+			},
+		}}
+		userTolerates := &commonsv1alpha1.RoleGroupConfigSpec{Placement: &commonsv1alpha1.PlacementSpec{
+			Tolerations: []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+		}}
+
+		out, replaced, err := reconciler.FoldCommonConfig(productDefault, nil, userTolerates)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.Placement.Tolerations).To(HaveLen(1))
+		Expect(out.Placement.NodeSelector).To(BeNil())
+		Expect(out.Placement.TopologySpreadConstraints).To(BeNil())
+
+		Expect(replaced).To(HaveLen(1))
+		Expect(replaced[0].Field).To(Equal(reconciler.ReplacedPlacement))
+		Expect(replaced[0].Layer).To(Equal(2))
+		Expect(replaced[0].Dropped).To(Equal([]string{"nodeSelector", "topologySpreadConstraints"}))
+	})
+
+	It("treats an empty placement as stating nothing, and a member stated empty as a clear", func() {
+		// `placement` is structural, so `{}` is what the API server prunes a misspelt member to.
+		productDefault := &commonsv1alpha1.RoleGroupConfigSpec{Placement: &commonsv1alpha1.PlacementSpec{
+			TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+				reconciler.PreferredTopologySpread(1, reconciler.TopologyKeyZone),
+			},
+		}}
+
+		out, replaced, err := reconciler.FoldCommonConfig(productDefault,
+			&commonsv1alpha1.RoleGroupConfigSpec{Placement: &commonsv1alpha1.PlacementSpec{}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.Placement.TopologySpreadConstraints).To(HaveLen(1), "inherited")
+		Expect(replaced).To(BeEmpty())
+
+		out, replaced, err = reconciler.FoldCommonConfig(productDefault,
+			&commonsv1alpha1.RoleGroupConfigSpec{Placement: &commonsv1alpha1.PlacementSpec{
+				TopologySpreadConstraints: []corev1.TopologySpreadConstraint{},
+			}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.Placement).To(BeNil(), "normalized, so the clear renders like absence")
+		Expect(replaced).To(BeEmpty(), "an explicit clear is the user's own visible decision")
+	})
+
 	It("treats an empty `resources` leaf as stating nothing, so a pruning artifact cannot delete a default", func() {
 		// The opposite call, and the schema is why: `resources` is structural, so the API server
 		// PRUNES a mistyped `resources.cpu.maxx: "4"` down to a stored `cpu: {}`. Reading that as a
@@ -409,4 +552,26 @@ var _ = Describe("RoleGroupBuildContext.EffectiveConfig", func() {
 		Expect(ok).To(BeTrue())
 		Expect(mb).To(Equal(int64(819)), "floor(1024 * 0.8)")
 	})
+
+	It("lets a heap policy take the off-heap reserve first, replace the factor and clamp the result", func() {
+		cfg := &commonsv1alpha1.RoleGroupConfigSpec{Resources: &commonsv1alpha1.ResourcesSpec{
+			Memory: &commonsv1alpha1.MemoryResource{Limit: foldQ("4Gi")},
+			Heap: &commonsv1alpha1.HeapPolicy{
+				Fraction:        ptr.To("0.5"),
+				ReservedOffHeap: foldQ("1Gi"),
+			},
+		}}
+		mb, ok := reconciler.HeapMB(cfg, 0.8)
+		Expect(ok).To(BeTrue())
+		Expect(mb).To(Equal(int64(1536)), "(4096 - 1024) * 0.5")
+
+		cfg.Resources.Heap.Max = foldQ("1Gi")
+		mb, _ = reconciler.HeapMB(cfg, 0.8)
+		Expect(mb).To(Equal(int64(1024)), "capped at max")
+
+		cfg.Resources.Heap.Max = nil
+		cfg.Resources.Heap.Min = foldQ("8Gi")
+		mb, _ = reconciler.HeapMB(cfg, 0.8)
+		Expect(mb).To(Equal(int64(3072)), "min never pushes the heap past what the reserve left")
+	})
 })
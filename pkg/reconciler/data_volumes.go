@@ -0,0 +1,139 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+)
+
+// namedDataVolume is one claim template expanded from resources.volumes: a volume with Count three
+// is three of these.
+type namedDataVolume struct {
+	// Key is the resources.volumes key it was expanded from.
+	Key string
+	// Name is the claim-template and mount name.
+	Name      string
+	MountPath string
+	Storage   v1alpha1.StorageResource
+}
+
+// expandDataVolumes expands the folded resources.volumes into one entry per claim template, in a
+// stable order: by key, then by index. The order is the order of the claim templates on the
+// StatefulSet, and map iteration order would rewrite the StatefulSet on every pass.
+func expandDataVolumes(volumes map[string]v1alpha1.VolumeResource) []namedDataVolume {
+	keys := make([]string, 0, len(volumes))
+	for key := range volumes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var expanded []namedDataVolume
+	for _, key := range keys {
+		vol := volumes[key]
+		count := vol.GetCount()
+		if count == 1 {
+			expanded = append(expanded, namedDataVolume{
+				Key: key, Name: key, MountPath: vol.MountPath, Storage: vol.StorageResource,
+			})
+			continue
+		}
+		for i := int32(0); i < count; i++ {
+			expanded = append(expanded, namedDataVolume{
+				Key:       key,
+				Name:      fmt.Sprintf("%s-%d", key, i),
+				MountPath: path.Join(vol.MountPath, fmt.Sprint(i)),
+				Storage:   vol.StorageResource,
+			})
+		}
+	}
+	return expanded
+}
+
+// resolveDataVolumeMountPaths validates the folded resources.volumes of a role group and returns
+// the mount paths of each, keyed by volume name and in index order — what
+// RoleGroupBuildContext.DataVolumeMountPaths carries to the product.
+//
+// The name rules are checked on the EXPANDED names, because those are what reach the API server:
+// "disk" with a count of two and a separate "disk-1" are both valid keys and the same claim
+// template. A name taken by a framework volume or by the role's primary data volume is rejected
+// for the reason RoleDeclaration.Validate rejects a reserved DataVolume name: the API server
+// accepts a claim template sharing a pod volume's name, and the StatefulSet controller then
+// silently mounts the PVC in that volume's place.
+func resolveDataVolumeMountPaths(
+	volumes map[string]v1alpha1.VolumeResource, dataVolume *DataVolume,
+) (map[string][]string, error) {
+	if len(volumes) == 0 {
+		return nil, nil
+	}
+
+	primary := ""
+	if dataVolume != nil {
+		primary = dataVolume.Name
+		if primary == "" {
+			primary = DefaultDataVolumeName
+		}
+	}
+
+	var problems []string
+	for key, vol := range volumes {
+		if vol.MountPath == "" {
+			problems = append(problems, fmt.Sprintf("volume %q declares no mountPath", key))
+		} else if !path.IsAbs(vol.MountPath) {
+			problems = append(problems, fmt.Sprintf("volume %q mountPath %q is not absolute", key, vol.MountPath))
+		}
+		if vol.GetCount() < 1 {
+			problems = append(problems, fmt.Sprintf("volume %q count must be at least 1", key))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+
+	paths := make(map[string][]string, len(volumes))
+	seen := make(map[string]string)
+	for _, v := range expandDataVolumes(volumes) {
+		paths[v.Key] = append(paths[v.Key], v.MountPath)
+
+		if errs := validation.IsDNS1123Label(v.Name); len(errs) > 0 {
+			problems = append(problems, fmt.Sprintf("volume name %q is not a DNS label: %s",
+				v.Name, strings.Join(errs, ", ")))
+		}
+		if reason, reserved := reservedVolumeNames[v.Name]; reserved {
+			problems = append(problems, fmt.Sprintf("volume name %q is reserved for %s", v.Name, reason))
+		}
+		if v.Name == primary {
+			problems = append(problems, fmt.Sprintf("volume name %q is the role's primary data volume", v.Name))
+		}
+		if other, dup := seen[v.Name]; dup {
+			problems = append(problems, fmt.Sprintf("volumes %q and %q both expand to %q", other, v.Key, v.Name))
+		}
+		seen[v.Name] = v.Key
+	}
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+	return paths, nil
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/ptr"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+)
+
+var _ = Describe("resolveDataVolumeMountPaths", func() {
+	It("returns each volume's mount paths in index order", func() {
+		paths, err := resolveDataVolumeMountPaths(map[string]v1alpha1.VolumeResource{
+			"disk":    {MountPath: "/kubedoop/disk", Count: ptr.To(int32(3))},
+			"journal": {MountPath: "/kubedoop/journal"},
+		}, &DataVolume{MountPath: "/kubedoop/data"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(paths).To(Equal(map[string][]string{
+			"disk":    {"/kubedoop/disk/0", "/kubedoop/disk/1", "/kubedoop/disk/2"},
+			"journal": {"/kubedoop/journal"},
+		}))
+	})
+
+	It("rejects a missing or relative mount path", func() {
+		_, err := resolveDataVolumeMountPaths(map[string]v1alpha1.VolumeResource{
+			"a": {},
+			"b": {MountPath: "kubedoop/b"},
+		}, nil)
+		Expect(err).To(MatchError(`volume "a" declares no mountPath; volume "b" mountPath "kubedoop/b" is not absolute`))
+	})
+
+	It("rejects a name a framework volume, the primary data volume or another expansion already takes", func() {
+		_, err := resolveDataVolumeMountPaths(map[string]v1alpha1.VolumeResource{
+			"config": {MountPath: "/c"},
+			"data":   {MountPath: "/d"},
+			"disk":   {MountPath: "/disk", Count: ptr.To(int32(2))},
+			"disk-1": {MountPath: "/other"},
+			"Disk":   {MountPath: "/upper"},
+		}, &DataVolume{MountPath: "/kubedoop/data"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(`volume name "config" is reserved for the role group's ConfigMap volume`))
+		Expect(err.Error()).To(ContainSubstring(`volume name "data" is the role's primary data volume`))
+		Expect(err.Error()).To(ContainSubstring(`volumes "disk" and "disk-1" both expand to "disk-1"`))
+		Expect(err.Error()).To(ContainSubstring(`volume name "Disk" is not a DNS label`))
+	})
+})
@@ -0,0 +1,378 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	listenersv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/listeners/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/common"
+	"github.com/zncdatadev/operator-go/pkg/constant"
+	"github.com/zncdatadev/operator-go/pkg/listener"
+)
+
+// DefaultClusterDomain is the DNS domain in-cluster discovery addresses are rendered under when
+// DiscoverySpec.ClusterDomain is empty.
+const DefaultClusterDomain = "cluster.local"
+
+// externalDiscoverySuffix names the external discovery ConfigMap: "<name>-external".
+const externalDiscoverySuffix = "-external"
+
+// DiscoveryEndpoint is one address a role group publishes for the role's discovery port.
+type DiscoveryEndpoint struct {
+	Role      string
+	RoleGroup string
+	Host      string
+	Port      int32
+}
+
+// HostPort renders the endpoint as "host:port", bracketing an IPv6 host.
+func (e DiscoveryEndpoint) HostPort() string {
+	return net.JoinHostPort(e.Host, strconv.Itoa(int(e.Port)))
+}
+
+// DiscoveryEndpoints is the set of endpoints one audience — in-cluster or external clients — is
+// given, ordered by role, then role group, then address, so a rendered value is byte-stable across
+// reconciles and an unchanged cluster never rewrites its discovery ConfigMaps.
+type DiscoveryEndpoints []DiscoveryEndpoint
+
+// ForRole returns the endpoints of one role.
+func (e DiscoveryEndpoints) ForRole(role string) DiscoveryEndpoints {
+	var out DiscoveryEndpoints
+	for _, endpoint := range e {
+		if endpoint.Role == role {
+			out = append(out, endpoint)
+		}
+	}
+	return out
+}
+
+// HostPorts returns every endpoint as "host:port".
+func (e DiscoveryEndpoints) HostPorts() []string {
+	out := make([]string, len(e))
+	for i, endpoint := range e {
+		out[i] = endpoint.HostPort()
+	}
+	return out
+}
+
+// DiscoveryRenderer renders one discovery ConfigMap key from an audience's endpoints. A renderer
+// that returns "" omits the key, which is how a role with no address yet — a Listener the
+// listener-operator has not resolved — stays absent instead of being published as an empty value a
+// client would try to connect to.
+type DiscoveryRenderer func(endpoints DiscoveryEndpoints) (string, error)
+
+// HostPortList renders a role's endpoints as "host:port" joined by separator — a Kafka bootstrap
+// list, an HDFS namenode list.
+func HostPortList(role, separator string) DiscoveryRenderer {
+	return func(endpoints DiscoveryEndpoints) (string, error) {
+		return strings.Join(endpoints.ForRole(role).HostPorts(), separator), nil
+	}
+}
+
+// ZooKeeperConnectString renders a role's endpoints as a ZooKeeper connect string,
+// "h1:2181,h2:2181/chroot". The chroot is optional; one without a leading slash gets one.
+func ZooKeeperConnectString(role, chroot string) DiscoveryRenderer {
+	return func(endpoints DiscoveryEndpoints) (string, error) {
+		hosts := endpoints.ForRole(role).HostPorts()
+		if len(hosts) == 0 {
+			return "", nil
+		}
+		if chroot != "" && !strings.HasPrefix(chroot, "/") {
+			chroot = "/" + chroot
+		}
+		return strings.Join(hosts, ",") + chroot, nil
+	}
+}
+
+// JDBCURL renders a role's endpoints as a JDBC URL, "<prefix>h1:p,h2:p<suffix>" — for example
+// JDBCURL("server", "jdbc:hive2://", "/default"). A driver that accepts only one host needs a
+// renderer of its own that picks one.
+func JDBCURL(role, prefix, suffix string) DiscoveryRenderer {
+	return func(endpoints DiscoveryEndpoints) (string, error) {
+		hosts := endpoints.ForRole(role).HostPorts()
+		if len(hosts) == 0 {
+			return "", nil
+		}
+		return prefix + strings.Join(hosts, ",") + suffix, nil
+	}
+}
+
+// DiscoveryRole declares where one role's clients connect.
+type DiscoveryRole struct {
+	// Role is the role name, as in spec.roles.
+	Role string
+
+	// PortName is the port clients use: a port of the role group Service for in-cluster clients,
+	// and the same name in the Listener's status for external ones.
+	PortName string
+
+	// Listener returns the listener volume a role group's pods mount, or nil for a role group that
+	// is not exposed through the listener-operator. Nil (the default) means external endpoints come
+	// from the role group Service, which yields addresses only when it is a LoadBalancer.
+	//
+	// A registration with a listener name points at that one shared Listener. One without points at
+	// the Listener the listener-operator creates per pod, which it names after the pod (see
+	// listener.AnnotationListenerName): "<role group>-<ordinal>" for each replica.
+	Listener func(roleGroup string) *listener.VolumeRegistration
+}
+
+// DiscoverySpec describes the discovery ConfigMaps a cluster publishes: one for in-cluster clients
+// and one for external clients, rendered by the same Data renderers from different endpoints.
+//
+//   - In-cluster: each role group Service's DNS name, "<svc>.<namespace>.svc.<cluster domain>",
+//     and its port named PortName. These exist with or without a listener-operator.
+//   - External: the Listener status ingress addresses and their port named PortName, or, for a
+//     role without a Listener, a LoadBalancer Service's ingress.
+type DiscoverySpec struct {
+	// Name of the in-cluster ConfigMap; the external one is "<Name>-external". Defaults to the CR
+	// name, the convention every kubedoop product already follows.
+	Name string
+
+	// Roles lists the roles that publish endpoints. A role not listed contributes nothing.
+	Roles []DiscoveryRole
+
+	// Data maps each ConfigMap key to its renderer.
+	Data map[string]DiscoveryRenderer
+
+	// ClusterDomain is the cluster's DNS domain. Defaults to DefaultClusterDomain.
+	ClusterDomain string
+
+	// Options are passed to both EnsureDiscoveryConfigMap calls.
+	Options []DiscoveryConfigMapOption
+}
+
+// DiscoveryConfig publishes a cluster's discovery ConfigMaps from the framework's reconcile, after
+// health and before the PostReconcile hooks (so a hook still sees them), through
+// GenericReconcilerConfig.Discovery.
+type DiscoveryConfig[CR common.ClusterResource[CR]] struct {
+	// Spec returns the cluster's discovery spec. Nil publishes nothing for this cluster.
+	Spec func(cr CR) *DiscoverySpec
+
+	// WatchListeners re-reconciles a cluster when a Listener's status changes, so an address the
+	// listener-operator assigns after the pods start reaches the external ConfigMap without waiting
+	// for the next periodic reconcile. A Listener is mapped to its cluster by its
+	// app.kubernetes.io/instance label, or by a controller reference to the CR; a per-pod Listener
+	// carrying neither is picked up on the HealthCheckInterval requeue.
+	//
+	// It requires the listeners.kubedoop.dev CRD to be installed and
+	// `+kubebuilder:rbac:groups=listeners.kubedoop.dev,resources=listeners,verbs=get;list;watch`
+	// on the operator: a watch on a missing kind or a forbidden informer stops the manager.
+	WatchListeners bool
+}
+
+// GatherDiscoveryEndpoints collects the in-cluster and external endpoints of the roles spec lists.
+//
+// Objects that do not exist yet — a role group Service on the first pass, a Listener the
+// listener-operator has not created — contribute nothing rather than failing: discovery is
+// republished every reconcile, so a missing address fills in on a later pass.
+func GatherDiscoveryEndpoints(ctx context.Context, c client.Reader, owner common.ClusterInterface, spec *DiscoverySpec) (internal, external DiscoveryEndpoints, err error) {
+	domain := spec.ClusterDomain
+	if domain == "" {
+		domain = DefaultClusterDomain
+	}
+	namespace := owner.GetNamespace()
+	roles := owner.GetSpec().Roles
+
+	for _, role := range spec.Roles {
+		roleSpec, ok := roles[role.Role]
+		if !ok {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, group := range slices.Sorted(maps.Keys(roleSpec.RoleGroups)) {
+			groupSpec := roleSpec.RoleGroups[group]
+			name := RoleGroupResourceName(owner.GetName(), role.Role, group)
+
+			svc := &corev1.Service{}
+			svcErr := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, svc)
+			if svcErr != nil && !apierrors.IsNotFound(svcErr) {
+				return nil, nil, fmt.Errorf("reading Service %s for discovery: %w", name, svcErr)
+			}
+			var port *corev1.ServicePort
+			if svcErr == nil {
+				port = servicePortNamed(svc, role.PortName)
+			}
+			if port != nil {
+				internal = append(internal, DiscoveryEndpoint{
+					Role: role.Role, RoleGroup: group,
+					Host: fmt.Sprintf("%s.%s.svc.%s", name, namespace, domain),
+					Port: port.Port,
+				})
+			}
+
+			var reg *listener.VolumeRegistration
+			if role.Listener != nil {
+				reg = role.Listener(group)
+			}
+			var found DiscoveryEndpoints
+			if reg != nil {
+				found, err = listenerEndpoints(ctx, c, namespace, name, groupSpec.GetReplicas(), reg, role.PortName)
+				if err != nil {
+					return nil, nil, err
+				}
+			} else if svcErr == nil && port != nil && svc.Spec.Type == corev1.ServiceTypeLoadBalancer {
+				for _, ingress := range svc.Status.LoadBalancer.Ingress {
+					host := ingress.Hostname
+					if host == "" {
+						host = ingress.IP
+					}
+					if host != "" {
+						found = append(found, DiscoveryEndpoint{Host: host, Port: port.Port})
+					}
+				}
+			}
+			for _, endpoint := range found {
+				// A shared Listener serves several role groups; publishing its address once per
+				// group would hand clients duplicates.
+				if seen[endpoint.HostPort()] {
+					continue
+				}
+				seen[endpoint.HostPort()] = true
+				endpoint.Role, endpoint.RoleGroup = role.Role, group
+				external = append(external, endpoint)
+			}
+		}
+	}
+	return internal, external, nil
+}
+
+// listenerEndpoints reads the Listeners a role group's registration binds to and returns their
+// ingress addresses for portName, sorted. A class-based registration binds each pod to the Listener
+// named after it (see listener.AnnotationListenerName), which is also the name the built-in listener
+// fallback gives its per-pod Listeners.
+func listenerEndpoints(ctx context.Context, c client.Reader, namespace, roleGroupName string, replicas int32, reg *listener.VolumeRegistration, portName string) (DiscoveryEndpoints, error) {
+	names := []string{reg.ListenerName()}
+	if reg.ListenerName() == "" {
+		names = make([]string, 0, replicas)
+		for i := range replicas {
+			names = append(names, fmt.Sprintf("%s-%d", roleGroupName, i))
+		}
+	}
+
+	var out DiscoveryEndpoints
+	for _, name := range names {
+		l := &listenersv1alpha1.Listener{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, l); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("reading Listener %s for discovery: %w", name, err)
+		}
+		for _, address := range l.Status.IngressAddresses {
+			if port, ok := address.Ports[portName]; ok && address.Address != "" {
+				out = append(out, DiscoveryEndpoint{Host: address.Address, Port: port})
+			}
+		}
+	}
+	slices.SortFunc(out, func(a, b DiscoveryEndpoint) int { return strings.Compare(a.HostPort(), b.HostPort()) })
+	return out, nil
+}
+
+func servicePortNamed(svc *corev1.Service, name string) *corev1.ServicePort {
+	for i := range svc.Spec.Ports {
+		if svc.Spec.Ports[i].Name == name {
+			return &svc.Spec.Ports[i]
+		}
+	}
+	return nil
+}
+
+// RenderDiscoveryData renders every key of data from endpoints, omitting keys whose renderer
+// returns "".
+func RenderDiscoveryData(data map[string]DiscoveryRenderer, endpoints DiscoveryEndpoints) (map[string]string, error) {
+	out := make(map[string]string, len(data))
+	for _, key := range slices.Sorted(maps.Keys(data)) {
+		value, err := data[key](endpoints)
+		if err != nil {
+			return nil, fmt.Errorf("rendering discovery key %q: %w", key, err)
+		}
+		if value != "" {
+			out[key] = value
+		}
+	}
+	return out, nil
+}
+
+// PublishDiscovery gathers the endpoints spec describes and ensures both discovery ConfigMaps:
+// "<name>" for in-cluster clients and "<name>-external" for external ones. It is what
+// GenericReconcilerConfig.Discovery runs every reconcile; a product that publishes from its own
+// PostReconcile hook calls it directly.
+func PublishDiscovery(ctx context.Context, c client.Client, scheme *runtime.Scheme, owner common.ClusterInterface, spec *DiscoverySpec) error {
+	internal, external, err := GatherDiscoveryEndpoints(ctx, c, owner, spec)
+	if err != nil {
+		return err
+	}
+	name := spec.Name
+	if name == "" {
+		name = owner.GetName()
+	}
+
+	internalData, err := RenderDiscoveryData(spec.Data, internal)
+	if err != nil {
+		return err
+	}
+	if err := EnsureDiscoveryConfigMap(ctx, c, scheme, owner, name, internalData, spec.Options...); err != nil {
+		return err
+	}
+	externalData, err := RenderDiscoveryData(spec.Data, external)
+	if err != nil {
+		return err
+	}
+	return EnsureDiscoveryConfigMap(ctx, c, scheme, owner, name+externalDiscoverySuffix, externalData, spec.Options...)
+}
+
+// publishDiscovery runs the configured discovery publisher for cr. No-op when none is configured.
+func (r *GenericReconciler[CR]) publishDiscovery(ctx context.Context, cr CR) error {
+	if r.discovery == nil || r.discovery.Spec == nil {
+		return nil
+	}
+	spec := r.discovery.Spec(cr)
+	if spec == nil {
+		return nil
+	}
+	return PublishDiscovery(ctx, r.client, r.scheme, cr, spec)
+}
+
+// mapListenerToCluster enqueues the cluster a Listener belongs to: the one its instance label
+// names, or the CR holding its controller reference.
+func (r *GenericReconciler[CR]) mapListenerToCluster(_ context.Context, obj client.Object) []reconcile.Request {
+	name := obj.GetLabels()[constant.LabelKubernetesInstance]
+	if name == "" {
+		if ref := metav1.GetControllerOf(obj); ref != nil && ref.Kind == r.resourceKind(r.prototype) {
+			name = ref.Name
+		}
+	}
+	if name == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}}}
+}
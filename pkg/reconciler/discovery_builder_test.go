@@ -0,0 +1,168 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	listenersv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/listeners/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/listener"
+	"github.com/zncdatadev/operator-go/pkg/reconciler"
+	"github.com/zncdatadev/operator-go/pkg/testutil"
+)
+
+var _ = Describe("Discovery builder", func() {
+	const namespace = "default"
+
+	var (
+		scheme *runtime.Scheme
+		cr     *testutil.MockCluster
+	)
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(listenersv1alpha1.AddToScheme(scheme)).To(Succeed())
+		Expect(testutil.AddToScheme(scheme)).To(Succeed())
+
+		cr = testutil.NewMockCluster("zk", namespace)
+		cr.UID = "zk-uid"
+		cr.Spec.Roles = map[string]v1alpha1.RoleSpec{
+			"server": {RoleGroups: map[string]v1alpha1.RoleGroupSpec{
+				"a": {Replicas: ptr.To[int32](2)},
+				"b": {Replicas: ptr.To[int32](1)},
+			}},
+		}
+	})
+
+	service := func(name string, svcType corev1.ServiceType, ingress ...corev1.LoadBalancerIngress) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: corev1.ServiceSpec{Type: svcType, Ports: []corev1.ServicePort{
+				{Name: "metrics", Port: 9505},
+				{Name: "client", Port: 2181},
+			}},
+			Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: ingress}},
+		}
+	}
+	listenerWith := func(name string, addresses ...string) *listenersv1alpha1.Listener {
+		l := &listenersv1alpha1.Listener{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+		for _, address := range addresses {
+			l.Status.IngressAddresses = append(l.Status.IngressAddresses, listenersv1alpha1.IngressAddressSpec{
+				Address: address, AddressType: listenersv1alpha1.AddressTypeIP,
+				Ports: map[string]int32{"client": 32181},
+			})
+		}
+		return l
+	}
+	spec := func(roles ...reconciler.DiscoveryRole) *reconciler.DiscoverySpec {
+		return &reconciler.DiscoverySpec{
+			Roles: roles,
+			Data: map[string]reconciler.DiscoveryRenderer{
+				"ZOOKEEPER":       reconciler.ZooKeeperConnectString("server", "/znode"),
+				"ZOOKEEPER_HOSTS": reconciler.HostPortList("server", ","),
+			},
+		}
+	}
+	newClient := func(objs ...client.Object) client.Client {
+		return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	}
+
+	It("publishes the role group Services in-cluster, sorted by role group", func() {
+		c := newClient(cr, service("zk-server-b", corev1.ServiceTypeClusterIP), service("zk-server-a", corev1.ServiceTypeClusterIP))
+
+		Expect(reconciler.PublishDiscovery(ctx, c, scheme, cr, spec(reconciler.DiscoveryRole{Role: "server", PortName: "client"}))).To(Succeed())
+
+		internal := &corev1.ConfigMap{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "zk"}, internal)).To(Succeed())
+		Expect(internal.Data).To(Equal(map[string]string{
+			"ZOOKEEPER":       "zk-server-a.default.svc.cluster.local:2181,zk-server-b.default.svc.cluster.local:2181/znode",
+			"ZOOKEEPER_HOSTS": "zk-server-a.default.svc.cluster.local:2181,zk-server-b.default.svc.cluster.local:2181",
+		}))
+
+		// ClusterIP Services have no external address, so the external ConfigMap carries no keys
+		// rather than in-cluster names a remote client cannot resolve.
+		external := &corev1.ConfigMap{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "zk-external"}, external)).To(Succeed())
+		Expect(external.Data).To(BeEmpty())
+	})
+
+	It("takes external addresses from per-pod and shared Listeners", func() {
+		c := newClient(cr,
+			service("zk-server-a", corev1.ServiceTypeClusterIP), service("zk-server-b", corev1.ServiceTypeClusterIP),
+			listenerWith("zk-server-a-0", "10.0.0.2"),
+			listenerWith("zk-server-a-1", "10.0.0.1"),
+			listenerWith("zk-shared", "203.0.113.7"),
+		)
+		role := reconciler.DiscoveryRole{Role: "server", PortName: "client", Listener: func(group string) *listener.VolumeRegistration {
+			if group == "a" {
+				return listener.NewVolume("listener", listener.ListenerClass("external-unstable"))
+			}
+			return listener.NewVolume("listener", "").WithListenerName("zk-shared")
+		}}
+
+		_, external, err := reconciler.GatherDiscoveryEndpoints(ctx, c, cr, spec(role))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(external.HostPorts()).To(Equal([]string{"10.0.0.1:32181", "10.0.0.2:32181", "203.0.113.7:32181"}))
+		Expect(external[2].RoleGroup).To(Equal("b"))
+	})
+
+	It("skips Listeners the listener-operator has not created or resolved yet", func() {
+		c := newClient(cr, listenerWith("zk-server-a-0"))
+		role := reconciler.DiscoveryRole{Role: "server", PortName: "client", Listener: func(string) *listener.VolumeRegistration {
+			return listener.NewVolume("listener", listener.ListenerClass("external-stable"))
+		}}
+
+		internal, external, err := reconciler.GatherDiscoveryEndpoints(ctx, c, cr, spec(role))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(internal).To(BeEmpty())
+		Expect(external).To(BeEmpty())
+	})
+
+	It("falls back to a LoadBalancer Service's ingress without a Listener", func() {
+		c := newClient(cr,
+			service("zk-server-a", corev1.ServiceTypeLoadBalancer, corev1.LoadBalancerIngress{Hostname: "zk.example.com"}),
+			service("zk-server-b", corev1.ServiceTypeLoadBalancer, corev1.LoadBalancerIngress{IP: "198.51.100.4"}),
+		)
+		_, external, err := reconciler.GatherDiscoveryEndpoints(ctx, c, cr, spec(reconciler.DiscoveryRole{Role: "server", PortName: "client"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(external.HostPorts()).To(Equal([]string{"zk.example.com:2181", "198.51.100.4:2181"}))
+	})
+
+	It("renders JDBC URLs and omits keys with no endpoints", func() {
+		endpoints := reconciler.DiscoveryEndpoints{
+			{Role: "server", Host: "h1", Port: 10000},
+			{Role: "server", Host: "::1", Port: 10000},
+		}
+		data, err := reconciler.RenderDiscoveryData(map[string]reconciler.DiscoveryRenderer{
+			"HIVE":   reconciler.JDBCURL("server", "jdbc:hive2://", "/default"),
+			"OTHERS": reconciler.HostPortList("metastore", ","),
+		}, endpoints)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(Equal(map[string]string{"HIVE": "jdbc:hive2://h1:10000,[::1]:10000/default"}))
+	})
+})
@@ -18,20 +18,93 @@ package reconciler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"maps"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/zncdatadev/operator-go/pkg/constant"
 )
 
+// Annotations EnsureGeneratedSecret reads and writes when rotation is enabled
+// (WithGeneratedSecretRotation). They live on the Secret itself, so the rotation state survives an
+// operator restart and is visible with `kubectl describe secret`.
+const (
+	// AnnotationGeneratedSecretRotate requests one rotation. Its value is an arbitrary token: any
+	// value other than the last one handled (AnnotationGeneratedSecretRotateHandled) rotates, so
+	// `kubectl annotate --overwrite secret <name> generated-secret.zncdata.dev/rotate=$(date +%s)`
+	// can be repeated without first removing the previous request.
+	AnnotationGeneratedSecretRotate = "generated-secret.zncdata.dev/rotate"
+	// AnnotationGeneratedSecretRotateHandled records the AnnotationGeneratedSecretRotate token the
+	// last rotation answered, so the same request is not honoured twice.
+	AnnotationGeneratedSecretRotateHandled = "generated-secret.zncdata.dev/rotate-handled"
+	// AnnotationGeneratedSecretGeneratedAt records, as an RFC3339 timestamp, when the current values
+	// were generated. GeneratedSecretRotation.MaxAge is measured from it; a Secret created before
+	// rotation was enabled falls back to its creationTimestamp.
+	AnnotationGeneratedSecretGeneratedAt = "generated-secret.zncdata.dev/generated-at"
+	// AnnotationGeneratedSecretGeneration counts rotations. It is absent until the first one, and its
+	// value is what dependent pod templates are stamped with (AnnotationGeneratedSecretRolloutPrefix).
+	AnnotationGeneratedSecretGeneration = "generated-secret.zncdata.dev/generation"
+	// AnnotationGeneratedSecretPreviousExpiresAt records, as an RFC3339 timestamp, when the
+	// previous values kept under GeneratedSecretPreviousKeySuffix are removed.
+	AnnotationGeneratedSecretPreviousExpiresAt = "generated-secret.zncdata.dev/previous-expires-at"
+
+	// AnnotationGeneratedSecretRolloutPrefix prefixes the pod-template annotation that rolls a
+	// StatefulSet onto a rotated Secret: "<prefix><secret name>: <generation>".
+	AnnotationGeneratedSecretRolloutPrefix = "rollout.generated-secret.zncdata.dev/"
+
+	// GeneratedSecretPreviousKeySuffix names the key a rotated value is kept under for the grace
+	// window: "cookie-secret" becomes "cookie-secret.previous".
+	GeneratedSecretPreviousKeySuffix = ".previous"
+
+	// ReasonGeneratedSecretRotated is the reason of the Normal event emitted on the owner when a
+	// generated Secret is rotated.
+	ReasonGeneratedSecretRotated = "GeneratedSecretRotated"
+)
+
+// GeneratedSecretRotation is the rotation policy for EnsureGeneratedSecret. Without one a generated
+// value is never replaced — the default, and the right one for anything whose rotation logs users
+// out or breaks clients holding the old value.
+//
+// A rotation replaces the value of every generated key at once. The value it replaces is kept under
+// "<key>.previous" for GracePeriod, so a product that accepts two keys — oauth2-proxy does not, a
+// JWT verifier or a cluster-internal shared secret often can — can trust both while the new value
+// reaches every pod. Then every StatefulSet of the owner whose pod template references the Secret
+// is rolled.
+//
+// Rotation is evaluated when EnsureGeneratedSecret runs, which is once per reconcile. A MaxAge
+// therefore fires up to one HealthCheckInterval late, and an AnnotationGeneratedSecretRotate is
+// honoured on the next reconcile rather than the moment it is set: annotating an owned Secret wakes
+// the reconciler only if the operator watches Secrets.
+type GeneratedSecretRotation struct {
+	// MaxAge rotates the values once they are this old. Zero rotates only on request
+	// (AnnotationGeneratedSecretRotate).
+	MaxAge time.Duration
+
+	// GracePeriod keeps the replaced values under "<key>.previous" for this long after a rotation.
+	// Zero drops them immediately. A rotation inside an earlier one's grace window replaces the
+	// previous values: only one generation back is ever kept.
+	GracePeriod time.Duration
+
+	// Recorder, when set, receives a Normal ReasonGeneratedSecretRotated event on the owner for each
+	// rotation. It is typically the manager's recorder, the one GenericReconcilerConfig.Recorder
+	// carries.
+	Recorder record.EventRecorder
+}
+
 // GeneratedSecretOptions carries the optional metadata for EnsureGeneratedSecret.
 // Use the WithGeneratedSecret* option functions to populate it.
 type GeneratedSecretOptions struct {
@@ -50,6 +123,9 @@ type GeneratedSecretOptions struct {
 	// when the Secret is created: `type` is immutable, so setting it on an existing Secret would
 	// make every reconcile fail.
 	Type corev1.SecretType
+
+	// Rotation, when set, lets the generated values be replaced. Nil (the default) never rotates.
+	Rotation *GeneratedSecretRotation
 }
 
 // GeneratedSecretOption customizes EnsureGeneratedSecret.
@@ -88,6 +164,11 @@ func WithGeneratedSecretType(t corev1.SecretType) GeneratedSecretOption {
 	return func(o *GeneratedSecretOptions) { o.Type = t }
 }
 
+// WithGeneratedSecretRotation enables rotation of the generated values under the given policy.
+func WithGeneratedSecretRotation(rotation GeneratedSecretRotation) GeneratedSecretOption {
+	return func(o *GeneratedSecretOptions) { o.Rotation = &rotation }
+}
+
 // EnsureGeneratedSecret creates a Secret whose values are generated once and then never change.
 //
 // This is the counterpart to EnsureDiscoveryConfigMap for the case where the framework needs an
@@ -128,6 +209,9 @@ func WithGeneratedSecretType(t corev1.SecretType) GeneratedSecretOption {
 //
 // The Secret is deliberately NOT created from the sidecar provider's Validate: a validation hook
 // that creates objects is a side effect in the one step whose job is to have none.
+//
+// "Never rewritten" is relaxed only by an explicit WithGeneratedSecretRotation, and then only on the
+// policy's terms: see GeneratedSecretRotation.
 func EnsureGeneratedSecret(
 	ctx context.Context,
 	c client.Client,
@@ -167,12 +251,24 @@ func EnsureGeneratedSecret(
 		},
 	}
 
-	var generateErr error
+	var (
+		generateErr error
+		rotated     bool
+	)
 	_, err := controllerutil.CreateOrUpdate(ctx, c, secret, func() error {
+		now := time.Now().UTC()
+		if options.Rotation != nil && !secret.CreationTimestamp.IsZero() {
+			rotated = rotationDue(secret, options.Rotation, now)
+			if !rotated {
+				expirePreviousValues(secret, generators, now)
+			}
+		}
+
 		// Runs against the LIVE object on update, so secret.Data already holds whatever exists.
-		// Only absent keys are generated; present ones are left untouched.
+		// Only absent keys are generated; present ones are left untouched unless this pass rotates.
 		for _, key := range sortedKeys(generators) {
-			if _, exists := secret.Data[key]; exists {
+			current, exists := secret.Data[key]
+			if exists && !rotated {
 				continue
 			}
 			value, err := generators[key]()
@@ -185,9 +281,16 @@ func EnsureGeneratedSecret(
 			if secret.Data == nil {
 				secret.Data = map[string][]byte{}
 			}
+			if rotated && exists && options.Rotation.GracePeriod > 0 {
+				secret.Data[key+GeneratedSecretPreviousKeySuffix] = current
+			}
 			secret.Data[key] = []byte(value)
 		}
 
+		if options.Rotation != nil {
+			stampRotation(secret, generators, options.Rotation, rotated, now)
+		}
+
 		secret.Labels = labels
 		for k, v := range options.Annotations {
 			if secret.Annotations == nil {
@@ -218,10 +321,188 @@ func EnsureGeneratedSecret(
 		if err := c.Get(ctx, client.ObjectKeyFromObject(secret), secret); err != nil {
 			return nil, fmt.Errorf("re-reading generated secret %q after a concurrent create: %w", name, err)
 		}
+		rotated = false
+	}
+
+	if options.Rotation == nil {
+		return secret, nil
+	}
+	if rotated && options.Rotation.Recorder != nil {
+		message := fmt.Sprintf("Rotated generated secret %s to generation %s", name,
+			secret.Annotations[AnnotationGeneratedSecretGeneration])
+		if expiresAt, ok := secret.Annotations[AnnotationGeneratedSecretPreviousExpiresAt]; ok {
+			message += "; the previous values are kept until " + expiresAt
+		}
+		options.Rotation.Recorder.Event(owner, corev1.EventTypeNormal, ReasonGeneratedSecretRotated, message)
+	}
+	// Run on every pass, not only the rotating one: if rolling fails after the Secret was written,
+	// the next pass finds the StatefulSets still unstamped and finishes the job.
+	if err := rollGeneratedSecretDependents(ctx, c, owner, secret); err != nil {
+		return nil, err
 	}
 	return secret, nil
 }
 
+// rotationDue reports whether the live Secret's values are to be replaced on this pass: either an
+// unanswered AnnotationGeneratedSecretRotate, or values older than MaxAge.
+func rotationDue(secret *corev1.Secret, rotation *GeneratedSecretRotation, now time.Time) bool {
+	if token := secret.Annotations[AnnotationGeneratedSecretRotate]; token != "" &&
+		token != secret.Annotations[AnnotationGeneratedSecretRotateHandled] {
+		return true
+	}
+	if rotation.MaxAge <= 0 {
+		return false
+	}
+	generatedAt := secret.CreationTimestamp.Time
+	if raw, ok := secret.Annotations[AnnotationGeneratedSecretGeneratedAt]; ok {
+		// An unparseable timestamp — a hand-edit — falls back to creation rather than failing the
+		// reconcile; at worst it rotates early, which is what the policy allows anyway.
+		if parsed, err := time.Parse(time.RFC3339, raw); err == nil {
+			generatedAt = parsed
+		}
+	}
+	return !now.Before(generatedAt.Add(rotation.MaxAge))
+}
+
+// expirePreviousValues drops the "<key>.previous" values once their grace window has passed.
+func expirePreviousValues(secret *corev1.Secret, generators map[string]func() (string, error), now time.Time) {
+	raw, ok := secret.Annotations[AnnotationGeneratedSecretPreviousExpiresAt]
+	if !ok {
+		return
+	}
+	if expiresAt, err := time.Parse(time.RFC3339, raw); err == nil && now.Before(expiresAt) {
+		return
+	}
+	for key := range generators {
+		delete(secret.Data, key+GeneratedSecretPreviousKeySuffix)
+	}
+	delete(secret.Annotations, AnnotationGeneratedSecretPreviousExpiresAt)
+}
+
+// stampRotation records the rotation state on the Secret. On a pass that does not rotate it only
+// fills what is missing, so a steady-state Secret is not rewritten.
+func stampRotation(secret *corev1.Secret, generators map[string]func() (string, error), rotation *GeneratedSecretRotation, rotated bool, now time.Time) {
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	if !rotated {
+		if _, ok := secret.Annotations[AnnotationGeneratedSecretGeneratedAt]; !ok {
+			generatedAt := now
+			if !secret.CreationTimestamp.IsZero() {
+				generatedAt = secret.CreationTimestamp.UTC()
+			}
+			secret.Annotations[AnnotationGeneratedSecretGeneratedAt] = generatedAt.Format(time.RFC3339)
+		}
+		return
+	}
+
+	generation, _ := strconv.Atoi(secret.Annotations[AnnotationGeneratedSecretGeneration])
+	secret.Annotations[AnnotationGeneratedSecretGeneration] = strconv.Itoa(generation + 1)
+	secret.Annotations[AnnotationGeneratedSecretGeneratedAt] = now.Format(time.RFC3339)
+	if token := secret.Annotations[AnnotationGeneratedSecretRotate]; token != "" {
+		secret.Annotations[AnnotationGeneratedSecretRotateHandled] = token
+	}
+	if rotation.GracePeriod > 0 {
+		secret.Annotations[AnnotationGeneratedSecretPreviousExpiresAt] = now.Add(rotation.GracePeriod).Format(time.RFC3339)
+		return
+	}
+	for key := range generators {
+		delete(secret.Data, key+GeneratedSecretPreviousKeySuffix)
+	}
+	delete(secret.Annotations, AnnotationGeneratedSecretPreviousExpiresAt)
+}
+
+// rollGeneratedSecretDependents stamps the Secret's generation onto the pod template of every
+// StatefulSet of the owner that references it, which rolls those pods onto the new value.
+//
+// This is the same mechanism commons-operator's restarter uses, and it survives for the same
+// reason: copyStatefulSetState merges the pod template's annotations rather than replacing them, so
+// the next apply of the role group keeps the stamp. It is done here rather than left to the
+// restarter because the restarter only acts on workloads labelled restarter.kubedoop.dev/enable,
+// and a rotated key reaching some pods and not others is the outcome rotation must not have.
+//
+// A StatefulSet created after the rotation already started its pods with the new value, so it is
+// not stamped — stamping it would roll pods that have only just started.
+func rollGeneratedSecretDependents(ctx context.Context, c client.Client, owner client.Object, secret *corev1.Secret) error {
+	generation := secret.Annotations[AnnotationGeneratedSecretGeneration]
+	if generation == "" {
+		return nil
+	}
+	generatedAt, err := time.Parse(time.RFC3339, secret.Annotations[AnnotationGeneratedSecretGeneratedAt])
+	if err != nil {
+		return nil
+	}
+
+	var list appsv1.StatefulSetList
+	if err := c.List(ctx, &list, client.InNamespace(secret.Namespace),
+		client.MatchingLabels{constant.LabelKubernetesInstance: owner.GetName()}); err != nil {
+		return fmt.Errorf("listing StatefulSets to roll onto generated secret %q: %w", secret.Name, err)
+	}
+	key := generatedSecretRolloutKey(secret.Name)
+	for i := range list.Items {
+		sts := &list.Items[i]
+		// The instance label alone is not unique: two products' CRs may share a name in a namespace.
+		if !metav1.IsControlledBy(sts, owner) || sts.Spec.Template.Annotations[key] == generation ||
+			sts.CreationTimestamp.After(generatedAt) || !podSpecReferencesSecret(&sts.Spec.Template.Spec, secret.Name) {
+			continue
+		}
+		patch := client.MergeFrom(sts.DeepCopy())
+		if sts.Spec.Template.Annotations == nil {
+			sts.Spec.Template.Annotations = map[string]string{}
+		}
+		sts.Spec.Template.Annotations[key] = generation
+		if err := c.Patch(ctx, sts, patch); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("rolling StatefulSet %q onto generated secret %q: %w", sts.Name, secret.Name, err)
+		}
+	}
+	return nil
+}
+
+// generatedSecretRolloutKey returns the pod-template annotation key for a Secret. The part after
+// the prefix is bounded at 63 characters like any annotation name, so an over-long Secret name is
+// truncated with a hash suffix, as RoleGroupResourceName does.
+func generatedSecretRolloutKey(secretName string) string {
+	const maxNameLen = 63
+	if len(secretName) <= maxNameLen {
+		return AnnotationGeneratedSecretRolloutPrefix + secretName
+	}
+	sum := sha256.Sum256([]byte(secretName))
+	suffix := hex.EncodeToString(sum[:])[:8]
+	head := strings.TrimRight(secretName[:maxNameLen-len(suffix)-1], "-.")
+	return AnnotationGeneratedSecretRolloutPrefix + head + "-" + suffix
+}
+
+// podSpecReferencesSecret reports whether a pod consumes the named Secret through a volume, a
+// projected volume source, an env var or an envFrom.
+func podSpecReferencesSecret(spec *corev1.PodSpec, name string) bool {
+	for _, v := range spec.Volumes {
+		if v.Secret != nil && v.Secret.SecretName == name {
+			return true
+		}
+		if v.Projected != nil {
+			for _, source := range v.Projected.Sources {
+				if source.Secret != nil && source.Secret.Name == name {
+					return true
+				}
+			}
+		}
+	}
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, container := range containers {
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == name {
+				return true
+			}
+		}
+		for _, from := range container.EnvFrom {
+			if from.SecretRef != nil && from.SecretRef.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // sortedKeys returns the generator keys in a stable order, so a failure names the same key on
 // every run instead of whichever one map iteration reached first.
 func sortedKeys(generators map[string]func() (string, error)) []string {
@@ -28,9 +28,12 @@ import (
 	"github.com/zncdatadev/operator-go/pkg/reconciler"
 	"github.com/zncdatadev/operator-go/pkg/sidecar"
 	"github.com/zncdatadev/operator-go/pkg/testutil"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 var _ = Describe("EnsureGeneratedSecret", func() {
@@ -269,3 +272,152 @@ var _ = Describe("EnsureGeneratedSecret", func() {
 		Expect(err).To(MatchError(ContainSubstring("name must not be empty")))
 	})
 })
+
+var _ = Describe("EnsureGeneratedSecret rotation", func() {
+	const namespace = "default"
+
+	var (
+		secretName string
+		mockCR     *testutil.MockCluster
+		generated  atomic.Int32
+		generate   map[string]func() (string, error)
+		getSecret  func() *corev1.Secret
+	)
+
+	BeforeEach(func() {
+		stamp := time.Now().UnixNano()
+		secretName = fmt.Sprintf("rotsecret-%d", stamp)
+		mockCR = testutil.NewMockCluster(fmt.Sprintf("rotsecret-cr-%d", stamp), namespace)
+		Expect(k8sClient.Create(ctx, mockCR)).To(Succeed())
+
+		generated.Store(0)
+		generate = map[string]func() (string, error){"cookie-secret": func() (string, error) {
+			return fmt.Sprintf("value-%d", generated.Add(1)), nil
+		}}
+		getSecret = func() *corev1.Secret {
+			s := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: secretName}, s)).To(Succeed())
+			return s
+		}
+		DeferCleanup(func() {
+			_ = k8sClient.Delete(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: namespace}})
+			_ = k8sClient.Delete(ctx, mockCR)
+		})
+	})
+
+	ensure := func(rotation reconciler.GeneratedSecretRotation) {
+		_, err := reconciler.EnsureGeneratedSecret(ctx, k8sClient, testScheme, mockCR, secretName, generate,
+			reconciler.WithGeneratedSecretRotation(rotation))
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	annotate := func(key, value string) {
+		live := getSecret()
+		live.Annotations[key] = value
+		Expect(k8sClient.Update(ctx, live)).To(Succeed())
+	}
+
+	It("stamps the generation time but does not rotate before the max age", func() {
+		ensure(reconciler.GeneratedSecretRotation{MaxAge: time.Hour})
+		ensure(reconciler.GeneratedSecretRotation{MaxAge: time.Hour})
+
+		live := getSecret()
+		Expect(live.Data).To(HaveKeyWithValue("cookie-secret", []byte("value-1")))
+		Expect(live.Annotations).To(HaveKey(reconciler.AnnotationGeneratedSecretGeneratedAt))
+		Expect(live.Annotations).NotTo(HaveKey(reconciler.AnnotationGeneratedSecretGeneration))
+	})
+
+	It("rotates values past the max age and keeps the previous one for the grace window", func() {
+		ensure(reconciler.GeneratedSecretRotation{})
+		annotate(reconciler.AnnotationGeneratedSecretGeneratedAt, time.Now().Add(-2*time.Hour).UTC().Format(time.RFC3339))
+
+		rec := record.NewFakeRecorder(10)
+		ensure(reconciler.GeneratedSecretRotation{MaxAge: time.Hour, GracePeriod: time.Hour, Recorder: rec})
+
+		live := getSecret()
+		Expect(live.Data).To(HaveKeyWithValue("cookie-secret", []byte("value-2")))
+		Expect(live.Data).To(HaveKeyWithValue("cookie-secret"+reconciler.GeneratedSecretPreviousKeySuffix, []byte("value-1")))
+		Expect(live.Annotations).To(HaveKeyWithValue(reconciler.AnnotationGeneratedSecretGeneration, "1"))
+		Expect(live.Annotations).To(HaveKey(reconciler.AnnotationGeneratedSecretPreviousExpiresAt))
+		Expect(rec.Events).To(Receive(ContainSubstring(reconciler.ReasonGeneratedSecretRotated)))
+
+		// Freshly rotated: the next pass leaves it alone.
+		ensure(reconciler.GeneratedSecretRotation{MaxAge: time.Hour, GracePeriod: time.Hour})
+		Expect(getSecret().Data).To(HaveKeyWithValue("cookie-secret", []byte("value-2")))
+	})
+
+	It("drops the previous value once the grace window has passed", func() {
+		ensure(reconciler.GeneratedSecretRotation{GracePeriod: time.Hour})
+		annotate(reconciler.AnnotationGeneratedSecretRotate, "1")
+		ensure(reconciler.GeneratedSecretRotation{GracePeriod: time.Hour})
+		Expect(getSecret().Data).To(HaveKey("cookie-secret" + reconciler.GeneratedSecretPreviousKeySuffix))
+
+		annotate(reconciler.AnnotationGeneratedSecretPreviousExpiresAt, time.Now().Add(-time.Minute).UTC().Format(time.RFC3339))
+		ensure(reconciler.GeneratedSecretRotation{GracePeriod: time.Hour})
+
+		live := getSecret()
+		Expect(live.Data).NotTo(HaveKey("cookie-secret" + reconciler.GeneratedSecretPreviousKeySuffix))
+		Expect(live.Data).To(HaveKeyWithValue("cookie-secret", []byte("value-2")))
+		Expect(live.Annotations).NotTo(HaveKey(reconciler.AnnotationGeneratedSecretPreviousExpiresAt))
+	})
+
+	It("honours each rotation request once", func() {
+		ensure(reconciler.GeneratedSecretRotation{})
+		annotate(reconciler.AnnotationGeneratedSecretRotate, "first")
+
+		ensure(reconciler.GeneratedSecretRotation{})
+		ensure(reconciler.GeneratedSecretRotation{})
+		live := getSecret()
+		Expect(live.Data).To(HaveKeyWithValue("cookie-secret", []byte("value-2")), "one request, one rotation")
+		Expect(live.Data).NotTo(HaveKey("cookie-secret"+reconciler.GeneratedSecretPreviousKeySuffix),
+			"without a grace period the replaced value is not kept")
+		Expect(live.Annotations).To(HaveKeyWithValue(reconciler.AnnotationGeneratedSecretRotateHandled, "first"))
+
+		annotate(reconciler.AnnotationGeneratedSecretRotate, "second")
+		ensure(reconciler.GeneratedSecretRotation{})
+		Expect(getSecret().Annotations).To(HaveKeyWithValue(reconciler.AnnotationGeneratedSecretGeneration, "2"))
+	})
+
+	It("rolls the owner's StatefulSets that reference the secret", func() {
+		ensure(reconciler.GeneratedSecretRotation{})
+
+		newSts := func(name string, volumes []corev1.Volume) *appsv1.StatefulSet {
+			labels := map[string]string{"app": name}
+			sts := &appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{
+					constant.LabelKubernetesInstance: mockCR.GetName(),
+				}},
+				Spec: appsv1.StatefulSetSpec{
+					Selector: &metav1.LabelSelector{MatchLabels: labels},
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Labels: labels},
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: "main", Image: "busybox"}},
+							Volumes:    volumes,
+						},
+					},
+				},
+			}
+			Expect(controllerutil.SetControllerReference(mockCR, sts, testScheme)).To(Succeed())
+			Expect(k8sClient.Create(ctx, sts)).To(Succeed())
+			DeferCleanup(func() { _ = k8sClient.Delete(ctx, sts) })
+			return sts
+		}
+		dependent := newSts(secretName+"-dep", []corev1.Volume{{
+			Name:         "cookie",
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: secretName}},
+		}})
+		unrelated := newSts(secretName+"-other", nil)
+
+		annotate(reconciler.AnnotationGeneratedSecretRotate, "now")
+		ensure(reconciler.GeneratedSecretRotation{})
+
+		rolloutKey := reconciler.AnnotationGeneratedSecretRolloutPrefix + secretName
+		live := &appsv1.StatefulSet{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: dependent.Name}, live)).To(Succeed())
+		Expect(live.Spec.Template.Annotations).To(HaveKeyWithValue(rolloutKey, "1"))
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: unrelated.Name}, live)).To(Succeed())
+		Expect(live.Spec.Template.Annotations).NotTo(HaveKey(rolloutKey))
+	})
+})
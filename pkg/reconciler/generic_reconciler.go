@@ -29,20 +29,27 @@ import (
 	"time"
 
 	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	listenersv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/listeners/v1alpha1"
+	opbuilder "github.com/zncdatadev/operator-go/pkg/builder"
 	"github.com/zncdatadev/operator-go/pkg/common"
 	"github.com/zncdatadev/operator-go/pkg/config"
 	"github.com/zncdatadev/operator-go/pkg/constant"
 	"github.com/zncdatadev/operator-go/pkg/sidecar"
 	"github.com/zncdatadev/operator-go/pkg/util"
 	"github.com/zncdatadev/operator-go/pkg/vector"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/validation"
@@ -53,6 +60,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -166,8 +174,11 @@ type GenericReconcilerConfig[CR common.ClusterResource[CR]] struct {
 	// DrainTimeout bounds how long an orphaned StatefulSet's ordered drain may block the rest of
 	// its role group's teardown. Once it elapses the StatefulSet is deleted regardless of the pods
 	// still reported, so a pod that cannot terminate (a stuck finalizer, an unreachable node)
-	// cannot strand the group's ConfigMap, Services and status entry indefinitely. Defaults to
-	// DefaultDrainTimeout (10m).
+	// cannot strand the group's ConfigMap, Services and status entry indefinitely. Unset, each
+	// StatefulSet's bound derives from the grace period its role group's gracefulShutdownTimeout
+	// gave its pods, so the drain waits exactly as long as the shutdown may take — 60s for a
+	// parallel role group on the default 30s — and a StatefulSet whose pods state none falls back
+	// to DefaultDrainTimeout (10m). Set it to keep the flat bound earlier releases applied.
 	// +optional
 	DrainTimeout time.Duration
 
@@ -226,6 +237,83 @@ type GenericReconcilerConfig[CR common.ClusterResource[CR]] struct {
 	// +optional
 	WorkloadRBACRules func(cr CR) []rbacv1.PolicyRule
 
+	// NetworkPolicies turns on the per-role-group NetworkPolicy built from each role's
+	// RoleDeclaration.NetworkPolicy: it is applied with the role group's other resources, reclaimed
+	// when the role stops declaring one, and deleted by RoleGroupCleaner with the role group.
+	//
+	// It is an operator-level switch for the same reason WorkloadRBACRules is nil-by-default: the
+	// NetworkPolicy watch and reclaim need `+kubebuilder:rbac:groups=networking.k8s.io,
+	// resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete` on the operator,
+	// and a forbidden informer stops the whole manager. Operators that never opt in pay nothing.
+	// +optional
+	NetworkPolicies bool
+
+	// VolumeExpansion lets a storage capacity increase reach the role group's existing PVCs. A
+	// StatefulSet's volumeClaimTemplates are immutable, so without it the increase is dropped with an
+	// ImmutableFieldIgnored warning. With it, when every existing claim's StorageClass sets
+	// allowVolumeExpansion, the framework raises each claim's request and recreates the StatefulSet
+	// with orphaned pods so the template matches; progress is reported as ConditionVolumeExpansion.
+	// See expandStatefulSetClaims.
+	//
+	// It is a switch because it needs `+kubebuilder:rbac:groups=storage.k8s.io,
+	// resources=storageclasses,verbs=get;list;watch` and `+kubebuilder:rbac:groups="",
+	// resources=persistentvolumeclaims,verbs=get;list;watch;patch` on the operator, and because it
+	// deletes StatefulSets of running role groups, which an operator should choose to do.
+	// +optional
+	VolumeExpansion bool
+
+	// ListenerFallback realises Listeners in-process for clusters without the listener-operator —
+	// kind and dev clusters — where a pod mounting a listener CSI volume would stay Pending forever.
+	// For every role group whose role declares a RoleDeclaration.ListenerClass, the framework applies
+	// the class to the role group Service, creates the per-pod Services and Listeners the
+	// listener-operator would, fills their ListenerStatus, and replaces the listener volumes with
+	// downward-API volumes carrying the same files. See planListenerFallback.
+	//
+	// It needs the listeners.kubedoop.dev CRDs installed (not the operator) and, on the operator,
+	// `+kubebuilder:rbac:groups=listeners.kubedoop.dev,resources=listeners;listeners/status,
+	// verbs=get;list;watch;create;update;patch;delete`, get on listenerclasses, and
+	// `+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;patch`. Leave it off where
+	// the listener-operator runs: both would then write the same Listeners.
+	// +optional
+	ListenerFallback bool
+
+	// ServiceMonitors turns on a Prometheus Operator ServiceMonitor for every role group that ships
+	// a MetricsService. Prometheus Operator ignores the prometheus.io annotations MetricsServiceBuilder
+	// writes, so without one a cluster running it scrapes nothing. The ServiceMonitor is built from the
+	// metrics Service (see buildRoleGroupServiceMonitor), owned by the CR, reclaimed when the role
+	// group stops shipping metrics, and deleted by RoleGroupCleaner with the role group.
+	//
+	// It is created only where the monitoring.coreos.com/v1 CRD is installed, detected through
+	// discovery on every reconcile, so one operator build serves clusters with and without
+	// Prometheus Operator. The watch is registered only if the CRD was present when the controller
+	// was set up. The operator needs `+kubebuilder:rbac:groups=monitoring.coreos.com,
+	// resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete`. Nil generates
+	// nothing.
+	// +optional
+	ServiceMonitors *ServiceMonitorConfig
+
+	// TracerProvider, when set, traces every reconcile with OpenTelemetry: one root span per
+	// Reconcile call, with children for the role declaration, the dependency check, each role
+	// group, each applied resource (kind, name and whether it was created, updated or unchanged),
+	// the orphan cleanup, the health check and each extension hook. Failures are recorded on the
+	// span they happened in; waits are recorded as events. Pass the provider the operator's
+	// exporter is wired to, typically otel.GetTracerProvider() after the SDK is set up in main.go.
+	// Nil uses a no-op provider, so an operator that does not trace pays for no spans.
+	// +optional
+	TracerProvider trace.TracerProvider
+
+	// PodSecurity checks every role group's built pod template against a Pod Security Standard and
+	// reports violations as Warning events and ConditionPodSecurityViolation, or, when Strict,
+	// fails the role group. The zero value checks nothing.
+	// +optional
+	PodSecurity PodSecurityPolicy
+
+	// Discovery, when set, publishes the cluster's discovery ConfigMaps every reconcile, built from
+	// the role group Services and Listener status rather than from connection strings each product
+	// computes by hand. See DiscoveryConfig.
+	// +optional
+	Discovery *DiscoveryConfig[CR]
+
 	// Dependencies, when set, returns the external objects the CR references (ConfigMaps and
 	// Secrets that the product does not create itself, e.g. a Kerberos keytab Secret or an
 	// authentication ConfigMap). They are verified to exist before any role is reconciled; a
@@ -273,14 +361,16 @@ type GenericReconcilerConfig[CR common.ClusterResource[CR]] struct {
 //     - RoleGroup PreReconcile Extensions
 //     - Build RoleGroupBuildContext
 //     - Delegate to RoleGroupHandler.BuildResources()
-//     - Apply Resources (CM -> HeadlessSvc -> Service -> Extras -> STS -> PDB -> MetricsSvc)
+//     - Apply Resources (CM -> HeadlessSvc -> Service -> NetworkPolicy -> Extras -> STS -> PDB -> MetricsSvc -> ServiceMonitor)
+//     - Realise Listeners in-process (when ListenerFallback is set)
 //     - Track in Status
 //     - RoleGroup PostReconcile Extensions
 //     - Role PostReconcile Extensions
 //     e. Cleanup Orphaned Resources
 //     f. Update Health Status
-//     g. PostReconcile Extensions
-//     h. Final Status Update
+//     g. Publish Discovery (when configured)
+//     h. PostReconcile Extensions
+//     i. Final Status Update
 //  4. Error handling: OnReconcileError hooks, set Degraded condition
 type GenericReconciler[CR common.ClusterResource[CR]] struct {
 	client              client.Client
@@ -301,6 +391,18 @@ type GenericReconciler[CR common.ClusterResource[CR]] struct {
 	healthCheckInterval time.Duration
 	// workloadRBACRules, when set, declares the workload's API permissions (see the config field).
 	workloadRBACRules func(cr CR) []rbacv1.PolicyRule
+	// networkPolicies gates the NetworkPolicy slot (see the config field).
+	networkPolicies bool
+	// volumeExpansion gates in-place PVC expansion (see the config field).
+	volumeExpansion bool
+	// listenerFallback gates the built-in listener fallback (see the config field).
+	listenerFallback bool
+	// serviceMonitors gates the ServiceMonitor slot (see the config field).
+	serviceMonitors *ServiceMonitorConfig
+	// tracer starts the root span of every reconcile (see the TracerProvider config field).
+	tracer            trace.Tracer
+	podSecurity       PodSecurityPolicy
+	discovery         *DiscoveryConfig[CR]
 	dependencies      func(cr CR) []Dependency
 	roleProvider      RoleProvider[CR]
 	roleGroupResolver RoleGroupResolver[CR]
@@ -322,6 +424,15 @@ func NewGenericReconciler[CR common.ClusterResource[CR]](cfg *GenericReconcilerC
 		return nil, fmt.Errorf("roleGroupHandler is required")
 	}
 
+	// A broken mirror rule or digest manifest fails at startup, once, rather than as an image pull
+	// failure on every pod it reaches.
+	if err := cfg.ImageResolution.validateImageRewrite(); err != nil {
+		return nil, fmt.Errorf("imageResolution: %w", err)
+	}
+	if err := cfg.ImageResolution.Versions.Validate(); err != nil {
+		return nil, fmt.Errorf("imageResolution.versions: %w", err)
+	}
+
 	healthCheckInterval := cfg.HealthCheckInterval
 	if healthCheckInterval == 0 {
 		healthCheckInterval = DefaultHealthCheckInterval
@@ -360,6 +471,15 @@ func NewGenericReconciler[CR common.ClusterResource[CR]](cfg *GenericReconcilerC
 	if cfg.GrayDeleteGracePeriod > 0 {
 		cleaner.WithGrayDeleteGracePeriod(cfg.GrayDeleteGracePeriod)
 	}
+	if cfg.NetworkPolicies {
+		cleaner.WithNetworkPolicies()
+	}
+	if cfg.ListenerFallback {
+		cleaner.WithListenerFallback()
+	}
+	if cfg.ServiceMonitors != nil {
+		cleaner.WithServiceMonitors()
+	}
 
 	// An empty registry rather than nil keeps the hook call sites unconditional; a product that
 	// registers no extensions pays an empty loop per hook.
@@ -368,6 +488,11 @@ func NewGenericReconciler[CR common.ClusterResource[CR]](cfg *GenericReconcilerC
 		extensionRegistry = common.NewExtensionRegistry[CR]()
 	}
 
+	tracerProvider := cfg.TracerProvider
+	if tracerProvider == nil {
+		tracerProvider = noop.NewTracerProvider()
+	}
+
 	return &GenericReconciler[CR]{
 		client:              cfg.Client,
 		apiReader:           cfg.APIReader,
@@ -384,6 +509,13 @@ func NewGenericReconciler[CR common.ClusterResource[CR]](cfg *GenericReconcilerC
 		rateLimitRetryAfter: rateLimitRetryAfter,
 		healthCheckInterval: healthCheckInterval,
 		workloadRBACRules:   cfg.WorkloadRBACRules,
+		networkPolicies:     cfg.NetworkPolicies,
+		volumeExpansion:     cfg.VolumeExpansion,
+		listenerFallback:    cfg.ListenerFallback,
+		serviceMonitors:     cfg.ServiceMonitors,
+		tracer:              tracerProvider.Tracer(TracerName),
+		podSecurity:         cfg.PodSecurity,
+		discovery:           cfg.Discovery,
 		dependencies:        cfg.Dependencies,
 		roleProvider:        cfg.RoleProvider,
 		roleGroupResolver:   cfg.RoleGroupResolver,
@@ -399,6 +531,11 @@ func NewGenericReconciler[CR common.ClusterResource[CR]](cfg *GenericReconcilerC
 // neither requeue nor back off. The status is deliberately left untouched on this path — an
 // internal error says nothing about the cluster's actual state (docs/architecture.md §4.8.2).
 func (r *GenericReconciler[CR]) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	// Deferred before the panic recovery so it runs after it and records the recovered panic.
+	ctx, span := r.tracer.Start(ctx, "Reconcile",
+		trace.WithAttributes(attrNamespace.String(req.Namespace), attrCluster.String(req.Name)))
+	defer func() { endSpan(span, err) }()
+
 	logger := log.FromContext(ctx)
 
 	// panicSubject is the object the Warning event is attached to. It is only known once the CR
@@ -469,6 +606,8 @@ func (r *GenericReconciler[CR]) Reconcile(ctx context.Context, req ctrl.Request)
 		var rateLimitErr *RateLimitError
 		if stderrors.As(err, &rateLimitErr) {
 			logger.Info("Rate limited by Kubernetes API, backing off", "retryAfter", rateLimitErr.RetryAfter)
+			ReconcileRateLimited.WithLabelValues(req.Namespace, req.Name).Inc()
+			span.AddEvent("rateLimited", trace.WithAttributes(attribute.String("retryAfter", rateLimitErr.RetryAfter.String())))
 			return ctrl.Result{RequeueAfter: rateLimitErr.RetryAfter}, nil
 		}
 
@@ -483,9 +622,13 @@ func (r *GenericReconciler[CR]) Reconcile(ctx context.Context, req ctrl.Request)
 		status.SetReconcileComplete(false, v1alpha1.ReasonReconcileError, err.Error())
 
 		// Update status
-		if updateErr := r.updateStatus(ctx, cr, stored); updateErr != nil {
+		statusStart := time.Now()
+		updateErr := r.updateStatus(ctx, cr, stored)
+		observePhase(req.Namespace, req.Name, phaseStatus, statusStart, updateErr)
+		if updateErr != nil {
 			logger.Error(updateErr, "Failed to update status after reconciliation error")
 		}
+		recordConditionMetrics(req.Namespace, req.Name, status)
 
 		// Emit error event
 		r.eventManager.EmitErrorEvent(cr.GetName(), cr, err)
@@ -493,6 +636,9 @@ func (r *GenericReconciler[CR]) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
+	// Every successful pass, the paused one included, ends by writing the status, so this mirrors
+	// what the CR now says.
+	recordConditionMetrics(req.Namespace, req.Name, cr.GetStatus())
 	return result, nil
 }
 
@@ -511,6 +657,20 @@ func (r *GenericReconciler[CR]) fetchCR(ctx context.Context, key types.Namespace
 	return cr, nil
 }
 
+// passFindings collects what one reconcile pass observes about its role groups for the
+// cluster-level conditions written after the role loop: Pod Security violations and volume
+// expansion states, and whether any pod still waits for its node labels. It is threaded down the
+// call chain, not stored on the reconciler, because one reconciler serves every cluster.
+type passFindings struct {
+	podSecurity       podSecurityFindings
+	volumeExpansion   volumeExpansionFindings
+	nodeLabelsPending bool
+}
+
+func newPassFindings() *passFindings {
+	return &passFindings{podSecurity: podSecurityFindings{}, volumeExpansion: volumeExpansionFindings{}}
+}
+
 // reconcile performs the main reconciliation logic.
 func (r *GenericReconciler[CR]) reconcile(ctx context.Context, cr CR, stored client.Object) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
@@ -585,7 +745,11 @@ func (r *GenericReconciler[CR]) reconcile(ctx context.Context, cr CR, stored cli
 	// A wait does NOT return here. The pass continues so cleanup and health still run, which is what
 	// keeps Degraded and Available current; the wait itself is reported at step 8.
 	var waitFor *waitState
-	if err := r.extensionRegistry.ExecuteClusterPreReconcile(ctx, r.client, cr); err != nil {
+	ns, name := cr.GetNamespace(), cr.GetName()
+	extensionsStart := time.Now()
+	err := r.extensionRegistry.ExecuteClusterPreReconcile(ctx, r.client, cr)
+	observePhase(ns, name, phaseExtensions, extensionsStart, err)
+	if err != nil {
 		waitErr, waiting := common.WaitingErrors(err)
 		if !waiting {
 			return ctrl.Result{}, NewReconcileError("PreReconcile", "extension hook failed", err)
@@ -594,7 +758,10 @@ func (r *GenericReconciler[CR]) reconcile(ctx context.Context, cr CR, stored cli
 	}
 
 	// 2. Validate dependencies declared by the product (no-op when the hook is unset)
-	if err := r.validateDependencies(ctx, cr); err != nil {
+	dependenciesStart := time.Now()
+	err = r.validateDependencies(ctx, cr)
+	observePhase(ns, name, phaseDependencies, dependenciesStart, err)
+	if err != nil {
 		waitErr, waiting := common.WaitingErrors(err)
 		if !waiting {
 			return ctrl.Result{}, NewReconcileError("DependencyValidation", "dependency validation failed", err)
@@ -645,6 +812,7 @@ func (r *GenericReconciler[CR]) reconcile(ctx context.Context, cr CR, stored cli
 	}
 
 	var roleErrs []error
+	findings := newPassFindings()
 	// A CLUSTER-level wait blocks the roles. That is the whole point of #608's case — a schema
 	// migration Job that must finish before any workload starts — and applying the StatefulSets
 	// anyway would start the product against a database that is not initialised. The pass still
@@ -658,7 +826,7 @@ func (r *GenericReconciler[CR]) reconcile(ctx context.Context, cr CR, stored cli
 			break
 		}
 		roleSpec := spec.Roles[roleName]
-		if err := r.reconcileRole(ctx, cr, roleName, &roleSpec, catalog[roleName]); err != nil {
+		if err := r.reconcileRole(ctx, cr, roleName, &roleSpec, catalog[roleName], findings); err != nil {
 			// Throttling is the one failure that must stop the pass: the API server is rejecting
 			// this operator's requests, so pushing the remaining roles through would only deepen
 			// the backlog.
@@ -668,11 +836,21 @@ func (r *GenericReconciler[CR]) reconcile(ctx context.Context, cr CR, stored cli
 			roleErrs = append(roleErrs, err)
 		}
 	}
+	// Only a pass that built the roles has an answer: one blocked by a wait or a failed catalog
+	// checked nothing, and writing its empty findings would clear a violation that still stands.
+	if r.podSecurity.Level != "" && waitFor == nil && catalog != nil {
+		setPodSecurityCondition(status, r.podSecurity.Level, findings.podSecurity)
+	}
+	if r.volumeExpansion && waitFor == nil && catalog != nil {
+		setVolumeExpansionCondition(status, findings.volumeExpansion)
+	}
 
 	// 4. Cleanup orphaned resources. The returned duration is the earliest wakeup the cleanup needs
 	// (a pending gray-delete deadline, or the next poll of a deletion in flight); it feeds the
 	// wakeup aggregation below so the deletion state machine advances on time.
+	cleanupStart := time.Now()
 	cleanupRequeue, err := r.cleaner.Cleanup(ctx, cr.GetNamespace(), cr.GetName(), spec, status, cr.GetUID(), cr.GetAnnotations())
+	observePhase(ns, name, phaseCleanup, cleanupStart, err)
 	if err != nil {
 		// Throttling is not a cleanup problem: the API server is rejecting this operator's
 		// requests, so the whole cycle has to back off rather than push the remaining writes
@@ -685,13 +863,28 @@ func (r *GenericReconciler[CR]) reconcile(ctx context.Context, cr CR, stored cli
 	}
 
 	// 5. Update health status
-	if err := r.healthManager.Check(ctx, cr.GetNamespace(), cr.GetName(), spec, status); err != nil {
+	healthStart := time.Now()
+	err = r.healthManager.Check(ctx, cr.GetNamespace(), cr.GetName(), spec, status)
+	observePhase(ns, name, phaseHealth, healthStart, err)
+	if err != nil {
 		logger.Error(err, "Failed to update health status")
 		// Don't fail reconciliation for health check errors
 	}
 
+	// 5b. Publish discovery. After health, so it reflects the Services and Listeners this pass
+	// applied; before PostReconcile, so a hook that reads the ConfigMaps sees this pass's values.
+	if err := r.publishDiscovery(ctx, cr); err != nil {
+		if IsRateLimitError(err) {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, NewReconcileError("Discovery", "failed to publish discovery", err)
+	}
+
 	// 6. Execute PostReconcile extensions
-	if err := r.extensionRegistry.ExecuteClusterPostReconcile(ctx, r.client, cr); err != nil {
+	extensionsStart = time.Now()
+	err = r.extensionRegistry.ExecuteClusterPostReconcile(ctx, r.client, cr)
+	observePhase(ns, name, phaseExtensions, extensionsStart, err)
+	if err != nil {
 		return ctrl.Result{}, NewReconcileError("PostReconcile", "extension hook failed", err)
 	}
 
@@ -745,7 +938,10 @@ func (r *GenericReconciler[CR]) reconcile(ctx context.Context, cr CR, stored cli
 		status.SetReconcileComplete(true, v1alpha1.ReasonReconcileComplete, "Reconciliation completed successfully")
 	}
 
-	if err := r.updateStatus(ctx, cr, stored); err != nil {
+	statusStart := time.Now()
+	err = r.updateStatus(ctx, cr, stored)
+	observePhase(ns, name, phaseStatus, statusStart, err)
+	if err != nil {
 		return ctrl.Result{}, err
 	}
 
@@ -754,6 +950,9 @@ func (r *GenericReconciler[CR]) reconcile(ctx context.Context, cr CR, stored cli
 	// gray-delete grace period running out — needs a timed requeue. Both sources collapse into
 	// a single RequeueAfter (the earliest one); zero means "no periodic wakeup".
 	requeueAfter := earliestRequeue(r.healthCheckInterval, cleanupRequeue)
+	if findings.nodeLabelsPending {
+		requeueAfter = earliestRequeue(requeueAfter, nodeLabelsRetryInterval)
+	}
 	if waitFor != nil {
 		requeueAfter = earliestRequeue(requeueAfter, waitFor.After)
 		logger.Info("Reconciliation is waiting", "reason", waitFor.Reason, "requeueAfter", waitFor.After)
@@ -782,10 +981,12 @@ func earliestRequeue(candidates ...time.Duration) time.Duration {
 // validateDependencies verifies that every external object the product declares for this CR
 // exists. Returns a *DependencyError (which the caller turns into a Degraded condition and a
 // requeue) for the first missing one. No-op when the Dependencies hook is unset.
-func (r *GenericReconciler[CR]) validateDependencies(ctx context.Context, cr CR) error {
+func (r *GenericReconciler[CR]) validateDependencies(ctx context.Context, cr CR) (err error) {
 	if r.dependencies == nil {
 		return nil
 	}
+	ctx, span := startSpan(ctx, "validateDependencies")
+	defer func() { endSpan(span, err) }()
 
 	for _, dep := range r.dependencies(cr) {
 		namespace := dep.Namespace
@@ -820,7 +1021,7 @@ func (r *GenericReconciler[CR]) validateDependencies(ctx context.Context, cr CR)
 }
 
 // reconcileRole reconciles a single role.
-func (r *GenericReconciler[CR]) reconcileRole(ctx context.Context, cr CR, roleName string, roleSpec *v1alpha1.RoleSpec, decl RoleDeclaration) error {
+func (r *GenericReconciler[CR]) reconcileRole(ctx context.Context, cr CR, roleName string, roleSpec *v1alpha1.RoleSpec, decl RoleDeclaration, findings *passFindings) error {
 	logger := log.FromContext(ctx)
 
 	// Execute role PreReconcile extensions
@@ -845,7 +1046,7 @@ func (r *GenericReconciler[CR]) reconcileRole(ctx context.Context, cr CR, roleNa
 	for _, groupName := range slices.Sorted(maps.Keys(roleGroups)) {
 		groupSpec := roleGroups[groupName]
 		groupSpecCopy := *groupSpec.DeepCopy()
-		if err := r.reconcileRoleGroup(ctx, cr, roleName, roleSpec, groupName, &groupSpecCopy, decl); err != nil {
+		if err := r.reconcileRoleGroup(ctx, cr, roleName, roleSpec, groupName, &groupSpecCopy, decl, findings); err != nil {
 			// A 429 stops everything: see the role loop in reconcile.
 			if IsRateLimitError(err) {
 				return err
@@ -898,8 +1099,12 @@ func (r *GenericReconciler[CR]) reconcileRole(ctx context.Context, cr CR, roleNa
 // future reclaim that keys off a marker.
 var reservedSlotLabelKeys = []string{
 	LabelMetricsService,
+	LabelLogConfigMap,
 	LabelRolePodDisruptionBudget,
 	LabelRoleGroupPodDisruptionBudget,
+	LabelNetworkPolicy,
+	LabelListenerFallback,
+	LabelServiceMonitor,
 }
 
 // handlerWritableLabels returns the CR's labels as a map a handler may both read and write, minus
@@ -949,6 +1154,19 @@ func (r *GenericReconciler[CR]) reconcileRolePodDisruptionBudget(ctx context.Con
 
 	name := RoleResourceName(cr.GetName(), roleName)
 
+	var replicas int32
+	if decl.Quorum != nil {
+		if roleConfig := roleSpec.GetRoleConfig(); roleConfig != nil &&
+			roleConfig.PodDisruptionBudget.GetScope() == v1alpha1.PodDisruptionBudgetScopeRoleGroup {
+			return NewValidationError("podDisruptionBudget.scope", roleName, "", fmt.Errorf(
+				"the role is a quorum, which spans all of its role groups; per-role-group budgets cannot protect it"))
+		}
+		var err error
+		if replicas, err = r.liveRoleReplicas(ctx, cr, roleName, roleSpec); err != nil {
+			return err
+		}
+	}
+
 	// The role's image resolves from role-scoped inputs only, so the PDB gets the same
 	// app.kubernetes.io/name and /version every other resource of this role carries. An error here
 	// is not fatal to the PDB: the labels are descriptive, and the role group build reports the
@@ -963,6 +1181,8 @@ func (r *GenericReconciler[CR]) reconcileRolePodDisruptionBudget(ctx context.Con
 		RoleSpec:         roleSpec,
 		ProductName:      r.imageResolution.ProductName,
 		ProductVersion:   resolved.ProductVersion,
+		Declaration:      decl,
+		Replicas:         replicas,
 	})
 	if pdb == nil {
 		// PDB unset or disabled: remove the role PDB we previously created. Gated on the slot
@@ -985,8 +1205,31 @@ func (r *GenericReconciler[CR]) reconcileRolePodDisruptionBudget(ctx context.Con
 	return nil
 }
 
+// liveRoleReplicas sums the replicas of the role's StatefulSets as they are applied. It runs after
+// the role's groups were reconciled, so it sees this pass's scale; a group whose StatefulSet does
+// not exist — it failed to build, or is still being created — counts as no members, which is what
+// it is to the quorum.
+func (r *GenericReconciler[CR]) liveRoleReplicas(ctx context.Context, cr CR, roleName string, roleSpec *v1alpha1.RoleSpec) (int32, error) {
+	var replicas int32
+	for _, groupName := range slices.Sorted(maps.Keys(roleSpec.GetRoleGroups())) {
+		sts := &appsv1.StatefulSet{}
+		key := types.NamespacedName{Namespace: cr.GetNamespace(), Name: RoleGroupResourceName(cr.GetName(), roleName, groupName)}
+		if err := r.client.Get(ctx, key, sts); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return 0, NewResourceApplyError("StatefulSet", key.Namespace, key.Name, "failed to read replicas for the quorum", r.apiError(err))
+		}
+		replicas += ptr.Deref(sts.Spec.Replicas, 1)
+	}
+	return replicas, nil
+}
+
 // reconcileRoleGroup reconciles a single role group.
-func (r *GenericReconciler[CR]) reconcileRoleGroup(ctx context.Context, cr CR, roleName string, roleSpec *v1alpha1.RoleSpec, groupName string, groupSpec *v1alpha1.RoleGroupSpec, decl RoleDeclaration) error {
+func (r *GenericReconciler[CR]) reconcileRoleGroup(ctx context.Context, cr CR, roleName string, roleSpec *v1alpha1.RoleSpec, groupName string, groupSpec *v1alpha1.RoleGroupSpec, decl RoleDeclaration, findings *passFindings) (err error) {
+	ctx, span := startSpan(ctx, "reconcileRoleGroup", attrRole.String(roleName), attrRoleGroup.String(groupName))
+	defer func() { endSpan(span, err) }()
+
 	logger := log.FromContext(ctx)
 
 	// Execute role group PreReconcile extensions
@@ -994,10 +1237,54 @@ func (r *GenericReconciler[CR]) reconcileRoleGroup(ctx context.Context, cr CR, r
 		return NewReconcileError("RoleGroupPreReconcile", fmt.Sprintf("role %s group %s extension hook failed", roleName, groupName), err)
 	}
 
-	// Build context
+	// The build and the apply are timed apart (see ReconcilePhaseDuration): a role group whose
+	// handler is slow and one whose writes are slow call for different fixes.
+	buildStart := time.Now()
+	buildCtx, resources, listenerFallback, err := r.buildRoleGroup(ctx, cr, roleName, roleSpec, groupName, groupSpec, decl, findings)
+	observePhase(cr.GetNamespace(), cr.GetName(), phaseRoleGroupBuild, buildStart, err)
+	if err != nil {
+		return err
+	}
+
+	applyStart := time.Now()
+	err = r.applyRoleGroup(ctx, cr, buildCtx, resources, listenerFallback, findings)
+	observePhase(cr.GetNamespace(), cr.GetName(), phaseApply, applyStart, err)
+	if err != nil {
+		return err
+	}
+
+	// Track role group in status, with the product version it now runs: the version the next
+	// upgrade is judged from.
+	cr.GetStatus().SetRoleGroup(roleName, groupName)
+	cr.GetStatus().SetProductVersion(roleName, groupName, buildCtx.ResolvedImage.ProductVersion)
+
+	// Execute role group PostReconcile extensions
+	if err := r.extensionRegistry.ExecuteRoleGroupPostReconcile(ctx, r.client, cr, roleName, groupName); err != nil {
+		return NewReconcileError("RoleGroupPostReconcile", fmt.Sprintf("role %s group %s extension hook failed", roleName, groupName), err)
+	}
+
+	logger.V(1).Info("Role group reconciled", "role", roleName, "group", groupName)
+	return nil
+}
+
+// buildRoleGroup builds a role group's resources without applying any of them: the build context,
+// the handler's resources, the slots the framework fills itself, the listener fallback plan, the
+// node labels' init container and the Pod Security check.
+func (r *GenericReconciler[CR]) buildRoleGroup(
+	ctx context.Context,
+	cr CR,
+	roleName string,
+	roleSpec *v1alpha1.RoleSpec,
+	groupName string,
+	groupSpec *v1alpha1.RoleGroupSpec,
+	decl RoleDeclaration,
+	findings *passFindings,
+) (*RoleGroupBuildContext, *RoleGroupResources, *listenerFallbackPlan, error) {
+	logger := log.FromContext(ctx)
+
 	buildCtx, err := r.buildRoleGroupContext(ctx, cr, roleName, roleSpec, groupName, groupSpec, decl)
 	if err != nil {
-		return WrapConfigError(fmt.Sprintf("role %s group %s", roleName, groupName), err)
+		return nil, nil, nil, WrapConfigError(fmt.Sprintf("role %s group %s", roleName, groupName), err)
 	}
 
 	// A podOverrides layer that fails to decode is dropped by the merger so the rest of the
@@ -1012,23 +1299,75 @@ func (r *GenericReconciler[CR]) reconcileRoleGroup(ctx context.Context, cr CR, r
 	// Delegate to handler for resource building
 	resources, err := r.roleGroupHandler.BuildResources(ctx, r.client, cr, buildCtx)
 	if err != nil {
-		return NewResourceBuildError("resources", roleName, groupName, "failed to build resources", err)
+		return nil, nil, nil, NewResourceBuildError("resources", roleName, groupName, "failed to build resources", err)
 	}
 
-	// Apply resources in dependency order
-	if err := r.applyResources(ctx, cr, resources, buildCtx); err != nil {
-		return err
+	// The NetworkPolicy is built here rather than by the handler: it follows the StatefulSet's
+	// selector and the MetricsService's ports, and a product that builds either itself would
+	// otherwise have to rebuild the policy too.
+	if r.networkPolicies && resources.NetworkPolicy == nil {
+		resources.NetworkPolicy = buildRoleGroupNetworkPolicy(buildCtx, resources)
+	}
+	// Likewise the ServiceMonitor, which restates the MetricsService in Prometheus Operator's terms.
+	if r.serviceMonitors != nil && resources.ServiceMonitor == nil {
+		resources.ServiceMonitor = buildRoleGroupServiceMonitor(buildCtx, resources, r.serviceMonitors)
 	}
 
-	// Track role group in status
-	cr.GetStatus().SetRoleGroup(roleName, groupName)
+	// The listener fallback rewrites the pod template's listener volumes, so it runs before the
+	// Pod Security check sees the template.
+	var listenerFallback *listenerFallbackPlan
+	if r.listenerFallback {
+		if listenerFallback, err = r.planListenerFallback(ctx, buildCtx, resources); err != nil {
+			return nil, nil, nil, err
+		}
+	}
 
-	// Execute role group PostReconcile extensions
-	if err := r.extensionRegistry.ExecuteRoleGroupPostReconcile(ctx, r.client, cr, roleName, groupName); err != nil {
-		return NewReconcileError("RoleGroupPostReconcile", fmt.Sprintf("role %s group %s extension hook failed", roleName, groupName), err)
+	// Node labels add an init container, a volume and mounts, which the Pod Security check must see.
+	if len(decl.NodeLabels) > 0 && resources.StatefulSet != nil {
+		mainName := decl.MainContainerName
+		if mainName == "" {
+			mainName = buildCtx.ResourceName
+		}
+		if err := injectNodeLabels(resources.StatefulSet, mainName); err != nil {
+			return nil, nil, nil, NewResourceBuildError("StatefulSet", roleName, groupName, "failed to inject node labels", err)
+		}
+	}
+
+	// The Pod Security check reads the StatefulSet the handler returned, which is the finished pod:
+	// podOverrides merged, sidecars injected. Strict mode fails here, before anything is applied.
+	if err := r.checkPodSecurity(cr, buildCtx, resources, findings.podSecurity); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return buildCtx, resources, listenerFallback, nil
+}
+
+// applyRoleGroup applies what buildRoleGroup built.
+func (r *GenericReconciler[CR]) applyRoleGroup(
+	ctx context.Context,
+	cr CR,
+	buildCtx *RoleGroupBuildContext,
+	resources *RoleGroupResources,
+	listenerFallback *listenerFallbackPlan,
+	findings *passFindings,
+) error {
+	// Apply resources in dependency order
+	if err := r.applyResources(ctx, cr, resources, buildCtx, findings); err != nil {
+		return err
+	}
+	if r.listenerFallback {
+		if err := r.reconcileListenerFallback(ctx, cr, buildCtx, listenerFallback); err != nil {
+			return err
+		}
+	}
+	if len(buildCtx.Declaration.NodeLabels) > 0 && resources.StatefulSet != nil {
+		pending, err := r.publishNodeLabels(ctx, buildCtx, resources.StatefulSet)
+		if err != nil {
+			return err
+		}
+		findings.nodeLabelsPending = findings.nodeLabelsPending || pending
 	}
 
-	logger.V(1).Info("Role group reconciled", "role", roleName, "group", groupName)
 	return nil
 }
 
@@ -1156,8 +1495,21 @@ func RoleGroupMarkerLabelKey(clusterName, roleName, roleGroupName string) string
 
 // buildRoleGroupContext creates the build context for a role group.
 func (r *GenericReconciler[CR]) buildRoleGroupContext(ctx context.Context, cr CR, roleName string, roleSpec *v1alpha1.RoleSpec, groupName string, groupSpec *v1alpha1.RoleGroupSpec, decl RoleDeclaration) (*RoleGroupBuildContext, error) {
-	// Stage 1 — FOLD the framework-owned half of the config block, ONCE, over three layers: the
-	// product's declared defaults, the CR's role level, its role group level.
+	// Resolve the image ONCE, here, so the primary container, the sidecars, the pod's
+	// imagePullSecrets and the app.kubernetes.io/version label all read the same answer. Deriving
+	// them independently is how the pull secret got silently dropped for ten product CRDs. It comes
+	// first because the resolved product version selects the version's config defaults below.
+	resolvedImage, err := resolveImage(cr.GetSpec(), decl, r.imageResolution)
+	if err != nil {
+		return nil, NewValidationError("image", roleName, groupName, err)
+	}
+	if err := r.checkProductVersion(cr, roleName, groupName, resolvedImage.ProductVersion); err != nil {
+		return nil, NewValidationError("image", roleName, groupName, err)
+	}
+
+	// Stage 1 — FOLD the framework-owned half of the config block, ONCE, over four layers: the
+	// product's defaults for the resolved version, its declared defaults, the CR's role level, its
+	// role group level.
 	//
 	// It used to be computed twice and disagree with itself — the reconciler folded role+group into
 	// the field products read, while the handler folded defaults+role+group into a local it threw
@@ -1170,6 +1522,7 @@ func (r *GenericReconciler[CR]) buildRoleGroupContext(ctx context.Context, cr CR
 	//
 	// The fold works on copies; the CR's spec objects are never mutated.
 	foldedConfig, replacedAffinity, err := FoldCommonConfig(
+		r.imageResolution.Versions.ConfigDefaults(resolvedImage.ProductVersion, roleName),
 		decl.ConfigDefaults, roleSpec.GetConfig(), groupSpec.GetConfig())
 	if err != nil {
 		return nil, NewValidationError("config", roleName, groupName, err)
@@ -1178,18 +1531,11 @@ func (r *GenericReconciler[CR]) buildRoleGroupContext(ctx context.Context, cr CR
 	mergedGroupSpec := groupSpec.DeepCopy()
 	mergedGroupSpec.Config = foldedConfig
 
-	// Resolve the image ONCE, here, so the primary container, the sidecars, the pod's
-	// imagePullSecrets and the app.kubernetes.io/version label all read the same answer. Deriving
-	// them independently is how the pull secret got silently dropped for ten product CRDs.
-	resolvedImage, err := resolveImage(cr.GetSpec(), decl, r.imageResolution)
-	if err != nil {
-		return nil, NewValidationError("image", roleName, groupName, err)
-	}
-
 	buildCtx := &RoleGroupBuildContext{
 		Declaration:      decl,
 		ResolvedImage:    resolvedImage,
 		ProductName:      r.imageResolution.ProductName,
+		imageResolution:  &r.imageResolution,
 		ClusterName:      cr.GetName(),
 		ClusterNamespace: cr.GetNamespace(),
 		ClusterLabels:    handlerWritableLabels(ctx, cr.GetLabels()),
@@ -1203,6 +1549,19 @@ func (r *GenericReconciler[CR]) buildRoleGroupContext(ctx context.Context, cr CR
 		// the SA the reconciler creates. Derived from the CR (kind + name), never configured and
 		// never empty — this is the consumption half of the identity settled at step 0.
 		ServiceAccountName: r.resolveServiceAccountName(cr),
+		// spec.tls resolves from the cluster spec and the role's Services alone, so it is settled
+		// here with the image, ahead of the resolver that writes the paths into config files.
+		TLS: resolveRoleGroupTLS(cr.GetSpec(), RoleGroupResourceName(cr.GetName(), roleName, groupName), decl),
+	}
+
+	// The named data volumes resolve from the folded config alone. Settled here, ahead of the
+	// resolver, because their mount paths are what a product writes into its data-directory setting.
+	if res := foldedConfig.Resources; res != nil {
+		paths, err := resolveDataVolumeMountPaths(res.Volumes, decl.DataVolume)
+		if err != nil {
+			return nil, NewValidationError("resources.volumes", roleName, groupName, err)
+		}
+		buildCtx.DataVolumeMountPaths = paths
 	}
 
 	// Stage 1b — RESOLVE THE LOG PIPELINE, before anything derives from it.
@@ -1216,6 +1575,9 @@ func (r *GenericReconciler[CR]) buildRoleGroupContext(ctx context.Context, cr CR
 	// config file does so in the resolver below, and it must see the resolved answer rather than a
 	// nil it would silently read as "console only" — which would emit a config with no file
 	// appender on every pass, leaving Vector to collect nothing while every signal stayed green.
+	if err := r.resolveVectorSinks(ctx, cr, buildCtx); err != nil {
+		return nil, fmt.Errorf("resolving the vector sinks for role %s group %s: %w", roleName, groupName, err)
+	}
 	if err := r.resolveVectorAggregatorAddress(ctx, cr, buildCtx); err != nil {
 		return nil, fmt.Errorf("resolving the vector aggregator address for role %s group %s: %w",
 			roleName, groupName, err)
@@ -1262,13 +1624,43 @@ func (r *GenericReconciler[CR]) buildRoleGroupContext(ctx context.Context, cr CR
 
 // affinityLayerNames names FoldCommonConfig's layers in the words a CR author uses. The fold
 // reports an index because it does not know who supplied a layer; the reconciler always passes the
-// same three, in this order.
+// same four, in this order.
 var affinityLayerNames = [...]string{
+	"the product's defaults for this version",
 	"the product's role defaults",
 	"the role's config",
 	"the role group's config",
 }
 
+// checkProductVersion judges a role group's resolved product version against the version catalog:
+// the version must be supported, and moving to it from the version recorded in
+// status.productVersions must be an upgrade the catalog allows. A deprecated version runs, with a
+// Warning event.
+//
+// The webhook refuses the same moves at admission, but only for versions the CR states. A version
+// taken from ImageResolution.Defaults changes when the OPERATOR is upgraded, with no admission
+// request at all, so this is the check that stops an operator release from walking every cluster
+// it manages across a major version the product cannot skip. The refused role group keeps running
+// its current version until the CR pins one the catalog allows from there.
+func (r *GenericReconciler[CR]) checkProductVersion(cr CR, roleName, groupName, version string) error {
+	catalog := r.imageResolution.Versions
+	if catalog == nil || version == "" {
+		return nil
+	}
+	warning, err := catalog.Check(version)
+	if err != nil {
+		return err
+	}
+	if err := catalog.CheckUpgrade(cr.GetStatus().GetProductVersion(roleName, groupName), version); err != nil {
+		return err
+	}
+	if warning != "" {
+		r.eventManager.EmitWarningEvent(cr, "DeprecatedProductVersion",
+			fmt.Sprintf("role %q group %q: %s", roleName, groupName, warning))
+	}
+	return nil
+}
+
 // reportAffinityReplacements turns the fold's record of a wholesale affinity replacement into a
 // Warning event on the CR.
 //
@@ -1280,6 +1672,10 @@ var affinityLayerNames = [...]string{
 // reports it: the CR still says what the user wrote, the pod spec is valid, and every status
 // condition stays green while the quorum quietly stops being spread.
 //
+// `config.placement` is replaced the same way and reported the same way, as PlacementOverridden: a
+// user adding a toleration for a dedicated node pool discards the zone spread a product declared
+// beneath it, and that is just as invisible.
+//
 // The message is assembled from a fixed member order and a fixed layer name, so it is byte-stable
 // across passes — an unstable message would defeat the no-op guard in updateStatus. Emitting it on
 // every pass is deliberate and matches ImmutableFieldIgnored: the API server aggregates repeats of
@@ -1291,11 +1687,21 @@ func (r *GenericReconciler[CR]) reportAffinityReplacements(
 		if replacement.Layer >= 0 && replacement.Layer < len(affinityLayerNames) {
 			layer = affinityLayerNames[replacement.Layer]
 		}
-		r.eventManager.EmitWarningEvent(cr, "AffinityOverridden", fmt.Sprintf(
-			"role %q group %q: %s replaces config.affinity wholesale, discarding the %s declared "+
-				"beneath it. config.affinity follows the Kubernetes rule and is not merged per "+
-				"member; restate the discarded member alongside your own to keep it",
-			roleName, groupName, layer, strings.Join(replacement.Dropped, " and ")))
+		dropped := strings.Join(replacement.Dropped, " and ")
+		switch replacement.Field {
+		case ReplacedPlacement:
+			r.eventManager.EmitWarningEvent(cr, "PlacementOverridden", fmt.Sprintf(
+				"role %q group %q: %s replaces config.placement wholesale, discarding the %s declared "+
+					"beneath it. config.placement is not merged per member, like config.affinity; "+
+					"restate the discarded member alongside your own to keep it",
+				roleName, groupName, layer, dropped))
+		case ReplacedAffinity:
+			r.eventManager.EmitWarningEvent(cr, "AffinityOverridden", fmt.Sprintf(
+				"role %q group %q: %s replaces config.affinity wholesale, discarding the %s declared "+
+					"beneath it. config.affinity follows the Kubernetes rule and is not merged per "+
+					"member; restate the discarded member alongside your own to keep it",
+				roleName, groupName, layer, dropped))
+		}
 	}
 }
 
@@ -1307,12 +1713,14 @@ func (r *GenericReconciler[CR]) reportAffinityReplacements(
 // success. A role the product declares and this CR does not use is a warning event, because a
 // product may legitimately support more roles than a given cluster deploys.
 func (r *GenericReconciler[CR]) declareRoles(
-	ctx context.Context, cr CR, spec *v1alpha1.GenericClusterSpec) (RoleCatalog, error) {
+	ctx context.Context, cr CR, spec *v1alpha1.GenericClusterSpec) (catalog RoleCatalog, err error) {
 	if r.roleProvider == nil {
 		return RoleCatalog{}, nil
 	}
+	ctx, span := startSpan(ctx, "declareRoles")
+	defer func() { endSpan(span, err) }()
 
-	catalog, err := r.roleProvider.DeclareRoles(ctx, r.client, cr)
+	catalog, err = r.roleProvider.DeclareRoles(ctx, r.client, cr)
 	if err != nil {
 		return nil, err
 	}
@@ -1331,6 +1739,16 @@ func (r *GenericReconciler[CR]) declareRoles(
 			declErrs = append(declErrs, NewValidationError("RoleProvider", roleName, "", err))
 		}
 	}
+	declErrs = append(declErrs, validateNetworkPolicyPeers(catalog)...)
+	if !r.networkPolicies {
+		for _, roleName := range slices.Sorted(maps.Keys(catalog)) {
+			if catalog[roleName].NetworkPolicy != nil {
+				declErrs = append(declErrs, NewValidationError("RoleProvider", roleName, "",
+					fmt.Errorf("the role declares a networkPolicy but GenericReconcilerConfig.NetworkPolicies "+
+						"is off, so no policy would be applied and the pods would run unprotected")))
+			}
+		}
+	}
 	if len(declErrs) > 0 {
 		return nil, stderrors.Join(declErrs...)
 	}
@@ -1402,10 +1820,11 @@ func (r *GenericReconciler[CR]) buildSidecarManager(ctx context.Context, cr CR,
 	// validation on every cycle and abort the whole cluster's reconcile over a product that is
 	// simply not wired for Vector — so this is reported as the product-configuration mistake it
 	// is, and the rest of the cluster keeps converging.
-	if !r.vectorConfigIsProvided(cr, buildCtx.Declaration) {
+	if !r.vectorConfigIsProvided(cr, buildCtx) {
 		message := fmt.Sprintf(
-			"role %s group %s enables the vector agent, but nothing supplies vector.yaml: the cluster resource does not "+
-				"implement VectorAggregatorProvider, and the role's declaration does not set OwnsVectorConfig. "+
+			"role %s group %s enables the vector agent, but nothing supplies vector.yaml: the cluster resource neither "+
+				"implements VectorAggregatorProvider nor returns sinks from VectorSinkProvider, and the role's declaration "+
+				"does not set OwnsVectorConfig. "+
 				"No vector.yaml would be generated, so the sidecar is skipped",
 			buildCtx.RoleName, buildCtx.RoleGroupName)
 		log.FromContext(ctx).Info("Skipping vector sidecar: no source for vector.yaml",
@@ -1421,6 +1840,7 @@ func (r *GenericReconciler[CR]) buildSidecarManager(ctx context.Context, cr CR,
 	opts := []vector.ProviderOption{
 		vector.WithConfigMapName(buildCtx.ResourceName),
 		vector.WithProducers(producers),
+		vector.WithSinks(buildCtx.VectorSinks),
 	}
 	if logVolumeSize != "" {
 		// Already validated in RoleDeclaration.Validate, once per pass; this branch cannot be
@@ -1440,14 +1860,41 @@ func (r *GenericReconciler[CR]) buildSidecarManager(ctx context.Context, cr CR,
 
 // vectorConfigIsProvided reports whether anything will write vector.yaml into the role group
 // ConfigMap: the framework generates it for a CR exposing an aggregator ConfigMap
-// (VectorAggregatorProvider, see resolveVectorAggregatorAddress), and a product that builds the
-// file itself says so through OwnsVectorConfig on its role declaration. It gates Vector sidecar
-// registration, so the two sides of the contract cannot drift apart.
-func (r *GenericReconciler[CR]) vectorConfigIsProvided(cr CR, decl RoleDeclaration) bool {
+// (VectorAggregatorProvider, see resolveVectorAggregatorAddress) or returning sinks
+// (VectorSinkProvider, see resolveVectorSinks), and a product that builds the file itself says so
+// through OwnsVectorConfig on its role declaration. It gates Vector sidecar registration, so the
+// two sides of the contract cannot drift apart.
+func (r *GenericReconciler[CR]) vectorConfigIsProvided(cr CR, buildCtx *RoleGroupBuildContext) bool {
 	if _, ok := any(cr).(VectorAggregatorProvider); ok {
 		return true
 	}
-	return decl.OwnsVectorConfig
+	return len(buildCtx.VectorSinks) > 0 || buildCtx.Declaration.OwnsVectorConfig
+}
+
+// resolveVectorSinks asks a CR implementing VectorSinkProvider for its sinks and stores them on
+// buildCtx, under the same gate as resolveVectorAggregatorAddress (which runs after it and reads
+// the result). The sinks are validated here, by rendering them, so a malformed sink fails the
+// role group with a ValidationError naming it instead of a vector.yaml the agent rejects at
+// startup.
+func (r *GenericReconciler[CR]) resolveVectorSinks(ctx context.Context, cr CR, buildCtx *RoleGroupBuildContext) error {
+	if !vectorEnabledFor(buildCtx) || len(buildCtx.Declaration.LogProducers) == 0 {
+		return nil
+	}
+	provider, ok := any(cr).(VectorSinkProvider)
+	if !ok {
+		return nil
+	}
+	sinks, err := provider.VectorSinks(ctx, r.client)
+	if err != nil {
+		return err
+	}
+	if len(sinks) > 0 {
+		if _, err := vector.RenderVectorConfig(vector.VectorConfigData{LogDir: constant.KubedoopLogDir, Sinks: sinks}); err != nil {
+			return NewValidationError("logging", buildCtx.RoleName, buildCtx.RoleGroupName, err)
+		}
+	}
+	buildCtx.VectorSinks = sinks
+	return nil
 }
 
 // resolveVectorAggregatorAddress resolves the Vector aggregator discovery address for a role group
@@ -1466,6 +1913,10 @@ func (r *GenericReconciler[CR]) resolveVectorAggregatorAddress(ctx context.Conte
 		return nil
 	}
 	name := provider.VectorAggregatorConfigMapName()
+	if name == "" && len(buildCtx.VectorSinks) > 0 {
+		// The logs go to the sinks; there is no aggregator to discover.
+		return nil
+	}
 	if name == "" {
 		return fmt.Errorf("vector agent is enabled but vectorAggregatorConfigMapName is not configured (role %q, group %q)",
 			buildCtx.RoleName, buildCtx.RoleGroupName)
@@ -1479,12 +1930,12 @@ func (r *GenericReconciler[CR]) resolveVectorAggregatorAddress(ctx context.Conte
 }
 
 // applyResources applies all resources in the correct dependency order.
-// Order: ConfigMap -> Headless Service -> Service -> ExtraResources -> StatefulSet -> PDB -> MetricsService
+// Order: ConfigMap -> LogConfigMap -> Headless Service -> Service -> NetworkPolicy -> ExtraResources -> StatefulSet -> PDB -> MetricsService -> ServiceMonitor
 // ExtraResources are applied before the StatefulSet because they are typically prerequisites
 // for pod scheduling (e.g. a Listener CR referenced by an ephemeral CSI volume).
 // Each resource is created when absent and updated to the handler-built desired state when it
 // already exists (see applyResource / copyDesiredState for the exact update semantics).
-func (r *GenericReconciler[CR]) applyResources(ctx context.Context, cr CR, resources *RoleGroupResources, buildCtx *RoleGroupBuildContext) error {
+func (r *GenericReconciler[CR]) applyResources(ctx context.Context, cr CR, resources *RoleGroupResources, buildCtx *RoleGroupBuildContext, findings *passFindings) error {
 
 	// 0. Reject a declaration the lifecycle cannot honour, BEFORE anything is applied — a role
 	// group that half-converged and then failed is worse than one that did not start.
@@ -1499,6 +1950,17 @@ func (r *GenericReconciler[CR]) applyResources(ctx context.Context, cr CR, resou
 		}
 	}
 
+	// 1b. Apply the logging ConfigMap before the StatefulSet that mounts it. Its reclaim, when the
+	// role stops reloading its logging config, waits until after the StatefulSet (step 5b): the
+	// pods running the previous template still mount it.
+	logConfigName := logConfigMapName(buildCtx.ResourceName)
+	if resources.LogConfigMap != nil {
+		markLogConfigMap(resources.LogConfigMap)
+		if err := r.applyResource(ctx, cr, resources.LogConfigMap); err != nil {
+			return NewResourceApplyError("ConfigMap", buildCtx.ClusterNamespace, logConfigName, "failed to apply logging config", err)
+		}
+	}
+
 	// 2. Apply Headless Service
 	if resources.HeadlessService != nil {
 		if err := r.applyResource(ctx, cr, resources.HeadlessService); err != nil {
@@ -1513,6 +1975,27 @@ func (r *GenericReconciler[CR]) applyResources(ctx context.Context, cr CR, resou
 		}
 	}
 
+	// 3b. Apply the NetworkPolicy, or reclaim it when the role stops declaring one. Before the
+	// StatefulSet, so that in a default-deny namespace the allowances exist by the time the first
+	// pod starts dialling its peers.
+	if r.networkPolicies {
+		if resources.NetworkPolicy != nil {
+			if resources.NetworkPolicy.Labels == nil {
+				resources.NetworkPolicy.Labels = map[string]string{}
+			}
+			resources.NetworkPolicy.Labels[LabelNetworkPolicy] = buildCtx.RoleGroupName
+			if err := r.applyResource(ctx, cr, resources.NetworkPolicy); err != nil {
+				return NewResourceApplyError("NetworkPolicy", buildCtx.ClusterNamespace, buildCtx.ResourceName, "failed to apply", err)
+			}
+		} else if err := r.reclaimNetworkPolicy(ctx, buildCtx, cr.GetUID()); err != nil {
+			return NewResourceApplyError("NetworkPolicy", buildCtx.ClusterNamespace, buildCtx.ResourceName, "failed to delete undeclared network policy", err)
+		}
+	} else if resources.NetworkPolicy != nil {
+		return NewValidationError("RoleGroupResources.NetworkPolicy", buildCtx.RoleName, buildCtx.RoleGroupName,
+			fmt.Errorf("a NetworkPolicy was built but GenericReconcilerConfig.NetworkPolicies is off, "+
+				"so it could be neither watched nor reclaimed"))
+	}
+
 	// 4. Apply extra product resources BEFORE the StatefulSet: extras are typically
 	// prerequisites for pod scheduling (e.g. a Listener CR that pods reference through an
 	// ephemeral CSI volume — without it the pods hang in ContainerCreating). They go through
@@ -1536,6 +2019,15 @@ func (r *GenericReconciler[CR]) applyResources(ctx context.Context, cr CR, resou
 		return err
 	}
 
+	// 4c. Grow the existing data PVCs when the only change to the claim templates is a larger
+	// capacity. Before step 5, because the templates only line up by recreating the StatefulSet,
+	// and that wait has to keep step 5 from updating the object being deleted.
+	if r.volumeExpansion && resources.StatefulSet != nil {
+		if err := r.expandStatefulSetClaims(ctx, cr, resources.StatefulSet, buildCtx, findings.volumeExpansion); err != nil {
+			return err
+		}
+	}
+
 	// 5. Apply StatefulSet
 	if resources.StatefulSet != nil {
 		if err := r.applyResource(ctx, cr, resources.StatefulSet); err != nil {
@@ -1543,6 +2035,15 @@ func (r *GenericReconciler[CR]) applyResources(ctx context.Context, cr CR, resou
 		}
 	}
 
+	// 5b. Reclaim the logging ConfigMap of a role group that no longer reloads its logging config.
+	// Only after the new template is applied: until then a pod recreated from the old template
+	// still mounts it, and would sit in ContainerCreating with the volume missing.
+	if resources.LogConfigMap == nil {
+		if err := r.reclaimLogConfigMap(ctx, buildCtx.ClusterNamespace, logConfigName, cr.GetUID(), buildCtx.ClusterName); err != nil {
+			return NewResourceApplyError("ConfigMap", buildCtx.ClusterNamespace, logConfigName, "failed to delete unused logging config", err)
+		}
+	}
+
 	// 6. Apply the custom per-group PodDisruptionBudget (escape hatch), or reclaim the legacy
 	// per-role-group PDB. The framework's own PDB is now role-level (reconcileRolePodDisruptionBudget);
 	// a product may still ship a custom per-group PDB via RoleGroupResources.PodDisruptionBudget.
@@ -1577,6 +2078,17 @@ func (r *GenericReconciler[CR]) applyResources(ctx context.Context, cr CR, resou
 		return NewResourceApplyError("Service", buildCtx.ClusterNamespace, metricsName, "failed to delete disabled metrics service", err)
 	}
 
+	// 7b. The ServiceMonitor follows the metrics Service it scrapes, into existence and out of it.
+	if r.serviceMonitors != nil {
+		if err := r.applyServiceMonitor(ctx, cr, resources, buildCtx); err != nil {
+			return err
+		}
+	} else if resources.ServiceMonitor != nil {
+		return NewValidationError("RoleGroupResources.ServiceMonitor", buildCtx.RoleName, buildCtx.RoleGroupName,
+			fmt.Errorf("a ServiceMonitor was built but GenericReconcilerConfig.ServiceMonitors is nil, "+
+				"so it would be applied but never watched or reclaimed; set the config or leave the slot nil"))
+	}
+
 	return nil
 }
 
@@ -1599,7 +2111,7 @@ func (r *GenericReconciler[CR]) apiError(err error) error {
 
 // validateRoleGroupResources rejects a RoleGroupResources the lifecycle cannot honour.
 //
-// The six fixed slots are addressed BY DERIVED NAME on every path that removes them: the in-spec
+// The fixed slots are addressed BY DERIVED NAME on every path that removes them: the in-spec
 // reclaims here in applyResources, and the orphan teardown in RoleGroupCleaner when the role group
 // leaves the spec. Nothing recovers a slot filled under a different name — discoverLiveOrphans
 // rejects any object whose name is not what RoleGroupResourceName produces, and confirmRoleGroupReclaimed
@@ -1629,11 +2141,14 @@ func validateRoleGroupResources(scheme *runtime.Scheme, resources *RoleGroupReso
 		obj   client.Object
 	}{
 		{"ConfigMap", buildCtx.ResourceName, objectOrNil(resources.ConfigMap)},
+		{"LogConfigMap", logConfigMapName(buildCtx.ResourceName), objectOrNil(resources.LogConfigMap)},
 		{"HeadlessService", buildCtx.ResourceName + "-headless", objectOrNil(resources.HeadlessService)},
 		{"Service", buildCtx.ResourceName, objectOrNil(resources.Service)},
 		{"StatefulSet", buildCtx.ResourceName, objectOrNil(resources.StatefulSet)},
 		{"PodDisruptionBudget", buildCtx.ResourceName, objectOrNil(resources.PodDisruptionBudget)},
 		{"MetricsService", buildCtx.ResourceName + "-metrics", objectOrNil(resources.MetricsService)},
+		{"NetworkPolicy", buildCtx.ResourceName, objectOrNil(resources.NetworkPolicy)},
+		{"ServiceMonitor", buildCtx.ResourceName + "-metrics", objectOrNil(resources.ServiceMonitor)},
 	}
 
 	for _, slot := range slots {
@@ -1792,6 +2307,20 @@ func markMetricsService(svc *corev1.Service) {
 	svc.Labels[LabelMetricsService] = valueTrue
 }
 
+// LabelLogConfigMap marks a ConfigMap as the framework's per-role-group logging config slot
+// (RoleGroupResources.LogConfigMap). Only ConfigMaps carrying it are reclaimed when a role group
+// stops reloading its logging config: "<resource>-log-config" is also the ConfigMap name of a role
+// group called "<group>-log-config".
+const LabelLogConfigMap = "logging." + constant.KubedoopDomain + "/config"
+
+// markLogConfigMap stamps the logging config slot label on a handler-built logging ConfigMap.
+func markLogConfigMap(cm *corev1.ConfigMap) {
+	if cm.Labels == nil {
+		cm.Labels = map[string]string{}
+	}
+	cm.Labels[LabelLogConfigMap] = valueTrue
+}
+
 // markRolePodDisruptionBudget stamps the role slot label, carrying the role the PDB covers, on a
 // handler-built role PodDisruptionBudget. See LabelRolePodDisruptionBudget and the cleaner's
 // cleanupOrphanedRolePDBs for why the role name has to travel on the object.
@@ -1889,6 +2418,24 @@ func (r *GenericReconciler[CR]) reclaimMetricsService(ctx context.Context, names
 	return r.cleaner.deleteService(ctx, namespace, name, ownerUID, clusterName)
 }
 
+// reclaimLogConfigMap deletes the role group's logging ConfigMap, but only when the live object is
+// the one this framework applied as that slot — see reclaimMetricsService for why the derived name
+// alone does not decide it.
+func (r *GenericReconciler[CR]) reclaimLogConfigMap(ctx context.Context, namespace, name string, ownerUID types.UID, clusterName string) error {
+	cm := &corev1.ConfigMap{}
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, cm); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return r.apiError(err)
+	}
+	if cm.Labels[LabelLogConfigMap] != valueTrue {
+		return nil
+	}
+	_, err := deleteOwned[corev1.ConfigMap](ctx, r.cleaner, namespace, name, ownerUID, clusterName)
+	return err
+}
+
 // validateSidecars runs the registered sidecar providers' dependency checks for a role group.
 // The manager only validates once a client and namespace are wired (both are no-ops otherwise),
 // which is why the seam is closed here rather than at construction: the namespace is per CR.
@@ -1932,7 +2479,11 @@ func (r *GenericReconciler[CR]) resourceKind(obj client.Object) string {
 // reconcile may still issue an Update whose server-side result is identical to the stored
 // object; the API server short-circuits such writes (no resourceVersion bump, no watch
 // event), so this cannot cause a reconcile loop.
-func (r *GenericReconciler[CR]) applyResource(ctx context.Context, owner client.Object, obj client.Object) error {
+func (r *GenericReconciler[CR]) applyResource(ctx context.Context, owner client.Object, obj client.Object) (err error) {
+	kind := r.resourceKind(obj)
+	ctx, span := startSpan(ctx, "applyResource", attrResourceKind.String(kind), attrResourceName.String(obj.GetName()))
+	defer func() { endSpan(span, err) }()
+
 	// Capture the desired state before CreateOrUpdate clobbers obj with live state on Get.
 	desired, ok := obj.DeepCopyObject().(client.Object)
 	if !ok {
@@ -1972,13 +2523,26 @@ func (r *GenericReconciler[CR]) applyResource(ctx context.Context, owner client.
 	if len(ignoredImmutable) > 0 {
 		r.eventManager.EmitWarningEvent(owner, "ImmutableFieldIgnored", fmt.Sprintf(
 			"%s %q: %s cannot be changed after creation, so the live value is kept and the spec has no effect. Recreate the resource to apply it.",
-			r.resourceKind(obj), obj.GetName(), strings.Join(ignoredImmutable, ", ")))
+			kind, obj.GetName(), strings.Join(ignoredImmutable, ", ")))
+		ImmutableFieldsIgnored.WithLabelValues(owner.GetNamespace(), owner.GetName(), kind).Inc()
 	}
 
 	if err != nil {
 		return r.apiError(err)
 	}
 
+	// Counted by what actually happened, with the same resourceVersion test as the Updated event
+	// below: an Update the API server short-circuited changed nothing.
+	applied := applyResultUnchanged
+	switch {
+	case result == controllerutil.OperationResultCreated:
+		applied = applyResultCreated
+	case result == controllerutil.OperationResultUpdated && obj.GetResourceVersion() != liveResourceVersion:
+		applied = applyResultUpdated
+	}
+	ResourceApplies.WithLabelValues(owner.GetNamespace(), owner.GetName(), kind, applied).Inc()
+	span.SetAttributes(attrApplyResult.String(applied))
+
 	switch result {
 	case controllerutil.OperationResultCreated:
 		r.eventManager.EmitCreateEvent(owner.GetName(), obj)
@@ -2117,6 +2681,29 @@ func (r *GenericReconciler[CR]) ControllerBuilder(mgr ctrl.Manager, opts SetupWi
 	if r.workloadRBACRules != nil {
 		b = b.Owns(&rbacv1.Role{}).Owns(&rbacv1.RoleBinding{})
 	}
+	// Same rule for NetworkPolicies, which is the only thing that ever reads or writes them.
+	if r.networkPolicies {
+		b = b.Owns(&networkingv1.NetworkPolicy{})
+	}
+	// And for Listeners, which are read only to publish discovery. They are Watches rather than
+	// Owns: the listener-operator creates most of them, so the CR is rarely their controller.
+	// The listener fallback controls the Listeners it creates, so it owns them outright; Owns
+	// already maps them to the cluster and the discovery Watches below would only add a duplicate.
+	if r.listenerFallback {
+		b = b.Owns(&listenersv1alpha1.Listener{})
+	} else if r.discovery != nil && r.discovery.WatchListeners {
+		b = b.Watches(&listenersv1alpha1.Listener{}, handler.EnqueueRequestsFromMapFunc(r.mapListenerToCluster))
+	}
+
+	// And for ServiceMonitors, whose kind may not exist at all: an informer for a kind the API
+	// server does not serve never syncs, so the watch is only registered when the CRD is there.
+	if r.serviceMonitors != nil {
+		if installed, err := serviceMonitorsInstalled(mgr.GetRESTMapper()); err == nil && installed {
+			sm := &unstructured.Unstructured{}
+			sm.SetGroupVersionKind(opbuilder.ServiceMonitorGVK)
+			b = b.Owns(sm)
+		}
+	}
 
 	for _, obj := range opts.ExtraOwns {
 		if obj == nil {
@@ -1239,7 +1239,7 @@ var _ = Describe("GenericReconciler role PodDisruptionBudget lifecycle", func()
 				RoleConfig: &v1alpha1.RoleConfigSpec{
 					PodDisruptionBudget: &v1alpha1.PodDisruptionBudgetSpec{
 						Enabled:        ptr.To(true),
-						MaxUnavailable: ptr.To(int32(1)),
+						MaxUnavailable: ptr.To(intstr.FromInt32(1)),
 					},
 				},
 				RoleGroups: map[string]v1alpha1.RoleGroupSpec{"default": {Replicas: ptr.To(int32(1))}},
@@ -2574,7 +2574,7 @@ var _ = Describe("GenericReconciler role PodDisruptionBudget", func() {
 		r, err = reconciler.NewGenericReconciler(cfg)
 		Expect(err).NotTo(HaveOccurred())
 
-		maxUnavailable := int32(2)
+		maxUnavailable := intstr.FromInt32(2)
 		mockCR = testutil.NewMockCluster(crName, namespace).WithRoles(map[string]v1alpha1.RoleSpec{
 			"server": {
 				RoleConfig: &v1alpha1.RoleConfigSpec{
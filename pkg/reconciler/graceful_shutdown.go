@@ -0,0 +1,123 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+)
+
+// DefaultShutdownExitReserve is the part of the grace period an exec ShutdownAction leaves for the
+// process itself: the kubelet sends SIGTERM only once the preStop hook returns, and SIGKILL when the
+// grace period ends, so an action allowed the whole period would leave the product no time to
+// exit cleanly. A grace period shorter than twice this reserves half of it instead.
+const DefaultShutdownExitReserve = 5 * time.Second
+
+// GracefulShutdownPeriod parses a role group's effective gracefulShutdownTimeout
+// (v1alpha1.DefaultGracefulShutdownTimeout when unset) into the pod's termination grace period. A
+// positive sub-second duration rounds up to a whole second, so it never truncates to 0 — which
+// Kubernetes reads as an immediate SIGKILL. An unparsable or non-positive duration is an error
+// rather than a silent fallback.
+//
+// It is exported for handlers that build their own StatefulSet, so their pods get the same grace
+// period — and their ShutdownAction the same bound — as the base handler's.
+func GracefulShutdownPeriod(config *v1alpha1.RoleGroupConfigSpec) (time.Duration, error) {
+	timeout := config.GetGracefulShutdownTimeout()
+	d, err := time.ParseDuration(timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid gracefulShutdownTimeout %q: %w", timeout, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid gracefulShutdownTimeout %q: must be a positive duration", timeout)
+	}
+	return (d + time.Second - 1) / time.Second * time.Second, nil
+}
+
+// ShutdownAction is what a role's primary container does before it is stopped — decommission a
+// DataNode, hand a broker's partition leadership over, flush a journal. The framework runs it as
+// the container's preStop hook, inside the grace period gracefulShutdownTimeout sets, so the user's
+// one setting bounds both the action and the exit that follows it.
+//
+// Exactly one of Exec and HTTPGet must be set.
+type ShutdownAction struct {
+	// Exec runs a command in the primary container. It is stopped with SIGTERM once the grace
+	// period minus its exit reserve (DefaultShutdownExitReserve) has elapsed, so the product
+	// still gets that reserve to exit after an action that hangs. The wrapper needs /bin/sh.
+	Exec []string
+
+	// HTTPGet calls an endpoint of the primary container. The kubelet gives an HTTP hook no
+	// timeout of its own short of the grace period, and the framework cannot add one without a
+	// client in the image, so the endpoint should return within the grace period minus the time
+	// the product needs to exit.
+	HTTPGet *corev1.HTTPGetAction
+}
+
+// validate reports what is wrong with the action, for RoleDeclaration.Validate.
+func (a *ShutdownAction) validate(lifecycle *corev1.Lifecycle) []string {
+	if a == nil {
+		return nil
+	}
+	var problems []string
+	switch {
+	case len(a.Exec) == 0 && a.HTTPGet == nil:
+		problems = append(problems, "shutdown declares neither exec nor httpGet")
+	case len(a.Exec) > 0 && a.HTTPGet != nil:
+		problems = append(problems, "shutdown declares both exec and httpGet; a preStop hook runs one")
+	}
+	if lifecycle != nil && lifecycle.PreStop != nil {
+		problems = append(problems,
+			"shutdown and lifecycle.preStop are both declared; the shutdown action IS the preStop hook")
+	}
+	return problems
+}
+
+// PreStopHandler renders the action as the preStop hook of a container whose grace period is
+// grace. An Exec action is wrapped in a shell watchdog that stops it once grace minus the exit
+// reserve has elapsed; a non-positive grace leaves it unbounded, as the kubelet would.
+func (a *ShutdownAction) PreStopHandler(grace time.Duration) *corev1.LifecycleHandler {
+	if a.HTTPGet != nil {
+		return &corev1.LifecycleHandler{HTTPGet: a.HTTPGet.DeepCopy()}
+	}
+	seconds := int64(grace / time.Second)
+	if seconds <= 0 {
+		return &corev1.LifecycleHandler{Exec: &corev1.ExecAction{Command: append([]string(nil), a.Exec...)}}
+	}
+	reserve := int64(DefaultShutdownExitReserve / time.Second)
+	if reserve > seconds/2 {
+		reserve = seconds / 2
+	}
+	budget := seconds - reserve
+	// "$@" is the declared command, passed as arguments rather than spliced into the script, so
+	// nothing in it is interpreted by the shell twice. The watchdog's output goes nowhere: its
+	// sleep outlives an action that finishes early, and holding the hook's output open would make
+	// the kubelet wait for it.
+	script := `"$@" &
+pid=$!
+( sleep ` + strconv.FormatInt(budget, 10) + `; kill -TERM "$pid" ) >/dev/null 2>&1 &
+watchdog=$!
+wait "$pid"
+status=$?
+kill "$watchdog" 2>/dev/null
+exit "$status"`
+	command := append([]string{"/bin/sh", "-c", script, "shutdown"}, a.Exec...)
+	return &corev1.LifecycleHandler{Exec: &corev1.ExecAction{Command: command}}
+}
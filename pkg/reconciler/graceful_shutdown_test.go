@@ -0,0 +1,80 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"os/exec"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/reconciler"
+)
+
+var _ = Describe("Graceful shutdown", func() {
+	Describe("GracefulShutdownPeriod", func() {
+		It("defaults, and rounds a sub-second remainder up rather than down to an immediate SIGKILL", func() {
+			Expect(reconciler.GracefulShutdownPeriod(nil)).To(Equal(30 * time.Second))
+			Expect(reconciler.GracefulShutdownPeriod(&v1alpha1.RoleGroupConfigSpec{
+				GracefulShutdownTimeout: ptr.To("1500ms"),
+			})).To(Equal(2 * time.Second))
+		})
+
+		It("refuses a value that is not a positive duration", func() {
+			for _, timeout := range []string{"soon", "0s", "-1m"} {
+				_, err := reconciler.GracefulShutdownPeriod(&v1alpha1.RoleGroupConfigSpec{GracefulShutdownTimeout: &timeout})
+				Expect(err).To(MatchError(ContainSubstring(timeout)))
+			}
+		})
+	})
+
+	Describe("ShutdownAction.PreStopHandler", func() {
+		It("passes an HTTP call through unchanged", func() {
+			get := &corev1.HTTPGetAction{Path: "/decommission", Port: intstr.FromString("http")}
+			handler := (&reconciler.ShutdownAction{HTTPGet: get}).PreStopHandler(time.Minute)
+			Expect(handler).To(Equal(&corev1.LifecycleHandler{HTTPGet: get}))
+		})
+
+		It("returns with the command's output and exit status as soon as it finishes", func() {
+			handler := (&reconciler.ShutdownAction{Exec: []string{"/bin/sh", "-c", "echo done; exit 3"}}).
+				PreStopHandler(time.Minute)
+			start := time.Now()
+			// Output waits for the output to close, as the kubelet's exec does, so a watchdog
+			// holding it would keep this waiting for the whole budget.
+			out, err := exec.Command(handler.Exec.Command[0], handler.Exec.Command[1:]...).Output()
+			Expect(time.Since(start)).To(BeNumerically("<", 10*time.Second))
+			Expect(string(out)).To(Equal("done\n"))
+			var exitErr *exec.ExitError
+			Expect(err).To(BeAssignableToTypeOf(exitErr))
+			Expect(err.(*exec.ExitError).ExitCode()).To(Equal(3))
+		})
+
+		It("stops a command that outlives the grace period minus the exit reserve", func() {
+			// Two seconds of grace keep one for the exit, so the action gets one.
+			handler := (&reconciler.ShutdownAction{Exec: []string{"sleep", "30"}}).PreStopHandler(2 * time.Second)
+			start := time.Now()
+			err := exec.Command(handler.Exec.Command[0], handler.Exec.Command[1:]...).Run()
+			Expect(err).To(HaveOccurred(), "the killed action reports failure")
+			Expect(time.Since(start)).To(BeNumerically("<", 10*time.Second))
+		})
+	})
+})
@@ -77,7 +77,10 @@ func (h *HealthManager) WithServiceHealthCheck(check common.ServiceHealthCheck)
 // that cannot be read, or a failing application health check. Those are state-based, not
 // time-based, so a stuck rollout still reports Degraded=True — the pods are visibly failing — while
 // a healthy rollout does not.
-func (h *HealthManager) Check(ctx context.Context, namespace, clusterName string, spec *v1alpha1.GenericClusterSpec, status *v1alpha1.GenericClusterStatus) error {
+func (h *HealthManager) Check(ctx context.Context, namespace, clusterName string, spec *v1alpha1.GenericClusterSpec, status *v1alpha1.GenericClusterStatus) (err error) {
+	ctx, span := startSpan(ctx, "HealthManager.Check", attrNamespace.String(namespace), attrCluster.String(clusterName))
+	defer func() { endSpan(span, err) }()
+
 	logger := log.FromContext(ctx)
 
 	paused := spec.ClusterOperation != nil && spec.ClusterOperation.ReconciliationPaused
@@ -0,0 +1,185 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+)
+
+// RegistryMirror rewrites every image reference that starts with Prefix to start with Replacement
+// instead: `quay.io/` to `registry.internal/quay.io/` sends every quay.io image of the cluster —
+// the product's, the Vector agent's, an oauth2-proxy's — to an air-gapped mirror.
+//
+// Prefix matches at a path boundary: `quay.io/kubedoop` matches `quay.io/kubedoop/kafka:3.7.1` but
+// not `quay.io/kubedoop-staging/kafka:3.7.1`. References are matched as written, so a Docker Hub
+// short name such as `nginx` is not matched by a `docker.io/` rule.
+type RegistryMirror struct {
+	Prefix      string
+	Replacement string
+}
+
+// ImageDigests maps an image reference, as the framework resolves it before any mirror rule, to the
+// digest it must run as — "sha256:" and 64 hex digits. A pinned reference keeps its tag for the
+// reader and gains the digest, which is what the kubelet pulls: `repo/kafka:3.7.1@sha256:…`.
+//
+// It is keyed by the upstream reference, not the mirrored one, because a digest names content and
+// survives mirroring: a product publishes one manifest, and every air-gapped install can use it.
+type ImageDigests map[string]string
+
+// imageDigestManifest is the file format LoadImageDigests reads.
+//
+//	images:
+//	  quay.io/kubedoop/kafka:3.7.1-kubedoop0.2.0: sha256:4f1e…
+type imageDigestManifest struct {
+	Images map[string]string `json:"images"`
+}
+
+// LoadImageDigests reads a product-shipped digest manifest from fsys — an embed.FS compiled into
+// the operator, or os.DirFS for a manifest mounted from a ConfigMap. Every entry is checked here, at
+// startup: a malformed digest would otherwise surface as an image pull failure on a pod.
+func LoadImageDigests(fsys fs.FS, name string) (ImageDigests, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("reading image digest manifest %s: %w", name, err)
+	}
+	var manifest imageDigestManifest
+	if err := yaml.UnmarshalStrict(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing image digest manifest %s: %w", name, err)
+	}
+	digests := ImageDigests(manifest.Images)
+	if err := digests.validate(); err != nil {
+		return nil, fmt.Errorf("image digest manifest %s: %w", name, err)
+	}
+	return digests, nil
+}
+
+func (d ImageDigests) validate() error {
+	refs := make([]string, 0, len(d))
+	for ref := range d {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	var problems []string
+	for _, ref := range refs {
+		if strings.Contains(ref, "@") {
+			problems = append(problems, fmt.Sprintf("%s is already pinned to a digest", ref))
+		} else if err := v1alpha1.ValidateImageReference(ref); err != nil {
+			problems = append(problems, err.Error())
+		}
+		if !v1alpha1.IsImageDigest(d[ref]) {
+			problems = append(problems, fmt.Sprintf("%s: %q is not a sha256 digest", ref, d[ref]))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validateImageRewrite rejects mirror rules that cannot be applied once and only once. A rule whose
+// replacement still matches its own prefix would rewrite a sidecar image again on every container
+// it reaches, and two rules with one prefix leave the answer to their order.
+func (r ImageResolution) validateImageRewrite() error {
+	var problems []string
+	seen := map[string]bool{}
+	for i, m := range r.Mirrors {
+		switch {
+		case m.Prefix == "" || m.Replacement == "":
+			problems = append(problems, fmt.Sprintf("mirrors[%d] needs both a prefix and a replacement", i))
+			continue
+		case seen[m.Prefix]:
+			problems = append(problems, fmt.Sprintf("mirrors[%d] repeats prefix %q", i, m.Prefix))
+		case hasImagePrefix(m.Replacement, m.Prefix):
+			problems = append(problems, fmt.Sprintf(
+				"mirrors[%d] replacement %q still starts with its prefix %q", i, m.Replacement, m.Prefix))
+		}
+		seen[m.Prefix] = true
+	}
+	if err := r.Digests.validate(); err != nil {
+		problems = append(problems, "digests: "+err.Error())
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// rewriteImage pins reference to its digest, then rewrites it onto its mirror. The order matters
+// only for the digest lookup, which is keyed by the upstream reference. A reference that already
+// carries a digest is never re-pinned.
+func (r ImageResolution) rewriteImage(reference string) string {
+	if reference == "" {
+		return ""
+	}
+	if digest, ok := r.Digests[reference]; ok && !strings.Contains(reference, "@") {
+		reference += "@" + digest
+	}
+
+	best := -1
+	for i, m := range r.Mirrors {
+		if hasImagePrefix(reference, m.Prefix) && (best < 0 || len(m.Prefix) > len(r.Mirrors[best].Prefix)) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		m := r.Mirrors[best]
+		reference = m.Replacement + strings.TrimPrefix(reference, m.Prefix)
+	}
+	return reference
+}
+
+// rewritePodImages applies rewriteImage to every container and init container of a built pod
+// spec, except those already running one of the final references in done. It runs after sidecar
+// injection because the sidecars' own images — an oauth2-proxy default, a Vector agent image a
+// product set — are chosen inside their providers, and an air-gapped install must mirror those too.
+func (r ImageResolution) rewritePodImages(podSpec *corev1.PodSpec, done ...string) {
+	if len(r.Mirrors) == 0 && len(r.Digests) == 0 {
+		return
+	}
+	skip := map[string]bool{}
+	for _, ref := range done {
+		skip[ref] = true
+	}
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for i := range containers {
+			if !skip[containers[i].Image] {
+				containers[i].Image = r.rewriteImage(containers[i].Image)
+			}
+		}
+	}
+}
+
+// hasImagePrefix reports whether reference starts with prefix at a path boundary.
+func hasImagePrefix(reference, prefix string) bool {
+	if !strings.HasPrefix(reference, prefix) {
+		return false
+	}
+	if len(reference) == len(prefix) || strings.HasSuffix(prefix, "/") {
+		return true
+	}
+	return strings.ContainsRune("/:@", rune(reference[len(prefix)]))
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"strings"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+)
+
+var _ = Describe("image rewriting", func() {
+	digest := "sha256:" + strings.Repeat("4f", 32)
+	kafka := "quay.io/kubedoop/kafka:3.7.1-kubedoop0.2.0"
+
+	resolution := ImageResolution{
+		Mirrors: []RegistryMirror{
+			{Prefix: "quay.io/", Replacement: "registry.internal/quay/"},
+			{Prefix: "quay.io/kubedoop", Replacement: "registry.internal/kubedoop"},
+		},
+		Digests: ImageDigests{kafka: digest},
+	}
+
+	It("pins by the upstream reference, then applies the longest matching prefix", func() {
+		Expect(resolution.rewriteImage(kafka)).To(Equal("registry.internal/kubedoop/kafka:3.7.1-kubedoop0.2.0@" + digest))
+		Expect(resolution.rewriteImage("quay.io/oauth2-proxy/oauth2-proxy:v7.8.2")).To(
+			Equal("registry.internal/quay/oauth2-proxy/oauth2-proxy:v7.8.2"))
+	})
+
+	It("matches a prefix only at a path boundary, and leaves unmatched references alone", func() {
+		Expect(resolution.rewriteImage("quay.io/kubedoop-staging/kafka:1")).To(
+			Equal("registry.internal/quay/kubedoop-staging/kafka:1"), "falls through to the shorter rule")
+		Expect(resolution.rewriteImage("docker.io/library/nginx:1")).To(Equal("docker.io/library/nginx:1"))
+	})
+
+	It("rewrites every container of the pod except those already final", func() {
+		final := resolution.rewriteImage(kafka)
+		podSpec := &corev1.PodSpec{
+			Containers:     []corev1.Container{{Name: "kafka", Image: final}},
+			InitContainers: []corev1.Container{{Name: "oauth2-proxy", Image: "quay.io/oauth2-proxy/oauth2-proxy:v7.8.2"}},
+		}
+		resolution.rewritePodImages(podSpec, final)
+		Expect(podSpec.Containers[0].Image).To(Equal(final))
+		Expect(podSpec.InitContainers[0].Image).To(Equal("registry.internal/quay/oauth2-proxy/oauth2-proxy:v7.8.2"))
+	})
+
+	It("rejects rules that cannot be applied exactly once, and malformed digests", func() {
+		err := ImageResolution{
+			Mirrors: []RegistryMirror{
+				{Prefix: "quay.io/", Replacement: "quay.io/mirror/"},
+				{Prefix: "docker.io/", Replacement: "a/"},
+				{Prefix: "docker.io/", Replacement: "b/"},
+			},
+			Digests: ImageDigests{kafka: "sha256:short"},
+		}.validateImageRewrite()
+		Expect(err).To(MatchError(ContainSubstring(`mirrors[0] replacement "quay.io/mirror/" still starts with its prefix "quay.io/"`)))
+		Expect(err).To(MatchError(ContainSubstring(`mirrors[2] repeats prefix "docker.io/"`)))
+		Expect(err).To(MatchError(ContainSubstring(`"sha256:short" is not a sha256 digest`)))
+	})
+
+	It("loads a product-shipped manifest, and refuses one with a malformed entry", func() {
+		fsys := fstest.MapFS{
+			"images.yaml": {Data: []byte("images:\n  " + kafka + ": " + digest + "\n")},
+			"broken.yaml": {Data: []byte("images:\n  " + kafka + "@" + digest + ": " + digest + "\n")},
+		}
+		digests, err := LoadImageDigests(fsys, "images.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(digests).To(Equal(ImageDigests{kafka: digest}))
+
+		_, err = LoadImageDigests(fsys, "broken.yaml")
+		Expect(err).To(MatchError(ContainSubstring("is already pinned to a digest")))
+	})
+
+	It("hands the final reference to ResolvedImage", func() {
+		out, err := resolveImage(&v1alpha1.GenericClusterSpec{}, RoleDeclaration{}, ImageResolution{
+			ProductName: "kafka",
+			Defaults:    v1alpha1.ImageSpec{Repo: "quay.io/kubedoop", ProductVersion: "3.7.1", KubedoopVersion: "0.2.0"},
+			Mirrors:     resolution.Mirrors,
+			Digests:     resolution.Digests,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(out.Reference).To(Equal("registry.internal/kubedoop/kafka:3.7.1-kubedoop0.2.0@" + digest))
+		Expect(out.ProductVersion).To(Equal("3.7.1"))
+	})
+})
@@ -0,0 +1,669 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	listenersv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/listeners/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/constant"
+	"github.com/zncdatadev/operator-go/pkg/listener"
+)
+
+// LabelListenerFallback marks the Services and Listeners the built-in listener fallback creates
+// for a role group (see GenericReconcilerConfig.ListenerFallback); the value is the role group name.
+// Only objects carrying it are reclaimed when a pod is scaled away or the role group stops
+// declaring a listener class — the role group's own Service, which the fallback also configures,
+// never carries it.
+const LabelListenerFallback = listener.ListenerAPIGroup + "/fallback-role-group"
+
+// listenerFallbackAnnotationPrefix prefixes the pod annotations the fallback publishes a listener
+// volume's address through. The downward-API volume that replaces the CSI volume projects them
+// into the files the listener-operator would have written.
+const listenerFallbackAnnotationPrefix = listener.ListenerAPIGroup + "/fallback."
+
+// ListenerFallbackAddressAnnotation is the pod annotation carrying the default address of the
+// listener volume named volume.
+func ListenerFallbackAddressAnnotation(volume string) string {
+	return listenerFallbackAnnotationPrefix + volume + ".address"
+}
+
+// ListenerFallbackPortAnnotation is the pod annotation carrying the port named port of the listener
+// volume named volume.
+func ListenerFallbackPortAnnotation(volume, port string) string {
+	return listenerFallbackAnnotationPrefix + volume + ".port." + port
+}
+
+// resolvedListenerClass is a listener class as the fallback applies it.
+type resolvedListenerClass struct {
+	name                  string
+	serviceType           corev1.ServiceType
+	annotations           map[string]string
+	externalTrafficPolicy corev1.ServiceExternalTrafficPolicy
+	preferredAddressType  listenersv1alpha1.AddressType
+}
+
+// resolveListenerClassSpec turns a ListenerClass into what the fallback applies. A nil class — the
+// listener-operator is absent, so nothing installed the well-known classes — falls back to
+// listener.ServiceTypeFor and the CRD's own defaults, which is what the installed class would
+// normally say.
+func resolveListenerClassSpec(name listener.ListenerClass, lc *listenersv1alpha1.ListenerClass) *resolvedListenerClass {
+	resolved := &resolvedListenerClass{
+		name:                  string(name),
+		serviceType:           listener.ServiceTypeFor(name),
+		externalTrafficPolicy: corev1.ServiceExternalTrafficPolicyLocal,
+		preferredAddressType:  listenersv1alpha1.AddressTypeHostnameConservative,
+	}
+	if lc != nil {
+		if lc.Spec.ServiceType != nil {
+			resolved.serviceType = *lc.Spec.ServiceType
+		}
+		resolved.annotations = lc.Spec.ServiceAnnotations
+		if lc.Spec.ServiceExternalTrafficPolicy != "" {
+			resolved.externalTrafficPolicy = lc.Spec.ServiceExternalTrafficPolicy
+		}
+		if lc.Spec.PreferredAddressType != "" {
+			resolved.preferredAddressType = lc.Spec.PreferredAddressType
+		}
+	}
+	return resolved
+}
+
+// applyTo sets the class's type, annotations and external traffic policy on svc. The class's
+// annotations go underneath the Service's own, so a product that sets one deliberately keeps it.
+// A ClusterIP Service gets no traffic policy: the API server rejects one.
+func (c *resolvedListenerClass) applyTo(svc *corev1.Service) {
+	svc.Spec.Type = c.serviceType
+	if len(c.annotations) > 0 {
+		annotations := maps.Clone(c.annotations)
+		maps.Copy(annotations, svc.Annotations)
+		svc.Annotations = annotations
+	}
+	if c.serviceType == corev1.ServiceTypeClusterIP {
+		svc.Spec.ExternalTrafficPolicy = ""
+	} else {
+		svc.Spec.ExternalTrafficPolicy = c.externalTrafficPolicy
+	}
+}
+
+// fallbackListener is one Listener the fallback realises, with the Service behind it.
+type fallbackListener struct {
+	listener *listenersv1alpha1.Listener
+	// service is the per-pod Service the fallback creates, or nil when the Listener is backed by
+	// the role group's own Service.
+	service     *corev1.Service
+	serviceName string
+	// pod is the pod a per-pod Listener belongs to; empty for a Listener shared by the role group.
+	pod   string
+	class *resolvedListenerClass
+	// managed is false for a Listener the product ships itself as an extra resource: the fallback
+	// then fills its status and leaves its spec alone.
+	managed bool
+}
+
+// fallbackVolume is one listener volume the fallback replaced with a downward-API volume.
+type fallbackVolume struct {
+	name string
+	// listenerName is the shared Listener the volume binds to; empty for a per-pod volume, which
+	// binds to the Listener named after its pod.
+	listenerName string
+}
+
+// roleGroupResourceNames returns the resource names of the cluster's other role groups. A
+// per-pod Service is named after its pod, "<role group resource>-<ordinal>", and no suffix the
+// fallback could pick is out of a role group name's reach, so a group named "<group>-<ordinal>"
+// — or a role whose name ends that way — is refused rather than left to overwrite the same
+// Service as this group's pod.
+func roleGroupResourceNames(buildCtx *RoleGroupBuildContext) map[string]bool {
+	names := map[string]bool{}
+	if buildCtx.ClusterSpec == nil {
+		return names
+	}
+	for roleName, role := range buildCtx.ClusterSpec.Roles {
+		for groupName := range role.RoleGroups {
+			if roleName == buildCtx.RoleName && groupName == buildCtx.RoleGroupName {
+				continue
+			}
+			names[RoleGroupResourceName(buildCtx.ClusterName, roleName, groupName)] = true
+		}
+	}
+	return names
+}
+
+// listenerFallbackPlan is what the fallback realises for one role group: built with the role
+// group's resources, carried out after they are applied.
+type listenerFallbackPlan struct {
+	listeners []*fallbackListener
+	volumes   []fallbackVolume
+	// selector selects the role group's pods: the StatefulSet's selector.
+	selector map[string]string
+}
+
+// planListenerFallback prepares a role group for the built-in listener fallback: it applies the
+// role group's listener class to the role group Service and replaces every listener CSI volume in
+// the pod template with a downward-API volume reading the address from the pod's annotations.
+//
+// A class-based volume gets one Listener and one Service per pod, both named after the pod — the
+// listener-operator's default (see listener.AnnotationListenerName) — and selecting it through the
+// StatefulSet's pod-name label. A volume bound to a Listener by name shares the role group's own
+// Service instead, so the role group's listener class decides how that Listener is exposed.
+//
+// Role groups whose role declares no listener class are left alone, listener volumes included: the
+// fallback is for the products that say how they want to be reached, not a replacement CSI driver.
+// It returns nil for them, which tells reconcileListenerFallback to reclaim anything it created
+// while they still declared one.
+func (r *GenericReconciler[CR]) planListenerFallback(ctx context.Context, buildCtx *RoleGroupBuildContext, resources *RoleGroupResources) (*listenerFallbackPlan, error) {
+	className := buildCtx.Declaration.ListenerClass
+	if className == "" || resources.StatefulSet == nil {
+		return nil, nil
+	}
+	classes := map[listener.ListenerClass]*resolvedListenerClass{}
+	roleGroupClass, err := r.resolveListenerClass(ctx, className, classes)
+	if err != nil {
+		return nil, NewResourceBuildError("ListenerClass", buildCtx.RoleName, buildCtx.RoleGroupName, "failed to read listener class", err)
+	}
+	if resources.Service != nil {
+		roleGroupClass.applyTo(resources.Service)
+	}
+
+	sts := resources.StatefulSet
+	var selector map[string]string
+	if sts.Spec.Selector != nil {
+		selector = sts.Spec.Selector.MatchLabels
+	}
+	plan := &listenerFallbackPlan{selector: selector}
+	labels := maps.Clone(sts.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[LabelListenerFallback] = buildCtx.RoleGroupName
+	extras := extraListenerNames(resources.ExtraResources)
+	shared := map[string]bool{}
+
+	volumes := sts.Spec.Template.Spec.Volumes
+	for i := range volumes {
+		annotations, ok := listenerVolumeAnnotations(&volumes[i])
+		if !ok {
+			continue
+		}
+		volumeName := volumes[i].Name
+		listenerName := annotations[listener.AnnotationListenerName]
+
+		if listenerName != "" {
+			if resources.Service == nil {
+				return nil, NewValidationError("listener volume "+volumeName, buildCtx.RoleName, buildCtx.RoleGroupName,
+					fmt.Errorf("it binds to Listener %q, which the listener fallback backs with the role group "+
+						"Service, but the handler built none", listenerName))
+			}
+			volumes[i] = fallbackDownwardVolume(volumeName, portNames(resources.Service.Spec.Ports))
+			plan.volumes = append(plan.volumes, fallbackVolume{name: volumeName, listenerName: listenerName})
+			if shared[listenerName] {
+				continue
+			}
+			shared[listenerName] = true
+			plan.listeners = append(plan.listeners, &fallbackListener{
+				listener:    buildFallbackListener(listenerName, buildCtx.ClusterNamespace, labels, string(className), resources.Service.Spec.Ports),
+				serviceName: resources.Service.Name,
+				class:       roleGroupClass,
+				managed:     !extras[listenerName],
+			})
+			continue
+		}
+
+		if len(buildCtx.Declaration.ServicePorts) == 0 {
+			return nil, NewValidationError("listener volume "+volumeName, buildCtx.RoleName, buildCtx.RoleGroupName,
+				fmt.Errorf("the listener fallback exposes a per-pod listener on the role's ServicePorts, and the role declares none"))
+		}
+		volumeClass := listener.ListenerClass(annotations[listener.ListenerClassAnnotation])
+		class, err := r.resolveListenerClass(ctx, volumeClass, classes)
+		if err != nil {
+			return nil, NewResourceBuildError("ListenerClass", buildCtx.RoleName, buildCtx.RoleGroupName, "failed to read listener class", err)
+		}
+		volumes[i] = fallbackDownwardVolume(volumeName, portNames(buildCtx.Declaration.ServicePorts))
+		plan.volumes = append(plan.volumes, fallbackVolume{name: volumeName})
+		roleGroupResources := roleGroupResourceNames(buildCtx)
+		for ordinal := range ptr.Deref(sts.Spec.Replicas, 1) {
+			pod := fmt.Sprintf("%s-%d", sts.Name, ordinal)
+			if slices.ContainsFunc(plan.listeners, func(l *fallbackListener) bool { return l.pod == pod }) {
+				// A second class-based volume on the same pod binds to the same per-pod Listener,
+				// as it would under the listener-operator's naming.
+				continue
+			}
+			if roleGroupResources[pod] {
+				return nil, NewValidationError("listener volume "+volumeName, buildCtx.RoleName, buildCtx.RoleGroupName,
+					fmt.Errorf("the per-pod Service of pod %q would take the name of another role group's Service; "+
+						"rename that role group, since a group named \"<group>-<ordinal>\" collides with this "+
+						"group's pods under the listener fallback", pod))
+			}
+			svc := &corev1.Service{}
+			svc.Name, svc.Namespace, svc.Labels = pod, buildCtx.ClusterNamespace, maps.Clone(labels)
+			svc.Spec.Selector = maps.Clone(selector)
+			if svc.Spec.Selector == nil {
+				svc.Spec.Selector = map[string]string{}
+			}
+			svc.Spec.Selector[appsv1.StatefulSetPodNameLabel] = pod
+			svc.Spec.Ports = slices.Clone(buildCtx.Declaration.ServicePorts)
+			svc.Spec.PublishNotReadyAddresses = true
+			class.applyTo(svc)
+			plan.listeners = append(plan.listeners, &fallbackListener{
+				listener:    buildFallbackListener(pod, buildCtx.ClusterNamespace, labels, string(volumeClass), svc.Spec.Ports),
+				service:     svc,
+				serviceName: pod,
+				pod:         pod,
+				class:       class,
+				managed:     !extras[pod],
+			})
+		}
+	}
+	return plan, nil
+}
+
+// resolveListenerClass reads the named ListenerClass, once per role group. It reads through the
+// APIReader when one is wired: ListenerClasses are cluster-scoped and read rarely, so an informer
+// for them would cost a cluster-wide list/watch grant for nothing. A class that does not exist, or
+// a cluster without the ListenerClass CRD, resolves to the built-in defaults.
+func (r *GenericReconciler[CR]) resolveListenerClass(ctx context.Context, name listener.ListenerClass, cache map[listener.ListenerClass]*resolvedListenerClass) (*resolvedListenerClass, error) {
+	if resolved, ok := cache[name]; ok {
+		return resolved, nil
+	}
+	var reader client.Reader = r.client
+	if r.apiReader != nil {
+		reader = r.apiReader
+	}
+	lc := &listenersv1alpha1.ListenerClass{}
+	err := reader.Get(ctx, types.NamespacedName{Name: string(name)}, lc)
+	switch {
+	case err == nil:
+	case errors.IsNotFound(err) || meta.IsNoMatchError(err):
+		lc = nil
+	default:
+		return nil, r.apiError(err)
+	}
+	resolved := resolveListenerClassSpec(name, lc)
+	cache[name] = resolved
+	return resolved, nil
+}
+
+// listenerVolumeAnnotations returns the claim-template annotations of a listener CSI volume, and
+// whether the volume is one.
+func listenerVolumeAnnotations(v *corev1.Volume) (map[string]string, bool) {
+	if v.Ephemeral == nil || v.Ephemeral.VolumeClaimTemplate == nil {
+		return nil, false
+	}
+	template := v.Ephemeral.VolumeClaimTemplate
+	if ptr.Deref(template.Spec.StorageClassName, "") != listener.ListenerStorageClass {
+		return nil, false
+	}
+	return template.Annotations, true
+}
+
+// fallbackDownwardVolume builds the downward-API volume that stands in for a listener CSI volume. It
+// lays out the files the listener-operator writes — default-address/address and
+// default-address/ports/<name> — so a product reads its address the same way with or without the
+// driver. The kubelet refreshes the files when the annotations change, so a LoadBalancer address
+// assigned after the pod started still reaches it; until then the files are empty.
+func fallbackDownwardVolume(name string, ports []string) corev1.Volume {
+	items := make([]corev1.DownwardAPIVolumeFile, 0, len(ports)+1)
+	items = append(items, corev1.DownwardAPIVolumeFile{
+		Path:     "default-address/address",
+		FieldRef: &corev1.ObjectFieldSelector{FieldPath: annotationFieldPath(ListenerFallbackAddressAnnotation(name))},
+	})
+	for _, port := range ports {
+		items = append(items, corev1.DownwardAPIVolumeFile{
+			Path:     "default-address/ports/" + port,
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: annotationFieldPath(ListenerFallbackPortAnnotation(name, port))},
+		})
+	}
+	return corev1.Volume{
+		Name:         name,
+		VolumeSource: corev1.VolumeSource{DownwardAPI: &corev1.DownwardAPIVolumeSource{Items: items}},
+	}
+}
+
+func annotationFieldPath(key string) string {
+	return "metadata.annotations['" + key + "']"
+}
+
+func portNames(ports []corev1.ServicePort) []string {
+	names := make([]string, 0, len(ports))
+	for _, p := range ports {
+		if p.Name != "" {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+func buildFallbackListener(name, namespace string, labels map[string]string, className string, ports []corev1.ServicePort) *listenersv1alpha1.Listener {
+	l := &listenersv1alpha1.Listener{}
+	l.Name, l.Namespace, l.Labels = name, namespace, maps.Clone(labels)
+	l.Spec.ClassName = className
+	for _, p := range ports {
+		l.Spec.Ports = append(l.Spec.Ports, listenersv1alpha1.PortSpec{Name: p.Name, Protocol: p.Protocol, Port: p.Port})
+	}
+	return l
+}
+
+// extraListenerNames returns the names of the Listeners a product ships as extra resources.
+func extraListenerNames(extras []client.Object) map[string]bool {
+	names := map[string]bool{}
+	for _, extra := range extras {
+		if l, ok := extra.(*listenersv1alpha1.Listener); ok && l != nil {
+			names[l.Name] = true
+		}
+	}
+	return names
+}
+
+// reconcileListenerFallback carries out a role group's plan after its resources are applied: it
+// applies the per-pod Services and the Listeners, reclaims those no longer planned, writes each
+// Listener's status from its Service, and publishes each pod's address on the pod. A nil plan only
+// reclaims.
+//
+// It runs after the StatefulSet rather than before it, unlike the extras a real listener needs:
+// the replaced volumes no longer make a pod wait for its Listener, and a NodePort address is the
+// node the pod was scheduled to, which does not exist before the pod does.
+func (r *GenericReconciler[CR]) reconcileListenerFallback(ctx context.Context, cr CR, buildCtx *RoleGroupBuildContext, plan *listenerFallbackPlan) error {
+	keep := map[string]bool{}
+	if plan != nil {
+		for _, fl := range plan.listeners {
+			keep[fl.listener.Name] = true
+			if fl.service != nil {
+				if err := r.applyResource(ctx, cr, fl.service); err != nil {
+					return NewResourceApplyError("Service", buildCtx.ClusterNamespace, fl.service.Name, "failed to apply listener fallback service", err)
+				}
+			}
+			if !fl.managed {
+				continue
+			}
+			if err := r.checkFallbackListenerOwner(ctx, buildCtx, fl.listener.Name); err != nil {
+				return err
+			}
+			if err := r.applyResource(ctx, cr, fl.listener); err != nil {
+				return NewResourceApplyError("Listener", buildCtx.ClusterNamespace, fl.listener.Name, "failed to apply listener fallback listener", err)
+			}
+		}
+	}
+	if err := r.reclaimListenerFallback(ctx, buildCtx, cr.GetUID(), keep); err != nil {
+		return NewResourceApplyError("Listener", buildCtx.ClusterNamespace, buildCtx.ResourceName, "failed to delete unplanned listener fallback objects", err)
+	}
+	if plan == nil || len(plan.listeners) == 0 {
+		return nil
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.client.List(ctx, pods, client.InNamespace(buildCtx.ClusterNamespace), client.MatchingLabels(plan.selector)); err != nil {
+		return NewResourceApplyError("Pod", buildCtx.ClusterNamespace, buildCtx.ResourceName, "failed to list pods for the listener fallback", r.apiError(err))
+	}
+
+	statuses := make(map[string]listenersv1alpha1.ListenerStatus, len(plan.listeners))
+	for _, fl := range plan.listeners {
+		status, err := r.updateFallbackListenerStatus(ctx, buildCtx, fl, pods.Items)
+		if err != nil {
+			return err
+		}
+		statuses[fl.listener.Name] = status
+	}
+
+	for i := range pods.Items {
+		if err := r.publishFallbackAddress(ctx, &pods.Items[i], plan.volumes, statuses); err != nil {
+			return NewResourceApplyError("Pod", buildCtx.ClusterNamespace, pods.Items[i].Name, "failed to publish listener address", err)
+		}
+	}
+	return nil
+}
+
+// checkFallbackListenerOwner refuses to take over a Listener another role group realises. A Listener
+// bound by name is backed by ONE role group's Service in fallback mode; two role groups binding the
+// same name would otherwise rewrite each other's every pass.
+func (r *GenericReconciler[CR]) checkFallbackListenerOwner(ctx context.Context, buildCtx *RoleGroupBuildContext, name string) error {
+	live := &listenersv1alpha1.Listener{}
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: buildCtx.ClusterNamespace, Name: name}, live); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return NewResourceApplyError("Listener", buildCtx.ClusterNamespace, name, "failed to read listener", r.apiError(err))
+	}
+	group, marked := live.Labels[LabelListenerFallback]
+	if !marked || group == buildCtx.RoleGroupName || live.Labels[constant.LabelKubernetesComponent] != buildCtx.RoleName {
+		return nil
+	}
+	return NewValidationError("listener "+name, buildCtx.RoleName, buildCtx.RoleGroupName,
+		fmt.Errorf("role group %q already realises this Listener; the listener fallback backs a Listener bound by name "+
+			"with one role group's Service, so role groups cannot share it", group))
+}
+
+// updateFallbackListenerStatus computes a Listener's status from its Service and writes it when it
+// changed, returning it.
+func (r *GenericReconciler[CR]) updateFallbackListenerStatus(ctx context.Context, buildCtx *RoleGroupBuildContext, fl *fallbackListener, pods []corev1.Pod) (listenersv1alpha1.ListenerStatus, error) {
+	key := types.NamespacedName{Namespace: buildCtx.ClusterNamespace, Name: fl.listener.Name}
+	svc := &corev1.Service{}
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: key.Namespace, Name: fl.serviceName}, svc); err != nil {
+		if errors.IsNotFound(err) {
+			// Not in the cache yet; the watch on it brings the cluster back.
+			return listenersv1alpha1.ListenerStatus{}, nil
+		}
+		return listenersv1alpha1.ListenerStatus{}, NewResourceApplyError("Service", key.Namespace, fl.serviceName, "failed to read listener fallback service", r.apiError(err))
+	}
+	if fl.pod != "" {
+		pods = slices.DeleteFunc(slices.Clone(pods), func(p corev1.Pod) bool { return p.Name != fl.pod })
+	}
+	status := fallbackListenerStatus(svc, pods, fl.class, DefaultClusterDomain)
+
+	live := &listenersv1alpha1.Listener{}
+	if err := r.client.Get(ctx, key, live); err != nil {
+		if errors.IsNotFound(err) {
+			return status, nil
+		}
+		return status, NewResourceApplyError("Listener", key.Namespace, key.Name, "failed to read listener", r.apiError(err))
+	}
+	if equality.Semantic.DeepEqual(live.Status, status) {
+		return status, nil
+	}
+	live.Status = status
+	if err := r.client.Status().Update(ctx, live); err != nil {
+		return status, NewResourceApplyError("Listener", key.Namespace, key.Name, "failed to update listener status", r.apiError(err))
+	}
+	return status, nil
+}
+
+// fallbackListenerStatus is the status the listener-operator would report for a Listener backed by
+// svc: the cluster-internal DNS name for a ClusterIP Service, the load balancer's ingress for a
+// LoadBalancer, and the node of every backing pod for a NodePort. A NodePort address is always the
+// node's IP: its hostname would need a cluster-wide read of Nodes, and the listener-operator's own
+// HostnameConservative default picks the IP for NodePort anyway.
+func fallbackListenerStatus(svc *corev1.Service, pods []corev1.Pod, class *resolvedListenerClass, clusterDomain string) listenersv1alpha1.ListenerStatus {
+	status := listenersv1alpha1.ListenerStatus{ServiceName: svc.Name}
+	servicePorts := map[string]int32{}
+	nodePorts := map[string]int32{}
+	for _, p := range svc.Spec.Ports {
+		servicePorts[p.Name] = p.Port
+		if p.NodePort != 0 {
+			nodePorts[p.Name] = p.NodePort
+		}
+	}
+	preferIP := class.preferredAddressType == listenersv1alpha1.AddressTypeIP
+
+	switch svc.Spec.Type {
+	case corev1.ServiceTypeNodePort:
+		if len(nodePorts) == 0 {
+			return status
+		}
+		status.NodePorts = nodePorts
+		seen := map[string]bool{}
+		for i := range pods {
+			hostIP := pods[i].Status.HostIP
+			if hostIP == "" || seen[hostIP] {
+				continue
+			}
+			seen[hostIP] = true
+			status.IngressAddresses = append(status.IngressAddresses, listenersv1alpha1.IngressAddressSpec{
+				Address: hostIP, AddressType: listenersv1alpha1.AddressTypeIP, Ports: maps.Clone(nodePorts),
+			})
+		}
+		slices.SortFunc(status.IngressAddresses, func(a, b listenersv1alpha1.IngressAddressSpec) int {
+			return strings.Compare(a.Address, b.Address)
+		})
+	case corev1.ServiceTypeLoadBalancer:
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			address := listenersv1alpha1.IngressAddressSpec{Ports: maps.Clone(servicePorts)}
+			switch {
+			case ingress.Hostname != "" && (!preferIP || ingress.IP == ""):
+				address.Address, address.AddressType = ingress.Hostname, listenersv1alpha1.AddressTypeHostname
+			case ingress.IP != "":
+				address.Address, address.AddressType = ingress.IP, listenersv1alpha1.AddressTypeIP
+			default:
+				continue
+			}
+			status.IngressAddresses = append(status.IngressAddresses, address)
+		}
+	default:
+		address := listenersv1alpha1.IngressAddressSpec{
+			Address:     fmt.Sprintf("%s.%s.svc.%s", svc.Name, svc.Namespace, clusterDomain),
+			AddressType: listenersv1alpha1.AddressTypeHostname,
+			Ports:       servicePorts,
+		}
+		if preferIP {
+			if svc.Spec.ClusterIP == "" || svc.Spec.ClusterIP == corev1.ClusterIPNone {
+				return status
+			}
+			address.Address, address.AddressType = svc.Spec.ClusterIP, listenersv1alpha1.AddressTypeIP
+		}
+		status.IngressAddresses = []listenersv1alpha1.IngressAddressSpec{address}
+	}
+	return status
+}
+
+// publishFallbackAddress writes the address each of the pod's listener volumes should see into the
+// pod's annotations, patching only when something changed. A volume whose Listener has no address
+// yet gets none, and any it had is removed rather than left pointing at a previous address.
+func (r *GenericReconciler[CR]) publishFallbackAddress(ctx context.Context, pod *corev1.Pod, volumes []fallbackVolume, statuses map[string]listenersv1alpha1.ListenerStatus) error {
+	desired := fallbackPodAnnotations(pod, volumes, statuses)
+	before := pod.DeepCopy()
+	changed := false
+	for key := range pod.Annotations {
+		if _, keep := desired[key]; strings.HasPrefix(key, listenerFallbackAnnotationPrefix) && !keep {
+			delete(pod.Annotations, key)
+			changed = true
+		}
+	}
+	for key, value := range desired {
+		if pod.Annotations[key] == value {
+			continue
+		}
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[key] = value
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	if err := r.client.Patch(ctx, pod, client.MergeFrom(before)); err != nil && !errors.IsNotFound(err) {
+		return r.apiError(err)
+	}
+	log.FromContext(ctx).V(1).Info("Published listener address on pod", "pod", pod.Name)
+	return nil
+}
+
+// fallbackPodAnnotations computes the address annotations of a pod. Of several addresses, a pod
+// sees the one on its own node when there is one — the address a NodePort client reaches it at —
+// and otherwise the first.
+func fallbackPodAnnotations(pod *corev1.Pod, volumes []fallbackVolume, statuses map[string]listenersv1alpha1.ListenerStatus) map[string]string {
+	out := map[string]string{}
+	for _, v := range volumes {
+		name := v.listenerName
+		if name == "" {
+			name = pod.Name
+		}
+		addresses := statuses[name].IngressAddresses
+		if len(addresses) == 0 {
+			continue
+		}
+		address := addresses[0]
+		for _, candidate := range addresses {
+			if pod.Status.HostIP != "" && candidate.Address == pod.Status.HostIP {
+				address = candidate
+				break
+			}
+		}
+		out[ListenerFallbackAddressAnnotation(v.name)] = address.Address
+		for port, number := range address.Ports {
+			out[ListenerFallbackPortAnnotation(v.name, port)] = fmt.Sprint(number)
+		}
+	}
+	return out
+}
+
+// reclaimListenerFallback deletes the fallback Services and Listeners of a role group that the
+// current plan does not keep: the pods a scale-down removed, or everything once the role group
+// stops declaring a listener class. Only objects carrying LabelListenerFallback for this role group
+// and controlled by this cluster qualify.
+func (r *GenericReconciler[CR]) reclaimListenerFallback(ctx context.Context, buildCtx *RoleGroupBuildContext, ownerUID types.UID, keep map[string]bool) error {
+	selector := client.MatchingLabels{
+		constant.LabelKubernetesInstance:  buildCtx.ClusterName,
+		constant.LabelKubernetesComponent: buildCtx.RoleName,
+		LabelListenerFallback:             buildCtx.RoleGroupName,
+	}
+	inNamespace := client.InNamespace(buildCtx.ClusterNamespace)
+
+	services := &corev1.ServiceList{}
+	if err := r.client.List(ctx, services, inNamespace, selector); err != nil {
+		return r.apiError(err)
+	}
+	for i := range services.Items {
+		if err := r.deleteUnplannedFallback(ctx, &services.Items[i], ownerUID, keep); err != nil {
+			return err
+		}
+	}
+	listeners := &listenersv1alpha1.ListenerList{}
+	if err := r.client.List(ctx, listeners, inNamespace, selector); err != nil {
+		return r.apiError(err)
+	}
+	for i := range listeners.Items {
+		if err := r.deleteUnplannedFallback(ctx, &listeners.Items[i], ownerUID, keep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *GenericReconciler[CR]) deleteUnplannedFallback(ctx context.Context, obj client.Object, ownerUID types.UID, keep map[string]bool) error {
+	if keep[obj.GetName()] || !isOwnedByCluster(obj, ownerUID) || !obj.GetDeletionTimestamp().IsZero() {
+		return nil
+	}
+	if err := r.client.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+		return r.apiError(err)
+	}
+	log.FromContext(ctx).Info("Deleted unplanned listener fallback object", "kind", r.resourceKind(obj), "name", obj.GetName())
+	return nil
+}
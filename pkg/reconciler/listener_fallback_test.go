@@ -0,0 +1,278 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	listenersv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/listeners/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/listener"
+	"github.com/zncdatadev/operator-go/pkg/reconciler"
+	"github.com/zncdatadev/operator-go/pkg/testutil"
+)
+
+var _ = Describe("Listener fallback", func() {
+	const namespace = "default"
+
+	var (
+		scheme *runtime.Scheme
+		cr     *testutil.MockCluster
+		c      client.Client
+		class  listener.ListenerClass
+	)
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(listenersv1alpha1.AddToScheme(scheme)).To(Succeed())
+		Expect(testutil.AddToScheme(scheme)).To(Succeed())
+
+		class = listener.ListenerClassExternalUnstable
+		cr = testutil.NewMockCluster("zk", namespace).WithRoles(map[string]v1alpha1.RoleSpec{
+			"server": {RoleGroups: map[string]v1alpha1.RoleGroupSpec{"a": {Replicas: ptr.To[int32](2)}}},
+		})
+		cr.UID = "zk-uid"
+	})
+
+	newClient := func(objs ...client.Object) {
+		c = fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(append([]client.Object{cr}, objs...)...).
+			WithStatusSubresource(cr, &listenersv1alpha1.Listener{}).
+			Build()
+	}
+	reconcile := func(volumes ...*listener.VolumeRegistration) error {
+		GinkgoHelper()
+		provider := reconciler.RoleProviderFunc[*testutil.MockCluster](
+			func(context.Context, client.Client, *testutil.MockCluster) (reconciler.RoleCatalog, error) {
+				return reconciler.RoleCatalog{"server": {
+					ListenerClass:  class,
+					ContainerPorts: []corev1.ContainerPort{{Name: "client", ContainerPort: 2181}},
+					ServicePorts:   []corev1.ServicePort{{Name: "client", Port: 2181}},
+				}}, nil
+			})
+		r, err := reconciler.NewGenericReconciler(&reconciler.GenericReconcilerConfig[*testutil.MockCluster]{
+			Client:           c,
+			Scheme:           scheme,
+			ImageResolution:  reconciler.ImageResolution{Defaults: v1alpha1.ImageSpec{Custom: "test-image:latest"}},
+			RoleProvider:     provider,
+			Recorder:         record.NewFakeRecorder(100),
+			RoleGroupHandler: &listenerVolumeHandler{reconciler.NewBaseRoleGroupHandler[*testutil.MockCluster](scheme), volumes},
+			ListenerFallback: true,
+			Prototype:        testutil.NewMockCluster("proto", namespace),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: "zk"}})
+		return err
+	}
+	reconcileOnce := func(volumes ...*listener.VolumeRegistration) {
+		GinkgoHelper()
+		Expect(reconcile(volumes...)).To(Succeed())
+	}
+	get := func(name string, obj client.Object) client.Object {
+		GinkgoHelper()
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, obj)).To(Succeed())
+		return obj
+	}
+	// runPod stands in for the StatefulSet controller and the scheduler, which the fake client has
+	// neither of.
+	runPod := func(name, hostIP string) {
+		GinkgoHelper()
+		sts := get("zk-server-a", &appsv1.StatefulSet{}).(*appsv1.StatefulSet)
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{
+			appsv1.StatefulSetPodNameLabel: name,
+		}}}
+		for k, v := range sts.Spec.Selector.MatchLabels {
+			pod.Labels[k] = v
+		}
+		pod.Status.HostIP = hostIP
+		Expect(c.Create(ctx, pod)).To(Succeed())
+	}
+	// allocateNodePort stands in for the API server's node port allocator.
+	allocateNodePort := func(name string, port int32) {
+		GinkgoHelper()
+		svc := get(name, &corev1.Service{}).(*corev1.Service)
+		svc.Spec.Ports[0].NodePort = port
+		Expect(c.Update(ctx, svc)).To(Succeed())
+	}
+
+	It("replaces listener volumes with downward-API volumes carrying the listener-operator's layout", func() {
+		newClient()
+		reconcileOnce(listener.NewVolume("listener", class))
+
+		sts := get("zk-server-a", &appsv1.StatefulSet{}).(*appsv1.StatefulSet)
+		var volume *corev1.Volume
+		for i := range sts.Spec.Template.Spec.Volumes {
+			if sts.Spec.Template.Spec.Volumes[i].Name == "listener" {
+				volume = &sts.Spec.Template.Spec.Volumes[i]
+			}
+		}
+		Expect(volume).NotTo(BeNil())
+		Expect(volume.Ephemeral).To(BeNil())
+		Expect(volume.DownwardAPI.Items).To(HaveLen(2))
+		Expect(volume.DownwardAPI.Items[0].Path).To(Equal("default-address/address"))
+		Expect(volume.DownwardAPI.Items[0].FieldRef.FieldPath).To(Equal(
+			"metadata.annotations['" + reconciler.ListenerFallbackAddressAnnotation("listener") + "']"))
+		Expect(volume.DownwardAPI.Items[1].Path).To(Equal("default-address/ports/client"))
+	})
+
+	It("exposes each pod through its own Service and Listener, and publishes the node address", func() {
+		newClient()
+		volume := listener.NewVolume("listener", class)
+		reconcileOnce(volume)
+
+		roleGroupSvc := get("zk-server-a", &corev1.Service{}).(*corev1.Service)
+		Expect(roleGroupSvc.Spec.Type).To(Equal(corev1.ServiceTypeNodePort))
+		Expect(roleGroupSvc.Spec.ExternalTrafficPolicy).To(Equal(corev1.ServiceExternalTrafficPolicyLocal))
+		Expect(roleGroupSvc.Labels).NotTo(HaveKey(reconciler.LabelListenerFallback))
+
+		for _, pod := range []string{"zk-server-a-0", "zk-server-a-1"} {
+			svc := get(pod, &corev1.Service{}).(*corev1.Service)
+			Expect(svc.Spec.Type).To(Equal(corev1.ServiceTypeNodePort))
+			Expect(svc.Spec.Selector).To(HaveKeyWithValue(appsv1.StatefulSetPodNameLabel, pod))
+			Expect(svc.Labels).To(HaveKeyWithValue(reconciler.LabelListenerFallback, "a"))
+			l := get(pod, &listenersv1alpha1.Listener{}).(*listenersv1alpha1.Listener)
+			Expect(l.Spec.ClassName).To(Equal(string(class)))
+			Expect(metav1.IsControlledBy(l, cr)).To(BeTrue())
+		}
+
+		allocateNodePort("zk-server-a-0", 30181)
+		runPod("zk-server-a-0", "192.0.2.10")
+		reconcileOnce(volume)
+
+		l := get("zk-server-a-0", &listenersv1alpha1.Listener{}).(*listenersv1alpha1.Listener)
+		Expect(l.Status.ServiceName).To(Equal("zk-server-a-0"))
+		Expect(l.Status.NodePorts).To(Equal(map[string]int32{"client": 30181}))
+		Expect(l.Status.IngressAddresses).To(Equal([]listenersv1alpha1.IngressAddressSpec{{
+			Address: "192.0.2.10", AddressType: listenersv1alpha1.AddressTypeIP, Ports: map[string]int32{"client": 30181},
+		}}))
+
+		pod := get("zk-server-a-0", &corev1.Pod{}).(*corev1.Pod)
+		Expect(pod.Annotations).To(HaveKeyWithValue(reconciler.ListenerFallbackAddressAnnotation("listener"), "192.0.2.10"))
+		Expect(pod.Annotations).To(HaveKeyWithValue(reconciler.ListenerFallbackPortAnnotation("listener", "client"), "30181"))
+	})
+
+	It("refuses a sibling role group whose Service a pod's per-pod Service would take", func() {
+		cr.Spec.Roles["server"].RoleGroups["a-1"] = v1alpha1.RoleGroupSpec{Replicas: ptr.To[int32](1)}
+		newClient()
+
+		err := reconcile(listener.NewVolume("listener", class))
+		Expect(reconciler.IsValidationError(err)).To(BeTrue(), "want a *ValidationError, got %T: %v", err, err)
+		Expect(err.Error()).To(ContainSubstring(`pod "zk-server-a-1"`))
+		Expect(apierrors.IsNotFound(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "zk-server-a-1"}, &listenersv1alpha1.Listener{}))).To(BeTrue())
+	})
+
+	It("reclaims the per-pod objects of pods a scale-down removed", func() {
+		newClient()
+		volume := listener.NewVolume("listener", class)
+		reconcileOnce(volume)
+		get("zk-server-a-1", &listenersv1alpha1.Listener{})
+
+		live := get("zk", &testutil.MockCluster{}).(*testutil.MockCluster)
+		live.Spec.Roles["server"].RoleGroups["a"] = v1alpha1.RoleGroupSpec{Replicas: ptr.To[int32](1)}
+		Expect(c.Update(ctx, live)).To(Succeed())
+		reconcileOnce(volume)
+
+		get("zk-server-a-0", &listenersv1alpha1.Listener{})
+		Expect(apierrors.IsNotFound(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "zk-server-a-1"}, &listenersv1alpha1.Listener{}))).To(BeTrue())
+		Expect(apierrors.IsNotFound(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "zk-server-a-1"}, &corev1.Service{}))).To(BeTrue())
+	})
+
+	It("applies an installed ListenerClass and backs a by-name Listener with the role group Service", func() {
+		class = listener.ListenerClassExternalStable
+		newClient(&listenersv1alpha1.ListenerClass{
+			ObjectMeta: metav1.ObjectMeta{Name: string(class)},
+			Spec: listenersv1alpha1.ListenerClassSpec{
+				ServiceType:                  ptr.To(corev1.ServiceTypeLoadBalancer),
+				ServiceAnnotations:           map[string]string{"lb.example.com/scheme": "internet-facing"},
+				ServiceExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyCluster,
+				PreferredAddressType:         listenersv1alpha1.AddressTypeIP,
+			},
+		})
+		volume := listener.NewVolume("listener", "").WithListenerName("zk-shared")
+		reconcileOnce(volume)
+
+		svc := get("zk-server-a", &corev1.Service{}).(*corev1.Service)
+		Expect(svc.Spec.Type).To(Equal(corev1.ServiceTypeLoadBalancer))
+		Expect(svc.Annotations).To(HaveKeyWithValue("lb.example.com/scheme", "internet-facing"))
+		Expect(svc.Spec.ExternalTrafficPolicy).To(Equal(corev1.ServiceExternalTrafficPolicyCluster))
+		Expect(apierrors.IsNotFound(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "zk-server-a-0"}, &corev1.Service{}))).To(BeTrue())
+
+		svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{Hostname: "zk.example.com", IP: "203.0.113.7"}}
+		Expect(c.Status().Update(ctx, svc)).To(Succeed())
+		runPod("zk-server-a-0", "192.0.2.10")
+		reconcileOnce(volume)
+
+		l := get("zk-shared", &listenersv1alpha1.Listener{}).(*listenersv1alpha1.Listener)
+		Expect(l.Spec.ClassName).To(Equal(string(class)))
+		Expect(l.Status.ServiceName).To(Equal("zk-server-a"))
+		// PreferredAddressType IP picks the ingress IP over its hostname.
+		Expect(l.Status.IngressAddresses).To(Equal([]listenersv1alpha1.IngressAddressSpec{{
+			Address: "203.0.113.7", AddressType: listenersv1alpha1.AddressTypeIP, Ports: map[string]int32{"client": 2181},
+		}}))
+		pod := get("zk-server-a-0", &corev1.Pod{}).(*corev1.Pod)
+		Expect(pod.Annotations).To(HaveKeyWithValue(reconciler.ListenerFallbackAddressAnnotation("listener"), "203.0.113.7"))
+	})
+
+	It("reclaims everything once the role stops declaring a listener class", func() {
+		newClient()
+		volume := listener.NewVolume("listener", class)
+		reconcileOnce(volume)
+		get("zk-server-a-0", &listenersv1alpha1.Listener{})
+
+		class = ""
+		reconcileOnce(volume)
+		listeners := &listenersv1alpha1.ListenerList{}
+		Expect(c.List(ctx, listeners, client.InNamespace(namespace))).To(Succeed())
+		Expect(listeners.Items).To(BeEmpty())
+		Expect(apierrors.IsNotFound(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "zk-server-a-0"}, &corev1.Service{}))).To(BeTrue())
+	})
+})
+
+// listenerVolumeHandler registers listener volumes on the build context, as a product does, before
+// the base handler builds the StatefulSet.
+type listenerVolumeHandler struct {
+	*reconciler.BaseRoleGroupHandler[*testutil.MockCluster]
+	volumes []*listener.VolumeRegistration
+}
+
+func (h *listenerVolumeHandler) BuildResources(
+	ctx context.Context,
+	c client.Client,
+	cr *testutil.MockCluster,
+	buildCtx *reconciler.RoleGroupBuildContext,
+) (*reconciler.RoleGroupResources, error) {
+	if len(h.volumes) > 0 {
+		buildCtx.VolumeProviders = append(buildCtx.VolumeProviders, listener.NewProvisioner().RegisterVolume(h.volumes...))
+	}
+	return h.BaseRoleGroupHandler.BuildResources(ctx, c, cr, buildCtx)
+}
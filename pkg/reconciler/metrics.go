@@ -17,32 +17,74 @@ limitations under the License.
 package reconciler
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/common"
 )
 
-// The framework exports metrics for exactly one thing: the orphan cleanup state machine. That is
-// deliberate, and the boundary is worth stating so this file does not grow into a second, worse
-// copy of tools that already exist.
+// The framework exports two groups of metrics, and the boundary is worth stating so this file does
+// not grow into a second, worse copy of tools that already exist.
 //
-//   - controller-runtime already exports reconcile counts, error counts and durations
-//     (controller_runtime_reconcile_*). Re-exporting those per cluster would add cardinality and no
-//     information.
-//   - kube-state-metrics already turns CR status conditions into metrics once it is configured for
-//     the product's CRD. Available/Progressing/Degraded belong there, not here.
+//   - The orphan cleanup state machine. It is internal to this SDK: it runs across many reconciles,
+//     records its progress in annotations on the objects it is retiring, and reports the rest
+//     through log lines. A role group stuck mid-teardown for three days produces no error, no
+//     failing reconcile and no condition transition — only an annotation nobody reads.
+//   - The reconcile pass, broken down the way only this SDK can break it down: per cluster and per
+//     phase, per applied kind, and the cluster's own conditions. controller-runtime's
+//     controller_runtime_reconcile_* series are per controller, so they say that reconciles are
+//     slow or failing but not for which cluster, or in which step. The condition gauge exists
+//     because kube-state-metrics needs per-CRD configuration before it exports a condition, which
+//     is a per-deployment step an operator author cannot take on the user's behalf — and "Degraded
+//     for 30m" is the first alert anyone writes.
 //
-// What neither covers is the cleanup state machine, because it is internal to this SDK: it runs
-// across many reconciles, records its progress in annotations on the objects it is retiring, and
-// reports the rest through log lines. A role group stuck mid-teardown for three days produces no
-// error, no failing reconcile and no condition transition — only an annotation nobody reads.
+// Every series is labelled with the cluster, and every label beyond that has a bounded value set
+// the framework chooses (a phase, a kind, a result, a condition type). Nothing is labelled with a
+// role group or resource name, whose cardinality is the user's.
 const (
 	metricsNamespace = "operator_go"
 
 	// Every series here is scoped to one cluster CR, so both labels are always this pair.
 	metricLabelNamespace = "namespace"
 	metricLabelCluster   = "cluster"
+	metricLabelPhase     = "phase"
+	metricLabelKind      = "kind"
+	metricLabelResult    = "result"
+	metricLabelCondition = "condition"
+)
+
+// The phases of a reconcile pass, as they appear in the phase label. role_group_build and apply are
+// observed once per role group, extensions once before the roles and once after, and the others
+// once per pass.
+const (
+	phaseExtensions     = "extensions"
+	phaseDependencies   = "dependencies"
+	phaseRoleGroupBuild = "role_group_build"
+	phaseApply          = "apply"
+	phaseCleanup        = "cleanup"
+	phaseHealth         = "health"
+	phaseStatus         = "status"
+)
+
+// The results of an apply, as they appear in the result label.
+const (
+	applyResultCreated   = "created"
+	applyResultUpdated   = "updated"
+	applyResultUnchanged = "unchanged"
 )
 
+// conditionMetricTypes are the conditions ClusterCondition mirrors: the three workload conditions,
+// which answer the three questions an alert is written against (see HealthManager.Check).
+var conditionMetricTypes = []string{
+	string(v1alpha1.ConditionAvailable),
+	string(v1alpha1.ConditionDegraded),
+	string(v1alpha1.ConditionProgressing),
+}
+
 var (
 	// OrphanCleanupPending is the number of role groups whose resources the framework has not
 	// finished reclaiming, per cluster.
@@ -74,12 +116,77 @@ var (
 		Help: "Number of orphaned StatefulSets deleted with pods still terminating, because the " +
 			"ordered drain exceeded DrainTimeout.",
 	}, []string{metricLabelNamespace, metricLabelCluster})
+
+	// ReconcilePhaseDuration is the time a reconcile pass spends in each phase, per cluster. A slow
+	// pass in controller-runtime's own histogram becomes a question with an answer: a cleanup
+	// waiting on a drain, a role group whose build calls out to a dependency, a status write
+	// fighting conflicts.
+	ReconcilePhaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_phase_duration_seconds",
+		Help: "Time spent in each phase of a reconcile pass. role_group_build and apply are " +
+			"observed once per role group, extensions once per hook point, the others once per pass.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{metricLabelNamespace, metricLabelCluster, metricLabelPhase})
+
+	// ReconcilePhaseErrors counts the phases that failed. A wait is not a failure and is not
+	// counted, and neither is throttling, which ReconcileRateLimited counts on its own.
+	ReconcilePhaseErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_phase_errors_total",
+		Help:      "Number of reconcile phases that failed, by phase.",
+	}, []string{metricLabelNamespace, metricLabelCluster, metricLabelPhase})
+
+	// ResourceApplies counts every apply the framework issues, by kind and by what the API server
+	// did with it. A steady-state cluster is all "unchanged"; a cluster whose "updated" never
+	// settles is one whose desired state flaps, which nothing else in the API reports.
+	ResourceApplies = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "resource_applies_total",
+		Help:      "Number of resources applied, by kind and result (created, updated, unchanged).",
+	}, []string{metricLabelNamespace, metricLabelCluster, metricLabelKind, metricLabelResult})
+
+	// ImmutableFieldsIgnored counts the applies that kept a live immutable field over the spec's
+	// value — each one an ImmutableFieldIgnored Warning event. The event is aggregated by
+	// Kubernetes and expires; the counter is what an alert on "a user's change is not being
+	// applied" keys off.
+	ImmutableFieldsIgnored = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "immutable_fields_ignored_total",
+		Help:      "Number of applies that kept a live immutable field instead of the desired value, by kind.",
+	}, []string{metricLabelNamespace, metricLabelCluster, metricLabelKind})
+
+	// ReconcileRateLimited counts the passes that backed off on a 429. Throttling deliberately sets
+	// no condition and emits no event (see Reconcile), so this is its only surface.
+	ReconcileRateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_rate_limited_total",
+		Help:      "Number of reconcile passes that backed off because the API server returned 429.",
+	}, []string{metricLabelNamespace, metricLabelCluster})
+
+	// ClusterCondition mirrors each cluster's Available, Degraded and Progressing conditions: 1
+	// when the condition is True, 0 when it is False, Unknown or not yet set. It is written after
+	// every pass that writes the status, so it never reports a condition the CR does not carry.
+	ClusterCondition = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "cluster_condition",
+		Help:      "Whether the cluster's condition is True (1) or not (0), for Available, Degraded and Progressing.",
+	}, []string{metricLabelNamespace, metricLabelCluster, metricLabelCondition})
 )
 
 func init() {
 	// The manager's registry, so these appear on the metrics endpoint an operator already serves
 	// without any wiring in main.go.
-	metrics.Registry.MustRegister(OrphanCleanupPending, OrphanDrainTimeouts)
+	metrics.Registry.MustRegister(
+		OrphanCleanupPending,
+		OrphanDrainTimeouts,
+		ReconcilePhaseDuration,
+		ReconcilePhaseErrors,
+		ResourceApplies,
+		ImmutableFieldsIgnored,
+		ReconcileRateLimited,
+		ClusterCondition,
+	)
 }
 
 // forgetClusterMetrics drops every series belonging to one cluster. Without it a deleted CR leaves
@@ -89,4 +196,37 @@ func forgetClusterMetrics(namespace, cluster string) {
 	labels := prometheus.Labels{metricLabelNamespace: namespace, metricLabelCluster: cluster}
 	OrphanCleanupPending.Delete(labels)
 	OrphanDrainTimeouts.Delete(labels)
+	ReconcileRateLimited.Delete(labels)
+	// The rest carry a further label, so each cluster has several series in them.
+	ReconcilePhaseDuration.DeletePartialMatch(labels)
+	ReconcilePhaseErrors.DeletePartialMatch(labels)
+	ResourceApplies.DeletePartialMatch(labels)
+	ImmutableFieldsIgnored.DeletePartialMatch(labels)
+	ClusterCondition.DeletePartialMatch(labels)
+}
+
+// observePhase records one phase of a reconcile pass: its duration, and a failure when err is one.
+// A wait and a 429 are not failures of the phase — the first is the product saying "not yet", the
+// second is counted by ReconcileRateLimited.
+func observePhase(namespace, cluster, phase string, start time.Time, err error) {
+	ReconcilePhaseDuration.WithLabelValues(namespace, cluster, phase).Observe(time.Since(start).Seconds())
+	if err == nil || IsRateLimitError(err) {
+		return
+	}
+	if _, waiting := common.WaitingErrors(err); waiting {
+		return
+	}
+	ReconcilePhaseErrors.WithLabelValues(namespace, cluster, phase).Inc()
+}
+
+// recordConditionMetrics mirrors the cluster's workload conditions into ClusterCondition.
+func recordConditionMetrics(namespace, cluster string, status *v1alpha1.GenericClusterStatus) {
+	for _, conditionType := range conditionMetricTypes {
+		value := 0.0
+		if condition := status.GetCondition(v1alpha1.ConditionType(conditionType)); condition != nil &&
+			condition.Status == metav1.ConditionTrue {
+			value = 1
+		}
+		ClusterCondition.WithLabelValues(namespace, cluster, conditionType).Set(value)
+	}
 }
@@ -0,0 +1,128 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	testutilmetrics "github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/reconciler"
+	"github.com/zncdatadev/operator-go/pkg/testutil"
+)
+
+var _ = Describe("Reconcile metrics", func() {
+	const namespace = "default"
+
+	var (
+		scheme *runtime.Scheme
+		c      client.Client
+		name   string
+	)
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(testutil.AddToScheme(scheme)).To(Succeed())
+
+		// The collectors are process-wide, so every spec gets a cluster of its own.
+		name = uniqueCRName("metrics")
+		cr := testutil.NewMockCluster(name, namespace).WithRoles(map[string]v1alpha1.RoleSpec{
+			"server": {RoleGroups: map[string]v1alpha1.RoleGroupSpec{"a": {Replicas: ptr.To[int32](1)}}},
+		})
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(cr).WithStatusSubresource(cr).Build()
+	})
+
+	reconcileOnce := func(handler reconciler.RoleGroupHandler[*testutil.MockCluster]) error {
+		GinkgoHelper()
+		r, err := reconciler.NewGenericReconciler(&reconciler.GenericReconcilerConfig[*testutil.MockCluster]{
+			Client:           c,
+			Scheme:           scheme,
+			ImageResolution:  reconciler.ImageResolution{Defaults: v1alpha1.ImageSpec{Custom: "test-image:latest"}},
+			Recorder:         record.NewFakeRecorder(100),
+			RoleGroupHandler: handler,
+			Prototype:        testutil.NewMockCluster("proto", namespace),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}})
+		return err
+	}
+	condition := func(conditionType string) float64 {
+		return testutilmetrics.ToFloat64(reconciler.ClusterCondition.WithLabelValues(namespace, name, conditionType))
+	}
+
+	It("times every phase, counts applies by kind and mirrors the workload conditions", func() {
+		Expect(reconcileOnce(reconciler.NewBaseRoleGroupHandler[*testutil.MockCluster](scheme))).To(Succeed())
+
+		for _, phase := range []string{"extensions", "dependencies", "role_group_build", "apply", "cleanup", "health", "status"} {
+			histogram, err := reconciler.ReconcilePhaseDuration.GetMetricWithLabelValues(namespace, name, phase)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(histogram.(prometheus.Histogram)).NotTo(BeNil())
+			Expect(testutilmetrics.CollectAndCount(histogram.(prometheus.Collector))).To(Equal(1), "phase %s", phase)
+		}
+		Expect(testutilmetrics.ToFloat64(
+			reconciler.ResourceApplies.WithLabelValues(namespace, name, "StatefulSet", "created"))).To(Equal(1.0))
+		Expect(testutilmetrics.ToFloat64(
+			reconciler.ReconcilePhaseErrors.WithLabelValues(namespace, name, "role_group_build"))).To(BeZero())
+
+		// No pod is ready on a fake client, so the cluster is not Available yet — and not Degraded.
+		Expect(condition("Available")).To(BeZero())
+		Expect(condition("Degraded")).To(BeZero())
+	})
+
+	It("counts a failed role group build and reports the cluster Degraded", func() {
+		Expect(reconcileOnce(&failingBuildHandler{})).NotTo(Succeed())
+
+		Expect(testutilmetrics.ToFloat64(
+			reconciler.ReconcilePhaseErrors.WithLabelValues(namespace, name, "role_group_build"))).To(Equal(1.0))
+		Expect(condition("Degraded")).To(Equal(1.0))
+	})
+
+	It("drops every series of a deleted cluster, whatever further labels it carries", func() {
+		Expect(reconcileOnce(reconciler.NewBaseRoleGroupHandler[*testutil.MockCluster](scheme))).To(Succeed())
+		applies := testutilmetrics.CollectAndCount(reconciler.ResourceApplies)
+		conditions := testutilmetrics.CollectAndCount(reconciler.ClusterCondition)
+		Expect(c.Delete(ctx, testutil.NewMockCluster(name, namespace))).To(Succeed())
+
+		Expect(reconcileOnce(reconciler.NewBaseRoleGroupHandler[*testutil.MockCluster](scheme))).To(Succeed())
+
+		Expect(testutilmetrics.CollectAndCount(reconciler.ResourceApplies)).To(BeNumerically("<", applies))
+		Expect(testutilmetrics.CollectAndCount(reconciler.ClusterCondition)).To(Equal(conditions - 3))
+	})
+})
+
+// failingBuildHandler fails every role group build.
+type failingBuildHandler struct{}
+
+func (*failingBuildHandler) BuildResources(
+	context.Context, client.Client, *testutil.MockCluster, *reconciler.RoleGroupBuildContext,
+) (*reconciler.RoleGroupResources, error) {
+	return nil, errors.New("build failed")
+}
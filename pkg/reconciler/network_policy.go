@@ -0,0 +1,298 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	"github.com/zncdatadev/operator-go/pkg/constant"
+)
+
+// LabelNetworkPolicy marks a NetworkPolicy as the framework's per-role-group slot
+// (RoleGroupResources.NetworkPolicy). Only objects carrying it are reclaimed when a role stops
+// declaring a policy, for the same reason LabelMetricsService exists: "<cluster>-<role>-<group>" is
+// also a name a product may use for a NetworkPolicy of its own, shipped as an extra resource.
+const LabelNetworkPolicy = "networking." + constant.KubedoopDomain + "/role-group"
+
+// NetworkPolicyDeclaration states which of a role's Service ports may be reached, and by whom. The
+// framework turns it into one NetworkPolicy per role group, selecting exactly that role group's
+// pods (see GenericReconcilerConfig.NetworkPolicies).
+//
+// Ports are named by their RoleDeclaration.ServicePorts name, because that is the one list a role
+// already keeps of what it serves. A Service port that is named in neither list is closed: the
+// point of a policy in a default-deny namespace is that forgetting a port fails visibly, not that
+// every port is open until someone thinks of it.
+type NetworkPolicyDeclaration struct {
+	// ClientPorts are open to any source — other namespaces and clients outside the cluster
+	// included. They are the ports behind the client-facing Service.
+	ClientPorts []string
+
+	// PeerPorts are open only to pods of this role, in this cluster — a quorum port, a replication
+	// port — and to the roles listed in PeerRoles.
+	PeerPorts []string
+
+	// PeerRoles are the OTHER roles of the same cluster that may reach PeerPorts: a DataNode
+	// registering with its NameNode, a worker with its coordinator. Each must be a role in the same
+	// catalog; an unknown name is a validation error rather than a rule selecting nothing.
+	PeerRoles []string
+
+	// IsolateEgress adds Egress to the policy. Left false, the policy restricts ingress only and the
+	// pods' egress is whatever the namespace allows.
+	//
+	// It is opt-in because a policy with Egress in its types isolates the pods it selects, even in a
+	// namespace with no egress policy at all: every destination the product dials and this block
+	// does not name — an S3 endpoint, an external ZooKeeper — would stop working. When set, the
+	// framework allows DNS, every pod of the same cluster, the Vector aggregator when the log
+	// pipeline is active, and ExtraEgress.
+	IsolateEgress bool
+
+	// ExtraEgress are further egress rules, appended as given. Only read when IsolateEgress is set.
+	ExtraEgress []networkingv1.NetworkPolicyEgressRule
+}
+
+// validate checks the declaration against the role's own ServicePorts. Role names in PeerRoles are
+// checked against the catalog by validateNetworkPolicyPeers, which has it in hand.
+func (n *NetworkPolicyDeclaration) validate(servicePorts []corev1.ServicePort) []string {
+	if n == nil {
+		return nil
+	}
+	var problems []string
+	declared := make(map[string]struct{}, len(servicePorts))
+	for _, p := range servicePorts {
+		declared[p.Name] = struct{}{}
+	}
+	client := make(map[string]struct{}, len(n.ClientPorts))
+	for _, name := range n.ClientPorts {
+		if _, ok := declared[name]; !ok {
+			problems = append(problems, fmt.Sprintf("networkPolicy.clientPorts names %q, which is not a servicePort", name))
+		}
+		client[name] = struct{}{}
+	}
+	for _, name := range n.PeerPorts {
+		if _, ok := declared[name]; !ok {
+			problems = append(problems, fmt.Sprintf("networkPolicy.peerPorts names %q, which is not a servicePort", name))
+		}
+		// A port in both lists is open to everyone, so the peer entry states a restriction the
+		// policy cannot enforce.
+		if _, ok := client[name]; ok {
+			problems = append(problems, fmt.Sprintf("networkPolicy port %q is both a client and a peer port", name))
+		}
+	}
+	if len(n.PeerRoles) > 0 && len(n.PeerPorts) == 0 {
+		problems = append(problems, "networkPolicy.peerRoles are declared but peerPorts are empty, so they may reach nothing")
+	}
+	return problems
+}
+
+// validateNetworkPolicyPeers checks every PeerRoles entry of a catalog against the catalog's own
+// role names. It runs once per pass, after each declaration validated on its own.
+func validateNetworkPolicyPeers(catalog RoleCatalog) []error {
+	var errs []error
+	for _, roleName := range slices.Sorted(maps.Keys(catalog)) {
+		np := catalog[roleName].NetworkPolicy
+		if np == nil {
+			continue
+		}
+		for _, peer := range np.PeerRoles {
+			if _, ok := catalog[peer]; !ok {
+				errs = append(errs, NewValidationError("RoleProvider", roleName, "",
+					fmt.Errorf("networkPolicy.peerRoles names %q, which this product does not declare", peer)))
+			}
+		}
+	}
+	return errs
+}
+
+// buildRoleGroupNetworkPolicy builds the role group's NetworkPolicy from its role's declaration and
+// the resources the handler built. Nil means the role declares no policy, or there is no workload
+// to select.
+//
+// It reads the built resources rather than the declaration alone for two things the declaration
+// does not know: the pod selector is the StatefulSet's own — whatever labels the handler chose — so
+// the policy selects exactly the pods it governs; and the Prometheus scrape rule follows the
+// MetricsService, which a product builds itself and may turn off.
+//
+// Peers are selected by app.kubernetes.io/instance and app.kubernetes.io/component, which
+// BaseRoleGroupHandler stamps on every pod template. A product building its own StatefulSet has to
+// carry them too for PeerPorts to admit anything.
+func buildRoleGroupNetworkPolicy(buildCtx *RoleGroupBuildContext, resources *RoleGroupResources) *networkingv1.NetworkPolicy {
+	decl := buildCtx.Declaration.NetworkPolicy
+	if decl == nil || resources.StatefulSet == nil || resources.StatefulSet.Spec.Selector == nil {
+		return nil
+	}
+
+	servicePorts := make(map[string]corev1.ServicePort, len(buildCtx.Declaration.ServicePorts))
+	for _, p := range buildCtx.Declaration.ServicePorts {
+		servicePorts[p.Name] = p
+	}
+	portsNamed := func(names []string) []networkingv1.NetworkPolicyPort {
+		ports := make([]networkingv1.NetworkPolicyPort, 0, len(names))
+		for _, name := range names {
+			if p, ok := servicePorts[name]; ok {
+				ports = append(ports, podPort(p))
+			}
+		}
+		return ports
+	}
+
+	var ingress []networkingv1.NetworkPolicyIngressRule
+	if ports := portsNamed(decl.ClientPorts); len(ports) > 0 {
+		ingress = append(ingress, networkingv1.NetworkPolicyIngressRule{Ports: ports})
+	}
+	if ports := portsNamed(decl.PeerPorts); len(ports) > 0 {
+		from := []networkingv1.NetworkPolicyPeer{rolePeer(buildCtx.ClusterName, buildCtx.RoleName)}
+		for _, role := range decl.PeerRoles {
+			from = append(from, rolePeer(buildCtx.ClusterName, role))
+		}
+		ingress = append(ingress, networkingv1.NetworkPolicyIngressRule{Ports: ports, From: from})
+	}
+	// Prometheus runs in a namespace the framework cannot name, so the scrape port is open to any
+	// source — which is what the metrics Service already advertises through its annotations.
+	if svc := resources.MetricsService; svc != nil && len(svc.Spec.Ports) > 0 {
+		ports := make([]networkingv1.NetworkPolicyPort, 0, len(svc.Spec.Ports))
+		for _, p := range svc.Spec.Ports {
+			ports = append(ports, podPort(p))
+		}
+		ingress = append(ingress, networkingv1.NetworkPolicyIngressRule{Ports: ports})
+	}
+
+	policyTypes := []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+	var egress []networkingv1.NetworkPolicyEgressRule
+	if decl.IsolateEgress {
+		policyTypes = append(policyTypes, networkingv1.PolicyTypeEgress)
+		egress = roleGroupEgress(buildCtx, decl)
+	}
+
+	labels := maps.Clone(resources.StatefulSet.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[LabelNetworkPolicy] = buildCtx.RoleGroupName
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      buildCtx.ResourceName,
+			Namespace: buildCtx.ClusterNamespace,
+			Labels:    labels,
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: *resources.StatefulSet.Spec.Selector.DeepCopy(),
+			PolicyTypes: policyTypes,
+			Ingress:     ingress,
+			Egress:      egress,
+		},
+	}
+}
+
+// roleGroupEgress returns the egress a role group needs from the framework's side: name resolution,
+// its own cluster, and the Vector aggregator and sinks it ships logs to.
+func roleGroupEgress(buildCtx *RoleGroupBuildContext, decl *NetworkPolicyDeclaration) []networkingv1.NetworkPolicyEgressRule {
+	dns := intstr.FromInt32(53)
+	rules := []networkingv1.NetworkPolicyEgressRule{
+		{Ports: []networkingv1.NetworkPolicyPort{
+			{Protocol: ptr.To(corev1.ProtocolUDP), Port: &dns},
+			{Protocol: ptr.To(corev1.ProtocolTCP), Port: &dns},
+		}},
+		{To: []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{
+			constant.LabelKubernetesInstance: buildCtx.ClusterName,
+		}}}}},
+	}
+	// The aggregator is reached through a discovery address, not a selector, so only its port is
+	// known. A vectorLogPipelineActive check is implied: the address is resolved only when the
+	// sidecar will run.
+	if addr := buildCtx.VectorAggregatorAddress; addr != "" {
+		if _, portStr, err := net.SplitHostPort(addr); err == nil {
+			if port, err := strconv.ParseInt(portStr, 10, 32); err == nil {
+				p := intstr.FromInt32(int32(port))
+				rules = append(rules, networkingv1.NetworkPolicyEgressRule{
+					Ports: []networkingv1.NetworkPolicyPort{{Protocol: ptr.To(corev1.ProtocolTCP), Port: &p}},
+				})
+			}
+		}
+	}
+	// Sinks connect to whatever they name, usually outside the cluster's namespace, so like the
+	// aggregator only their ports can be opened.
+	var sinkPorts []networkingv1.NetworkPolicyPort
+	seen := map[int32]bool{}
+	for _, sink := range buildCtx.VectorSinks {
+		for _, port := range sink.Requirements().EgressPorts {
+			if !seen[port] {
+				seen[port] = true
+				p := intstr.FromInt32(port)
+				sinkPorts = append(sinkPorts, networkingv1.NetworkPolicyPort{Protocol: ptr.To(corev1.ProtocolTCP), Port: &p})
+			}
+		}
+	}
+	if len(sinkPorts) > 0 {
+		rules = append(rules, networkingv1.NetworkPolicyEgressRule{Ports: sinkPorts})
+	}
+	return append(rules, decl.ExtraEgress...)
+}
+
+// podPort is the pod-side port behind a Service port. A NetworkPolicy is enforced on the pod, so a
+// Service that maps 80 to 8080 must be allowed on 8080; a named targetPort is passed through,
+// which the API resolves against the pod's container ports.
+func podPort(p corev1.ServicePort) networkingv1.NetworkPolicyPort {
+	protocol := p.Protocol
+	if protocol == "" {
+		protocol = corev1.ProtocolTCP
+	}
+	port := p.TargetPort
+	if port.Type == intstr.Int && port.IntVal == 0 {
+		port = intstr.FromInt32(p.Port)
+	}
+	return networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &port}
+}
+
+// rolePeer selects every pod of one role of one cluster.
+func rolePeer(clusterName, roleName string) networkingv1.NetworkPolicyPeer {
+	return networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{
+		constant.LabelKubernetesInstance:  clusterName,
+		constant.LabelKubernetesComponent: roleName,
+	}}}
+}
+
+// reclaimNetworkPolicy deletes the role group's NetworkPolicy when its role stops declaring one, but
+// only when the live object carries the slot label this apply path stamps. See LabelNetworkPolicy.
+func (r *GenericReconciler[CR]) reclaimNetworkPolicy(ctx context.Context, buildCtx *RoleGroupBuildContext, ownerUID types.UID) error {
+	np := &networkingv1.NetworkPolicy{}
+	key := types.NamespacedName{Namespace: buildCtx.ClusterNamespace, Name: buildCtx.ResourceName}
+	if err := r.client.Get(ctx, key, np); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return r.apiError(err)
+	}
+	if np.Labels[LabelNetworkPolicy] != buildCtx.RoleGroupName {
+		return nil
+	}
+	_, err := deleteOwned[networkingv1.NetworkPolicy](ctx, r.cleaner, key.Namespace, key.Name, ownerUID, buildCtx.ClusterName)
+	return err
+}
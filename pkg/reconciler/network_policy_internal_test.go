@@ -0,0 +1,169 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/zncdatadev/operator-go/pkg/constant"
+	"github.com/zncdatadev/operator-go/pkg/vector"
+)
+
+var _ = Describe("buildRoleGroupNetworkPolicy", func() {
+	var (
+		buildCtx  *RoleGroupBuildContext
+		resources *RoleGroupResources
+	)
+
+	BeforeEach(func() {
+		buildCtx = &RoleGroupBuildContext{
+			ClusterName:      "zk",
+			ClusterNamespace: "default",
+			RoleName:         "server",
+			RoleGroupName:    "default",
+			ResourceName:     "zk-server-default",
+			Declaration: RoleDeclaration{
+				ServicePorts: []corev1.ServicePort{
+					{Name: "client", Port: 2181},
+					{Name: "quorum", Port: 2888, TargetPort: intstr.FromString("quorum")},
+					{Name: "admin", Port: 8080},
+				},
+				NetworkPolicy: &NetworkPolicyDeclaration{
+					ClientPorts: []string{"client"},
+					PeerPorts:   []string{"quorum"},
+					PeerRoles:   []string{"observer"},
+				},
+			},
+		}
+		selector := map[string]string{constant.LabelKubernetesInstance: "zk", "marker": "true"}
+		resources = &RoleGroupResources{StatefulSet: &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"team": "data"}},
+			Spec:       appsv1.StatefulSetSpec{Selector: &metav1.LabelSelector{MatchLabels: selector}},
+		}}
+	})
+
+	It("is nil when the role declares no policy", func() {
+		buildCtx.Declaration.NetworkPolicy = nil
+		Expect(buildRoleGroupNetworkPolicy(buildCtx, resources)).To(BeNil())
+	})
+
+	It("opens client ports to anyone and peer ports to the role and its peer roles", func() {
+		np := buildRoleGroupNetworkPolicy(buildCtx, resources)
+		Expect(np.Name).To(Equal("zk-server-default"))
+		Expect(np.Labels).To(HaveKeyWithValue("team", "data"))
+		Expect(np.Labels).To(HaveKeyWithValue(LabelNetworkPolicy, "default"))
+		Expect(np.Spec.PodSelector.MatchLabels).To(Equal(resources.StatefulSet.Spec.Selector.MatchLabels))
+		Expect(np.Spec.PolicyTypes).To(ConsistOf(networkingv1.PolicyTypeIngress))
+		Expect(np.Spec.Egress).To(BeEmpty())
+
+		Expect(np.Spec.Ingress).To(HaveLen(2))
+		client := np.Spec.Ingress[0]
+		Expect(client.From).To(BeEmpty())
+		Expect(client.Ports).To(HaveLen(1))
+		Expect(client.Ports[0].Port.IntValue()).To(Equal(2181))
+		Expect(*client.Ports[0].Protocol).To(Equal(corev1.ProtocolTCP))
+
+		peer := np.Spec.Ingress[1]
+		// The pod-side port: a named targetPort is passed through rather than the Service port.
+		Expect(peer.Ports[0].Port.String()).To(Equal("quorum"))
+		Expect(peer.From).To(HaveLen(2))
+		Expect(peer.From[0].PodSelector.MatchLabels).To(HaveKeyWithValue(constant.LabelKubernetesComponent, "server"))
+		Expect(peer.From[1].PodSelector.MatchLabels).To(HaveKeyWithValue(constant.LabelKubernetesComponent, "observer"))
+	})
+
+	It("lets anyone scrape the metrics Service's ports", func() {
+		resources.MetricsService = &corev1.Service{Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+			{Name: "metrics", Port: 9505, TargetPort: intstr.FromInt32(9404)},
+		}}}
+		np := buildRoleGroupNetworkPolicy(buildCtx, resources)
+		Expect(np.Spec.Ingress).To(HaveLen(3))
+		Expect(np.Spec.Ingress[2].From).To(BeEmpty())
+		Expect(np.Spec.Ingress[2].Ports[0].Port.IntValue()).To(Equal(9404))
+	})
+
+	It("allows DNS, the cluster and the Vector aggregator when egress is isolated", func() {
+		buildCtx.Declaration.NetworkPolicy.IsolateEgress = true
+		buildCtx.VectorAggregatorAddress = "vector-aggregator.logging:6000"
+		extra := networkingv1.NetworkPolicyEgressRule{To: []networkingv1.NetworkPolicyPeer{
+			{IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/8"}},
+		}}
+		buildCtx.Declaration.NetworkPolicy.ExtraEgress = []networkingv1.NetworkPolicyEgressRule{extra}
+
+		np := buildRoleGroupNetworkPolicy(buildCtx, resources)
+		Expect(np.Spec.PolicyTypes).To(ConsistOf(networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress))
+		Expect(np.Spec.Egress).To(HaveLen(4))
+		Expect(np.Spec.Egress[0].Ports[0].Port.IntValue()).To(Equal(53))
+		Expect(np.Spec.Egress[1].To[0].PodSelector.MatchLabels).To(Equal(map[string]string{
+			constant.LabelKubernetesInstance: "zk",
+		}))
+		Expect(np.Spec.Egress[2].Ports[0].Port.IntValue()).To(Equal(6000))
+		Expect(np.Spec.Egress[3]).To(Equal(extra))
+	})
+
+	It("opens the ports of the Vector sinks, once each", func() {
+		buildCtx.Declaration.NetworkPolicy.IsolateEgress = true
+		buildCtx.VectorSinks = []vector.Sink{
+			&vector.LokiSink{Endpoint: "https://loki.example.com"},
+			&vector.ElasticsearchSink{Endpoints: []string{"https://es-0:9200", "https://es-1:9200"}},
+		}
+
+		np := buildRoleGroupNetworkPolicy(buildCtx, resources)
+		Expect(np.Spec.Egress).To(HaveLen(3))
+		var ports []int
+		for _, port := range np.Spec.Egress[2].Ports {
+			ports = append(ports, port.Port.IntValue())
+		}
+		Expect(ports).To(Equal([]int{443, 9200}))
+	})
+})
+
+var _ = Describe("NetworkPolicyDeclaration validation", func() {
+	ports := []corev1.ServicePort{{Name: "client", Port: 2181}, {Name: "quorum", Port: 2888}}
+
+	DescribeTable("rejects declarations the policy cannot honour",
+		func(np NetworkPolicyDeclaration, message string) {
+			decl := RoleDeclaration{
+				ContainerPorts: []corev1.ContainerPort{{Name: "client", ContainerPort: 2181}},
+				ServicePorts:   ports,
+				NetworkPolicy:  &np,
+			}
+			Expect(decl.Validate("server")).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("unknown client port", NetworkPolicyDeclaration{ClientPorts: []string{"http"}}, `"http", which is not a servicePort`),
+		Entry("unknown peer port", NetworkPolicyDeclaration{PeerPorts: []string{"election"}}, `"election", which is not a servicePort`),
+		Entry("port in both lists", NetworkPolicyDeclaration{ClientPorts: []string{"client"}, PeerPorts: []string{"client"}}, "both a client and a peer port"),
+		Entry("peer roles without peer ports", NetworkPolicyDeclaration{PeerRoles: []string{"observer"}}, "may reach nothing"),
+	)
+
+	It("rejects a peer role the catalog does not declare", func() {
+		catalog := RoleCatalog{
+			"server": {ServicePorts: ports, NetworkPolicy: &NetworkPolicyDeclaration{
+				PeerPorts: []string{"quorum"}, PeerRoles: []string{"observer", "obsever"},
+			}},
+			"observer": {},
+		}
+		errs := validateNetworkPolicyPeers(catalog)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0]).To(MatchError(ContainSubstring(`"obsever"`)))
+	})
+})
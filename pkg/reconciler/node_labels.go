@@ -0,0 +1,306 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/zncdatadev/operator-go/pkg/constant"
+)
+
+// NodeLabelsVolumeName is the downward-API volume that projects a pod's published node labels
+// (RoleDeclaration.NodeLabels) into NodeLabelsFile. Reserved like ConfigVolumeName.
+const NodeLabelsVolumeName = "node-labels"
+
+// NodeLabelsFile is the file a pod reads its node's labels from: one "key=value" line per
+// declared key, sorted by key, with an empty value for a key the node does not carry. It is empty
+// until the framework has published the labels and never empty afterwards, which is what the
+// NodeLabelsInitContainerName container waits for.
+const NodeLabelsFile = constant.KubedoopNodeLabelsDir + "node-labels"
+
+// NodeLabelsInitContainerName is the init container that holds a pod until its node labels are
+// published. It runs first, so the product's own init containers already see the file.
+const NodeLabelsInitContainerName = "wait-for-node-labels"
+
+// nodeLabelsWaitSeconds bounds the wait. A pod that runs out of it fails its init container with a
+// message naming the annotation, and the kubelet retries it with back-off — the visible outcome
+// when the operator lacks the RBAC to publish, rather than a pod that hangs in Init forever.
+const nodeLabelsWaitSeconds = 300
+
+// nodeLabelsRetryInterval is how soon a pass that left a pod unscheduled, and so without its node
+// labels, comes back. Pods are not watched, and the health check interval is far longer than a pod
+// should sit in its init container.
+//
+// The fast retry is bounded by the pod's own wait: a pod unscheduled for longer than
+// nodeLabelsWaitSeconds — no node fits, a quota it cannot meet — is left to the health check
+// cadence, so a cluster that cannot schedule does not reconcile every 5s indefinitely.
+const nodeLabelsRetryInterval = 5 * time.Second
+
+// injectNodeLabels prepares a role group's pod template for published node labels: the
+// downward-API volume, a read-only mount of it in every container, and the init container that
+// waits for the file. The init container reuses the primary container's image, pull policy and
+// security context, so it needs no image of its own and passes the same Pod Security level.
+//
+// It runs on the finished template, after the handler, so a product that builds its own
+// StatefulSet gets it too.
+func injectNodeLabels(sts *appsv1.StatefulSet, mainContainerName string) error {
+	podSpec := &sts.Spec.Template.Spec
+	main := primaryContainer(podSpec, mainContainerName)
+	if main == nil {
+		return fmt.Errorf("the pod template has no container to take the %s init container's image from",
+			NodeLabelsInitContainerName)
+	}
+
+	wait := corev1.Container{
+		Name:            NodeLabelsInitContainerName,
+		Image:           main.Image,
+		ImagePullPolicy: main.ImagePullPolicy,
+		Command:         []string{"/bin/sh", "-c"},
+		Args:            []string{nodeLabelsWaitScript()},
+		SecurityContext: main.SecurityContext.DeepCopy(),
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("10m"),
+				corev1.ResourceMemory: resource.MustParse("16Mi"),
+			},
+			Limits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("100m"),
+				corev1.ResourceMemory: resource.MustParse("32Mi"),
+			},
+		},
+	}
+	podSpec.InitContainers = append([]corev1.Container{wait}, podSpec.InitContainers...)
+
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: NodeLabelsVolumeName,
+		VolumeSource: corev1.VolumeSource{DownwardAPI: &corev1.DownwardAPIVolumeSource{
+			Items: []corev1.DownwardAPIVolumeFile{{
+				Path:     strings.TrimPrefix(NodeLabelsFile, constant.KubedoopNodeLabelsDir),
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: annotationFieldPath(constant.AnnotationNodeLabels)},
+			}},
+		}},
+	})
+	forEachContainer(&sts.Spec.Template, func(c *corev1.Container) {
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name:      NodeLabelsVolumeName,
+			MountPath: constant.KubedoopNodeLabelsDir,
+			ReadOnly:  true,
+		})
+	})
+	return nil
+}
+
+// primaryContainer returns the named container, or the first one when the name is empty or not
+// found — the builder puts the primary container first.
+func primaryContainer(podSpec *corev1.PodSpec, name string) *corev1.Container {
+	for i := range podSpec.Containers {
+		if podSpec.Containers[i].Name == name {
+			return &podSpec.Containers[i]
+		}
+	}
+	if len(podSpec.Containers) == 0 {
+		return nil
+	}
+	return &podSpec.Containers[0]
+}
+
+func nodeLabelsWaitScript() string {
+	return fmt.Sprintf(`i=0
+until [ -s %[1]s ]; do
+  if [ "$i" -ge %[2]d ]; then
+    echo "node labels were not published to %[1]s within %[2]ds; check that the operator may get nodes and patch pods (annotation %[3]s)" >&2
+    exit 1
+  fi
+  i=$((i + 1))
+  sleep 1
+done`, NodeLabelsFile, nodeLabelsWaitSeconds, constant.AnnotationNodeLabels)
+}
+
+// publishNodeLabels copies the declared labels of each scheduled pod's node into the pod's
+// constant.AnnotationNodeLabels annotation, patching only pods whose value changed. It reports
+// whether a pod is still waiting to be scheduled, within nodeLabelsWaitSeconds of its creation.
+//
+// Pods that do not exist yet and pods whose node is gone are not waiting in that sense: the
+// StatefulSet controller updates the StatefulSet's status when it creates or replaces a pod, which
+// the Owns() watch turns into a reconcile, and neither clears while admission or quota refuses the
+// pod or the node stays gone — polling for them would only reconcile the cluster every 5s for good.
+//
+// Nodes are read through the APIReader when one is wired, once per node per role group: a
+// cluster-wide Node informer would cost a list/watch grant and a cache of every node for a read
+// that happens only while pods start.
+func (r *GenericReconciler[CR]) publishNodeLabels(ctx context.Context, buildCtx *RoleGroupBuildContext, sts *appsv1.StatefulSet) (pending bool, err error) {
+	if sts.Spec.Selector == nil {
+		return false, nil
+	}
+	pods := &corev1.PodList{}
+	if err := r.client.List(ctx, pods, client.InNamespace(buildCtx.ClusterNamespace),
+		client.MatchingLabels(sts.Spec.Selector.MatchLabels)); err != nil {
+		return false, NewResourceApplyError("Pod", buildCtx.ClusterNamespace, buildCtx.ResourceName,
+			"failed to list pods to publish node labels", r.apiError(err))
+	}
+	var reader client.Reader = r.client
+	if r.apiReader != nil {
+		reader = r.apiReader
+	}
+	nodes := map[string]*corev1.Node{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if pod.Spec.NodeName == "" {
+			if time.Since(pod.CreationTimestamp.Time) < nodeLabelsWaitSeconds*time.Second {
+				pending = true
+			}
+			continue
+		}
+		node, cached := nodes[pod.Spec.NodeName]
+		if !cached {
+			node = &corev1.Node{}
+			if err := reader.Get(ctx, types.NamespacedName{Name: pod.Spec.NodeName}, node); err != nil {
+				if !errors.IsNotFound(err) {
+					return false, NewResourceApplyError("Node", "", pod.Spec.NodeName,
+						"failed to read the node to publish its labels", r.apiError(err))
+				}
+				node = nil
+			}
+			nodes[pod.Spec.NodeName] = node
+		}
+		if node == nil {
+			continue
+		}
+
+		value := nodeLabelsAnnotation(buildCtx.Declaration.NodeLabels, node.Labels)
+		if pod.Annotations[constant.AnnotationNodeLabels] == value {
+			continue
+		}
+		before := pod.DeepCopy()
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[constant.AnnotationNodeLabels] = value
+		if err := r.client.Patch(ctx, pod, client.MergeFrom(before)); err != nil && !errors.IsNotFound(err) {
+			return false, NewResourceApplyError("Pod", pod.Namespace, pod.Name,
+				"failed to publish node labels", r.apiError(err))
+		}
+		log.FromContext(ctx).V(1).Info("Published node labels on pod", "pod", pod.Name, "node", node.Name)
+	}
+	return pending, nil
+}
+
+// nodeLabelsAnnotation renders the declared keys of a node's labels in NodeLabelsFile's format.
+// Every key is listed, so the value is never empty once published.
+func nodeLabelsAnnotation(keys []string, labels map[string]string) string {
+	sorted := slices.Clone(keys)
+	slices.Sort(sorted)
+	var b strings.Builder
+	for _, key := range slices.Compact(sorted) {
+		b.WriteString(key + "=" + labels[key] + "\n")
+	}
+	return b.String()
+}
+
+// PublishedNodeLabels returns the node labels published on a pod, and whether they have been
+// published at all. It is the operator-side reading of NodeLabelsFile: a product whose topology is
+// resolved centrally — an HDFS NameNode mapping DataNode addresses to racks — builds that mapping
+// from its pods with this and RackMapping.Rack rather than from inside each pod.
+func PublishedNodeLabels(pod *corev1.Pod) (map[string]string, bool) {
+	value, ok := pod.Annotations[constant.AnnotationNodeLabels]
+	if !ok || value == "" {
+		return nil, false
+	}
+	labels := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSuffix(value, "\n"), "\n") {
+		key, val, _ := strings.Cut(line, "=")
+		labels[key] = val
+	}
+	return labels, true
+}
+
+// RackMapping derives a rack name from the node labels a role publishes. The same mapping answers
+// in Go (Rack) and inside the pod (ShellExport), so an operator-rendered topology table and a
+// pod's own start-up script cannot disagree about a pod's rack.
+//
+// For HDFS, Keys [topology.kubernetes.io/zone, <a rack label>] with Prefix and Separator "/" and
+// Default "/default-rack" give "/eu-west-1a/r12". For a Kafka broker.rack, Keys
+// [topology.kubernetes.io/zone] with no Prefix give "eu-west-1a".
+type RackMapping struct {
+	// Keys are the node label keys whose values make up the rack, outermost first. Each must also
+	// be declared in the role's NodeLabels, or it is never published and always reads empty.
+	Keys []string
+
+	// Prefix precedes the rack and Separator joins the values of the keys.
+	Prefix    string
+	Separator string
+
+	// Default is the rack of a pod whose node carries none of the keys.
+	Default string
+}
+
+// Rack returns the rack for a node's labels. Keys the node does not carry, or carries empty, are
+// left out; when none remain the rack is Default.
+func (m RackMapping) Rack(labels map[string]string) string {
+	var parts []string
+	for _, key := range m.Keys {
+		if v := labels[key]; v != "" {
+			parts = append(parts, v)
+		}
+	}
+	if len(parts) == 0 {
+		return m.Default
+	}
+	return m.Prefix + strings.Join(parts, m.Separator)
+}
+
+// ShellExport renders a POSIX shell snippet that reads NodeLabelsFile and exports the rack, as
+// Rack would compute it, in the environment variable named variable — for a product entrypoint to
+// source or run before it writes the rack into its config. variable must be a shell identifier.
+// The snippet's own working variables are unset again at its end.
+func (m RackMapping) ShellExport(variable string) string {
+	var b strings.Builder
+	b.WriteString("kubedoop_rack=''\n")
+	for _, key := range m.Keys {
+		fmt.Fprintf(&b, "kubedoop_rack_value=$(awk -F= -v k=%s '$1 == k { sub(/^[^=]*=/, \"\"); print; exit }' %s)\n",
+			shellQuote(key), NodeLabelsFile)
+		b.WriteString("if [ -n \"$kubedoop_rack_value\" ]; then\n")
+		fmt.Fprintf(&b, "  if [ -n \"$kubedoop_rack\" ]; then kubedoop_rack=\"$kubedoop_rack\"%s; fi\n", shellQuote(m.Separator))
+		b.WriteString("  kubedoop_rack=\"$kubedoop_rack$kubedoop_rack_value\"\n")
+		b.WriteString("fi\n")
+	}
+	fmt.Fprintf(&b, "if [ -n \"$kubedoop_rack\" ]; then %[1]s=%[2]s\"$kubedoop_rack\"; else %[1]s=%[3]s; fi\n",
+		variable, shellQuote(m.Prefix), shellQuote(m.Default))
+	fmt.Fprintf(&b, "export %s\n", variable)
+	b.WriteString("unset kubedoop_rack kubedoop_rack_value\n")
+	return b.String()
+}
+
+// shellQuote renders s as a single-quoted POSIX shell word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
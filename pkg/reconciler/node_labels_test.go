@@ -0,0 +1,222 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/constant"
+	"github.com/zncdatadev/operator-go/pkg/reconciler"
+	"github.com/zncdatadev/operator-go/pkg/testutil"
+)
+
+const (
+	labelRack = "example.com/rack"
+	labelZone = corev1.LabelTopologyZone
+)
+
+var _ = Describe("Node labels", func() {
+	const namespace = "default"
+
+	var c client.Client
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(testutil.AddToScheme(scheme)).To(Succeed())
+		cr := testutil.NewMockCluster("hdfs", namespace).WithRoles(map[string]v1alpha1.RoleSpec{
+			"datanode": {RoleGroups: map[string]v1alpha1.RoleGroupSpec{"default": {Replicas: ptr.To[int32](2)}}},
+		})
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(cr).WithStatusSubresource(cr).Build()
+	})
+
+	reconcileOnce := func() ctrl.Result {
+		GinkgoHelper()
+		r, err := reconciler.NewGenericReconciler(&reconciler.GenericReconcilerConfig[*testutil.MockCluster]{
+			Client:          c,
+			Scheme:          c.Scheme(),
+			ImageResolution: reconciler.ImageResolution{Defaults: v1alpha1.ImageSpec{Custom: "test-image:latest"}},
+			RoleProvider: reconciler.RoleProviderFunc[*testutil.MockCluster](
+				func(context.Context, client.Client, *testutil.MockCluster) (reconciler.RoleCatalog, error) {
+					return reconciler.RoleCatalog{"datanode": {NodeLabels: []string{labelZone, labelRack}}}, nil
+				}),
+			Recorder:         record.NewFakeRecorder(100),
+			RoleGroupHandler: reconciler.NewBaseRoleGroupHandler[*testutil.MockCluster](c.Scheme()),
+			Prototype:        testutil.NewMockCluster("proto", namespace),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: "hdfs"}})
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+	getStatefulSet := func() *appsv1.StatefulSet {
+		GinkgoHelper()
+		sts := &appsv1.StatefulSet{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "hdfs-datanode-default"}, sts)).To(Succeed())
+		return sts
+	}
+	// createPodAt stands in for the StatefulSet controller, and nodeName for the scheduler.
+	createPodAt := func(name, nodeName string, created time.Time) {
+		GinkgoHelper()
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name, Namespace: namespace, Labels: getStatefulSet().Spec.Selector.MatchLabels,
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Spec: corev1.PodSpec{NodeName: nodeName},
+		}
+		Expect(c.Create(ctx, pod)).To(Succeed())
+	}
+	createPod := func(name, nodeName string) {
+		GinkgoHelper()
+		createPodAt(name, nodeName, time.Now())
+	}
+	publishedOn := func(name string) string {
+		GinkgoHelper()
+		pod := &corev1.Pod{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, pod)).To(Succeed())
+		return pod.Annotations[constant.AnnotationNodeLabels]
+	}
+
+	It("holds every pod in a first init container until the projected file is written", func() {
+		reconcileOnce()
+
+		spec := getStatefulSet().Spec.Template.Spec
+		Expect(spec.InitContainers).NotTo(BeEmpty())
+		wait := spec.InitContainers[0]
+		Expect(wait.Name).To(Equal(reconciler.NodeLabelsInitContainerName))
+		Expect(wait.Image).To(Equal(spec.Containers[0].Image))
+		Expect(wait.Args).To(ConsistOf(ContainSubstring("[ -s " + reconciler.NodeLabelsFile + " ]")))
+
+		var volume *corev1.Volume
+		for i := range spec.Volumes {
+			if spec.Volumes[i].Name == reconciler.NodeLabelsVolumeName {
+				volume = &spec.Volumes[i]
+			}
+		}
+		Expect(volume).NotTo(BeNil())
+		Expect(volume.DownwardAPI.Items).To(ConsistOf(HaveField("FieldRef.FieldPath",
+			"metadata.annotations['"+constant.AnnotationNodeLabels+"']")))
+
+		for _, container := range append(spec.InitContainers, spec.Containers...) {
+			Expect(container.VolumeMounts).To(ContainElement(corev1.VolumeMount{
+				Name: reconciler.NodeLabelsVolumeName, MountPath: constant.KubedoopNodeLabelsDir, ReadOnly: true,
+			}), "container %q", container.Name)
+		}
+	})
+
+	It("publishes the declared labels of each scheduled pod's node, and comes back soon for the rest", func() {
+		Expect(reconcileOnce().RequeueAfter).To(BeNumerically(">", 5*time.Second),
+			"a pod not created yet wakes the reconciler through the StatefulSet's status")
+
+		Expect(c.Create(ctx, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-a", Labels: map[string]string{
+			labelZone: "eu-1a", "kubernetes.io/os": "linux",
+		}}})).To(Succeed())
+		createPod("hdfs-datanode-default-0", "node-a")
+		createPod("hdfs-datanode-default-1", "")
+
+		Expect(reconcileOnce().RequeueAfter).To(Equal(5*time.Second), "one pod is not scheduled yet")
+		Expect(publishedOn("hdfs-datanode-default-0")).To(Equal("example.com/rack=\ntopology.kubernetes.io/zone=eu-1a\n"))
+		Expect(publishedOn("hdfs-datanode-default-1")).To(BeEmpty())
+
+		pod := &corev1.Pod{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "hdfs-datanode-default-1"}, pod)).To(Succeed())
+		pod.Spec.NodeName = "node-a"
+		Expect(c.Update(ctx, pod)).To(Succeed())
+
+		Expect(reconcileOnce().RequeueAfter).To(BeNumerically(">", 5*time.Second))
+		Expect(publishedOn("hdfs-datanode-default-1")).To(Equal(publishedOn("hdfs-datanode-default-0")))
+	})
+
+	It("stops polling fast for pods that outlive the wait unscheduled or whose node is gone", func() {
+		reconcileOnce()
+		createPodAt("hdfs-datanode-default-0", "", time.Now().Add(-10*time.Minute))
+		createPod("hdfs-datanode-default-1", "node-gone")
+
+		Expect(reconcileOnce().RequeueAfter).To(BeNumerically(">", 5*time.Second),
+			"neither clears by polling, so the health check cadence takes over")
+		Expect(publishedOn("hdfs-datanode-default-1")).To(BeEmpty())
+	})
+})
+
+var _ = Describe("RackMapping", func() {
+	hdfs := reconciler.RackMapping{
+		Keys: []string{labelZone, labelRack}, Prefix: "/", Separator: "/", Default: "/default-rack",
+	}
+	kafka := reconciler.RackMapping{Keys: []string{labelZone}}
+
+	// shellRack runs the mapping's snippet against a file holding published, the way a pod would.
+	shellRack := func(m reconciler.RackMapping, published string) string {
+		GinkgoHelper()
+		file := filepath.Join(GinkgoT().TempDir(), "node-labels")
+		Expect(os.WriteFile(file, []byte(published), 0o600)).To(Succeed())
+		script := strings.ReplaceAll(m.ShellExport("RACK"), reconciler.NodeLabelsFile, file) + `printf %s "$RACK"`
+		out, err := exec.Command("/bin/sh", "-c", script).Output()
+		Expect(err).NotTo(HaveOccurred())
+		return string(out)
+	}
+
+	DescribeTable("agrees between Go and the shell",
+		func(m reconciler.RackMapping, labels map[string]string, expected string) {
+			Expect(m.Rack(labels)).To(Equal(expected))
+
+			published := ""
+			for _, key := range m.Keys {
+				published += key + "=" + labels[key] + "\n"
+			}
+			Expect(shellRack(m, published)).To(Equal(expected))
+		},
+		Entry("an HDFS rack path", hdfs, map[string]string{labelZone: "eu-1a", labelRack: "r12"}, "/eu-1a/r12"),
+		Entry("a node carrying only some keys", hdfs, map[string]string{labelRack: "r12"}, "/r12"),
+		Entry("a node carrying none", hdfs, map[string]string{}, "/default-rack"),
+		Entry("a Kafka broker.rack", kafka, map[string]string{labelZone: "eu-1a"}, "eu-1a"),
+		Entry("a value the shell must not interpret", kafka, map[string]string{labelZone: "a=b$c"}, "a=b$c"),
+	)
+
+	It("reads back what the framework published on a pod", func() {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			constant.AnnotationNodeLabels: "example.com/rack=\ntopology.kubernetes.io/zone=eu-1a\n",
+		}}}
+		labels, ok := reconciler.PublishedNodeLabels(pod)
+		Expect(ok).To(BeTrue())
+		Expect(labels).To(Equal(map[string]string{labelRack: "", labelZone: "eu-1a"}))
+		Expect(hdfs.Rack(labels)).To(Equal("/eu-1a"))
+
+		_, ok = reconciler.PublishedNodeLabels(&corev1.Pod{})
+		Expect(ok).To(BeFalse())
+	})
+})
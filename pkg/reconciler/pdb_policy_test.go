@@ -0,0 +1,156 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/reconciler"
+	"github.com/zncdatadev/operator-go/pkg/testutil"
+)
+
+var _ = Describe("PodDisruptionBudget policies", func() {
+	ctx := context.Background()
+
+	newReconcilerFor := func(decl reconciler.RoleDeclaration) *reconciler.GenericReconciler[*testutil.MockCluster] {
+		r, err := reconciler.NewGenericReconciler(&reconciler.GenericReconcilerConfig[*testutil.MockCluster]{
+			Client:          k8sClient,
+			Scheme:          testScheme,
+			ImageResolution: reconciler.ImageResolution{Defaults: v1alpha1.ImageSpec{Custom: "test-image:latest"}},
+			RoleProvider: reconciler.RoleProviderFunc[*testutil.MockCluster](
+				func(context.Context, client.Client, *testutil.MockCluster) (reconciler.RoleCatalog, error) {
+					return reconciler.RoleCatalog{"server": decl}, nil
+				}),
+			Recorder:         recorder,
+			RoleGroupHandler: reconciler.NewBaseRoleGroupHandler[*testutil.MockCluster](testScheme),
+			Prototype:        testutil.NewMockCluster("proto", testNamespace),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return r
+	}
+
+	newCluster := func(prefix string, pdb *v1alpha1.PodDisruptionBudgetSpec) *testutil.MockCluster {
+		GinkgoHelper()
+		cr := testutil.NewMockCluster(uniqueCRName(prefix), testNamespace).WithRoles(map[string]v1alpha1.RoleSpec{
+			"server": {
+				RoleConfig: &v1alpha1.RoleConfigSpec{PodDisruptionBudget: pdb},
+				RoleGroups: map[string]v1alpha1.RoleGroupSpec{
+					"default":   {Replicas: ptr.To(int32(2))},
+					"secondary": {Replicas: ptr.To(int32(3))},
+				},
+			},
+		})
+		Expect(k8sClient.Create(ctx, cr)).To(Succeed())
+		DeferCleanup(func() { _ = k8sClient.Delete(ctx, cr) })
+		return cr
+	}
+
+	reconcile := func(r *reconciler.GenericReconciler[*testutil.MockCluster], cr *testutil.MockCluster) {
+		GinkgoHelper()
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cr)})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	getPDB := func(name string) (*policyv1.PodDisruptionBudget, error) {
+		pdb := &policyv1.PodDisruptionBudget{}
+		return pdb, k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: name}, pdb)
+	}
+
+	It("builds one PDB per role group for the RoleGroup scope, and moves back to one per role", func() {
+		cr := newCluster("pdb-scope", &v1alpha1.PodDisruptionBudgetSpec{
+			Scope:        v1alpha1.PodDisruptionBudgetScopeRoleGroup,
+			MinAvailable: ptr.To(intstr.FromString("50%")),
+		})
+		r := newReconcilerFor(reconciler.RoleDeclaration{})
+		reconcile(r, cr)
+
+		for _, group := range []string{"default", "secondary"} {
+			pdb, err := getPDB(reconciler.RoleGroupResourceName(cr.Name, "server", group))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pdb.Spec.MinAvailable).To(HaveValue(Equal(intstr.FromString("50%"))))
+		}
+		_, err := getPDB(reconciler.RoleResourceName(cr.Name, "server"))
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(cr), cr)).To(Succeed())
+		role := cr.Spec.Roles["server"]
+		role.RoleConfig.PodDisruptionBudget.Scope = v1alpha1.PodDisruptionBudgetScopeRole
+		cr.Spec.Roles["server"] = role
+		Expect(k8sClient.Update(ctx, cr)).To(Succeed())
+		reconcile(r, cr)
+
+		_, err = getPDB(reconciler.RoleResourceName(cr.Name, "server"))
+		Expect(err).NotTo(HaveOccurred())
+		for _, group := range []string{"default", "secondary"} {
+			_, err := getPDB(reconciler.RoleGroupResourceName(cr.Name, "server", group))
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "the per-group PDB of %q should be reclaimed", group)
+		}
+	})
+
+	It("keeps the quorum of the live replicas across all role groups available", func() {
+		cr := newCluster("pdb-quorum", nil)
+		reconcile(newReconcilerFor(reconciler.RoleDeclaration{Quorum: &reconciler.Quorum{}}), cr)
+
+		// Two replicas in one group and three in the other: a majority of five is three.
+		pdb, err := getPDB(reconciler.RoleResourceName(cr.Name, "server"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pdb.Spec.MinAvailable).To(HaveValue(Equal(intstr.FromInt32(3))))
+		Expect(pdb.Spec.MaxUnavailable).To(BeNil())
+	})
+
+	It("rejects per-role-group budgets for a quorum role", func() {
+		cr := newCluster("pdb-quorum-scope", &v1alpha1.PodDisruptionBudgetSpec{Scope: v1alpha1.PodDisruptionBudgetScopeRoleGroup})
+		_, _ = newReconcilerFor(reconciler.RoleDeclaration{Quorum: &reconciler.Quorum{}}).
+			Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cr)})
+
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(cr), cr)).To(Succeed())
+		complete := cr.Status.GetCondition(v1alpha1.ConditionReconcileComplete)
+		Expect(complete).NotTo(BeNil())
+		Expect(complete.Message).To(ContainSubstring("podDisruptionBudget.scope"))
+	})
+
+	It("refuses a spec stating both minAvailable and maxUnavailable at admission", func() {
+		cr := testutil.NewMockCluster(uniqueCRName("pdb-both"), testNamespace).WithRoles(map[string]v1alpha1.RoleSpec{
+			"server": {
+				RoleConfig: &v1alpha1.RoleConfigSpec{PodDisruptionBudget: &v1alpha1.PodDisruptionBudgetSpec{
+					MinAvailable:   ptr.To(intstr.FromInt32(1)),
+					MaxUnavailable: ptr.To(intstr.FromInt32(1)),
+				}},
+				RoleGroups: map[string]v1alpha1.RoleGroupSpec{"default": {}},
+			},
+		})
+		err := k8sClient.Create(ctx, cr)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue(), "got %v", err)
+		Expect(err.Error()).To(ContainSubstring("mutually exclusive"))
+
+		cr.Spec.Roles["server"].RoleConfig.PodDisruptionBudget.MaxUnavailable = nil
+		cr.Spec.Roles["server"].RoleConfig.PodDisruptionBudget.MinAvailable = ptr.To(intstr.FromString("half"))
+		err = k8sClient.Create(ctx, cr)
+		Expect(apierrors.IsInvalid(err)).To(BeTrue(), "got %v", err)
+	})
+})
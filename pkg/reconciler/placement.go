@@ -0,0 +1,70 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PreferredTopologySpread builds one ScheduleAnyway topology spread constraint with no
+// labelSelector, for a product's RoleDeclaration.ConfigDefaults:
+//
+//	ConfigDefaults: &v1alpha1.RoleGroupConfigSpec{Placement: &v1alpha1.PlacementSpec{
+//		TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+//			reconciler.PreferredTopologySpread(1, reconciler.TopologyKeyZone),
+//		},
+//	}},
+//
+// The missing selector is the point: the framework fills in the role's selector when it builds the
+// pod (see SpreadConstraintsForRole), so the default spreads the role of whichever cluster it lands
+// in without the product naming one.
+//
+// PREFERRED only, for the reason PreferredAffinityTerm gives: DoNotSchedule turns a single-zone
+// cluster into pods that never schedule. A product that genuinely requires the spread writes the
+// corev1 constraint itself.
+func PreferredTopologySpread(maxSkew int32, topologyKey string) corev1.TopologySpreadConstraint {
+	return corev1.TopologySpreadConstraint{
+		MaxSkew:           maxSkew,
+		TopologyKey:       topologyKey,
+		WhenUnsatisfiable: corev1.ScheduleAnyway,
+	}
+}
+
+// SpreadConstraintsForRole returns a copy of constraints with every constraint that has no
+// labelSelector given the role's selector (RoleSelectorLabels). A constraint that states its own
+// selector is kept as written.
+//
+// Without this, a constraint with no selector is legal and does nothing: it counts no pods, so every
+// domain has a skew of zero and the scheduler places the pods as if it were absent.
+func SpreadConstraintsForRole(
+	constraints []corev1.TopologySpreadConstraint, clusterName, roleName string,
+) []corev1.TopologySpreadConstraint {
+	if constraints == nil {
+		return nil
+	}
+	out := make([]corev1.TopologySpreadConstraint, len(constraints))
+	for i := range constraints {
+		constraints[i].DeepCopyInto(&out[i])
+		if out[i].LabelSelector == nil {
+			out[i].LabelSelector = &metav1.LabelSelector{
+				MatchLabels: RoleSelectorLabels(clusterName, roleName),
+			}
+		}
+	}
+	return out
+}
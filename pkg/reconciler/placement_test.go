@@ -0,0 +1,127 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/reconciler"
+	"github.com/zncdatadev/operator-go/pkg/testutil"
+)
+
+var _ = Describe("Role group placement", func() {
+	ctx := context.Background()
+
+	// zoneSpread is the product default the request describes: spread the role across zones,
+	// declared without knowing which cluster it will be applied to.
+	zoneSpread := reconciler.RoleDeclaration{ConfigDefaults: &v1alpha1.RoleGroupConfigSpec{
+		Placement: &v1alpha1.PlacementSpec{TopologySpreadConstraints: []corev1.TopologySpreadConstraint{
+			reconciler.PreferredTopologySpread(1, reconciler.TopologyKeyZone),
+		}},
+	}}
+
+	reconcileWith := func(cr *testutil.MockCluster, rec record.EventRecorder) {
+		GinkgoHelper()
+		r, err := reconciler.NewGenericReconciler(&reconciler.GenericReconcilerConfig[*testutil.MockCluster]{
+			Client:          k8sClient,
+			Scheme:          testScheme,
+			ImageResolution: reconciler.ImageResolution{Defaults: v1alpha1.ImageSpec{Custom: "test-image:latest"}},
+			RoleProvider: reconciler.RoleProviderFunc[*testutil.MockCluster](
+				func(context.Context, client.Client, *testutil.MockCluster) (reconciler.RoleCatalog, error) {
+					return reconciler.RoleCatalog{"server": zoneSpread}, nil
+				}),
+			Recorder:         rec,
+			RoleGroupHandler: reconciler.NewBaseRoleGroupHandler[*testutil.MockCluster](testScheme),
+			Prototype:        testutil.NewMockCluster("proto", testNamespace),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(cr)})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	newCluster := func(prefix string, roleConfig, groupConfig *v1alpha1.RoleGroupConfigSpec) *testutil.MockCluster {
+		GinkgoHelper()
+		cr := testutil.NewMockCluster(uniqueCRName(prefix), testNamespace).WithRoles(map[string]v1alpha1.RoleSpec{
+			"server": {
+				Config: roleConfig,
+				RoleGroups: map[string]v1alpha1.RoleGroupSpec{
+					"default": {Replicas: ptr.To(int32(1)), Config: groupConfig},
+				},
+			},
+		})
+		Expect(k8sClient.Create(ctx, cr)).To(Succeed())
+		DeferCleanup(func() { _ = k8sClient.Delete(ctx, cr) })
+		return cr
+	}
+
+	podSpecOf := func(cr *testutil.MockCluster) corev1.PodSpec {
+		GinkgoHelper()
+		sts := &appsv1.StatefulSet{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{
+			Namespace: testNamespace, Name: reconciler.RoleGroupResourceName(cr.Name, "server", "default"),
+		}, sts)).To(Succeed())
+		return sts.Spec.Template.Spec
+	}
+
+	It("spreads the role across zones by the product's default, with the role's selector", func() {
+		cr := newCluster("placement-default", nil, nil)
+		reconcileWith(cr, recorder)
+
+		spread := podSpecOf(cr).TopologySpreadConstraints
+		Expect(spread).To(HaveLen(1))
+		Expect(spread[0].TopologyKey).To(Equal(corev1.LabelTopologyZone))
+		Expect(spread[0].WhenUnsatisfiable).To(Equal(corev1.ScheduleAnyway))
+		Expect(spread[0].LabelSelector.MatchLabels).To(Equal(reconciler.RoleSelectorLabels(cr.Name, "server")))
+	})
+
+	It("lets the user's placement replace the default wholesale, and reports what it discarded", func() {
+		cr := newCluster("placement-user",
+			&v1alpha1.RoleGroupConfigSpec{Placement: &v1alpha1.PlacementSpec{
+				NodeSelector: map[string]string{"disk": "ssd"},
+			}},
+			&v1alpha1.RoleGroupConfigSpec{Placement: &v1alpha1.PlacementSpec{
+				NodeSelector: map[string]string{"disk": "nvme"},
+				Tolerations:  []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+			}})
+		rec := record.NewFakeRecorder(20)
+		reconcileWith(cr, rec)
+
+		podSpec := podSpecOf(cr)
+		Expect(podSpec.NodeSelector).To(Equal(map[string]string{"disk": "nvme"}))
+		Expect(podSpec.Tolerations).To(HaveLen(1))
+		Expect(podSpec.TopologySpreadConstraints).To(BeEmpty())
+
+		// The role's own placement takes over from the product's spread first; the group's then
+		// replaces the role's without discarding anything the group did not restate.
+		Expect(rec.Events).To(Receive(And(
+			ContainSubstring("PlacementOverridden"),
+			ContainSubstring("the role's config replaces config.placement wholesale, "+
+				"discarding the topologySpreadConstraints declared beneath it"))))
+		Expect(rec.Events).NotTo(Receive(ContainSubstring("PlacementOverridden")))
+	})
+})
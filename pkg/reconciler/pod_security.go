@@ -0,0 +1,125 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/security"
+)
+
+// ConditionPodSecurityViolation reports role groups whose built pod template fails the Pod
+// Security Standard the operator checks against (GenericReconcilerConfig.PodSecurity). True names
+// every offending role group; it is only cleared to False once it has been raised, so a compliant
+// cluster carries no condition at all.
+const ConditionPodSecurityViolation v1alpha1.ConditionType = "PodSecurityViolation"
+
+// Reasons for ConditionPodSecurityViolation.
+const (
+	ReasonPodSecurityViolation = "PodSecurityViolation"
+	ReasonPodSecurityCompliant = "PodSecurityCompliant"
+)
+
+// PodSecurityPolicy selects the Pod Security Standard every role group's pod template is checked
+// against after it is built.
+//
+// The check runs on the StatefulSet's final template — after podOverrides are merged and sidecars
+// injected — because that is where a user's override can quietly add `privileged: true` or a
+// hostPath, and nothing before it sees the finished pod. PodSecurityBuilder produces compliant
+// contexts; this verifies that they survived.
+type PodSecurityPolicy struct {
+	// Level is the standard to check against. Empty disables the check.
+	Level security.PodSecurityLevel
+
+	// Strict fails a violating role group with a *ValidationError before anything of it is applied.
+	// Left false, violations are reported — a Warning event per role group and
+	// ConditionPodSecurityViolation — and the role group is applied anyway, which is what a
+	// namespace enforcing the same level would then reject at pod creation.
+	Strict bool
+}
+
+// podSecurityFindings collects one reconcile pass's violations, keyed by "<role>/<group>". It is
+// threaded down the call chain for the same reason the role catalog is: one reconciler serves every
+// cluster, so a field on it would mix two clusters' findings.
+type podSecurityFindings map[string][]security.PodSecurityViolation
+
+// checkPodSecurity evaluates a role group's built StatefulSet against the configured policy and
+// records what it finds. It returns a *ValidationError only in strict mode.
+func (r *GenericReconciler[CR]) checkPodSecurity(cr CR, buildCtx *RoleGroupBuildContext, resources *RoleGroupResources, findings podSecurityFindings) error {
+	level := r.podSecurity.Level
+	if level == "" || resources.StatefulSet == nil {
+		return nil
+	}
+	violations := security.CheckPodSecurityStandard(level, &resources.StatefulSet.Spec.Template.Spec)
+	if len(violations) == 0 {
+		return nil
+	}
+	if findings != nil {
+		findings[buildCtx.RoleName+"/"+buildCtx.RoleGroupName] = violations
+	}
+	message := describeViolations(violations)
+	if r.podSecurity.Strict {
+		return NewValidationError("podSecurity", buildCtx.RoleName, buildCtx.RoleGroupName,
+			fmt.Errorf("the pod template violates the %s Pod Security Standard: %s", level, message))
+	}
+	r.eventManager.EmitWarningEvent(cr, ReasonPodSecurityViolation,
+		fmt.Sprintf("role %s group %s violates the %s Pod Security Standard: %s",
+			buildCtx.RoleName, buildCtx.RoleGroupName, level, message))
+	return nil
+}
+
+// setPodSecurityCondition writes one pass's findings to the status. The message lists role groups
+// in sorted order so an unchanged violation writes a byte-identical status every pass.
+func setPodSecurityCondition(status *v1alpha1.GenericClusterStatus, level security.PodSecurityLevel, findings podSecurityFindings) {
+	if len(findings) == 0 {
+		if status.GetCondition(ConditionPodSecurityViolation) == nil {
+			return
+		}
+		status.SetCondition(metav1.Condition{
+			Type:    string(ConditionPodSecurityViolation),
+			Status:  metav1.ConditionFalse,
+			Reason:  ReasonPodSecurityCompliant,
+			Message: fmt.Sprintf("Every role group meets the %s Pod Security Standard", level),
+		})
+		return
+	}
+
+	parts := make([]string, 0, len(findings))
+	for _, key := range slices.Sorted(maps.Keys(findings)) {
+		parts = append(parts, key+": "+describeViolations(findings[key]))
+	}
+	status.SetCondition(metav1.Condition{
+		Type:    string(ConditionPodSecurityViolation),
+		Status:  metav1.ConditionTrue,
+		Reason:  ReasonPodSecurityViolation,
+		Message: fmt.Sprintf("Role groups violating the %s Pod Security Standard: %s", level, strings.Join(parts, "; ")),
+	})
+}
+
+func describeViolations(violations []security.PodSecurityViolation) string {
+	parts := make([]string, len(violations))
+	for i, v := range violations {
+		parts[i] = v.String()
+	}
+	return strings.Join(parts, ", ")
+}
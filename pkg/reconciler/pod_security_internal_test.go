@@ -0,0 +1,60 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/security"
+)
+
+var _ = Describe("setPodSecurityCondition", func() {
+	privileged := []security.PodSecurityViolation{{Field: "containers[debug].securityContext.privileged", Reason: "must not be true"}}
+	hostPath := []security.PodSecurityViolation{{Field: "volumes[host].hostPath", Reason: "hostPath volumes are forbidden"}}
+
+	It("adds no condition to a compliant cluster", func() {
+		status := &v1alpha1.GenericClusterStatus{}
+		setPodSecurityCondition(status, security.PodSecurityRestricted, podSecurityFindings{})
+		Expect(status.GetCondition(ConditionPodSecurityViolation)).To(BeNil())
+	})
+
+	It("names every violating role group in sorted order", func() {
+		status := &v1alpha1.GenericClusterStatus{}
+		setPodSecurityCondition(status, security.PodSecurityBaseline, podSecurityFindings{
+			"worker/default": hostPath,
+			"server/default": privileged,
+		})
+		cond := status.GetCondition(ConditionPodSecurityViolation)
+		Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+		Expect(cond.Reason).To(Equal(ReasonPodSecurityViolation))
+		Expect(cond.Message).To(Equal("Role groups violating the baseline Pod Security Standard: " +
+			"server/default: containers[debug].securityContext.privileged: must not be true; " +
+			"worker/default: volumes[host].hostPath: hostPath volumes are forbidden"))
+	})
+
+	It("clears a raised condition once the violations are gone", func() {
+		status := &v1alpha1.GenericClusterStatus{}
+		setPodSecurityCondition(status, security.PodSecurityBaseline, podSecurityFindings{"server/default": privileged})
+		setPodSecurityCondition(status, security.PodSecurityBaseline, podSecurityFindings{})
+		cond := status.GetCondition(ConditionPodSecurityViolation)
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal(ReasonPodSecurityCompliant))
+	})
+})
@@ -0,0 +1,165 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/productversion"
+	"github.com/zncdatadev/operator-go/pkg/reconciler"
+	"github.com/zncdatadev/operator-go/pkg/testutil"
+)
+
+var _ = Describe("Product version catalog", func() {
+	ctx := context.Background()
+
+	const (
+		role      = "broker"
+		roleGroup = "default"
+	)
+
+	catalog := &productversion.Catalog{
+		Versions: map[string]productversion.Version{
+			"3.8.0": {ConfigDefaults: map[string]*v1alpha1.RoleGroupConfigSpec{
+				role: {GracefulShutdownTimeout: ptr.To("5m")},
+			}},
+			"3.9.0": {},
+			"4.0.0": {},
+		},
+		// 3.7.0 is retired: no longer supported, but clusters still on it may move to 3.8.0.
+		Upgrades: map[string][]string{"3.7.0": {"3.8.0"}, "3.8.0": {"3.9.0"}, "3.9.0": {"4.0.0"}},
+	}
+
+	// newReconcilerFor builds a reconciler whose operator defaults resolve to version, which is
+	// how an operator upgrade moves a cluster that does not pin spec.image.productVersion.
+	newReconcilerFor := func(version string) *reconciler.GenericReconciler[*testutil.MockCluster] {
+		r, err := reconciler.NewGenericReconciler(&reconciler.GenericReconcilerConfig[*testutil.MockCluster]{
+			Client: k8sClient,
+			Scheme: testScheme,
+			ImageResolution: reconciler.ImageResolution{
+				ProductName: "kafka",
+				Defaults: v1alpha1.ImageSpec{
+					Repo: "quay.io/kubedoop", ProductVersion: version, KubedoopVersion: "0.2.0",
+				},
+				Versions: catalog,
+			},
+			Recorder:         recorder,
+			RoleGroupHandler: reconciler.NewBaseRoleGroupHandler[*testutil.MockCluster](testScheme),
+			Prototype:        testutil.NewMockCluster("proto", testNamespace),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return r
+	}
+
+	newCluster := func() (types.NamespacedName, string) {
+		GinkgoHelper()
+		cr := testutil.NewMockCluster(uniqueCRName("versions"), testNamespace).WithRoles(map[string]v1alpha1.RoleSpec{
+			role: {RoleGroups: map[string]v1alpha1.RoleGroupSpec{roleGroup: {Replicas: ptr.To(int32(1))}}},
+		})
+		Expect(k8sClient.Create(ctx, cr)).To(Succeed())
+		resourceName := reconciler.RoleGroupResourceName(cr.Name, role, roleGroup)
+		DeferCleanup(func() {
+			_ = k8sClient.Delete(ctx, cr)
+			meta := metav1.ObjectMeta{Name: resourceName, Namespace: testNamespace}
+			_ = k8sClient.Delete(ctx, &appsv1.StatefulSet{ObjectMeta: meta})
+			_ = k8sClient.Delete(ctx, &corev1.ConfigMap{ObjectMeta: meta})
+			_ = k8sClient.Delete(ctx, &corev1.Service{ObjectMeta: metav1.ObjectMeta{
+				Name: resourceName + "-headless", Namespace: testNamespace}})
+		})
+		return types.NamespacedName{Namespace: testNamespace, Name: cr.Name}, resourceName
+	}
+
+	getStatefulSet := func(name string) *appsv1.StatefulSet {
+		GinkgoHelper()
+		sts := &appsv1.StatefulSet{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: name}, sts)).To(Succeed())
+		return sts
+	}
+
+	getCluster := func(key types.NamespacedName) *testutil.MockCluster {
+		GinkgoHelper()
+		cr := &testutil.MockCluster{}
+		Expect(k8sClient.Get(ctx, key, cr)).To(Succeed())
+		return cr
+	}
+
+	It("records the deployed version and folds that version's config defaults", func() {
+		key, resourceName := newCluster()
+		_, err := newReconcilerFor("3.8.0").Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(getCluster(key).Status.GetProductVersion(role, roleGroup)).To(Equal("3.8.0"))
+		sts := getStatefulSet(resourceName)
+		Expect(sts.Spec.Template.Spec.TerminationGracePeriodSeconds).To(HaveValue(Equal(int64(300))))
+
+		// 3.9.0 declares no such default, so the framework floor applies again after the upgrade.
+		_, err = newReconcilerFor("3.9.0").Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(getCluster(key).Status.GetProductVersion(role, roleGroup)).To(Equal("3.9.0"))
+		Expect(getStatefulSet(resourceName).Spec.Template.Spec.TerminationGracePeriodSeconds).
+			NotTo(HaveValue(Equal(int64(300))))
+	})
+
+	It("refuses an operator upgrade that skips a version the catalog requires", func() {
+		key, resourceName := newCluster()
+		_, err := newReconcilerFor("3.8.0").Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		image := getStatefulSet(resourceName).Spec.Template.Spec.Containers[0].Image
+
+		_, _ = newReconcilerFor("4.0.0").Reconcile(ctx, ctrl.Request{NamespacedName: key})
+
+		cr := getCluster(key)
+		Expect(cr.Status.GetProductVersion(role, roleGroup)).To(Equal("3.8.0"))
+		Expect(getStatefulSet(resourceName).Spec.Template.Spec.Containers[0].Image).To(Equal(image))
+		complete := cr.Status.GetCondition(v1alpha1.ConditionReconcileComplete)
+		Expect(complete).NotTo(BeNil())
+		Expect(complete.Status).To(Equal(metav1.ConditionFalse))
+		Expect(complete.Message).To(ContainSubstring(`product version "3.8.0" cannot be changed to "4.0.0"`))
+	})
+
+	It("moves a cluster off a retired version along the edge the catalog keeps for it", func() {
+		key, _ := newCluster()
+		cr := getCluster(key)
+		cr.Status.SetProductVersion(role, roleGroup, "3.7.0")
+		Expect(k8sClient.Status().Update(ctx, cr)).To(Succeed())
+
+		_, err := newReconcilerFor("3.8.0").Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(getCluster(key).Status.GetProductVersion(role, roleGroup)).To(Equal("3.8.0"))
+	})
+
+	It("refuses a version the catalog does not support", func() {
+		key, _ := newCluster()
+		_, _ = newReconcilerFor("3.6.0").Reconcile(ctx, ctrl.Request{NamespacedName: key})
+
+		cr := getCluster(key)
+		Expect(cr.Status.GetProductVersion(role, roleGroup)).To(BeEmpty())
+		complete := cr.Status.GetCondition(v1alpha1.ConditionReconcileComplete)
+		Expect(complete).NotTo(BeNil())
+		Expect(complete.Message).To(ContainSubstring(`product version "3.6.0" is not supported`))
+	})
+})
@@ -0,0 +1,211 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/constant"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// LabelRetainedPVC marks a PVC the orphan cleaner kept when it deleted the PVC's role group, because
+// the retention policy said Retain. The cleaner stamps it together with the app.kubernetes.io
+// instance / component / role-group labels naming where the claim came from, so
+//
+//	kubectl get pvc -l pvc.kubedoop.dev/retained=true
+//
+// lists the data that outlived its workload and what each claim belonged to. The label is removed
+// again when a role group of the same name comes back: its StatefulSet finds the claims by name,
+// so re-adopting them is only a matter of no longer calling them retained.
+const LabelRetainedPVC = "pvc." + constant.KubedoopDomain + "/retained"
+
+// statefulSetPVCRetentionPolicy maps the CRD's retention policy onto the StatefulSet's native field.
+// Nil stays nil, leaving the field to the Kubernetes default; a stated policy is written out in full,
+// Retain filling a field nobody set, so the live object matches what was built.
+//
+// The enum is enforced by the CRD schema, but a spec a product builds in Go never passed it, and
+// an unknown value would otherwise reach the API server as an opaque rejection of the StatefulSet.
+func statefulSetPVCRetentionPolicy(
+	spec *v1alpha1.PersistentVolumeClaimRetentionPolicySpec,
+) (*appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy, error) {
+	if spec == nil {
+		return nil, nil
+	}
+	convert := func(field string, value v1alpha1.PersistentVolumeClaimRetentionPolicyType) (
+		appsv1.PersistentVolumeClaimRetentionPolicyType, error) {
+		switch value {
+		case "", v1alpha1.PersistentVolumeClaimRetain:
+			return appsv1.RetainPersistentVolumeClaimRetentionPolicyType, nil
+		case v1alpha1.PersistentVolumeClaimDelete:
+			return appsv1.DeletePersistentVolumeClaimRetentionPolicyType, nil
+		default:
+			return "", fmt.Errorf("invalid persistentVolumeClaimRetentionPolicy.%s %q: must be %q or %q",
+				field, value, v1alpha1.PersistentVolumeClaimRetain, v1alpha1.PersistentVolumeClaimDelete)
+		}
+	}
+	whenDeleted, err := convert("whenDeleted", spec.WhenDeleted)
+	if err != nil {
+		return nil, err
+	}
+	whenScaled, err := convert("whenScaled", spec.WhenScaled)
+	if err != nil {
+		return nil, err
+	}
+	return &appsv1.StatefulSetPersistentVolumeClaimRetentionPolicy{
+		WhenDeleted: whenDeleted,
+		WhenScaled:  whenScaled,
+	}, nil
+}
+
+// claimsDeletedWith reports whether the StatefulSet's own retention policy deletes its PVCs with
+// it. The cleaner reads the policy from the orphaned StatefulSet rather than from the spec: the
+// role group is no longer in the spec, and the StatefulSet carries the last policy it was applied
+// with.
+func claimsDeletedWith(sts *appsv1.StatefulSet) bool {
+	policy := sts.Spec.PersistentVolumeClaimRetentionPolicy
+	return policy != nil && policy.WhenDeleted == appsv1.DeletePersistentVolumeClaimRetentionPolicyType
+}
+
+// retainPVCsForStatefulSet labels the PVCs of an orphaned StatefulSet as retained, naming the
+// cluster, role and role group they came from. It is the Retain counterpart of
+// deletePVCsForStatefulSet and runs at the same point of the teardown, for the same reason: once
+// the StatefulSet is gone its selector, the only way the cleaner finds the claims, is gone with it.
+//
+// An orphan discovered without role-group labels has no role or group to record; its claims get
+// the marker and the cluster only.
+//
+// The labels are a courtesy, not part of the teardown: the claims are kept either way. They need
+// PVC `patch`, which the baseline RBAC does not grant, so a 403 is logged and the remaining claims
+// are left unlabelled rather than failing the teardown — failing it would leave every removed role
+// group scaled to zero with its StatefulSet never deleted, and nothing visible to say why.
+func (c *RoleGroupCleaner) retainPVCsForStatefulSet(
+	ctx context.Context, sts *appsv1.StatefulSet, clusterName string, orphan orphanRef,
+) error {
+	if len(sts.Spec.VolumeClaimTemplates) == 0 {
+		return nil
+	}
+
+	logger := log.FromContext(ctx)
+	namespace := sts.Namespace
+
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	if err := c.Client.List(ctx, pvcList,
+		client.InNamespace(namespace),
+		client.MatchingLabels(sts.Spec.Selector.MatchLabels),
+	); err != nil {
+		return fmt.Errorf("failed to list PVCs for StatefulSet %s/%s: %w", namespace, sts.Name, c.apiError(err))
+	}
+
+	provenance := map[string]string{
+		LabelRetainedPVC:                 valueTrue,
+		constant.LabelKubernetesInstance: clusterName,
+	}
+	if orphan.roleName != "" {
+		provenance[constant.LabelKubernetesComponent] = orphan.roleName
+		provenance[constant.LabelKubernetesRoleGroup] = orphan.groupName
+	}
+
+	for i := range pvcList.Items {
+		pvc := &pvcList.Items[i]
+		if hasLabels(pvc.Labels, provenance) {
+			continue
+		}
+		before := pvc.DeepCopy()
+		if pvc.Labels == nil {
+			pvc.Labels = make(map[string]string, len(provenance))
+		}
+		for k, v := range provenance {
+			pvc.Labels[k] = v
+		}
+		if err := c.Client.Patch(ctx, pvc, client.MergeFrom(before)); err != nil {
+			switch {
+			case errors.IsNotFound(err):
+				continue
+			case errors.IsForbidden(err):
+				logger.Info("Retained the PVCs of a removed role group without labelling them: "+
+					"the operator lacks PVC patch (see security.md §3.3.2)",
+					"statefulset", sts.Name, "namespace", namespace, "error", err.Error())
+				return nil
+			}
+			return fmt.Errorf("failed to label retained PVC %s/%s: %w", namespace, pvc.Name, c.apiError(err))
+		}
+		logger.Info("Retained PVC of a removed role group", "name", pvc.Name, "namespace", namespace,
+			"role", orphan.roleName, "group", orphan.groupName)
+	}
+	return nil
+}
+
+// readoptRetainedPVCs removes LabelRetainedPVC from the claims a live role group left behind when
+// it was last removed. Nothing else is needed to adopt them: the StatefulSet controller binds a
+// claim by its name, "<template>-<statefulset>-<ordinal>", and a role group of the same name builds
+// a StatefulSet of the same name.
+//
+// Without PVC `patch` nothing was labelled to begin with, short of a label written by someone else;
+// a 403 skips the re-adoption for this pass rather than reporting an error on every reconcile.
+func (c *RoleGroupCleaner) readoptRetainedPVCs(
+	ctx context.Context, namespace, clusterName, roleName, groupName string,
+) error {
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	if err := c.Client.List(ctx, pvcList,
+		client.InNamespace(namespace),
+		client.MatchingLabels{
+			LabelRetainedPVC:                  valueTrue,
+			constant.LabelKubernetesInstance:  clusterName,
+			constant.LabelKubernetesComponent: roleName,
+			constant.LabelKubernetesRoleGroup: groupName,
+		},
+	); err != nil {
+		return c.apiError(err)
+	}
+
+	for i := range pvcList.Items {
+		pvc := &pvcList.Items[i]
+		before := pvc.DeepCopy()
+		delete(pvc.Labels, LabelRetainedPVC)
+		if err := c.Client.Patch(ctx, pvc, client.MergeFrom(before)); err != nil {
+			switch {
+			case errors.IsNotFound(err):
+				continue
+			case errors.IsForbidden(err):
+				log.FromContext(ctx).V(1).Info("Skipped re-adopting retained PVCs: the operator lacks PVC patch",
+					"namespace", namespace, "role", roleName, "group", groupName)
+				return nil
+			}
+			return c.apiError(err)
+		}
+		log.FromContext(ctx).Info("Re-adopted retained PVC", "name", pvc.Name, "namespace", namespace,
+			"role", roleName, "group", groupName)
+	}
+	return nil
+}
+
+// hasLabels reports whether labels carries every key of want with the same value.
+func hasLabels(labels, want map[string]string) bool {
+	for k, v := range want {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -20,6 +20,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 
 	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/productversion"
 )
 
 // ResolvedImage is everything that follows from a role's image, resolved ONCE per role group and
@@ -38,7 +39,8 @@ import (
 // so a private-registry install failed with ImagePullBackOff and nothing named the cause. Carrying
 // it beside the reference is what stops that from being re-derivable-but-forgotten.
 type ResolvedImage struct {
-	// Reference is the container image. Empty means neither the CR nor the product expressed an
+	// Reference is the container image, final: pinned to its digest and rewritten onto its
+	// registry mirror when ImageResolution says so. Empty means neither the CR nor the product expressed an
 	// opinion — with ImageResolution.ProductName unset, nothing beyond spec.image.custom is read,
 	// so a CR stating only spec.image.repo resolves to "".
 	//
@@ -82,6 +84,26 @@ type ImageResolution struct {
 	// one operator version would keep asking for that version's images forever. Evaluated here, an
 	// operator upgrade moves existing clusters onto the co-released product image.
 	Defaults v1alpha1.ImageSpec
+
+	// Mirrors rewrite image references onto internal registries, for air-gapped installs. They
+	// apply to EVERY image the framework puts in a pod — the product's, and each sidecar's, which a
+	// provider may choose itself (oauth2-proxy's upstream image, a Vector agent image a product
+	// set) — and to spec.image.custom too: a user who pasted an upstream reference still cannot pull
+	// it from behind the air gap. The longest matching prefix wins. Empty means no rewriting.
+	Mirrors []RegistryMirror
+
+	// Digests pin resolved references to digests, usually loaded with LoadImageDigests from a
+	// manifest the product ships beside its operator. The lookup uses the reference before
+	// mirroring, so one manifest serves every mirror. A reference not in the map runs by its tag.
+	Digests ImageDigests
+
+	// Versions is the product's version catalog: which resolved product versions may run, which
+	// upgrades between them are safe, and the config defaults that differ per version. The
+	// reconciler checks each role group's resolved version against it and against the version
+	// recorded in status.productVersions, so an unsafe move is refused whether it came from the CR
+	// or from new operator defaults; pkg/webhook's ValidateProductVersion refuses the CR half at
+	// admission. Nil accepts every version.
+	Versions *productversion.Catalog
 }
 
 // resolveImage folds, in decreasing precedence: the CR's spec.image, the role's declared Image, and
@@ -116,7 +138,9 @@ func resolveImage(
 	}
 
 	out := ResolvedImage{
-		Reference:      reference,
+		// The final reference, after digest pinning and mirroring: what the container runs, and
+		// what a product writing an image into a config file must write.
+		Reference:      res.rewriteImage(reference),
 		PullPolicy:     crImage.ResolvedPullPolicy(defaults),
 		PullSecretName: crImage.ResolvedPullSecretName(defaults),
 	}
@@ -21,9 +21,11 @@ import (
 	"fmt"
 	"slices"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
@@ -93,6 +95,13 @@ type RoleDeclaration struct {
 	// layer exists, so declaring it beats nobody.
 	Lifecycle *corev1.Lifecycle
 
+	// Shutdown is what the primary container does before it stops, run as its preStop hook and
+	// bounded by the role group's gracefulShutdownTimeout (see ShutdownAction). It replaces a
+	// preStop the product wired by hand, which knew nothing of the timeout the user set: an action
+	// longer than the grace period was SIGKILLed mid-way, and one exactly as long left the process
+	// no time to exit. It may not be combined with Lifecycle.PreStop; Lifecycle.PostStart stays.
+	Shutdown *ShutdownAction
+
 	// ReadinessProbe, LivenessProbe and StartupProbe replace the framework's defaults on the
 	// primary container. A nil ReadinessProbe keeps the generated TCP probe on ContainerPorts[0];
 	// nil Liveness and Startup mean none, which is the framework's deliberate position rather than
@@ -122,6 +131,36 @@ type RoleDeclaration struct {
 	// sees a quorum that cannot form until its peers resolve.
 	PublishNotReadyAddresses bool
 
+	// Quorum declares that the role's members form a quorum. The role then always gets a
+	// role-level PodDisruptionBudget, even when the CR declares no podDisruptionBudget block, whose
+	// minAvailable is the quorum computed from the role's live replica count across all of its role
+	// groups — so a node drain cannot evict the member whose loss stops the ensemble. A budget the
+	// user states still replaces it, and podDisruptionBudget.scope RoleGroup is rejected.
+	Quorum *Quorum
+
+	// NodeLabels are the keys of the node labels the role's pods need to know — the zone and rack
+	// an HDFS DataNode reports to its NameNode, the zone a Kafka broker states as broker.rack.
+	// A pod cannot read its node's labels itself: the downward API exposes spec.nodeName but not
+	// the node, and reading Nodes would need cluster-scoped RBAC in every product pod.
+	//
+	// So the framework reads them. It copies the declared labels of each scheduled pod's node
+	// into the pod's constant.AnnotationNodeLabels annotation, projects that into NodeLabelsFile,
+	// and holds the pod in an init container until the file is written, so the product's own
+	// start-up reads a finished file. RackMapping turns the file into a rack name. A key the node
+	// does not carry is listed with an empty value.
+	//
+	// The operator then needs `get` on nodes and `patch` on pods (docs/security.md §3.3.2).
+	// Empty — the default — injects nothing.
+	NodeLabels []string
+
+	// NetworkPolicy declares which of ServicePorts clients and peers may reach, and which other
+	// roles count as peers. The framework builds one NetworkPolicy per role group from it when the
+	// operator opts in with GenericReconcilerConfig.NetworkPolicies; declaring it without that
+	// opt-in fails the pass rather than silently leaving the pods unprotected.
+	//
+	// Nil means no policy for this role, and any policy a previous pass applied is reclaimed.
+	NetworkPolicy *NetworkPolicyDeclaration
+
 	// LogProducers declares which containers produce logs, and how each one's logging config file
 	// is rendered from the merged CRD logging spec into the role group ConfigMap.
 	//
@@ -150,6 +189,33 @@ type RoleDeclaration struct {
 	// gets evicted by the kubelet with nothing naming the operator.
 	LogVolumeSize string
 
+	// LogConfigReloadInterval, when positive, has the role's logging frameworks re-read their
+	// config files at this interval, so a change to logging.containers.<name>.loggers or its
+	// console/file levels applies to running pods instead of waiting for a restart. Zero — the
+	// default — changes nothing.
+	//
+	// Reloading needs three things, and the framework does all of them:
+	//
+	//   - the rendered file asks for it: logback `scan`/`scanPeriod`, log4j2 `monitorInterval`.
+	//     Every producer the framework renders a file for must therefore use a framework for which
+	//     productlogging.SupportsReload holds; log4j 1.x and Python cannot, and a declaration
+	//     naming them fails rather than promising a reload that never happens.
+	//   - the file is updated in place. The logging files move out of the role group ConfigMap
+	//     into their own, "<role group resource>-log-config", mounted as a whole directory at
+	//     constant.KubedoopLogDirMount on the primary container. The kubelet refreshes a directory
+	//     mount within its sync period; it never refreshes a subPath mount. The product must point
+	//     its process at the file THERE (LogConfigPath), not copy it elsewhere at start-up.
+	//   - the framework does not roll the pods for it: a logging-only change rewrites that
+	//     ConfigMap and nothing else, so the pod template is unchanged. Restarts are not skipped
+	//     on a workload labelled for the restarter (constant.LabelRestarterEnable): it rolls the
+	//     pods for any change to a ConfigMap they mount, this one included.
+	//     vector.yaml is not a logging file in this sense: the agent does not reload it, so it
+	//     stays in the role group ConfigMap.
+	//
+	// Must be whole seconds, which is what both frameworks' settings take. Turning it on or off
+	// changes the pod template (the mount), so that one change rolls the pods.
+	LogConfigReloadInterval time.Duration
+
 	// Env are environment variables on the primary container that the product declares rather than
 	// computes — a downward-API POD_NAME, a secretKeyRef.
 	//
@@ -165,8 +231,8 @@ type RoleDeclaration struct {
 	Env []corev1.EnvVar
 
 	// ConfigDefaults is the product's default for the FRAMEWORK-owned half of this role's config
-	// block — resources, affinity, gracefulShutdownTimeout, logging — folded BENEATH the CR's role
-	// and role group levels by FoldCommonConfig. Anything the user states anywhere wins.
+	// block — resources, affinity, placement, gracefulShutdownTimeout, logging — folded BENEATH the
+	// CR's role and role group levels by FoldCommonConfig. Anything the user states anywhere wins.
 	//
 	// It is the one field here with a user layer above it, and the only place in this struct where
 	// precedence is a question at all. The product's defaults for its OWN config fields are not
@@ -175,6 +241,9 @@ type RoleDeclaration struct {
 	// An anti-affinity default belongs here and could not live on the old handler, because its
 	// selector names the CLUSTER and the handler is a process-wide singleton shared by every
 	// cluster the operator serves.
+	//
+	// So does a zone spread: placement.topologySpreadConstraints built with PreferredTopologySpread
+	// carry no selector at all, and the framework gives them the role's when it builds the pod.
 	ConfigDefaults *v1alpha1.RoleGroupConfigSpec
 
 	// Optional silences the UnusedRoleDeclaration warning for a role a CR need not deploy.
@@ -186,6 +255,29 @@ type RoleDeclaration struct {
 	Optional bool
 }
 
+// validateLogConfigReload checks LogConfigReloadInterval against the producers it would apply to.
+// Only producers whose file the framework renders count: a product that writes its own config file
+// (an empty Framework) decides for itself whether that file reloads.
+func (d RoleDeclaration) validateLogConfigReload() []string {
+	interval := d.LogConfigReloadInterval
+	if interval == 0 {
+		return nil
+	}
+	if interval < 0 || interval%time.Second != 0 {
+		return []string{fmt.Sprintf("logConfigReloadInterval %s must be a positive number of whole seconds", interval)}
+	}
+	var problems []string
+	for _, producer := range d.LogProducers {
+		if producer.Framework != "" && !productlogging.SupportsReload(producer.Framework) {
+			problems = append(problems, fmt.Sprintf(
+				"logConfigReloadInterval is set, but container %q logs with %s, which cannot reload its "+
+					"config file by itself; a level change would wait for a restart nothing schedules",
+				producer.Container, producer.Framework))
+		}
+	}
+	return problems
+}
+
 // DataVolume describes a role's data PVC.
 type DataVolume struct {
 	// Name is the volume and claim-template name. Empty uses DefaultDataVolumeName.
@@ -203,6 +295,11 @@ const DefaultDataVolumeName = builder.DefaultDataVolumeName
 // configuration (see reservedVolumeNames).
 const ConfigVolumeName = "config"
 
+// LogConfigVolumeName is the pod volume that mounts the role group's logging ConfigMap when the
+// role reloads its logging config (RoleDeclaration.LogConfigReloadInterval). Reserved like
+// ConfigVolumeName.
+const LogConfigVolumeName = "log-config"
+
 // reservedVolumeNames are the pod volume names the framework creates itself, mapped to what each one
 // is for so the rejection can say WHICH thing the product would have displaced.
 //
@@ -217,10 +314,33 @@ const ConfigVolumeName = "config"
 // rather than a framework-supplied surprise.
 var reservedVolumeNames = map[string]string{
 	ConfigVolumeName:                    "the role group's ConfigMap volume",
+	LogConfigVolumeName:                 "the role group's logging ConfigMap volume",
 	vector.VectorLogVolumeName:          "the shared log volume",
 	vector.VectorConfigVolumeName:       "the Vector agent's config volume",
 	vector.VectorDataVolumeName:         "the Vector agent's data volume",
 	sidecar.JMXExporterConfigVolumeName: "the JMX exporter's config volume",
+	ServerTLSVolumeName:                 "the spec.tls server certificate volume",
+	InternalTLSVolumeName:               "the spec.tls internal certificate volume",
+	NodeLabelsVolumeName:                "the published node labels volume",
+}
+
+// Quorum is the number of a role's members that must stay up for the product to keep working.
+type Quorum struct {
+	// Size is a fixed quorum size. Zero means a strict majority of the role's live replicas,
+	// floor(n/2)+1, which is what a ZooKeeper ensemble and a set of HDFS JournalNodes need.
+	Size int32
+}
+
+// MinAvailable returns how many of replicas members must stay available. It is never below one:
+// a role with no live member yet still wants the first one protected once it exists.
+func (q *Quorum) MinAvailable(replicas int32) int32 {
+	if q == nil {
+		return 0
+	}
+	if q.Size > 0 {
+		return q.Size
+	}
+	return replicas/2 + 1
 }
 
 // RoleCatalog is a product's complete statement about the roles it supports, for ONE cluster. The
@@ -340,6 +460,18 @@ func (d RoleDeclaration) Validate(roleName string) error {
 	if err := productlogging.ValidateProducers(d.LogProducers); err != nil {
 		problems = append(problems, err.Error())
 	}
+	problems = append(problems, d.validateLogConfigReload()...)
+	problems = append(problems, d.NetworkPolicy.validate(d.ServicePorts)...)
+	if d.Quorum != nil && d.Quorum.Size < 0 {
+		problems = append(problems, fmt.Sprintf("quorum size %d is negative", d.Quorum.Size))
+	}
+	problems = append(problems, d.Shutdown.validate(d.Lifecycle)...)
+	for _, key := range d.NodeLabels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			problems = append(problems, fmt.Sprintf("nodeLabels key %q is not a label key: %s",
+				key, strings.Join(errs, ", ")))
+		}
+	}
 
 	if len(problems) > 0 {
 		return fmt.Errorf("role %q declaration: %s", roleName, strings.Join(problems, "; "))
@@ -18,6 +18,7 @@ package reconciler_test
 
 import (
 	"context"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -26,6 +27,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	commonsv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/productlogging"
 	"github.com/zncdatadev/operator-go/pkg/reconciler"
 	"github.com/zncdatadev/operator-go/pkg/testutil"
 )
@@ -193,6 +195,68 @@ var _ = Describe("RoleDeclaration.Validate", func() {
 		Expect(err.Error()).To(ContainSubstring("datanode"))
 		Expect(err.Error()).To(ContainSubstring("nodeAffinty"))
 	})
+
+	It("accepts a log config reload interval for producers that reload by themselves", func() {
+		decl := reconciler.RoleDeclaration{
+			LogProducers: []productlogging.ContainerLogging{
+				{Container: "main", Framework: productlogging.LoggingFrameworkLogback},
+				// The product writes this one itself, so whether it reloads is the product's call.
+				{Container: "helper", LogFileName: "helper.log4j.xml"},
+			},
+			LogConfigReloadInterval: 30 * time.Second,
+		}
+		Expect(decl.Validate("server")).To(Succeed())
+	})
+
+	It("rejects a log config reload interval for a log4j producer, naming the container", func() {
+		decl := reconciler.RoleDeclaration{
+			LogProducers: []productlogging.ContainerLogging{
+				{Container: "broker", Framework: productlogging.LoggingFrameworkLog4j},
+			},
+			LogConfigReloadInterval: 30 * time.Second,
+		}
+		err := decl.Validate("broker")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(`"broker"`))
+		Expect(err.Error()).To(ContainSubstring("cannot reload"))
+	})
+
+	It("rejects a log config reload interval that is not whole seconds", func() {
+		decl := reconciler.RoleDeclaration{LogConfigReloadInterval: 500 * time.Millisecond}
+		Expect(decl.Validate("server")).NotTo(Succeed())
+	})
+
+	It("rejects a shutdown action that is not exactly one hook, or that competes with a preStop", func() {
+		for _, decl := range []reconciler.RoleDeclaration{
+			{Shutdown: &reconciler.ShutdownAction{}},
+			{Shutdown: &reconciler.ShutdownAction{Exec: []string{"stop"}, HTTPGet: &corev1.HTTPGetAction{Path: "/stop"}}},
+			{
+				Shutdown:  &reconciler.ShutdownAction{Exec: []string{"stop"}},
+				Lifecycle: &corev1.Lifecycle{PreStop: &corev1.LifecycleHandler{Sleep: &corev1.SleepAction{Seconds: 5}}},
+			},
+		} {
+			err := decl.Validate("datanode")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("shutdown"))
+		}
+		Expect(reconciler.RoleDeclaration{Shutdown: &reconciler.ShutdownAction{Exec: []string{"stop"}}}.
+			Validate("datanode")).To(Succeed())
+	})
+
+	It("rejects a node label key that is not a label key, naming it", func() {
+		decl := reconciler.RoleDeclaration{NodeLabels: []string{"topology.kubernetes.io/zone", "rack name"}}
+		err := decl.Validate("datanode")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring(`"rack name"`))
+		Expect(err.Error()).NotTo(ContainSubstring("zone"))
+	})
+
+	It("rejects a data volume named after the logging ConfigMap volume", func() {
+		decl := reconciler.RoleDeclaration{
+			DataVolume: &reconciler.DataVolume{Name: reconciler.LogConfigVolumeName, MountPath: "/kubedoop/data"},
+		}
+		Expect(decl.Validate("server")).NotTo(Succeed())
+	})
 })
 
 var _ = Describe("RoleProviderFunc", func() {
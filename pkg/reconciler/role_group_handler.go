@@ -19,7 +19,9 @@ package reconciler
 import (
 	"context"
 	"fmt"
+	"maps"
 	"path"
+	"slices"
 
 	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
 	"github.com/zncdatadev/operator-go/pkg/common"
@@ -30,8 +32,11 @@ import (
 	"github.com/zncdatadev/operator-go/pkg/vector"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -58,22 +63,43 @@ type RoleGroupResources struct {
 	// ConfigMap contains configuration files for the role group.
 	ConfigMap *corev1.ConfigMap
 
+	// LogConfigMap holds the role group's logging config files when the role reloads them
+	// (RoleDeclaration.LogConfigReloadInterval), named "<ResourceName>-log-config". It is apart
+	// from ConfigMap so that a logging-only change touches nothing else. Nil otherwise, and a nil
+	// slot reclaims a logging ConfigMap an earlier reconcile applied.
+	LogConfigMap *corev1.ConfigMap
+
 	// Service is the client-facing service (optional).
 	Service *corev1.Service
 
 	// HeadlessService is the headless service for StatefulSet network identity.
 	HeadlessService *corev1.Service
 
-	// PodDisruptionBudget is an optional escape hatch for a custom, role-group-scoped PDB.
-	// The framework's own PDB (from roleConfig.podDisruptionBudget) is a role-level resource
-	// covering all of a role's groups and is emitted once per role by the generic reconciler
-	// (see BaseRoleGroupHandler.BuildRolePodDisruptionBudget); it is NOT set here. Leave this
-	// nil unless a product deliberately needs an extra per-group PDB.
+	// PodDisruptionBudget is the role group's own PDB. The framework's PDB (from
+	// roleConfig.podDisruptionBudget) is by default a role-level resource covering all of a role's
+	// groups and is emitted once per role by the generic reconciler (see
+	// BaseRoleGroupHandler.BuildRolePodDisruptionBudget); BaseRoleGroupHandler sets this slot only
+	// when the role's podDisruptionBudget.scope is RoleGroup. A product may also set it as an
+	// escape hatch for a deliberately extra per-group PDB.
 	PodDisruptionBudget *policyv1.PodDisruptionBudget
 
 	// MetricsService is a headless service with Prometheus scrape annotations (optional).
 	MetricsService *corev1.Service
 
+	// NetworkPolicy governs ingress to (and optionally egress from) the role group's pods. The
+	// framework fills it from RoleDeclaration.NetworkPolicy after BuildResources returns, because the
+	// policy follows the StatefulSet's selector and the MetricsService's ports; a handler that sets
+	// it itself is applied as-is. It is only applied, and only reclaimed, when
+	// GenericReconcilerConfig.NetworkPolicies is set.
+	NetworkPolicy *networkingv1.NetworkPolicy
+
+	// ServiceMonitor is the Prometheus Operator ServiceMonitor scraping MetricsService, named like
+	// it. The framework fills it from MetricsService after BuildResources returns (see
+	// buildRoleGroupServiceMonitor), so it always restates the Service's port, scheme and path; a
+	// handler that sets it itself is applied as-is. It is only applied, and only reclaimed, when
+	// GenericReconcilerConfig.ServiceMonitors is set and the monitoring.coreos.com CRD is installed.
+	ServiceMonitor *unstructured.Unstructured
+
 	// ExtraResources are additional product-specific resources for this role group that the
 	// framework's fixed fields have no slot for — e.g. a listeners.kubedoop.dev Listener CR
 	// that the pods reference by name through an ephemeral CSI volume. They flow through the
@@ -129,8 +155,10 @@ type RoleGroupResources struct {
 // Reserved names: the framework already uses the pod volume/mount name "config" (the config
 // ConfigMap volume, always present), and the name of the role's data volume when
 // RoleDeclaration.DataVolume is set — builder.DefaultDataVolumeName ("data") unless the
-// declaration names it. A provider must not reuse either, because duplicate volume names make the
-// Kubernetes API server reject the pod — a hard reconcile failure.
+// declaration names it — and the names of the role group's named data volumes
+// (RoleGroupBuildContext.DataVolumeMountPaths, expanded "<key>-<i>" for a count above one). A
+// provider must not reuse any of them, because duplicate volume names make the Kubernetes API
+// server reject the pod — a hard reconcile failure.
 type VolumeProvider interface {
 	Volumes() []corev1.Volume
 	VolumeMounts() []corev1.VolumeMount
@@ -180,6 +208,15 @@ type RoleBuildContext struct {
 	// follows the RESOLVED image, so it is present whenever the version came from the operator's
 	// own defaults too, and empty when the framework did not assemble the reference.
 	ProductVersion string
+
+	// Declaration is the product's declaration of this role, as on RoleGroupBuildContext.
+	Declaration RoleDeclaration
+
+	// Replicas is the live replica count across the role's role groups: the sum of their
+	// StatefulSets' replicas as applied, not as the spec asks for. It is filled only for a role
+	// that declares a Quorum, which is what it is for — a quorum is a fraction of the members that
+	// exist — and is zero otherwise.
+	Replicas int32
 }
 
 // RoleGroupBuildContext provides context for building role group resources.
@@ -283,11 +320,34 @@ type RoleGroupBuildContext struct {
 	// that could set it (see ResolvedImage.Reference).
 	ResolvedImage ResolvedImage
 
+	// imageResolution rewrites the images the framework did not resolve itself — the sidecars' —
+	// onto the same mirrors and digests as ResolvedImage. Nil outside the reconciler; see
+	// RewriteImage.
+	imageResolution *ImageResolution
+
 	// ProductName is GenericReconcilerConfig.ImageResolution.ProductName, stamped as
 	// app.kubernetes.io/name. Empty means the product resolves its own images and the label is
 	// omitted.
 	ProductName string
 
+	// DataVolumeMountPaths are the mount paths of the role group's named data volumes
+	// (resources.volumes), keyed by volume name, in index order: one path for a volume of count one,
+	// "<mountPath>/0" onwards for a JBOD volume. A product joins them into its data-directory setting
+	// (dfs.datanode.data.dir, log.dirs) rather than re-deriving the naming rule. Nil means the role
+	// group declares none; the primary DataVolume is not in it.
+	//
+	// WRITTEN BY THE FRAMEWORK from the folded config, before RoleGroupResolver runs, so a config
+	// file and the StatefulSet's mounts read the same paths.
+	DataVolumeMountPaths map[string][]string
+
+	// TLS is the cluster's spec.tls resolved for this role group: the keystore and truststore paths
+	// a product writes into its config, and the volumes BaseRoleGroupHandler mounts on every pod to
+	// back them. Nil means the cluster declares no TLS SecretClass.
+	//
+	// WRITTEN BY THE FRAMEWORK, before RoleGroupResolver runs, so a config file and the StatefulSet
+	// read the same paths.
+	TLS *RoleGroupTLS
+
 	// VectorAggregatorAddress is the resolved Vector aggregator discovery address, populated by
 	// GenericReconciler when the Vector agent is enabled and the CR implements
 	// VectorAggregatorProvider (the reconciler reads its ConfigMap name and resolves the address
@@ -296,6 +356,13 @@ type RoleGroupBuildContext struct {
 	// off).
 	VectorAggregatorAddress string
 
+	// VectorSinks are the further destinations of the role group's logs, populated by
+	// GenericReconciler from the CR's VectorSinkProvider under the same gate as
+	// VectorAggregatorAddress. RenderLoggingConfigMapData renders them into vector.yaml, the
+	// Vector provider gives the agent what they need (vector.WithSinks), and their ports are opened
+	// on the role group's NetworkPolicy.
+	VectorSinks []vector.Sink
+
 	// vectorLogPipelineActive is the resolved answer to "will the Vector sidecar actually be
 	// injected into this role group's pods?" — the agent is enabled AND at least one producer is
 	// declared AND something supplies vector.yaml (see GenericReconciler.buildSidecarManager,
@@ -328,11 +395,38 @@ type VectorAggregatorProvider interface {
 	VectorAggregatorConfigMapName() string
 }
 
+// VectorSinkProvider is optionally implemented by a product CR to ship role group logs to
+// destinations other than the Vector aggregator: Loki, Elasticsearch/OpenSearch, Kafka, S3, or a
+// local file or stdout (see vector.Sink). The product maps its own API onto the typed sinks —
+// a logging section of its cluster spec, or a referenced object it resolves with the client
+// (an S3Connection through s3.ResolveConnection, a Secret holding credentials) — because the CRD
+// shape of that choice is the product's.
+//
+// It is consulted under the same gate as VectorAggregatorProvider: the Vector agent is enabled
+// and at least one producer is declared. A CR may implement both; the aggregator then receives
+// the stream alongside the sinks. One that returns sinks may leave its aggregator ConfigMap name
+// empty, which is how a team without a central aggregator ships logs at all.
+type VectorSinkProvider interface {
+	VectorSinks(ctx context.Context, c client.Client) ([]vector.Sink, error)
+}
+
 // ContainerLogging returns the deep-merged logging config for a container (keyed by
 // container name), or nil when the product CRD configured no logging for it. The declaration
 // type and rendering live in pkg/productlogging; this accessor must live here because it is a
 // method on the reconciler's RoleGroupBuildContext.
 func (c *RoleGroupBuildContext) ContainerLogging(container string) *v1alpha1.LoggingConfigSpec {
+	logging := c.logging()
+	if logging == nil {
+		return nil
+	}
+	if cfg, ok := logging.Containers[container]; ok {
+		return &cfg
+	}
+	return nil
+}
+
+// logging returns the role group's folded logging spec, or nil when none is configured.
+func (c *RoleGroupBuildContext) logging() *v1alpha1.LoggingSpec {
 	// The FOLD, not MergedConfig.Logging, for the same reason vectorEnabledFor reads it: a product
 	// calls this from its RoleGroupResolver, which runs in stage 2 — before stage 3 assigns
 	// MergedConfig. Reading the copy returned nil there, so a product rendering its own logging
@@ -344,13 +438,49 @@ func (c *RoleGroupBuildContext) ContainerLogging(container string) *v1alpha1.Log
 		// A context assembled by hand carries no folded spec.
 		logging = c.MergedConfig.Logging
 	}
+	return logging
+}
+
+// vectorLogFilters collects the shipping rules (LoggingConfigSpec.Filter) of the declared
+// producers, keyed by the log-directory segment the pipeline reports as .container — the key the
+// rules are compiled against (vector.VectorConfigData.Filters).
+//
+// Rules the agent would never apply are rejected rather than dropped: a filter on a container
+// that declares no producer reads no stream, and two producers sharing a log directory with
+// different rules cannot both be honoured, since the pipeline tells them apart only by that
+// directory. Either way a user who asked for a secret to be redacted would otherwise see it
+// shipped with nothing reporting why.
+func vectorLogFilters(buildCtx *RoleGroupBuildContext, producers []productlogging.ContainerLogging) (map[string]v1alpha1.LogFilterSpec, error) {
+	logging := buildCtx.logging()
 	if logging == nil {
-		return nil
+		return nil, nil
 	}
-	if cfg, ok := logging.Containers[container]; ok {
-		return &cfg
+	invalid := func(err error) error {
+		return NewValidationError("logging", buildCtx.RoleName, buildCtx.RoleGroupName, err)
 	}
-	return nil
+	filters := make(map[string]v1alpha1.LogFilterSpec)
+	claimed := make(map[string]bool)
+	for _, decl := range producers {
+		claimed[decl.Container] = true
+		cfg, ok := logging.Containers[decl.Container]
+		if !ok || cfg.Filter == nil {
+			continue
+		}
+		segment := productlogging.LogDirSegment(decl)
+		if existing, ok := filters[segment]; ok && !equality.Semantic.DeepEqual(existing, *cfg.Filter) {
+			return nil, invalid(fmt.Errorf("containers sharing the log directory %q have different log filters", segment))
+		}
+		filters[segment] = *cfg.Filter
+	}
+	for _, container := range slices.Sorted(maps.Keys(logging.Containers)) {
+		if logging.Containers[container].Filter != nil && !claimed[container] {
+			return nil, invalid(fmt.Errorf("container %q has a log filter but writes no logs the Vector agent collects", container))
+		}
+	}
+	if err := vector.ValidateFilters(filters); err != nil {
+		return nil, invalid(err)
+	}
+	return filters, nil
 }
 
 // RenderContainerLogging is a build-context convenience over productlogging.RenderConfigFile:
@@ -361,16 +491,40 @@ func RenderContainerLogging(buildCtx *RoleGroupBuildContext, decl productlogging
 	// Emit the rolling file appender only when the Vector sidecar is really injected: file logging
 	// is coupled to Vector, which owns the shared log volume the appender writes into. Gating here
 	// means products building their own ConfigMap inherit the behavior for free.
-	return productlogging.RenderConfigFile(
-		buildCtx.ContainerLogging(decl.Container), decl, vectorLogPipelineActive(buildCtx))
+	return productlogging.RenderConfigFileWithOptions(buildCtx.ContainerLogging(decl.Container), decl,
+		productlogging.ConfigFileOptions{
+			FileAppender:   vectorLogPipelineActive(buildCtx),
+			ReloadInterval: buildCtx.Declaration.LogConfigReloadInterval,
+		})
+}
+
+// LogConfigPath returns where a container's process should read its logging config file from. With
+// RoleDeclaration.LogConfigReloadInterval set that is the logging ConfigMap's directory mount, which
+// the kubelet keeps current; a copy taken at start-up would never see a change. Without it the file
+// is in the role group ConfigMap, under configDir — wherever the product mounts or copies that.
+func LogConfigPath(buildCtx *RoleGroupBuildContext, decl productlogging.ContainerLogging, configDir string) (string, error) {
+	name, err := productlogging.ConfigFileName(decl)
+	if err != nil {
+		return "", err
+	}
+	if buildCtx.Declaration.LogConfigReloadInterval > 0 {
+		return path.Join(constant.KubedoopLogDirMount, name), nil
+	}
+	return path.Join(configDir, name), nil
+}
+
+// logConfigMapName is the name of a role group's logging ConfigMap (RoleGroupResources.LogConfigMap).
+func logConfigMapName(resourceName string) string {
+	return resourceName + "-log-config"
 }
 
 // RenderLoggingConfigMapData renders the logging-related entries for a role group ConfigMap:
 //   - one logging config file per declared producer (level config, plus the rolling file appender
 //     when the Vector sidecar is injected), keyed by the generator file name (e.g. "logback.xml"), and
 //   - the Vector agent config ("vector.yaml") when the Vector agent is enabled AND the aggregator
-//     address has been resolved (buildCtx.VectorAggregatorAddress, populated by GenericReconciler
-//     from the CR's VectorAggregatorProvider).
+//     address or at least one sink has been resolved (buildCtx.VectorAggregatorAddress and
+//     buildCtx.VectorSinks, populated by GenericReconciler from the CR's VectorAggregatorProvider
+//     and VectorSinkProvider).
 //
 // The Vector sidecar reads its config from the role group ConfigMap (the provider mounts it and
 // runs "vector --config <mount>/vector.yaml"), so the framework owns vector.yaml generation from
@@ -405,13 +559,19 @@ func RenderLoggingConfigMapData(buildCtx *RoleGroupBuildContext, producers []pro
 		}
 		data[filename] = content
 	}
-	// Generate vector.yaml only when the aggregator address is known. If Vector is enabled but the
-	// CR does not expose an aggregator ConfigMap (VectorAggregatorProvider), the address is empty
-	// and the framework leaves vector.yaml to the product.
-	if vectorLogPipelineActive(buildCtx) && buildCtx.VectorAggregatorAddress != "" {
+	// Generate vector.yaml only when there is somewhere to ship to. If Vector is enabled but the
+	// CR exposes neither an aggregator ConfigMap (VectorAggregatorProvider) nor sinks
+	// (VectorSinkProvider), both are empty and the framework leaves vector.yaml to the product.
+	if vectorLogPipelineActive(buildCtx) && (buildCtx.VectorAggregatorAddress != "" || len(buildCtx.VectorSinks) > 0) {
+		filters, err := vectorLogFilters(buildCtx, producers)
+		if err != nil {
+			return nil, err
+		}
 		vectorConfig, err := vector.RenderVectorConfig(vector.VectorConfigData{
 			LogDir:            constant.KubedoopLogDir,
 			AggregatorAddress: buildCtx.VectorAggregatorAddress,
+			Sinks:             buildCtx.VectorSinks,
+			Filters:           filters,
 			Namespace:         buildCtx.ClusterNamespace,
 			ClusterName:       buildCtx.ClusterName,
 			RoleName:          buildCtx.RoleName,
@@ -509,6 +669,17 @@ func (c *RoleGroupBuildContext) EffectiveConfig() *v1alpha1.RoleGroupConfigSpec
 	return c.RoleGroupSpec.GetConfig()
 }
 
+// RewriteImage returns reference pinned to its digest and rewritten onto its registry mirror, by the
+// operator's ImageResolution — the treatment ResolvedImage.Reference has already had. A product that
+// builds a container of its own, with an image the framework never saw, passes that image through
+// here so an air-gapped install can pull it. Outside the reconciler it returns reference unchanged.
+func (c *RoleGroupBuildContext) RewriteImage(reference string) string {
+	if c.imageResolution == nil {
+		return reference
+	}
+	return c.imageResolution.rewriteImage(reference)
+}
+
 // LogFileTarget returns the path a producer's rolling log file must be written to, or "" meaning
 // console only.
 //
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"maps"
+	"strconv"
 
 	corev1 "k8s.io/api/core/v1"
 	k8sruntime "k8s.io/apimachinery/pkg/runtime"
@@ -142,8 +143,14 @@ func (c *Contribution) overrides() (*v1alpha1.OverridesSpec, error) {
 	return out, nil
 }
 
-// HeapMB returns floor(limit * factor) in MiB from a role group's effective memory limit, and
-// whether a limit was set at all.
+// HeapMB returns a JVM heap size in MiB from a role group's effective memory limit, and whether a
+// limit was set at all.
+//
+// Without a resources.heap policy it is floor(limit * factor). With one, the policy applies:
+// reservedOffHeap comes off the limit first, the policy's fraction replaces factor, and the result
+// is clamped to [min, max] and never exceeds what the reserve left. factor stays the product's
+// default fraction, so a product that passed 0.8 keeps 0.8 until a user or its own ConfigDefaults
+// state a fraction. The policy was validated when the config was folded.
 //
 // Three operators compute exactly this, all with factor 0.8, all slightly differently; a fourth
 // froze the answer into a literal. It is here so the arithmetic — and the "no limit means no
@@ -157,5 +164,30 @@ func HeapMB(cfg *v1alpha1.RoleGroupConfigSpec, factor float64) (int64, bool) {
 	if limit <= 0 {
 		return 0, false
 	}
-	return int64(float64(limit/(1024*1024)) * factor), true
+
+	const mib = 1024 * 1024
+	policy := cfg.Resources.Heap
+	available := limit
+	if policy != nil && policy.ReservedOffHeap != nil {
+		available -= policy.ReservedOffHeap.Value()
+		if available <= 0 {
+			return 0, false
+		}
+	}
+	if policy != nil && policy.Fraction != nil {
+		if f, err := strconv.ParseFloat(*policy.Fraction, 64); err == nil {
+			factor = f
+		}
+	}
+	heap := int64(float64(available/mib) * factor)
+	if policy != nil {
+		if policy.Min != nil && heap < policy.Min.Value()/mib {
+			heap = policy.Min.Value() / mib
+		}
+		if policy.Max != nil && heap > policy.Max.Value()/mib {
+			heap = policy.Max.Value() / mib
+		}
+		heap = min(heap, available/mib)
+	}
+	return heap, true
 }
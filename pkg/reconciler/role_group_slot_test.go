@@ -109,7 +109,7 @@ func createSlotCR(ctx context.Context, name string, labels map[string]string, ro
 		for roleName, role := range roles {
 			for groupName := range role.RoleGroups {
 				base := reconciler.RoleGroupResourceName(name, roleName, groupName)
-				for _, n := range []string{base, base + "-headless", base + "-metrics"} {
+				for _, n := range []string{base, base + "-headless", base + "-metrics", base + "-log-config"} {
 					objMeta := metav1.ObjectMeta{Name: n, Namespace: testNamespace}
 					_ = k8sClient.Delete(ctx, &corev1.Service{ObjectMeta: objMeta})
 					_ = k8sClient.Delete(ctx, &corev1.ConfigMap{ObjectMeta: objMeta})
@@ -138,6 +138,60 @@ func defaultGroupRole() v1alpha1.RoleSpec {
 	}
 }
 
+var _ = Describe("Logging ConfigMap slot", func() {
+	var ctx context.Context
+
+	BeforeEach(func() { ctx = context.Background() })
+
+	logConfigMap := func(buildCtx *reconciler.RoleGroupBuildContext) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: buildCtx.ResourceName + "-log-config", Namespace: buildCtx.ClusterNamespace},
+			Data:       map[string]string{"logback.xml": "<configuration />"},
+		}
+	}
+
+	It("applies the logging ConfigMap marked as the slot, and deletes it once the role stops shipping one", func() {
+		name := slotCRName("lc-off")
+		createSlotCR(ctx, name, nil, map[string]v1alpha1.RoleSpec{"w": defaultGroupRole()})
+		key := types.NamespacedName{Namespace: testNamespace, Name: reconciler.RoleGroupResourceName(name, "w", "default") + "-log-config"}
+
+		emit := true
+		r := slotReconciler(k8sClient, nil, func(buildCtx *reconciler.RoleGroupBuildContext, res *reconciler.RoleGroupResources) {
+			if emit {
+				res.LogConfigMap = logConfigMap(buildCtx)
+			}
+		})
+
+		Expect(reconcileSlotCR(ctx, r, name)).To(Succeed())
+		cm := &corev1.ConfigMap{}
+		Expect(k8sClient.Get(ctx, key, cm)).To(Succeed())
+		Expect(cm.Labels).To(HaveKeyWithValue(reconciler.LabelLogConfigMap, "true"))
+
+		emit = false
+		Expect(reconcileSlotCR(ctx, r, name)).To(Succeed())
+		Expect(k8serrors.IsNotFound(k8sClient.Get(ctx, key, &corev1.ConfigMap{}))).To(BeTrue(),
+			"turning reload off must remove the logging ConfigMap nothing mounts any more")
+	})
+
+	It("leaves the ConfigMap of a role group named \"<group>-log-config\" alone", func() {
+		name := slotCRName("lc-sib")
+		createSlotCR(ctx, name, nil, map[string]v1alpha1.RoleSpec{"w": {
+			RoleGroups: map[string]v1alpha1.RoleGroupSpec{
+				"default":            {Replicas: ptr.To(int32(1))},
+				"default-log-config": {Replicas: ptr.To(int32(1))},
+			},
+		}})
+		sibling := types.NamespacedName{Namespace: testNamespace, Name: reconciler.RoleGroupResourceName(name, "w", "default-log-config")}
+
+		r := slotReconciler(k8sClient, nil, nil)
+		for range 2 {
+			Expect(reconcileSlotCR(ctx, r, name)).To(Succeed())
+			Expect(k8sClient.Get(ctx, sibling, &corev1.ConfigMap{})).To(Succeed(),
+				"the reclaim of role group \"default\" must not take the sibling's ConfigMap for its logging slot")
+		}
+	})
+})
+
 // The framework addresses every fixed slot of RoleGroupResources by a name it derives, on both
 // paths that remove one. A slot filled under a different name used to be applied, owner-referenced
 // and then reclaimed by nothing — surviving every teardown until the cluster CR itself was deleted.
@@ -0,0 +1,208 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/zncdatadev/operator-go/pkg/builder"
+	"github.com/zncdatadev/operator-go/pkg/constant"
+)
+
+// LabelServiceMonitor marks a ServiceMonitor as the framework's per-role-group slot
+// (RoleGroupResources.ServiceMonitor); its value is the role group name. Only objects carrying it
+// are reclaimed, for the reason LabelMetricsService exists: the slot's derived name is also a name a
+// product may use for a ServiceMonitor of its own, shipped as an extra resource.
+const LabelServiceMonitor = "metrics." + constant.KubedoopDomain + "/service-monitor"
+
+// ServiceMonitorConfig turns on Prometheus Operator ServiceMonitors for role group metrics
+// Services (see GenericReconcilerConfig.ServiceMonitors).
+type ServiceMonitorConfig struct {
+	// Labels are stamped on every ServiceMonitor, typically the label the Prometheus instance's
+	// serviceMonitorSelector matches ("release: prometheus" for kube-prometheus-stack).
+	Labels map[string]string
+
+	// TLSCA maps a SecretClass name to the Secret key holding the CA that SecretClass issues from,
+	// in the cluster's namespace. It is consulted only for metrics served over https. The SDK never
+	// reads SecretClass objects — where a class keeps its CA is the secret-operator's business — so
+	// the operator, which knows its deployment, supplies the mapping. Nil, or a nil result, leaves
+	// verification to the Prometheus instance's own trust store.
+	TLSCA func(secretClass string) *corev1.SecretKeySelector
+
+	// InsecureSkipVerify disables certificate verification for https metrics. Prefer TLSCA.
+	InsecureSkipVerify bool
+
+	// ClusterDomain is the DNS domain the TLS server name is rendered under. Defaults to
+	// DefaultClusterDomain.
+	ClusterDomain string
+}
+
+// serviceMonitorsInstalled reports whether the ServiceMonitor CRD is served, asking discovery
+// through the client's RESTMapper. A mapper that does not know the group answers NoMatch, which is
+// the ordinary "Prometheus Operator is not installed" case and not an error.
+func serviceMonitorsInstalled(mapper meta.RESTMapper) (bool, error) {
+	if mapper == nil {
+		return false, nil
+	}
+	gvk := builder.ServiceMonitorGVK
+	if _, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// buildRoleGroupServiceMonitor derives the role group's ServiceMonitor from its metrics Service, so
+// the port, scheme and path Prometheus Operator scrapes are by construction the ones the
+// prometheus.io annotations advertise. It returns nil when the role group ships no metrics Service.
+//
+// The relabelings write the cluster, role and role group onto every series: the pod's own labels
+// reach Prometheus only as __meta_* labels, which most scrape configs drop.
+//
+// For https, the server name is the role group's headless Service: resolveRoleGroupTLS scopes the
+// serving certificate to it, and the scraper dials pod IPs, which no certificate names.
+func buildRoleGroupServiceMonitor(buildCtx *RoleGroupBuildContext, resources *RoleGroupResources, cfg *ServiceMonitorConfig) *unstructured.Unstructured {
+	if resources.MetricsService == nil {
+		return nil
+	}
+	b := builder.NewServiceMonitorBuilder(resources.MetricsService).
+		WithLabels(cfg.Labels).
+		WithTargetLabels(map[string]string{
+			"cluster":    buildCtx.ClusterName,
+			"role":       buildCtx.RoleName,
+			"role_group": buildCtx.RoleGroupName,
+		})
+
+	if resources.MetricsService.Annotations["prometheus.io/scheme"] == "https" {
+		domain := cfg.ClusterDomain
+		if domain == "" {
+			domain = DefaultClusterDomain
+		}
+		tls := &builder.ServiceMonitorTLS{
+			ServerName:         fmt.Sprintf("%s-headless.%s.svc.%s", buildCtx.ResourceName, buildCtx.ClusterNamespace, domain),
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+		}
+		if secretClass := serverSecretClass(buildCtx); secretClass != "" && cfg.TLSCA != nil {
+			tls.CA = cfg.TLSCA(secretClass)
+		}
+		b.WithTLS(tls)
+	}
+	return b.Build()
+}
+
+// serverSecretClass is the SecretClass that issues the role group's serving certificate: the
+// cluster's server class, or its internal class when only that one is set.
+func serverSecretClass(buildCtx *RoleGroupBuildContext) string {
+	if buildCtx.ClusterSpec == nil || buildCtx.ClusterSpec.TLS == nil {
+		return ""
+	}
+	if class := buildCtx.ClusterSpec.TLS.ServerSecretClass; class != "" {
+		return class
+	}
+	return buildCtx.ClusterSpec.TLS.InternalSecretClass
+}
+
+// applyServiceMonitor applies the role group's ServiceMonitor, or reclaims it when the role group
+// stops shipping metrics. It does nothing while the CRD is not installed: the metrics Service still
+// carries the prometheus.io annotations, which is all a cluster without Prometheus Operator reads.
+func (r *GenericReconciler[CR]) applyServiceMonitor(ctx context.Context, cr CR, resources *RoleGroupResources, buildCtx *RoleGroupBuildContext) error {
+	installed, err := serviceMonitorsInstalled(r.client.RESTMapper())
+	if err != nil {
+		return r.apiError(err)
+	}
+	if !installed {
+		return nil
+	}
+
+	name := buildCtx.ResourceName + "-metrics"
+	if sm := resources.ServiceMonitor; sm != nil {
+		labels := sm.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[constant.LabelKubernetesInstance] = buildCtx.ClusterName
+		labels[constant.LabelKubernetesComponent] = buildCtx.RoleName
+		labels[LabelServiceMonitor] = buildCtx.RoleGroupName
+		sm.SetLabels(labels)
+		if err := r.applyResource(ctx, cr, sm); err != nil {
+			return NewResourceApplyError("ServiceMonitor", buildCtx.ClusterNamespace, name, "failed to apply", err)
+		}
+		return nil
+	}
+	if err := r.reclaimServiceMonitor(ctx, buildCtx, name, cr.GetUID()); err != nil {
+		return NewResourceApplyError("ServiceMonitor", buildCtx.ClusterNamespace, name, "failed to delete disabled service monitor", err)
+	}
+	return nil
+}
+
+// reclaimServiceMonitor deletes the role group's ServiceMonitor when it no longer ships one, but
+// only when the live object carries the slot label for this role group and this cluster's
+// controller reference.
+func (r *GenericReconciler[CR]) reclaimServiceMonitor(ctx context.Context, buildCtx *RoleGroupBuildContext, name string, ownerUID types.UID) error {
+	sm := &unstructured.Unstructured{}
+	sm.SetGroupVersionKind(builder.ServiceMonitorGVK)
+	if err := r.client.Get(ctx, types.NamespacedName{Namespace: buildCtx.ClusterNamespace, Name: name}, sm); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return r.apiError(err)
+	}
+	if sm.GetLabels()[LabelServiceMonitor] != buildCtx.RoleGroupName || !isOwnedByCluster(sm, ownerUID) {
+		return nil
+	}
+	if err := r.client.Delete(ctx, sm); err != nil && !errors.IsNotFound(err) {
+		return r.apiError(err)
+	}
+	r.cleaner.emitDeleted(buildCtx.ClusterName, sm)
+	return nil
+}
+
+// deleteServiceMonitors deletes the role group's ServiceMonitor during the orphan teardown. A
+// cluster without the CRD has nothing to delete; that settles rather than fails, so uninstalling
+// Prometheus Operator does not wedge every later teardown.
+func (c *RoleGroupCleaner) deleteServiceMonitors(
+	ctx context.Context,
+	namespace, clusterName, roleName, groupName string,
+	ownerUID types.UID,
+) (deletionState, error) {
+	if !c.serviceMonitors || ownerUID == "" || roleName == "" || groupName == "" {
+		return deletionSettled, nil
+	}
+	installed, err := serviceMonitorsInstalled(c.Client.RESTMapper())
+	if err != nil {
+		return deletionInFlight, c.apiError(err)
+	}
+	if !installed {
+		return deletionSettled, nil
+	}
+	selector := client.MatchingLabels{
+		constant.LabelKubernetesInstance:  clusterName,
+		constant.LabelKubernetesComponent: roleName,
+		LabelServiceMonitor:               groupName,
+	}
+	return c.deleteLabelled(ctx, builder.ServiceMonitorGVK, namespace, clusterName, roleName, groupName, ownerUID, selector, "service monitor")
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/meta/testrestmapper"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/builder"
+	"github.com/zncdatadev/operator-go/pkg/reconciler"
+	"github.com/zncdatadev/operator-go/pkg/testutil"
+)
+
+var _ = Describe("ServiceMonitors", func() {
+	const namespace = "default"
+
+	var (
+		scheme  *runtime.Scheme
+		cr      *testutil.MockCluster
+		c       client.Client
+		handler *metricsHandler
+		config  *reconciler.ServiceMonitorConfig
+	)
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(testutil.AddToScheme(scheme)).To(Succeed())
+
+		cr = testutil.NewMockCluster("zk", namespace).WithRoles(map[string]v1alpha1.RoleSpec{
+			"server": {RoleGroups: map[string]v1alpha1.RoleGroupSpec{"a": {Replicas: ptr.To[int32](1)}}},
+		})
+		cr.UID = "zk-uid"
+		handler = &metricsHandler{BaseRoleGroupHandler: reconciler.NewBaseRoleGroupHandler[*testutil.MockCluster](scheme), scheme: "http"}
+		config = &reconciler.ServiceMonitorConfig{Labels: map[string]string{"release": "prometheus"}}
+	})
+
+	// newClient builds a fake client whose RESTMapper serves the ServiceMonitor kind only when
+	// installed is set, standing in for discovery on a cluster with or without Prometheus Operator.
+	newClient := func(installed bool) {
+		var mapper meta.RESTMapper = testrestmapper.TestOnlyStaticRESTMapper(scheme)
+		if installed {
+			monitoring := meta.NewDefaultRESTMapper(nil)
+			monitoring.Add(builder.ServiceMonitorGVK, meta.RESTScopeNamespace)
+			mapper = meta.MultiRESTMapper{mapper, monitoring}
+		}
+		c = fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper).
+			WithObjects(cr).WithStatusSubresource(cr).Build()
+	}
+	reconcileOnce := func() {
+		GinkgoHelper()
+		r, err := reconciler.NewGenericReconciler(&reconciler.GenericReconcilerConfig[*testutil.MockCluster]{
+			Client:           c,
+			Scheme:           scheme,
+			ImageResolution:  reconciler.ImageResolution{Defaults: v1alpha1.ImageSpec{Custom: "test-image:latest"}},
+			Recorder:         record.NewFakeRecorder(100),
+			RoleGroupHandler: handler,
+			ServiceMonitors:  config,
+			Prototype:        testutil.NewMockCluster("proto", namespace),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: "zk"}})
+		Expect(err).NotTo(HaveOccurred())
+	}
+	getMonitor := func() (*unstructured.Unstructured, error) {
+		sm := &unstructured.Unstructured{}
+		sm.SetGroupVersionKind(builder.ServiceMonitorGVK)
+		return sm, c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "zk-server-a-metrics"}, sm)
+	}
+
+	It("restates the metrics Service with role group relabelings and the CR as owner", func() {
+		newClient(true)
+		reconcileOnce()
+
+		sm, err := getMonitor()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sm.GetLabels()).To(HaveKeyWithValue("release", "prometheus"))
+		Expect(sm.GetLabels()).To(HaveKeyWithValue(reconciler.LabelServiceMonitor, "a"))
+		Expect(sm.GetOwnerReferences()).To(ConsistOf(HaveField("UID", types.UID("zk-uid"))))
+
+		endpoints, _, _ := unstructured.NestedSlice(sm.Object, "spec", "endpoints")
+		Expect(endpoints).To(HaveLen(1))
+		endpoint := endpoints[0].(map[string]any)
+		Expect(endpoint).To(HaveKeyWithValue("port", "metrics"))
+		Expect(endpoint).To(HaveKeyWithValue("scheme", "http"))
+		Expect(endpoint).NotTo(HaveKey("tlsConfig"))
+		Expect(endpoint["relabelings"]).To(ConsistOf(
+			HaveKeyWithValue("targetLabel", "cluster"),
+			HaveKeyWithValue("targetLabel", "role"),
+			HaveKeyWithValue("targetLabel", "role_group"),
+		))
+
+		selector, _, _ := unstructured.NestedStringMap(sm.Object, "spec", "selector", "matchLabels")
+		Expect(selector).To(HaveKeyWithValue("prometheus.io/scrape", "true"))
+	})
+
+	It("takes the scrape TLS config from the cluster's server SecretClass over https", func() {
+		cr.Spec.TLS = &v1alpha1.ClusterTLSSpec{ServerSecretClass: "tls"}
+		handler.scheme = "https"
+		config.TLSCA = func(secretClass string) *corev1.SecretKeySelector {
+			return &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: secretClass + "-ca"}, Key: "ca.crt"}
+		}
+		newClient(true)
+		reconcileOnce()
+
+		sm, err := getMonitor()
+		Expect(err).NotTo(HaveOccurred())
+		endpoints, _, _ := unstructured.NestedSlice(sm.Object, "spec", "endpoints")
+		tlsConfig := endpoints[0].(map[string]any)["tlsConfig"].(map[string]any)
+		Expect(tlsConfig).To(HaveKeyWithValue("serverName", "zk-server-a-headless.default.svc.cluster.local"))
+		Expect(tlsConfig).To(HaveKeyWithValue("ca", HaveKeyWithValue("secret", And(
+			HaveKeyWithValue("name", "tls-ca"),
+			HaveKeyWithValue("key", "ca.crt"),
+		))))
+	})
+
+	It("creates nothing where the CRD is not installed", func() {
+		newClient(false)
+		reconcileOnce()
+
+		_, err := getMonitor()
+		Expect(apierrors.IsNotFound(err) || meta.IsNoMatchError(err)).To(BeTrue())
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "zk-server-a-metrics"}, &corev1.Service{})).To(Succeed())
+	})
+
+	It("reclaims the ServiceMonitor when the role group stops shipping metrics", func() {
+		newClient(true)
+		reconcileOnce()
+		_, err := getMonitor()
+		Expect(err).NotTo(HaveOccurred())
+
+		handler.scheme = ""
+		reconcileOnce()
+		_, err = getMonitor()
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+})
+
+// metricsHandler ships a metrics Service served over scheme; an empty scheme ships none.
+type metricsHandler struct {
+	*reconciler.BaseRoleGroupHandler[*testutil.MockCluster]
+	scheme string
+}
+
+func (h *metricsHandler) BuildResources(
+	ctx context.Context,
+	c client.Client,
+	cr *testutil.MockCluster,
+	buildCtx *reconciler.RoleGroupBuildContext,
+) (*reconciler.RoleGroupResources, error) {
+	resources, err := h.BaseRoleGroupHandler.BuildResources(ctx, c, cr, buildCtx)
+	if err != nil || h.scheme == "" {
+		return resources, err
+	}
+	resources.MetricsService = builder.NewMetricsServiceBuilder(buildCtx.ResourceName, buildCtx.ClusterNamespace, 9505,
+		h.SelectorLabels(buildCtx)).WithScheme(h.scheme).Build()
+	return resources, nil
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"path"
+	"strings"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/security"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Pod volumes the framework mounts for spec.tls, and the files the secret-operator writes into a
+// tls-p12 volume.
+const (
+	// ServerTLSVolumeName carries the certificate presented to clients (spec.tls.serverSecretClass).
+	ServerTLSVolumeName = "server-tls"
+	// InternalTLSVolumeName carries the certificate used between roles (spec.tls.internalSecretClass).
+	InternalTLSVolumeName = "internal-tls"
+
+	// TLSKeystoreFileName and TLSTruststoreFileName are the PKCS#12 stores inside either volume.
+	TLSKeystoreFileName   = "keystore.p12"
+	TLSTruststoreFileName = "truststore.p12"
+)
+
+// RoleGroupTLS is a role group's resolved spec.tls: where each store is mounted, and the volumes
+// that put them there.
+//
+// It is resolved by the reconciler before the resolver runs, for the same reason ResolvedImage is:
+// a product writing ssl.keystore.location into a config file does so from RoleGroupResolver, and
+// must read the same path the StatefulSet mounts rather than recomposing it.
+//
+// A path is empty when its SecretClass is not set. It is a VolumeProvider, and
+// BaseRoleGroupHandler injects it alongside RoleGroupBuildContext.VolumeProviders; a product
+// building its own StatefulSet calls Volumes and VolumeMounts itself.
+type RoleGroupTLS struct {
+	ServerKeystorePath     string
+	ServerTruststorePath   string
+	InternalKeystorePath   string
+	InternalTruststorePath string
+
+	provisioner *security.SecretProvisioner
+}
+
+// ServerEnabled reports whether client-facing TLS is on for this role group. It is what switches a
+// declared HTTP probe to HTTPS: the probe hits the port clients use.
+func (t *RoleGroupTLS) ServerEnabled() bool {
+	return t != nil && t.ServerKeystorePath != ""
+}
+
+// InternalEnabled reports whether inter-role TLS is on for this role group.
+func (t *RoleGroupTLS) InternalEnabled() bool {
+	return t != nil && t.InternalKeystorePath != ""
+}
+
+// Volumes implements VolumeProvider.
+func (t *RoleGroupTLS) Volumes() []corev1.Volume {
+	if t == nil || t.provisioner == nil {
+		return nil
+	}
+	return t.provisioner.Volumes()
+}
+
+// VolumeMounts implements VolumeProvider.
+func (t *RoleGroupTLS) VolumeMounts() []corev1.VolumeMount {
+	if t == nil || t.provisioner == nil {
+		return nil
+	}
+	return t.provisioner.VolumeMounts()
+}
+
+var _ VolumeProvider = &RoleGroupTLS{}
+
+// resolveRoleGroupTLS resolves spec.tls for one role group. Nil means the cluster has no tls block,
+// or one naming no SecretClass.
+//
+// Both certificates are scoped to the role group's own Services — the headless one always, the
+// client-facing one when the role declares ServicePorts — so a peer dialling a pod through either
+// DNS name sees a certificate that covers it. The pod and node scopes are kept, as every TLS
+// registration in pkg/security has them.
+func resolveRoleGroupTLS(spec *v1alpha1.GenericClusterSpec, resourceName string, decl RoleDeclaration) *RoleGroupTLS {
+	if spec == nil || spec.TLS == nil {
+		return nil
+	}
+	if spec.TLS.ServerSecretClass == "" && spec.TLS.InternalSecretClass == "" {
+		return nil
+	}
+
+	services := []string{resourceName + "-headless"}
+	if len(decl.ServicePorts) > 0 {
+		services = append(services, resourceName)
+	}
+	scope := []string{string(security.PodScope), string(security.NodeScope)}
+	for _, svc := range services {
+		scope = append(scope, string(security.ServiceScope)+"="+svc)
+	}
+
+	tls := &RoleGroupTLS{provisioner: security.NewSecretProvisioner()}
+	register := func(volumeName, secretClass string) (keystore, truststore string) {
+		tls.provisioner.Register(security.TLS(volumeName, secretClass).
+			WithScope(strings.Join(scope, security.CommonDelimiter)))
+		dir := tls.provisioner.MustPath(volumeName)
+		return path.Join(dir, TLSKeystoreFileName), path.Join(dir, TLSTruststoreFileName)
+	}
+	if class := spec.TLS.ServerSecretClass; class != "" {
+		tls.ServerKeystorePath, tls.ServerTruststorePath = register(ServerTLSVolumeName, class)
+	}
+	if class := spec.TLS.InternalSecretClass; class != "" {
+		tls.InternalKeystorePath, tls.InternalTruststorePath = register(InternalTLSVolumeName, class)
+	}
+	return tls
+}
+
+// httpsProbe returns a copy of probe whose HTTP handler, if it has one, uses HTTPS. The copy
+// matters: a declaration is produced once per pass and shared by every role group of the role.
+func httpsProbe(probe *corev1.Probe) *corev1.Probe {
+	if probe == nil || probe.HTTPGet == nil {
+		return probe
+	}
+	out := probe.DeepCopy()
+	out.HTTPGet.Scheme = corev1.URISchemeHTTPS
+	return out
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/security"
+)
+
+var _ = Describe("resolveRoleGroupTLS", func() {
+	It("is nil without a tls block or with one naming no SecretClass", func() {
+		Expect(resolveRoleGroupTLS(&v1alpha1.GenericClusterSpec{}, "trino-coordinator-default", RoleDeclaration{})).To(BeNil())
+		spec := &v1alpha1.GenericClusterSpec{TLS: &v1alpha1.ClusterTLSSpec{}}
+		Expect(resolveRoleGroupTLS(spec, "trino-coordinator-default", RoleDeclaration{})).To(BeNil())
+	})
+
+	It("mounts each SecretClass in its own volume and exposes the store paths", func() {
+		spec := &v1alpha1.GenericClusterSpec{TLS: &v1alpha1.ClusterTLSSpec{
+			ServerSecretClass:   "tls",
+			InternalSecretClass: "internal-tls",
+		}}
+		decl := RoleDeclaration{ServicePorts: []corev1.ServicePort{{Name: "http", Port: 8443}}}
+
+		tls := resolveRoleGroupTLS(spec, "trino-coordinator-default", decl)
+		Expect(tls.ServerEnabled()).To(BeTrue())
+		Expect(tls.InternalEnabled()).To(BeTrue())
+		Expect(tls.ServerKeystorePath).To(Equal("/kubedoop/mount/server-tls/keystore.p12"))
+		Expect(tls.ServerTruststorePath).To(Equal("/kubedoop/mount/server-tls/truststore.p12"))
+		Expect(tls.InternalKeystorePath).To(Equal("/kubedoop/mount/internal-tls/keystore.p12"))
+
+		volumes := tls.Volumes()
+		Expect(volumes).To(HaveLen(2))
+		attrs := volumes[0].Ephemeral.VolumeClaimTemplate.Annotations
+		Expect(attrs).To(HaveKeyWithValue(security.SecretClassAnnotation, "tls"))
+		// Both of the role group's Services, so a peer dialling either DNS name is covered.
+		Expect(attrs[security.SecretClassScopeAnnotation]).To(ContainSubstring("service=trino-coordinator-default-headless"))
+		Expect(attrs[security.SecretClassScopeAnnotation]).To(HaveSuffix(",service=trino-coordinator-default"))
+		Expect(tls.VolumeMounts()).To(HaveLen(2))
+	})
+
+	It("scopes to the headless Service alone when the role has no client-facing one", func() {
+		spec := &v1alpha1.GenericClusterSpec{TLS: &v1alpha1.ClusterTLSSpec{InternalSecretClass: "internal-tls"}}
+		tls := resolveRoleGroupTLS(spec, "zk-server-default", RoleDeclaration{})
+		Expect(tls.ServerEnabled()).To(BeFalse())
+		scope := tls.Volumes()[0].Ephemeral.VolumeClaimTemplate.Annotations[security.SecretClassScopeAnnotation]
+		Expect(scope).To(HaveSuffix("service=zk-server-default-headless"))
+	})
+})
+
+var _ = Describe("httpsProbe", func() {
+	It("switches an HTTP probe to HTTPS without touching the shared declaration", func() {
+		declared := &corev1.Probe{ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{Path: "/health", Port: intstr.FromInt32(8443)},
+		}}
+		out := httpsProbe(declared)
+		Expect(out.HTTPGet.Scheme).To(Equal(corev1.URISchemeHTTPS))
+		Expect(declared.HTTPGet.Scheme).To(BeEmpty())
+	})
+
+	It("leaves a non-HTTP probe alone", func() {
+		tcp := &corev1.Probe{ProbeHandler: corev1.ProbeHandler{
+			TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt32(2181)},
+		}}
+		Expect(httpsProbe(tcp)).To(BeIdenticalTo(tcp))
+		Expect(httpsProbe(nil)).To(BeNil())
+	})
+})
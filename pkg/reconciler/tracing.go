@@ -0,0 +1,69 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/zncdatadev/operator-go/pkg/common"
+)
+
+// TracerName is the instrumentation scope of the reconciler's spans, as passed to
+// trace.TracerProvider.Tracer.
+const TracerName = "github.com/zncdatadev/operator-go/pkg/reconciler"
+
+// Span attribute keys. The namespace follows the OpenTelemetry Kubernetes semantic conventions;
+// the rest name things those conventions have no word for.
+const (
+	attrNamespace    = attribute.Key("k8s.namespace.name")
+	attrCluster      = attribute.Key("kubedoop.cluster")
+	attrRole         = attribute.Key("kubedoop.role")
+	attrRoleGroup    = attribute.Key("kubedoop.role_group")
+	attrResourceKind = attribute.Key("kubedoop.resource.kind")
+	attrResourceName = attribute.Key("kubedoop.resource.name")
+	attrApplyResult  = attribute.Key("kubedoop.apply.result")
+)
+
+// startSpan starts a child of the span in ctx, from that span's own TracerProvider. Only the root
+// span in Reconcile is started from GenericReconcilerConfig.TracerProvider; every other span
+// inherits the provider through the context, so the cleaner, the health manager and the extension
+// registry trace without holding a provider of their own. Outside a traced reconcile the context
+// carries no span, the provider is a no-op, and so is the span.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return trace.SpanFromContext(ctx).TracerProvider().Tracer(TracerName).
+		Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends span, recording err on it. A wait is the product saying "not yet" rather than a
+// failure, so it is recorded as a "waiting" event and leaves the span's status unset; marking it
+// as an error would paint every first install red in the trace view, for the same reason
+// observePhase does not count it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		if _, waiting := common.WaitingErrors(err); waiting {
+			span.AddEvent("waiting", trace.WithAttributes(attribute.String("reason", err.Error())))
+		} else {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/common"
+	"github.com/zncdatadev/operator-go/pkg/reconciler"
+	"github.com/zncdatadev/operator-go/pkg/testutil"
+)
+
+var _ = Describe("Reconcile tracing", func() {
+	const namespace = "default"
+
+	var (
+		scheme   *runtime.Scheme
+		c        client.Client
+		exporter *tracetest.InMemoryExporter
+	)
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(testutil.AddToScheme(scheme)).To(Succeed())
+
+		cr := testutil.NewMockCluster("traced", namespace).WithRoles(map[string]v1alpha1.RoleSpec{
+			"server": {RoleGroups: map[string]v1alpha1.RoleGroupSpec{"a": {Replicas: ptr.To[int32](1)}}},
+		})
+		c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(cr).WithStatusSubresource(cr).Build()
+		exporter = tracetest.NewInMemoryExporter()
+	})
+
+	reconcileOnce := func(handler reconciler.RoleGroupHandler[*testutil.MockCluster]) error {
+		GinkgoHelper()
+		registry := common.NewExtensionRegistry[*testutil.MockCluster]()
+		registry.RegisterClusterExtension(&productStatusExtension{value: "traced"})
+		r, err := reconciler.NewGenericReconciler(&reconciler.GenericReconcilerConfig[*testutil.MockCluster]{
+			Client:            c,
+			Scheme:            scheme,
+			ImageResolution:   reconciler.ImageResolution{Defaults: v1alpha1.ImageSpec{Custom: "test-image:latest"}},
+			Recorder:          record.NewFakeRecorder(100),
+			RoleGroupHandler:  handler,
+			ExtensionRegistry: registry,
+			Dependencies:      func(*testutil.MockCluster) []reconciler.Dependency { return nil },
+			TracerProvider:    sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)),
+			Prototype:         testutil.NewMockCluster("proto", namespace),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: "traced"}})
+		return err
+	}
+	spansNamed := func(name string) tracetest.SpanStubs {
+		var matched tracetest.SpanStubs
+		for _, span := range exporter.GetSpans() {
+			if span.Name == name {
+				matched = append(matched, span)
+			}
+		}
+		return matched
+	}
+	onlySpan := func(name string) tracetest.SpanStub {
+		GinkgoHelper()
+		matched := spansNamed(name)
+		Expect(matched).To(HaveLen(1), "span %s", name)
+		return matched[0]
+	}
+
+	It("nests every step of the pass under one root span per reconcile", func() {
+		Expect(reconcileOnce(reconciler.NewBaseRoleGroupHandler[*testutil.MockCluster](scheme))).To(Succeed())
+
+		root := onlySpan("Reconcile")
+		Expect(root.Parent.IsValid()).To(BeFalse())
+		Expect(root.Attributes).To(ContainElements(
+			attribute.String("k8s.namespace.name", namespace),
+			attribute.String("kubedoop.cluster", "traced"),
+		))
+		for _, name := range []string{"validateDependencies", "reconcileRoleGroup", "RoleGroupCleaner.Cleanup", "HealthManager.Check"} {
+			Expect(onlySpan(name).Parent.SpanID()).To(Equal(root.SpanContext.SpanID()), "span %s", name)
+		}
+		Expect(onlySpan("reconcileRoleGroup").Attributes).To(ContainElements(
+			attribute.String("kubedoop.role", "server"),
+			attribute.String("kubedoop.role_group", "a"),
+		))
+
+		// One span per hook and extension, named after the hook.
+		preReconcile := onlySpan("ClusterExtension.PreReconcile")
+		Expect(preReconcile.Parent.SpanID()).To(Equal(root.SpanContext.SpanID()))
+		Expect(preReconcile.Attributes).To(ContainElement(attribute.String("kubedoop.extension", "product-status")))
+		onlySpan("ClusterExtension.PostReconcile")
+
+		roleGroup := onlySpan("reconcileRoleGroup")
+		var statefulSet *tracetest.SpanStub
+		for _, span := range spansNamed("applyResource") {
+			Expect(span.Parent.SpanID()).To(Equal(roleGroup.SpanContext.SpanID()))
+			if slices.Contains(span.Attributes, attribute.String("kubedoop.resource.kind", "StatefulSet")) {
+				statefulSet = &span
+			}
+		}
+		Expect(statefulSet).NotTo(BeNil())
+		Expect(statefulSet.Attributes).To(ContainElements(
+			attribute.String("kubedoop.resource.name", "traced-server-a"),
+			attribute.String("kubedoop.apply.result", "created"),
+		))
+	})
+
+	It("records a failure on the span it happened in and on the root", func() {
+		Expect(reconcileOnce(&failingBuildHandler{})).NotTo(Succeed())
+
+		roleGroup := onlySpan("reconcileRoleGroup")
+		Expect(roleGroup.Status.Code).To(Equal(codes.Error))
+		Expect(roleGroup.Status.Description).To(ContainSubstring("build failed"))
+		Expect(roleGroup.Events).To(ContainElement(HaveField("Name", "exception")))
+		Expect(spansNamed("applyResource")).To(BeEmpty())
+
+		Expect(onlySpan("Reconcile").Status.Code).To(Equal(codes.Error))
+		// The error hooks run inside the root span too.
+		onlySpan("ClusterExtension.OnReconcileError")
+	})
+})
@@ -0,0 +1,105 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/productlogging"
+	"github.com/zncdatadev/operator-go/pkg/vector"
+)
+
+// filterBuildContext is stageOneBuildContext with an aggregator resolved and the given containers'
+// logging configured.
+func filterBuildContext(containers map[string]v1alpha1.LoggingConfigSpec) *RoleGroupBuildContext {
+	buildCtx := stageOneBuildContext(true)
+	buildCtx.RoleGroupSpec.Config.Logging.Containers = containers
+	buildCtx.VectorAggregatorAddress = "aggregator:6000"
+	return buildCtx
+}
+
+// The rules reach vector.yaml keyed by the log directory the pipeline reports as .container, not
+// by the container name they are written under.
+func TestRenderLoggingConfigMapData_CompilesLogFilters(t *testing.T) {
+	buildCtx := filterBuildContext(map[string]v1alpha1.LoggingConfigSpec{
+		"trino": {Filter: &v1alpha1.LogFilterSpec{MinLevel: "WARN"}},
+	})
+	buildCtx.Declaration.LogProducers[0].LogDirName = "coordinator"
+
+	data, err := RenderLoggingConfigMapData(buildCtx, buildCtx.Declaration.LogProducers)
+	if err != nil {
+		t.Fatalf("RenderLoggingConfigMapData() error = %v", err)
+	}
+	if want := `if .container == "coordinator" {`; !strings.Contains(data[vector.VectorConfigFileName], want) {
+		t.Errorf("vector.yaml misses %q:\n%s", want, data[vector.VectorConfigFileName])
+	}
+}
+
+// A rule the agent would never apply fails the role group instead of shipping what it was meant
+// to hold back.
+func TestRenderLoggingConfigMapData_RejectsUnappliedLogFilters(t *testing.T) {
+	tests := []struct {
+		name       string
+		containers map[string]v1alpha1.LoggingConfigSpec
+		producers  []productlogging.ContainerLogging
+		wantErr    string
+	}{
+		{
+			name: "filter on a container that is not a producer",
+			containers: map[string]v1alpha1.LoggingConfigSpec{
+				"init": {Filter: &v1alpha1.LogFilterSpec{MinLevel: "WARN"}},
+			},
+			wantErr: `container "init" has a log filter`,
+		},
+		{
+			name: "producers sharing a log directory with different rules",
+			containers: map[string]v1alpha1.LoggingConfigSpec{
+				"trino":  {Filter: &v1alpha1.LogFilterSpec{MinLevel: "WARN"}},
+				"helper": {Filter: &v1alpha1.LogFilterSpec{MinLevel: "INFO"}},
+			},
+			producers: []productlogging.ContainerLogging{
+				{Container: "trino", LogDirName: "shared", Framework: productlogging.LoggingFrameworkLogback},
+				{Container: "helper", LogDirName: "shared", LogFileName: "helper.log4j2.xml"},
+			},
+			wantErr: `log directory "shared" have different log filters`,
+		},
+		{
+			name: "redaction Vector cannot compile",
+			containers: map[string]v1alpha1.LoggingConfigSpec{
+				"trino": {Filter: &v1alpha1.LogFilterSpec{Redactions: []v1alpha1.LogRedactionSpec{{Pattern: `(\w+`}}}},
+			},
+			wantErr: "redaction 0: pattern",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buildCtx := filterBuildContext(tt.containers)
+			producers := buildCtx.Declaration.LogProducers
+			if tt.producers != nil {
+				producers = tt.producers
+			}
+			_, err := vectorLogFilters(buildCtx, producers)
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("vectorLogFilters() error = %v, want a ValidationError containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/zncdatadev/operator-go/pkg/common"
+	"github.com/zncdatadev/operator-go/pkg/vector"
+)
+
+// sinkCluster is a CR shipping its logs to sinks, with an aggregator ConfigMap name it leaves
+// empty. Nothing but the two optional interfaces is called on it.
+type sinkCluster struct {
+	common.ClusterInterface
+	sinks []vector.Sink
+}
+
+func (c *sinkCluster) VectorSinks(context.Context, client.Client) ([]vector.Sink, error) {
+	return c.sinks, nil
+}
+
+func (c *sinkCluster) VectorAggregatorConfigMapName() string { return "" }
+
+func (c *sinkCluster) DeepCopy() *sinkCluster { return &sinkCluster{sinks: c.sinks} }
+
+// A CR with sinks needs no aggregator: leaving its ConfigMap name empty used to fail the role
+// group, which is what kept a team without a central aggregator from shipping logs at all.
+func TestVectorSinksStandInForTheAggregator(t *testing.T) {
+	r := &GenericReconciler[*sinkCluster]{}
+	cr := &sinkCluster{sinks: []vector.Sink{&vector.LokiSink{Endpoint: "http://loki:3100"}}}
+	buildCtx := stageOneBuildContext(true)
+
+	if err := r.resolveVectorSinks(context.Background(), cr, buildCtx); err != nil {
+		t.Fatalf("resolveVectorSinks() error = %v", err)
+	}
+	if len(buildCtx.VectorSinks) != 1 {
+		t.Fatalf("VectorSinks = %v, want the CR's sink", buildCtx.VectorSinks)
+	}
+	if err := r.resolveVectorAggregatorAddress(context.Background(), cr, buildCtx); err != nil {
+		t.Errorf("an empty aggregator ConfigMap name must be allowed beside sinks, got %v", err)
+	}
+	if !r.vectorConfigIsProvided(cr, buildCtx) {
+		t.Error("sinks supply vector.yaml, so the sidecar must not be skipped")
+	}
+
+	// Off unless the agent is: the sinks are not even asked for.
+	off := stageOneBuildContext(false)
+	if err := r.resolveVectorSinks(context.Background(), cr, off); err != nil || off.VectorSinks != nil {
+		t.Errorf("agent disabled: VectorSinks = %v, err = %v, want neither", off.VectorSinks, err)
+	}
+}
+
+// A malformed sink fails the role group naming the sink, rather than rendering a vector.yaml the
+// agent rejects at startup.
+func TestVectorSinksAreValidatedWhenResolved(t *testing.T) {
+	r := &GenericReconciler[*sinkCluster]{}
+	cr := &sinkCluster{sinks: []vector.Sink{&vector.KafkaSink{BootstrapServers: []string{"kafka:9092"}}}}
+
+	err := r.resolveVectorSinks(context.Background(), cr, stageOneBuildContext(true))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || !strings.Contains(err.Error(), "kafka sink") {
+		t.Errorf("resolveVectorSinks() error = %v, want a ValidationError naming the kafka sink", err)
+	}
+}
@@ -0,0 +1,332 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/common"
+)
+
+// ConditionVolumeExpansion reports role groups whose data claims are being expanded, are waiting on
+// the kubelet to grow their file system, or asked for more capacity than their StorageClass lets
+// the framework give them. Like ConditionPodSecurityViolation it is only cleared to False once it
+// has been raised, so a cluster that never resized carries no condition at all.
+const ConditionVolumeExpansion v1alpha1.ConditionType = "VolumeExpansion"
+
+// Reasons for ConditionVolumeExpansion, in increasing order of precedence: when role groups are in
+// different states the condition carries the reason of the one that most needs a human.
+const (
+	ReasonVolumeExpansionComplete    = "VolumeExpansionComplete"
+	ReasonFileSystemResizePending    = "FileSystemResizePending"
+	ReasonVolumeExpansionInProgress  = "VolumeExpansionInProgress"
+	ReasonVolumeExpansionUnsupported = "VolumeExpansionUnsupported"
+)
+
+// volumeExpansionPollInterval is how often a role group whose StatefulSet is being recreated is
+// looked at again. The orphaning delete is finished by the garbage collector, which watches
+// nothing of this operator's, so nothing else wakes the CR when the old object is gone.
+const volumeExpansionPollInterval = 5 * time.Second
+
+// volumeExpansionFinding is one role group's expansion state in one pass.
+type volumeExpansionFinding struct {
+	reason string
+	detail string
+}
+
+// volumeExpansionFindings collects one reconcile pass's expansion states, keyed by
+// "<role>/<group>". Role groups with nothing in flight are absent.
+type volumeExpansionFindings map[string]volumeExpansionFinding
+
+// volumeExpansionPrecedence orders the reasons for setVolumeExpansionCondition.
+var volumeExpansionPrecedence = map[string]int{
+	ReasonFileSystemResizePending:    1,
+	ReasonVolumeExpansionInProgress:  2,
+	ReasonVolumeExpansionUnsupported: 3,
+}
+
+// expandStatefulSetClaims grows the data claims of a live StatefulSet whose desired
+// volumeClaimTemplates differ from the live ones ONLY by a larger storage request, and then deletes
+// the StatefulSet with orphan propagation so step 5 of applyResources recreates it with the new
+// templates.
+//
+// volumeClaimTemplates is immutable, so without this a capacity increase was preserved away by
+// copyStatefulSetState and reported as ImmutableFieldIgnored on every pass, indefinitely. The claims
+// themselves are NOT immutable: a bound claim's storage request may grow when its StorageClass sets
+// allowVolumeExpansion, and the CSI driver and kubelet do the rest. So the resize is done where
+// Kubernetes allows it — on each claim — and the template is brought back in line afterwards, the
+// way `kubectl delete sts --cascade=orphan` is used by hand: the pods keep running, the recreated
+// StatefulSet adopts them by its selector, and because its pod template is unchanged its revision
+// hash is too, so nothing rolls. The new template only decides the size of claims created later,
+// by a scale-up.
+//
+// Every existing claim's StorageClass is checked before ANY claim is touched. A role group whose
+// claims cannot all grow is left exactly as before — the resize is dropped with the
+// ImmutableFieldIgnored warning, and ConditionVolumeExpansion says why — because a role group whose
+// replicas have different capacities is worse than one that did not resize. A claim that is not
+// bound yet cannot be resized by anyone, and is left to bind at the size it asked for.
+//
+// The return is nil when the StatefulSet may be applied now, or a *common.RequeueAfterError while
+// the old object is being deleted; that wait is what keeps step 5 from updating an object the
+// garbage collector is about to remove.
+func (r *GenericReconciler[CR]) expandStatefulSetClaims(
+	ctx context.Context, cr CR, desired *appsv1.StatefulSet, buildCtx *RoleGroupBuildContext, findings volumeExpansionFindings,
+) error {
+	key := buildCtx.RoleName + "/" + buildCtx.RoleGroupName
+	recreating := common.NewRequeueAfterError(volumeExpansionPollInterval, ReasonVolumeExpansionInProgress,
+		fmt.Sprintf("StatefulSet %s is being recreated so its claim templates match the expanded claims", desired.Name))
+
+	// The claims are looked up through the desired object, so a pass that finds no StatefulSet —
+	// the one recreating it — still reports the claims that are growing.
+	claims, err := r.statefulSetClaims(ctx, desired)
+	if err != nil {
+		return err
+	}
+
+	live := &appsv1.StatefulSet{}
+	if err := r.client.Get(ctx, client.ObjectKeyFromObject(desired), live); err != nil {
+		if errors.IsNotFound(err) {
+			recordClaimProgress(findings, key, claims)
+			return nil
+		}
+		return r.apiError(err)
+	}
+
+	if live.DeletionTimestamp != nil {
+		recordClaimProgress(findings, key, claims)
+		return recreating
+	}
+
+	grown := grownClaimTemplates(desired.Spec.VolumeClaimTemplates, live.Spec.VolumeClaimTemplates)
+	if len(grown) == 0 {
+		recordClaimProgress(findings, key, claims)
+		return nil
+	}
+
+	// Decide for the whole role group before writing anything.
+	var toPatch []*corev1.PersistentVolumeClaim
+	for _, pvc := range claims {
+		want, ok := grown[claimTemplateOf(pvc.Name, desired.Name, desired.Spec.VolumeClaimTemplates)]
+		if !ok || pvc.Status.Phase != corev1.ClaimBound {
+			continue
+		}
+		have := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		if have.Cmp(want) >= 0 {
+			continue
+		}
+		if reason, err := r.claimExpansionBlocker(ctx, pvc); err != nil {
+			return err
+		} else if reason != "" {
+			findings[key] = volumeExpansionFinding{reason: ReasonVolumeExpansionUnsupported, detail: reason}
+			return nil
+		}
+		toPatch = append(toPatch, pvc)
+	}
+
+	logger := log.FromContext(ctx)
+	for _, pvc := range toPatch {
+		want := grown[claimTemplateOf(pvc.Name, desired.Name, desired.Spec.VolumeClaimTemplates)]
+		before := pvc.DeepCopy()
+		pvc.Spec.Resources.Requests[corev1.ResourceStorage] = want
+		if err := r.client.Patch(ctx, pvc, client.MergeFrom(before)); err != nil {
+			return NewResourceApplyError("PersistentVolumeClaim", pvc.Namespace, pvc.Name, "failed to expand", r.apiError(err))
+		}
+		logger.Info("Expanding PVC", "name", pvc.Name, "namespace", pvc.Namespace, "capacity", want.String())
+	}
+
+	if err := r.client.Delete(ctx, live, client.PropagationPolicy(metav1.DeletePropagationOrphan)); err != nil && !errors.IsNotFound(err) {
+		return NewResourceApplyError("StatefulSet", live.Namespace, live.Name, "failed to recreate for volume expansion", r.apiError(err))
+	}
+	r.eventManager.EmitNormalEvent(cr, "VolumeExpansionStarted", fmt.Sprintf(
+		"expanded %d PVC(s) of role %s group %s; recreating the StatefulSet with orphaned pods so its claim templates match",
+		len(toPatch), buildCtx.RoleName, buildCtx.RoleGroupName))
+
+	recordClaimProgress(findings, key, claims)
+	return recreating
+}
+
+// statefulSetClaims lists the claims the StatefulSet controller created for sts, by its pod
+// selector (the lookup deletePVCsForStatefulSet uses) narrowed to the names its templates produce,
+// sorted by name.
+func (r *GenericReconciler[CR]) statefulSetClaims(ctx context.Context, sts *appsv1.StatefulSet) ([]*corev1.PersistentVolumeClaim, error) {
+	if len(sts.Spec.VolumeClaimTemplates) == 0 || sts.Spec.Selector == nil {
+		return nil, nil
+	}
+	pvcList := &corev1.PersistentVolumeClaimList{}
+	if err := r.client.List(ctx, pvcList,
+		client.InNamespace(sts.Namespace),
+		client.MatchingLabels(sts.Spec.Selector.MatchLabels),
+	); err != nil {
+		return nil, fmt.Errorf("failed to list PVCs for StatefulSet %s/%s: %w", sts.Namespace, sts.Name, r.apiError(err))
+	}
+	var claims []*corev1.PersistentVolumeClaim
+	for i := range pvcList.Items {
+		if claimTemplateOf(pvcList.Items[i].Name, sts.Name, sts.Spec.VolumeClaimTemplates) != "" {
+			claims = append(claims, &pvcList.Items[i])
+		}
+	}
+	slices.SortFunc(claims, func(a, b *corev1.PersistentVolumeClaim) int { return strings.Compare(a.Name, b.Name) })
+	return claims, nil
+}
+
+// claimTemplateOf returns the name of the template a claim was created from — the StatefulSet
+// controller names claims "<template>-<statefulset>-<ordinal>" — or "" when it matches none.
+func claimTemplateOf(claimName, stsName string, templates []corev1.PersistentVolumeClaim) string {
+	for _, tpl := range templates {
+		ordinal, ok := strings.CutPrefix(claimName, tpl.Name+"-"+stsName+"-")
+		if ok && ordinal != "" && strings.Trim(ordinal, "0123456789") == "" {
+			return tpl.Name
+		}
+	}
+	return ""
+}
+
+// grownClaimTemplates returns, by template name, the storage requests desired raises over live —
+// or nil unless raising them is the ONLY difference between the two lists. Any other difference (a
+// renamed template, a new storageClassName, a shrink) is not a resize and stays with the
+// ImmutableFieldIgnored path.
+func grownClaimTemplates(desired, live []corev1.PersistentVolumeClaim) map[string]resource.Quantity {
+	if len(desired) != len(live) {
+		return nil
+	}
+	grown := map[string]resource.Quantity{}
+	resized := make([]corev1.PersistentVolumeClaim, len(live))
+	for i := range live {
+		resized[i] = *live[i].DeepCopy()
+		want, ok := desired[i].Spec.Resources.Requests[corev1.ResourceStorage]
+		have := live[i].Spec.Resources.Requests[corev1.ResourceStorage]
+		if !ok || want.Cmp(have) <= 0 {
+			continue
+		}
+		resized[i].Spec.Resources.Requests[corev1.ResourceStorage] = want
+		grown[live[i].Name] = want
+	}
+	if len(grown) == 0 || claimTemplatesDiffer(desired, resized) {
+		return nil
+	}
+	return grown
+}
+
+// claimExpansionBlocker returns why pvc cannot be grown in place, or "" when its StorageClass
+// allows it. A claim with no class, or whose class is gone, cannot be.
+func (r *GenericReconciler[CR]) claimExpansionBlocker(ctx context.Context, pvc *corev1.PersistentVolumeClaim) (string, error) {
+	className := ptr.Deref(pvc.Spec.StorageClassName, "")
+	if className == "" {
+		return fmt.Sprintf("PVC %s has no StorageClass, so it cannot be expanded", pvc.Name), nil
+	}
+	class := &storagev1.StorageClass{}
+	if err := r.client.Get(ctx, client.ObjectKey{Name: className}, class); err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Sprintf("StorageClass %s of PVC %s does not exist", className, pvc.Name), nil
+		}
+		return "", r.apiError(err)
+	}
+	if class.AllowVolumeExpansion == nil || !*class.AllowVolumeExpansion {
+		return fmt.Sprintf("StorageClass %s does not allow volume expansion", className), nil
+	}
+	return "", nil
+}
+
+// recordClaimProgress records a role group whose claims have not all reached the size they
+// request. A claim that is neither is done, and a role group with only done claims is not recorded.
+func recordClaimProgress(findings volumeExpansionFindings, key string, claims []*corev1.PersistentVolumeClaim) {
+	var resizing, fsPending []string
+	for _, pvc := range claims {
+		requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]
+		if pvc.Status.Phase != corev1.ClaimBound || !ok || capacity.Cmp(requested) >= 0 {
+			continue
+		}
+		if claimCondition(pvc, corev1.PersistentVolumeClaimFileSystemResizePending) {
+			fsPending = append(fsPending, pvc.Name)
+		} else {
+			resizing = append(resizing, pvc.Name)
+		}
+	}
+
+	var parts []string
+	reason := ReasonFileSystemResizePending
+	if len(resizing) > 0 {
+		reason = ReasonVolumeExpansionInProgress
+		parts = append(parts, "expanding "+strings.Join(resizing, ", "))
+	}
+	if len(fsPending) > 0 {
+		parts = append(parts, "file system resize pending on "+strings.Join(fsPending, ", "))
+	}
+	if len(parts) > 0 {
+		findings[key] = volumeExpansionFinding{reason: reason, detail: strings.Join(parts, "; ")}
+	}
+}
+
+// claimCondition reports whether pvc carries the given condition with status True.
+func claimCondition(pvc *corev1.PersistentVolumeClaim, conditionType corev1.PersistentVolumeClaimConditionType) bool {
+	for _, c := range pvc.Status.Conditions {
+		if c.Type == conditionType && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// setVolumeExpansionCondition writes one pass's expansion states to the status. The message lists
+// role groups in sorted order so an unchanged state writes a byte-identical status every pass.
+func setVolumeExpansionCondition(status *v1alpha1.GenericClusterStatus, findings volumeExpansionFindings) {
+	if len(findings) == 0 {
+		if status.GetCondition(ConditionVolumeExpansion) == nil {
+			return
+		}
+		status.SetCondition(metav1.Condition{
+			Type:    string(ConditionVolumeExpansion),
+			Status:  metav1.ConditionFalse,
+			Reason:  ReasonVolumeExpansionComplete,
+			Message: "Every data PVC has the capacity it requests",
+		})
+		return
+	}
+
+	reason := ""
+	parts := make([]string, 0, len(findings))
+	for _, key := range slices.Sorted(maps.Keys(findings)) {
+		f := findings[key]
+		if volumeExpansionPrecedence[f.reason] > volumeExpansionPrecedence[reason] {
+			reason = f.reason
+		}
+		parts = append(parts, key+": "+f.detail)
+	}
+	status.SetCondition(metav1.Condition{
+		Type:    string(ConditionVolumeExpansion),
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: "Volume expansion: " + strings.Join(parts, "; "),
+	})
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+)
+
+var _ = Describe("grownClaimTemplates", func() {
+	template := func(name, size string) corev1.PersistentVolumeClaim {
+		return corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.VolumeResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse(size),
+				}},
+			},
+		}
+	}
+
+	It("returns the larger request when it is the only change", func() {
+		live := template("data", "1Gi")
+		// Server-populated, and not something the handler asked for.
+		live.Spec.VolumeMode = ptr.To(corev1.PersistentVolumeFilesystem)
+		grown := grownClaimTemplates([]corev1.PersistentVolumeClaim{template("data", "5Gi")}, []corev1.PersistentVolumeClaim{live})
+		Expect(grown).To(HaveKey("data"))
+		want := grown["data"]
+		Expect(want.String()).To(Equal("5Gi"))
+	})
+
+	It("returns nothing for a shrink, or a resize that comes with another change", func() {
+		Expect(grownClaimTemplates(
+			[]corev1.PersistentVolumeClaim{template("data", "1Gi")},
+			[]corev1.PersistentVolumeClaim{template("data", "5Gi")})).To(BeEmpty())
+
+		reclassed := template("data", "5Gi")
+		reclassed.Spec.StorageClassName = ptr.To("fast")
+		Expect(grownClaimTemplates(
+			[]corev1.PersistentVolumeClaim{reclassed},
+			[]corev1.PersistentVolumeClaim{template("data", "1Gi")})).To(BeEmpty())
+	})
+})
+
+var _ = Describe("claimTemplateOf", func() {
+	templates := []corev1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{Name: "data"}}}
+
+	It("matches only the names the StatefulSet controller produces", func() {
+		Expect(claimTemplateOf("data-c-server-default-0", "c-server-default", templates)).To(Equal("data"))
+		Expect(claimTemplateOf("data-c-server-default-12", "c-server-default", templates)).To(Equal("data"))
+		// A sibling role group whose name extends this one's.
+		Expect(claimTemplateOf("data-c-server-default-archive-0", "c-server-default", templates)).To(BeEmpty())
+		Expect(claimTemplateOf("data-c-server-default-", "c-server-default", templates)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("setVolumeExpansionCondition", func() {
+	It("adds no condition to a cluster that never resized", func() {
+		status := &v1alpha1.GenericClusterStatus{}
+		setVolumeExpansionCondition(status, volumeExpansionFindings{})
+		Expect(status.GetCondition(ConditionVolumeExpansion)).To(BeNil())
+	})
+
+	It("carries the reason that most needs a human, and every role group in sorted order", func() {
+		status := &v1alpha1.GenericClusterStatus{}
+		setVolumeExpansionCondition(status, volumeExpansionFindings{
+			"worker/default": {reason: ReasonVolumeExpansionUnsupported, detail: "StorageClass standard does not allow volume expansion"},
+			"server/default": {reason: ReasonFileSystemResizePending, detail: "file system resize pending on data-c-server-default-0"},
+		})
+		cond := status.GetCondition(ConditionVolumeExpansion)
+		Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+		Expect(cond.Reason).To(Equal(ReasonVolumeExpansionUnsupported))
+		Expect(cond.Message).To(Equal("Volume expansion: " +
+			"server/default: file system resize pending on data-c-server-default-0; " +
+			"worker/default: StorageClass standard does not allow volume expansion"))
+	})
+
+	It("clears a raised condition once every claim has its capacity", func() {
+		status := &v1alpha1.GenericClusterStatus{}
+		setVolumeExpansionCondition(status, volumeExpansionFindings{
+			"server/default": {reason: ReasonVolumeExpansionInProgress, detail: "expanding data-c-server-default-0"},
+		})
+		setVolumeExpansionCondition(status, volumeExpansionFindings{})
+		cond := status.GetCondition(ConditionVolumeExpansion)
+		Expect(cond.Status).To(Equal(metav1.ConditionFalse))
+		Expect(cond.Reason).To(Equal(ReasonVolumeExpansionComplete))
+	})
+})
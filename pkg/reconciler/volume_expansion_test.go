@@ -0,0 +1,254 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/reconciler"
+	"github.com/zncdatadev/operator-go/pkg/testutil"
+)
+
+// envtest runs no StatefulSet controller, no provisioner and no garbage collector, so each spec plays
+// their parts by hand: it creates the bound claim the StatefulSet controller would, moves its status
+// the way the resizer and the kubelet would, and finishes the orphaning delete the way the garbage
+// collector would.
+var _ = Describe("StatefulSet data PVC expansion", func() {
+	ctx := context.Background()
+
+	const (
+		role      = "datanode"
+		roleGroup = "default"
+	)
+
+	dataVolumeProvider := reconciler.RoleProviderFunc[*testutil.MockCluster](
+		func(context.Context, client.Client, *testutil.MockCluster) (reconciler.RoleCatalog, error) {
+			return reconciler.RoleCatalog{
+				role: {DataVolume: &reconciler.DataVolume{MountPath: "/kubedoop/data"}},
+			}, nil
+		})
+
+	newStorageClass := func(prefix string, expandable bool) string {
+		GinkgoHelper()
+		class := &storagev1.StorageClass{
+			ObjectMeta:           metav1.ObjectMeta{Name: uniqueCRName(prefix)},
+			Provisioner:          "example.com/fake",
+			AllowVolumeExpansion: ptr.To(expandable),
+		}
+		Expect(k8sClient.Create(ctx, class)).To(Succeed())
+		DeferCleanup(func() { _ = k8sClient.Delete(ctx, class) })
+		return class.Name
+	}
+
+	rolesWith := func(class, capacity string) map[string]v1alpha1.RoleSpec {
+		return map[string]v1alpha1.RoleSpec{
+			role: {RoleGroups: map[string]v1alpha1.RoleGroupSpec{
+				roleGroup: {Replicas: ptr.To(int32(1)), Config: &v1alpha1.RoleGroupConfigSpec{
+					Resources: &v1alpha1.ResourcesSpec{Storage: &v1alpha1.StorageResource{
+						Capacity:     ptr.To(resource.MustParse(capacity)),
+						StorageClass: ptr.To(class),
+					}},
+				}},
+			}},
+		}
+	}
+
+	newReconcilerFor := func(rec record.EventRecorder) *reconciler.GenericReconciler[*testutil.MockCluster] {
+		r, err := reconciler.NewGenericReconciler(&reconciler.GenericReconcilerConfig[*testutil.MockCluster]{
+			Client:           k8sClient,
+			Scheme:           testScheme,
+			ImageResolution:  reconciler.ImageResolution{Defaults: v1alpha1.ImageSpec{Custom: "test-image:latest"}},
+			RoleProvider:     dataVolumeProvider,
+			Recorder:         rec,
+			RoleGroupHandler: reconciler.NewBaseRoleGroupHandler[*testutil.MockCluster](testScheme),
+			Prototype:        testutil.NewMockCluster("proto", testNamespace),
+			VolumeExpansion:  true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return r
+	}
+
+	reconcileOnce := func(r *reconciler.GenericReconciler[*testutil.MockCluster], name string) {
+		GinkgoHelper()
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: name}})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	// newCluster creates the CR, reconciles it once, and creates the bound claim the StatefulSet
+	// controller would have created for ordinal 0.
+	newCluster := func(name, class string, r *reconciler.GenericReconciler[*testutil.MockCluster]) (string, *corev1.PersistentVolumeClaim) {
+		GinkgoHelper()
+		cr := testutil.NewMockCluster(name, testNamespace).WithRoles(rolesWith(class, "1Gi"))
+		Expect(k8sClient.Create(ctx, cr)).To(Succeed())
+		resourceName := reconciler.RoleGroupResourceName(name, role, roleGroup)
+		DeferCleanup(func() {
+			_ = k8sClient.Delete(ctx, cr)
+			meta := metav1.ObjectMeta{Name: resourceName, Namespace: testNamespace}
+			_ = k8sClient.Delete(ctx, &appsv1.StatefulSet{ObjectMeta: meta})
+			_ = k8sClient.Delete(ctx, &corev1.ConfigMap{ObjectMeta: meta})
+			_ = k8sClient.Delete(ctx, &corev1.Service{ObjectMeta: metav1.ObjectMeta{
+				Name: resourceName + "-headless", Namespace: testNamespace}})
+		})
+		reconcileOnce(r, name)
+
+		sts := &appsv1.StatefulSet{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: resourceName}, sts)).To(Succeed())
+		pvc := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "data-" + resourceName + "-0", Namespace: testNamespace, Labels: sts.Spec.Selector.MatchLabels,
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				StorageClassName: ptr.To(class),
+				VolumeName:       "pv-" + resourceName,
+				Resources: corev1.VolumeResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceStorage: resource.MustParse("1Gi"),
+				}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, pvc)).To(Succeed())
+		DeferCleanup(func() { _ = k8sClient.Delete(ctx, pvc) })
+		pvc.Status = corev1.PersistentVolumeClaimStatus{
+			Phase:    corev1.ClaimBound,
+			Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+		}
+		Expect(k8sClient.Status().Update(ctx, pvc)).To(Succeed())
+		return resourceName, pvc
+	}
+
+	resize := func(name, class, capacity string) {
+		GinkgoHelper()
+		cr := &testutil.MockCluster{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: name}, cr)).To(Succeed())
+		cr.Spec.Roles = rolesWith(class, capacity)
+		Expect(k8sClient.Update(ctx, cr)).To(Succeed())
+	}
+
+	getPVC := func(name string) *corev1.PersistentVolumeClaim {
+		GinkgoHelper()
+		pvc := &corev1.PersistentVolumeClaim{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: name}, pvc)).To(Succeed())
+		return pvc
+	}
+
+	condition := func(name string) *metav1.Condition {
+		GinkgoHelper()
+		cr := &testutil.MockCluster{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: name}, cr)).To(Succeed())
+		return cr.Status.GetCondition(reconciler.ConditionVolumeExpansion)
+	}
+
+	// finishOrphaningDelete does the garbage collector's half of `--cascade=orphan`.
+	finishOrphaningDelete := func(resourceName string) {
+		GinkgoHelper()
+		sts := &appsv1.StatefulSet{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: resourceName}, sts)).To(Succeed())
+		Expect(sts.DeletionTimestamp).NotTo(BeNil(), "the StatefulSet must have been deleted to be recreated")
+		Expect(sts.Finalizers).To(ContainElement(metav1.FinalizerOrphanDependents))
+		sts.Finalizers = nil
+		Expect(k8sClient.Update(ctx, sts)).To(Succeed())
+		Eventually(func() bool {
+			return apierrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKeyFromObject(sts), &appsv1.StatefulSet{}))
+		}).Should(BeTrue())
+	}
+
+	It("grows the bound claims and recreates the StatefulSet when the StorageClass allows it", func() {
+		class := newStorageClass("expandable", true)
+		name := uniqueCRName("expand")
+		rec := record.NewFakeRecorder(100)
+		r := newReconcilerFor(rec)
+		resourceName, pvc := newCluster(name, class, r)
+		drainRecorder(rec)
+
+		resize(name, class, "5Gi")
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: testNamespace, Name: name}})
+		Expect(err).NotTo(HaveOccurred(), "recreating the StatefulSet is a wait, not a failure")
+
+		Expect(getPVC(pvc.Name).Spec.Resources.Requests.Storage().String()).To(Equal("5Gi"))
+		Expect(condition(name).Reason).To(Equal(reconciler.ReasonVolumeExpansionInProgress))
+		Expect(drainRecorder(rec)).To(ContainElement(ContainSubstring("VolumeExpansionStarted")))
+
+		// While the old object is terminating, the pass waits instead of updating it.
+		reconcileOnce(r, name)
+		finishOrphaningDelete(resourceName)
+
+		reconcileOnce(r, name)
+		sts := &appsv1.StatefulSet{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: resourceName}, sts)).To(Succeed())
+		Expect(sts.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests.Storage().String()).To(Equal("5Gi"),
+			"the recreated StatefulSet carries the template the user asked for")
+		Expect(drainRecorder(rec)).NotTo(ContainElement(ContainSubstring("ImmutableFieldIgnored")))
+		Expect(condition(name).Reason).To(Equal(reconciler.ReasonVolumeExpansionInProgress),
+			"the claim's capacity has not caught up with its request yet")
+
+		// The controller has grown the volume; the file system waits on the kubelet.
+		live := getPVC(pvc.Name)
+		live.Status.Conditions = []corev1.PersistentVolumeClaimCondition{{
+			Type: corev1.PersistentVolumeClaimFileSystemResizePending, Status: corev1.ConditionTrue,
+		}}
+		Expect(k8sClient.Status().Update(ctx, live)).To(Succeed())
+		reconcileOnce(r, name)
+		Expect(condition(name).Reason).To(Equal(reconciler.ReasonFileSystemResizePending))
+		Expect(condition(name).Message).To(ContainSubstring(pvc.Name))
+
+		live = getPVC(pvc.Name)
+		live.Status.Conditions = nil
+		live.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("5Gi")}
+		Expect(k8sClient.Status().Update(ctx, live)).To(Succeed())
+		reconcileOnce(r, name)
+		Expect(condition(name).Status).To(Equal(metav1.ConditionFalse))
+		Expect(condition(name).Reason).To(Equal(reconciler.ReasonVolumeExpansionComplete))
+	})
+
+	It("leaves the role group alone when the StorageClass does not allow expansion", func() {
+		class := newStorageClass("fixed", false)
+		name := uniqueCRName("expand-refused")
+		rec := record.NewFakeRecorder(100)
+		r := newReconcilerFor(rec)
+		resourceName, pvc := newCluster(name, class, r)
+		drainRecorder(rec)
+
+		resize(name, class, "5Gi")
+		reconcileOnce(r, name)
+
+		Expect(getPVC(pvc.Name).Spec.Resources.Requests.Storage().String()).To(Equal("1Gi"))
+		sts := &appsv1.StatefulSet{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: testNamespace, Name: resourceName}, sts)).To(Succeed())
+		Expect(sts.DeletionTimestamp).To(BeNil(), "a role group that cannot grow must not be recreated")
+		Expect(drainRecorder(rec)).To(ContainElement(SatisfyAll(
+			ContainSubstring("ImmutableFieldIgnored"),
+			ContainSubstring("spec.volumeClaimTemplates"),
+		)))
+		Expect(condition(name).Reason).To(Equal(reconciler.ReasonVolumeExpansionUnsupported))
+		Expect(condition(name).Message).To(ContainSubstring("does not allow volume expansion"))
+	})
+})
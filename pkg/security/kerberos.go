@@ -0,0 +1,226 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package security
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/zncdatadev/operator-go/pkg/config"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Files the secret-operator writes into a kerberos-format volume, and the environment the
+// helper exports for them.
+const (
+	// KerberosKeytabFileName is the keytab holding one principal per registered service name.
+	KerberosKeytabFileName = "keytab"
+	// KerberosKrb5ConfFileName is the krb5.conf generated from the SecretClass's KDC and realm.
+	KerberosKrb5ConfFileName = "krb5.conf"
+
+	// EnvKrb5Config points MIT-style clients (kinit, klist, native libraries) at krb5.conf.
+	EnvKrb5Config = "KRB5_CONFIG"
+	// EnvHadoopOpts is the JVM option variable the Hadoop-family launch scripts read.
+	EnvHadoopOpts = "HADOOP_OPTS"
+
+	// KerberosPrincipalHostPlaceholder is the host part Hadoop-family services substitute with the
+	// local canonical hostname at startup.
+	KerberosPrincipalHostPlaceholder = "_HOST"
+)
+
+// KerberosOptions are the inputs to NewKerberosConfig that the registration does not carry.
+type KerberosOptions struct {
+	// Realm is the Kerberos realm of the SecretClass's KDC. Required: a principal without a realm
+	// resolves against whatever default_realm krb5.conf names, which is the right realm only by
+	// coincidence.
+	Realm string
+
+	// PrincipalProperties maps a registered service name to the config keys its principal pattern
+	// is published under — "nn" to "dfs.namenode.kerberos.principal", say. A service listed here
+	// that the registration does not carry is an error; a registered service that is not listed
+	// simply publishes no property.
+	PrincipalProperties map[string][]string
+
+	// JAASLoginContexts maps a registered service name to the JAAS login context its file
+	// declares ("Server", "Client", "KafkaServer"). A service without an entry gets no JAAS file.
+	JAASLoginContexts map[string]string
+
+	// JAASPrincipalHost is the host part of the principal each JAAS file logs in as. Unlike the
+	// Hadoop properties, JAAS performs no _HOST substitution, so it is required whenever a JAAS
+	// file is requested. A ${java.system.property} reference is legal: the JDK expands those when
+	// it reads the file.
+	JAASPrincipalHost string
+}
+
+// KerberosConfig is everything a product needs to run against a kerberos-format secret volume:
+// the rendered JAAS files, the principal properties and the main container's environment.
+//
+// It is derived from the registration rather than assembled beside it, so the service names the
+// secret-operator puts in the keytab and the ones the configuration refers to cannot drift.
+type KerberosConfig struct {
+	// KeytabPath and Krb5ConfPath are the files inside the mounted volume.
+	KeytabPath   string
+	Krb5ConfPath string
+
+	// JAASFiles maps a ConfigMap key ("<service>.jaas.conf") to its rendered content.
+	JAASFiles map[string]string
+
+	// Properties are the principal patterns, "<service>/_HOST@<realm>", keyed by the property
+	// names from KerberosOptions.PrincipalProperties.
+	Properties map[string]string
+
+	// Env is the main container's environment: KRB5_CONFIG.
+	Env []corev1.EnvVar
+
+	// JVMOptions points the JVM at krb5.conf ("-Djava.security.krb5.conf=…"). It is a fragment for
+	// the product's own JVM option variable rather than a variable of its own, since a standalone
+	// HADOOP_OPTS would replace or duplicate the heap, GC and JMX options the product sets there;
+	// AppendEnv adds it to that variable.
+	JVMOptions string
+}
+
+// NewKerberosConfig derives a KerberosConfig from a kerberos-format registration on p.
+//
+// The volume must already be registered, since its mount path is the provisioner's to decide.
+func NewKerberosConfig(p *SecretProvisioner, volumeName string, opts KerberosOptions) (*KerberosConfig, error) {
+	reg := p.registration(volumeName)
+	if reg == nil {
+		return nil, fmt.Errorf("secret volume %q not registered", volumeName)
+	}
+	if reg.format != Kerberos {
+		return nil, fmt.Errorf("secret volume %q has format %q, not %q", volumeName, reg.format, Kerberos)
+	}
+	if opts.Realm == "" {
+		return nil, fmt.Errorf("secret volume %q: kerberos realm is required", volumeName)
+	}
+	if len(opts.JAASLoginContexts) > 0 && opts.JAASPrincipalHost == "" {
+		return nil, fmt.Errorf("secret volume %q: JAAS files require a principal host", volumeName)
+	}
+	for _, svc := range slices.Sorted(maps.Keys(opts.PrincipalProperties)) {
+		if !slices.Contains(reg.kerberosSvcNames, svc) {
+			return nil, fmt.Errorf("secret volume %q: principal properties name service %q, which the keytab does not carry",
+				volumeName, svc)
+		}
+	}
+	for _, svc := range slices.Sorted(maps.Keys(opts.JAASLoginContexts)) {
+		if !slices.Contains(reg.kerberosSvcNames, svc) {
+			return nil, fmt.Errorf("secret volume %q: JAAS login context names service %q, which the keytab does not carry",
+				volumeName, svc)
+		}
+	}
+
+	mount := p.mountPath(volumeName)
+	kc := &KerberosConfig{
+		KeytabPath:   path.Join(mount, KerberosKeytabFileName),
+		Krb5ConfPath: path.Join(mount, KerberosKrb5ConfFileName),
+		JAASFiles:    make(map[string]string),
+		Properties:   make(map[string]string),
+	}
+
+	for _, svc := range reg.kerberosSvcNames {
+		principal := KerberosPrincipal(svc, KerberosPrincipalHostPlaceholder, opts.Realm)
+		for _, key := range opts.PrincipalProperties[svc] {
+			kc.Properties[key] = principal
+		}
+		if loginContext, ok := opts.JAASLoginContexts[svc]; ok {
+			kc.JAASFiles[JAASFileName(svc)] = renderJAAS(loginContext, kc.KeytabPath,
+				KerberosPrincipal(svc, opts.JAASPrincipalHost, opts.Realm))
+		}
+	}
+
+	kc.Env = []corev1.EnvVar{{Name: EnvKrb5Config, Value: kc.Krb5ConfPath}}
+	kc.JVMOptions = "-Djava.security.krb5.conf=" + kc.Krb5ConfPath
+	return kc, nil
+}
+
+// AppendEnv wires the configuration into container: Env is set, replacing a variable of the same
+// name, and JVMOptions is appended to jvmOptsEnv — the JVM option variable the product's start
+// script reads, such as EnvHadoopOpts — after any value the product already put there. It is
+// idempotent, so a handler that rebuilds the container each reconcile can call it unconditionally.
+func (k *KerberosConfig) AppendEnv(container *corev1.Container, jvmOptsEnv string) error {
+	for _, env := range k.Env {
+		if idx := slices.IndexFunc(container.Env, func(e corev1.EnvVar) bool { return e.Name == env.Name }); idx >= 0 {
+			container.Env[idx] = env
+		} else {
+			container.Env = append(container.Env, env)
+		}
+	}
+
+	idx := slices.IndexFunc(container.Env, func(e corev1.EnvVar) bool { return e.Name == jvmOptsEnv })
+	switch {
+	case idx < 0:
+		container.Env = append(container.Env, corev1.EnvVar{Name: jvmOptsEnv, Value: k.JVMOptions})
+	case container.Env[idx].ValueFrom != nil:
+		return fmt.Errorf("%s on container %q is set from a reference, so the krb5.conf option cannot be appended to it",
+			jvmOptsEnv, container.Name)
+	case !strings.Contains(container.Env[idx].Value, k.JVMOptions):
+		container.Env[idx].Value = strings.TrimSpace(container.Env[idx].Value + " " + k.JVMOptions)
+	}
+	return nil
+}
+
+// KerberosPrincipal renders "<service>/<host>@<realm>".
+func KerberosPrincipal(service, host, realm string) string {
+	return service + "/" + host + "@" + realm
+}
+
+// JAASFileName is the ConfigMap key a service's JAAS file is published under.
+func JAASFileName(service string) string {
+	return service + ".jaas.conf"
+}
+
+// renderJAAS renders a single keytab-backed Krb5LoginModule login context.
+func renderJAAS(loginContext, keytab, principal string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s {\n", loginContext)
+	sb.WriteString("  com.sun.security.auth.module.Krb5LoginModule required\n")
+	sb.WriteString("  useKeyTab=true\n")
+	fmt.Fprintf(&sb, "  keyTab=%q\n", keytab)
+	sb.WriteString("  storeKey=true\n")
+	sb.WriteString("  useTicketCache=false\n")
+	fmt.Fprintf(&sb, "  principal=%q;\n", principal)
+	sb.WriteString("};\n")
+	return sb.String()
+}
+
+// ConfigMapData renders the Kerberos entries of a role group ConfigMap: every JAAS file, and
+// propertiesFile rendered by gen from the principal properties laid BENEATH base.
+//
+// Beneath, because base is the product's own view of that file and already carries the user's
+// configOverrides: a user who pins a principal by hand must not be silently overruled by the
+// pattern derived here. An empty propertiesFile renders the JAAS files alone.
+func (k *KerberosConfig) ConfigMapData(gen *config.MultiFormatConfigGenerator, propertiesFile string,
+	base map[string]string) (map[string]string, error) {
+	data := maps.Clone(k.JAASFiles)
+	if propertiesFile == "" {
+		return data, nil
+	}
+	if _, exists := data[propertiesFile]; exists {
+		return nil, fmt.Errorf("kerberos properties file %q collides with a JAAS file", propertiesFile)
+	}
+	merged := maps.Clone(k.Properties)
+	maps.Copy(merged, base)
+	content, err := gen.Generate(propertiesFile, merged)
+	if err != nil {
+		return nil, err
+	}
+	data[propertiesFile] = content
+	return data, nil
+}
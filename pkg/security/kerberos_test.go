@@ -0,0 +1,121 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package security_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/zncdatadev/operator-go/pkg/config"
+	"github.com/zncdatadev/operator-go/pkg/security"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("KerberosConfig", func() {
+	var prov *security.SecretProvisioner
+
+	BeforeEach(func() {
+		prov = security.NewSecretProvisioner()
+		prov.Register(security.KerberosVolume("kerberos", "kerberos-class", "nn", "HTTP"))
+	})
+
+	It("derives paths, principal properties, JAAS files and env from the registration", func() {
+		kc, err := security.NewKerberosConfig(prov, "kerberos", security.KerberosOptions{
+			Realm: "EXAMPLE.COM",
+			PrincipalProperties: map[string][]string{
+				"nn":   {"dfs.namenode.kerberos.principal"},
+				"HTTP": {"dfs.web.authentication.kerberos.principal"},
+			},
+			JAASLoginContexts: map[string]string{"nn": "Client"},
+			JAASPrincipalHost: "nn-0.example.svc.cluster.local",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(kc.KeytabPath).To(Equal("/kubedoop/mount/kerberos/keytab"))
+		Expect(kc.Krb5ConfPath).To(Equal("/kubedoop/mount/kerberos/krb5.conf"))
+		Expect(kc.Properties).To(Equal(map[string]string{
+			"dfs.namenode.kerberos.principal":           "nn/_HOST@EXAMPLE.COM",
+			"dfs.web.authentication.kerberos.principal": "HTTP/_HOST@EXAMPLE.COM",
+		}))
+
+		Expect(kc.JAASFiles).To(HaveLen(1))
+		jaas := kc.JAASFiles["nn.jaas.conf"]
+		Expect(jaas).To(HavePrefix("Client {\n"))
+		Expect(jaas).To(ContainSubstring(`keyTab="/kubedoop/mount/kerberos/keytab"`))
+		Expect(jaas).To(ContainSubstring(`principal="nn/nn-0.example.svc.cluster.local@EXAMPLE.COM";`))
+
+		Expect(kc.Env).To(ConsistOf(
+			corev1.EnvVar{Name: "KRB5_CONFIG", Value: "/kubedoop/mount/kerberos/krb5.conf"},
+		))
+		Expect(kc.JVMOptions).To(Equal("-Djava.security.krb5.conf=/kubedoop/mount/kerberos/krb5.conf"))
+	})
+
+	It("appends the krb5.conf option to the product's own JVM options", func() {
+		kc, err := security.NewKerberosConfig(prov, "kerberos", security.KerberosOptions{Realm: "EXAMPLE.COM"})
+		Expect(err).NotTo(HaveOccurred())
+
+		container := &corev1.Container{Name: "namenode", Env: []corev1.EnvVar{{Name: "HADOOP_OPTS", Value: "-Xmx1g"}}}
+		Expect(kc.AppendEnv(container, security.EnvHadoopOpts)).To(Succeed())
+		Expect(kc.AppendEnv(container, security.EnvHadoopOpts)).To(Succeed(), "a second call changes nothing")
+		Expect(container.Env).To(ConsistOf(
+			corev1.EnvVar{Name: "HADOOP_OPTS", Value: "-Xmx1g -Djava.security.krb5.conf=/kubedoop/mount/kerberos/krb5.conf"},
+			corev1.EnvVar{Name: "KRB5_CONFIG", Value: "/kubedoop/mount/kerberos/krb5.conf"},
+		))
+
+		fromSecret := &corev1.Container{Name: "namenode", Env: []corev1.EnvVar{{
+			Name: "HADOOP_OPTS", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{Key: "opts"}},
+		}}}
+		Expect(kc.AppendEnv(fromSecret, security.EnvHadoopOpts)).To(MatchError(ContainSubstring("set from a reference")))
+	})
+
+	It("renders the properties beneath the product's own file", func() {
+		kc, err := security.NewKerberosConfig(prov, "kerberos", security.KerberosOptions{
+			Realm: "EXAMPLE.COM",
+			PrincipalProperties: map[string][]string{
+				"nn":   {"dfs.namenode.kerberos.principal"},
+				"HTTP": {"dfs.web.authentication.kerberos.principal"},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		gen := config.NewMultiFormatConfigGenerator()
+		gen.RegisterDefaultFormats()
+		data, err := kc.ConfigMapData(gen, "hdfs-site.xml", map[string]string{
+			"dfs.namenode.kerberos.principal": "nn/pinned@EXAMPLE.COM",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(HaveKey("hdfs-site.xml"))
+		Expect(data["hdfs-site.xml"]).To(ContainSubstring("<value>nn/pinned@EXAMPLE.COM</value>"))
+		Expect(data["hdfs-site.xml"]).To(ContainSubstring("<value>HTTP/_HOST@EXAMPLE.COM</value>"))
+	})
+
+	DescribeTable("rejects inputs it cannot render",
+		func(volume string, opts security.KerberosOptions, message string) {
+			prov.Register(security.TLS("tls", "tls-class"))
+			_, err := security.NewKerberosConfig(prov, volume, opts)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("unregistered volume", "missing", security.KerberosOptions{Realm: "R"}, "not registered"),
+		Entry("non-kerberos volume", "tls", security.KerberosOptions{Realm: "R"}, `not "kerberos"`),
+		Entry("no realm", "kerberos", security.KerberosOptions{}, "realm is required"),
+		Entry("JAAS without a host", "kerberos", security.KerberosOptions{
+			Realm: "R", JAASLoginContexts: map[string]string{"nn": "Client"},
+		}, "principal host"),
+		Entry("property for a service the keytab lacks", "kerberos", security.KerberosOptions{
+			Realm: "R", PrincipalProperties: map[string][]string{"dn": {"dfs.datanode.kerberos.principal"}},
+		}, `service "dn"`),
+	)
+})
@@ -0,0 +1,265 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package security
+
+import (
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PodSecurityLevel is a Pod Security Standards profile. The privileged profile is not a level
+// here: it admits everything, so checking against it finds nothing.
+type PodSecurityLevel string
+
+const (
+	// PodSecurityBaseline forbids the known privilege escalations: host namespaces, privileged
+	// containers, hostPath volumes, host ports, added capabilities beyond the default set, and
+	// unconfined seccomp/AppArmor.
+	PodSecurityBaseline PodSecurityLevel = "baseline"
+	// PodSecurityRestricted is baseline plus the hardening requirements: non-root, no privilege
+	// escalation, all capabilities dropped, a RuntimeDefault or Localhost seccomp profile, and only
+	// the ephemeral volume types.
+	PodSecurityRestricted PodSecurityLevel = "restricted"
+)
+
+// PodSecurityViolation is one way a pod spec fails a level.
+type PodSecurityViolation struct {
+	// Field is the offending field, relative to the pod spec, with containers addressed by name —
+	// "containers[vector].securityContext.privileged" — so it reads the same in an event and in a
+	// podOverrides patch.
+	Field string
+	// Reason says what the level requires instead.
+	Reason string
+}
+
+// String renders the violation as "<field>: <reason>".
+func (v PodSecurityViolation) String() string {
+	return v.Field + ": " + v.Reason
+}
+
+// CheckPodSecurityStandard evaluates a complete pod spec — the one the API server will receive,
+// after podOverrides and sidecar injection — against level.
+//
+// It mirrors the Pod Security Admission checks of the same name, so a namespace labelled
+// pod-security.kubernetes.io/enforce=<level> admits the pod exactly when this returns nothing. It
+// exists so the operator sees the answer at build time, where it can name the role group, instead
+// of the StatefulSet controller failing pod creation with an event on an object no one is watching.
+func CheckPodSecurityStandard(level PodSecurityLevel, spec *corev1.PodSpec) []PodSecurityViolation {
+	return checkPodSecurity(level, spec, false)
+}
+
+// CheckPodSecurityOverride evaluates a PARTIAL pod spec — a podOverrides patch — against level. Only
+// values the patch states are judged: a patch that says nothing about runAsNonRoot leaves whatever
+// the framework set, so a missing field is not a violation here the way it is for a full spec.
+func CheckPodSecurityOverride(level PodSecurityLevel, spec *corev1.PodSpec) []PodSecurityViolation {
+	return checkPodSecurity(level, spec, true)
+}
+
+// baselineCapabilities are the capabilities the baseline level lets a container add: the container
+// runtime's default set.
+var baselineCapabilities = []corev1.Capability{
+	"AUDIT_WRITE", "CHOWN", "DAC_OVERRIDE", "FOWNER", "FSETID", "KILL", "MKNOD", "NET_BIND_SERVICE",
+	"SETFCAP", "SETGID", "SETPCAP", "SETUID", "SYS_CHROOT",
+}
+
+// baselineSysctls are the namespaced sysctls the baseline level considers safe.
+var baselineSysctls = []string{
+	"kernel.shm_rmid_forced", "net.ipv4.ip_local_port_range", "net.ipv4.ip_unprivileged_port_start",
+	"net.ipv4.tcp_syncookies", "net.ipv4.ping_group_range", "net.ipv4.ip_local_reserved_ports",
+	"net.ipv4.tcp_keepalive_time", "net.ipv4.tcp_fin_timeout", "net.ipv4.tcp_keepalive_intvl",
+	"net.ipv4.tcp_keepalive_probes",
+}
+
+// baselineSELinuxTypes are the SELinux types the baseline level allows.
+var baselineSELinuxTypes = []string{"", "container_t", "container_init_t", "container_kvm_t", "container_engine_t"}
+
+func checkPodSecurity(level PodSecurityLevel, spec *corev1.PodSpec, partial bool) []PodSecurityViolation {
+	if spec == nil || (level != PodSecurityBaseline && level != PodSecurityRestricted) {
+		return nil
+	}
+	restricted := level == PodSecurityRestricted
+	var out []PodSecurityViolation
+	add := func(field, format string, args ...any) {
+		out = append(out, PodSecurityViolation{Field: field, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	if spec.HostNetwork {
+		add("hostNetwork", "must not be true")
+	}
+	if spec.HostPID {
+		add("hostPID", "must not be true")
+	}
+	if spec.HostIPC {
+		add("hostIPC", "must not be true")
+	}
+
+	podSC := spec.SecurityContext
+	if podSC == nil {
+		podSC = &corev1.PodSecurityContext{}
+	}
+	checkSELinux(add, "securityContext.seLinuxOptions", podSC.SELinuxOptions)
+	checkSeccompBaseline(add, "securityContext.seccompProfile", podSC.SeccompProfile)
+	if p := podSC.AppArmorProfile; p != nil && p.Type == corev1.AppArmorProfileTypeUnconfined {
+		add("securityContext.appArmorProfile.type", "must not be Unconfined")
+	}
+	if p := podSC.WindowsOptions; p != nil && p.HostProcess != nil && *p.HostProcess {
+		add("securityContext.windowsOptions.hostProcess", "must not be true")
+	}
+	for _, s := range podSC.Sysctls {
+		if !slices.Contains(baselineSysctls, s.Name) {
+			add("securityContext.sysctls", "%q is not a safe sysctl", s.Name)
+		}
+	}
+	if restricted {
+		if podSC.RunAsUser != nil && *podSC.RunAsUser == 0 {
+			add("securityContext.runAsUser", "must not be 0")
+		}
+		if podSC.RunAsNonRoot != nil && !*podSC.RunAsNonRoot {
+			add("securityContext.runAsNonRoot", "must not be false")
+		}
+	}
+
+	for i := range spec.Volumes {
+		v := &spec.Volumes[i]
+		field := fmt.Sprintf("volumes[%s]", v.Name)
+		if v.HostPath != nil {
+			add(field+".hostPath", "hostPath volumes are forbidden")
+			continue
+		}
+		if restricted && !restrictedVolume(v) {
+			add(field, "only configMap, csi, downwardAPI, emptyDir, ephemeral, persistentVolumeClaim, projected and secret volumes are allowed")
+		}
+	}
+
+	containers := make([]namedContainer, 0, len(spec.InitContainers)+len(spec.Containers)+len(spec.EphemeralContainers))
+	for i := range spec.InitContainers {
+		c := &spec.InitContainers[i]
+		containers = append(containers, namedContainer{"initContainers[" + c.Name + "]", c.Ports, c.SecurityContext})
+	}
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		containers = append(containers, namedContainer{"containers[" + c.Name + "]", c.Ports, c.SecurityContext})
+	}
+	for i := range spec.EphemeralContainers {
+		c := &spec.EphemeralContainers[i]
+		containers = append(containers, namedContainer{"ephemeralContainers[" + c.Name + "]", c.Ports, c.SecurityContext})
+	}
+
+	for _, c := range containers {
+		for _, p := range c.ports {
+			if p.HostPort != 0 {
+				add(c.field+".ports", "hostPort %d is forbidden", p.HostPort)
+			}
+		}
+		sc := c.sc
+		if sc == nil {
+			sc = &corev1.SecurityContext{}
+		}
+		f := c.field + ".securityContext"
+		if sc.Privileged != nil && *sc.Privileged {
+			add(f+".privileged", "must not be true")
+		}
+		if sc.ProcMount != nil && *sc.ProcMount != corev1.DefaultProcMount {
+			add(f+".procMount", "must be Default")
+		}
+		if p := sc.WindowsOptions; p != nil && p.HostProcess != nil && *p.HostProcess {
+			add(f+".windowsOptions.hostProcess", "must not be true")
+		}
+		if p := sc.AppArmorProfile; p != nil && p.Type == corev1.AppArmorProfileTypeUnconfined {
+			add(f+".appArmorProfile.type", "must not be Unconfined")
+		}
+		checkSELinux(add, f+".seLinuxOptions", sc.SELinuxOptions)
+		checkSeccompBaseline(add, f+".seccompProfile", sc.SeccompProfile)
+
+		var added, dropped []corev1.Capability
+		if sc.Capabilities != nil {
+			added, dropped = sc.Capabilities.Add, sc.Capabilities.Drop
+		}
+		for _, capability := range added {
+			switch {
+			case restricted && capability != "NET_BIND_SERVICE":
+				add(f+".capabilities.add", "%s may not be added; only NET_BIND_SERVICE is allowed", capability)
+			case !restricted && !slices.Contains(baselineCapabilities, capability):
+				add(f+".capabilities.add", "%s is not in the default capability set", capability)
+			}
+		}
+		if !restricted {
+			continue
+		}
+
+		if sc.AllowPrivilegeEscalation == nil {
+			if !partial {
+				add(f+".allowPrivilegeEscalation", "must be false")
+			}
+		} else if *sc.AllowPrivilegeEscalation {
+			add(f+".allowPrivilegeEscalation", "must be false")
+		}
+		if sc.RunAsUser != nil && *sc.RunAsUser == 0 {
+			add(f+".runAsUser", "must not be 0")
+		}
+		switch {
+		case sc.RunAsNonRoot != nil && !*sc.RunAsNonRoot:
+			add(f+".runAsNonRoot", "must not be false")
+		case sc.RunAsNonRoot == nil && podSC.RunAsNonRoot == nil && !partial:
+			add(f+".runAsNonRoot", "must be true, here or on the pod")
+		}
+		if sc.SeccompProfile == nil && podSC.SeccompProfile == nil && !partial {
+			add(f+".seccompProfile", "must be RuntimeDefault or Localhost, here or on the pod")
+		}
+		// A patch that states capabilities replaces the framework's drop list under a strategic
+		// merge of the container, so it is judged even when partial.
+		if (sc.Capabilities != nil || !partial) && !slices.Contains(dropped, "ALL") {
+			add(f+".capabilities.drop", "must include ALL")
+		}
+	}
+	return out
+}
+
+type namedContainer struct {
+	field string
+	ports []corev1.ContainerPort
+	sc    *corev1.SecurityContext
+}
+
+func checkSELinux(add func(string, string, ...any), field string, opts *corev1.SELinuxOptions) {
+	if opts == nil {
+		return
+	}
+	if !slices.Contains(baselineSELinuxTypes, opts.Type) {
+		add(field+".type", "%q is not an allowed SELinux type", opts.Type)
+	}
+	if opts.User != "" {
+		add(field+".user", "must not be set")
+	}
+	if opts.Role != "" {
+		add(field+".role", "must not be set")
+	}
+}
+
+func checkSeccompBaseline(add func(string, string, ...any), field string, p *corev1.SeccompProfile) {
+	if p != nil && p.Type == corev1.SeccompProfileTypeUnconfined {
+		add(field+".type", "must not be Unconfined")
+	}
+}
+
+func restrictedVolume(v *corev1.Volume) bool {
+	s := v.VolumeSource
+	return s.ConfigMap != nil || s.CSI != nil || s.DownwardAPI != nil || s.EmptyDir != nil ||
+		s.Ephemeral != nil || s.PersistentVolumeClaim != nil || s.Projected != nil || s.Secret != nil
+}
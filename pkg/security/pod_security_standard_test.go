@@ -0,0 +1,117 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package security_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/zncdatadev/operator-go/pkg/security"
+)
+
+var _ = Describe("CheckPodSecurityStandard", func() {
+	// compliantPod is what BaseRoleGroupHandler builds with the default security contexts.
+	compliantPod := func() *corev1.PodSpec {
+		b := security.DefaultPodSecurityBuilder()
+		return &corev1.PodSpec{
+			SecurityContext: b.BuildDefaultPodSecurityContext(),
+			Containers: []corev1.Container{{
+				Name:            "main",
+				SecurityContext: b.BuildDefaultSecurityContext(),
+			}},
+			Volumes: []corev1.Volume{{
+				Name:         "config",
+				VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{}},
+			}},
+		}
+	}
+
+	It("passes the framework's default contexts at both levels", func() {
+		Expect(security.CheckPodSecurityStandard(security.PodSecurityBaseline, compliantPod())).To(BeEmpty())
+		Expect(security.CheckPodSecurityStandard(security.PodSecurityRestricted, compliantPod())).To(BeEmpty())
+	})
+
+	It("names a privileged sidecar and a hostPath volume at baseline", func() {
+		pod := compliantPod()
+		pod.Containers = append(pod.Containers, corev1.Container{
+			Name:            "debug",
+			SecurityContext: &corev1.SecurityContext{Privileged: ptr.To(true)},
+		})
+		pod.Volumes = append(pod.Volumes, corev1.Volume{
+			Name:         "host",
+			VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/run"}},
+		})
+
+		fields := fieldsOf(security.CheckPodSecurityStandard(security.PodSecurityBaseline, pod))
+		Expect(fields).To(ConsistOf(
+			"containers[debug].securityContext.privileged",
+			"volumes[host].hostPath",
+		))
+	})
+
+	It("requires the hardening fields at restricted but not at baseline", func() {
+		pod := &corev1.PodSpec{Containers: []corev1.Container{{Name: "main"}}}
+		Expect(security.CheckPodSecurityStandard(security.PodSecurityBaseline, pod)).To(BeEmpty())
+		Expect(fieldsOf(security.CheckPodSecurityStandard(security.PodSecurityRestricted, pod))).To(ConsistOf(
+			"containers[main].securityContext.allowPrivilegeEscalation",
+			"containers[main].securityContext.runAsNonRoot",
+			"containers[main].securityContext.seccompProfile",
+			"containers[main].securityContext.capabilities.drop",
+		))
+	})
+
+	DescribeTable("baseline forbids",
+		func(mutate func(*corev1.PodSpec), field string) {
+			pod := compliantPod()
+			mutate(pod)
+			Expect(fieldsOf(security.CheckPodSecurityStandard(security.PodSecurityBaseline, pod))).To(ContainElement(field))
+		},
+		Entry("host networking", func(p *corev1.PodSpec) { p.HostNetwork = true }, "hostNetwork"),
+		Entry("a host port", func(p *corev1.PodSpec) {
+			p.Containers[0].Ports = []corev1.ContainerPort{{ContainerPort: 80, HostPort: 80}}
+		}, "containers[main].ports"),
+		Entry("an added capability outside the default set", func(p *corev1.PodSpec) {
+			p.Containers[0].SecurityContext.Capabilities.Add = []corev1.Capability{"SYS_ADMIN"}
+		}, "containers[main].securityContext.capabilities.add"),
+		Entry("an unconfined seccomp profile", func(p *corev1.PodSpec) {
+			p.SecurityContext.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined}
+		}, "securityContext.seccompProfile.type"),
+		Entry("an unsafe sysctl", func(p *corev1.PodSpec) {
+			p.SecurityContext.Sysctls = []corev1.Sysctl{{Name: "kernel.msgmax", Value: "1"}}
+		}, "securityContext.sysctls"),
+	)
+
+	It("judges only what a podOverrides patch states", func() {
+		patch := &corev1.PodSpec{Containers: []corev1.Container{{Name: "main"}}}
+		Expect(security.CheckPodSecurityOverride(security.PodSecurityRestricted, patch)).To(BeEmpty())
+
+		patch.Containers[0].SecurityContext = &corev1.SecurityContext{AllowPrivilegeEscalation: ptr.To(true)}
+		Expect(fieldsOf(security.CheckPodSecurityOverride(security.PodSecurityRestricted, patch))).To(ConsistOf(
+			"containers[main].securityContext.allowPrivilegeEscalation",
+		))
+	})
+})
+
+func fieldsOf(violations []security.PodSecurityViolation) []string {
+	fields := make([]string, len(violations))
+	for i, v := range violations {
+		fields[i] = v.Field
+	}
+	return fields
+}
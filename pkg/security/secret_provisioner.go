@@ -435,6 +435,16 @@ func (p *SecretProvisioner) AutoInject(stsBuilder *builder.StatefulSetBuilder) {
 	}
 }
 
+// registration returns the registration for volumeName, or nil when none is registered.
+func (p *SecretProvisioner) registration(volumeName string) *SecretVolumeRegistration {
+	for _, reg := range p.registrations {
+		if reg.volumeName == volumeName {
+			return reg
+		}
+	}
+	return nil
+}
+
 // mountPath returns the full mount path for a volume name (no trailing slash).
 func (p *SecretProvisioner) mountPath(volumeName string) string {
 	return path.Join(p.mountBasePath, volumeName)
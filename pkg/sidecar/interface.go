@@ -103,3 +103,14 @@ type OwnImageProvider interface {
 	// OwnsImage reports whether the provider supplies its own default image.
 	OwnsImage() bool
 }
+
+// ConfigDataProvider is optionally implemented by sidecar providers whose configuration the
+// framework renders rather than the product maintaining it in a ConfigMap of its own — e.g. a JMX
+// exporter built from rules (JMXExporterSidecarProvider.WithGeneratedConfig). The entries land in
+// the role group ConfigMap through SidecarManager.ConfigMapData, so they are applied, pruned and
+// hashed into the pod template like every other config file of the role group.
+type ConfigDataProvider interface {
+	// ConfigMapData returns the role group ConfigMap entries the provider mounts, keyed by file
+	// name, or nil when it renders none.
+	ConfigMapData() (map[string]string, error)
+}
@@ -20,6 +20,9 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/zncdatadev/operator-go/pkg/constant"
 	corev1 "k8s.io/api/core/v1"
@@ -47,13 +50,34 @@ const (
 	JMXExporterConfigFileName = "config.yaml"
 	// JMXExporterDefaultConfigMapName is the default ConfigMap name for JMX Exporter config.
 	JMXExporterDefaultConfigMapName = "jmx-exporter-config"
+	// JMXExporterGeneratedConfigFileName is the role group ConfigMap key the framework renders a
+	// JMXExporterConfig into (see WithGeneratedConfig).
+	JMXExporterGeneratedConfigFileName = "jmx_exporter.yaml"
 )
 
-// JMXExporterSidecarProvider injects the Prometheus JMX Exporter sidecar.
+// JMXExporterSidecarProvider injects the Prometheus JMX Exporter.
+//
+// The exporter's configuration comes from one of two places:
+//   - a ConfigMap the product creates itself, carrying config.yaml (the default,
+//     JMXExporterDefaultConfigMapName, or WithConfigMapName), which Validate requires to exist;
+//   - rules the product declares with WithGeneratedConfig, which the provider renders into the
+//     role group ConfigMap as jmx_exporter.yaml (see ConfigMapData). Nothing is maintained beside
+//     the operator, and the rules change with the operator that understands them.
+//
+// And it runs in one of two ways:
+//   - as a native sidecar running jmx_prometheus_httpserver.jar, which connects to the product's
+//     JMX port (the default);
+//   - as a java agent in the product's own JVM (AsJavaAgent), which needs no JMX port, no second
+//     JVM and no container of its own.
 type JMXExporterSidecarProvider struct {
 	name          string
 	port          int32
 	configMapName string
+	// generated, when set, is rendered into configMapName under JMXExporterGeneratedConfigFileName.
+	generated *JMXExporterConfig
+	// agentContainer and jvmOptsEnv, when set, run the exporter as a java agent in that container.
+	agentContainer string
+	jvmOptsEnv     string
 }
 
 // NewJMXExporterSidecarProvider creates a new JMXExporterSidecarProvider.
@@ -81,16 +105,127 @@ func (p *JMXExporterSidecarProvider) WithConfigMapName(name string) *JMXExporter
 	return p
 }
 
+// WithGeneratedConfig makes the provider render cfg into the role group ConfigMap, named
+// roleGroupConfigMap (RoleGroupBuildContext.ResourceName), instead of mounting a ConfigMap the
+// product maintains. BaseRoleGroupHandler writes ConfigMapData into that ConfigMap; a handler that
+// builds its own ConfigMap merges SidecarManager.ConfigMapData itself.
+func (p *JMXExporterSidecarProvider) WithGeneratedConfig(roleGroupConfigMap string, cfg *JMXExporterConfig) *JMXExporterSidecarProvider {
+	p.configMapName = roleGroupConfigMap
+	p.generated = cfg
+	return p
+}
+
+// AsJavaAgent runs the exporter as a java agent inside container, the product's main container,
+// instead of as a sidecar. The `-javaagent` option is appended to the container's jvmOptsEnv —
+// the JVM option variable its start script reads, such as HADOOP_OPTS or KAFKA_OPTS — after any
+// value the product or an envOverride already set; the metrics port is added to the container.
+//
+// JAVA_TOOL_OPTIONS is deliberately not the default: every JVM in the container reads it, so a
+// CLI tool started with kubectl exec would load a second agent on the same port and fail.
+func (p *JMXExporterSidecarProvider) AsJavaAgent(container, jvmOptsEnv string) *JMXExporterSidecarProvider {
+	p.agentContainer = container
+	p.jvmOptsEnv = jvmOptsEnv
+	return p
+}
+
 // Name returns the sidecar name.
 func (p *JMXExporterSidecarProvider) Name() string {
 	return p.name
 }
 
-// Validate validates that the JMX Exporter ConfigMap exists.
+// ConfigMapData renders the generated configuration as the role group ConfigMap entry it is
+// mounted from. It returns nil when the product supplies its own ConfigMap.
+//
+// HostPort is checked against the mode here because it is the one setting that decides what the
+// exporter scrapes: the sidecar has nothing to read without it, and the agent would connect out
+// over JMX instead of reading its own JVM.
+func (p *JMXExporterSidecarProvider) ConfigMapData() (map[string]string, error) {
+	if p.generated == nil {
+		return nil, nil
+	}
+	switch {
+	case p.agentContainer == "" && p.generated.HostPort == "":
+		return nil, fmt.Errorf("jmx-exporter: hostPort is required for the sidecar, which reads the product's JMX port")
+	case p.agentContainer != "" && p.generated.HostPort != "":
+		return nil, fmt.Errorf("jmx-exporter: hostPort must be empty for the java agent, which reads the JVM it runs in")
+	}
+	content, err := p.generated.Render()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{JMXExporterGeneratedConfigFileName: content}, nil
+}
+
+// Validate validates that the JMX Exporter ConfigMap exists and, for a generated configuration,
+// that it carries jmx_exporter.yaml: a handler that builds its own ConfigMap and does not merge
+// ConfigMapData would otherwise mount a volume with nothing in it.
 func (p *JMXExporterSidecarProvider) Validate(ctx context.Context, c client.Client, namespace string) error {
-	if err := ValidateConfigMapExists(ctx, c, namespace, p.configMapName); err != nil {
+	if p.generated == nil {
+		if err := ValidateConfigMapExists(ctx, c, namespace, p.configMapName); err != nil {
+			return fmt.Errorf("jmx-exporter config map %q not found: %w", p.configMapName, err)
+		}
+		return nil
+	}
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, client.ObjectKey{Namespace: namespace, Name: p.configMapName}, cm); err != nil {
 		return fmt.Errorf("jmx-exporter config map %q not found: %w", p.configMapName, err)
 	}
+	if _, ok := cm.Data[JMXExporterGeneratedConfigFileName]; !ok {
+		return fmt.Errorf("jmx-exporter config map %q has no %q key: merge SidecarManager.ConfigMapData into the role group ConfigMap",
+			p.configMapName, JMXExporterGeneratedConfigFileName)
+	}
+	return nil
+}
+
+// configFilePath is where the exporter reads its configuration from.
+func (p *JMXExporterSidecarProvider) configFilePath() string {
+	if p.generated != nil {
+		return path.Join(JMXExporterConfigMountPath, JMXExporterGeneratedConfigFileName)
+	}
+	return path.Join(JMXExporterConfigMountPath, JMXExporterConfigFileName)
+}
+
+// configVolume is the exporter's config volume. A generated configuration projects only its own
+// key out of the role group ConfigMap, so the exporter's directory does not carry every other
+// config file of the product.
+func (p *JMXExporterSidecarProvider) configVolume() corev1.Volume {
+	source := &corev1.ConfigMapVolumeSource{
+		LocalObjectReference: corev1.LocalObjectReference{Name: p.configMapName},
+	}
+	if p.generated != nil {
+		source.Items = []corev1.KeyToPath{{Key: JMXExporterGeneratedConfigFileName, Path: JMXExporterGeneratedConfigFileName}}
+	}
+	return corev1.Volume{Name: JMXExporterConfigVolumeName, VolumeSource: corev1.VolumeSource{ConfigMap: source}}
+}
+
+// injectJavaAgent wires the exporter into the agent container's JVM. It is idempotent like the
+// sidecar path: the option is only appended when the variable does not already carry it.
+func (p *JMXExporterSidecarProvider) injectJavaAgent(podSpec *corev1.PodSpec, port int32) error {
+	idx := FindContainerIndex(podSpec, p.agentContainer)
+	if idx < 0 {
+		return fmt.Errorf("jmx-exporter: java agent container %q is not in the pod", p.agentContainer)
+	}
+	container := &podSpec.Containers[idx]
+
+	opt := "-javaagent:" + constant.KubedoopJmxAgentJar + "=" + strconv.Itoa(int(port)) + ":" + p.configFilePath()
+	envIdx := slices.IndexFunc(container.Env, func(e corev1.EnvVar) bool { return e.Name == p.jvmOptsEnv })
+	switch {
+	case envIdx < 0:
+		container.Env = append(container.Env, corev1.EnvVar{Name: p.jvmOptsEnv, Value: opt})
+	case container.Env[envIdx].ValueFrom != nil:
+		return fmt.Errorf("jmx-exporter: %s on container %q is set from a reference, so the java agent option cannot be appended to it",
+			p.jvmOptsEnv, p.agentContainer)
+	case !strings.Contains(container.Env[envIdx].Value, opt):
+		container.Env[envIdx].Value = strings.TrimSpace(container.Env[envIdx].Value + " " + opt)
+	}
+
+	AddPorts(container, []corev1.ContainerPort{{Name: "metrics", ContainerPort: port, Protocol: corev1.ProtocolTCP}})
+	AddVolumeMounts(container, []corev1.VolumeMount{{
+		Name:      JMXExporterConfigVolumeName,
+		MountPath: JMXExporterConfigMountPath,
+		ReadOnly:  true,
+	}})
+	AddVolumes(podSpec, []corev1.Volume{p.configVolume()})
 	return nil
 }
 
@@ -101,6 +236,17 @@ func (p *JMXExporterSidecarProvider) Inject(podSpec *corev1.PodSpec, config *Sid
 		config = &SidecarConfig{Enabled: true}
 	}
 
+	// Get port
+	port := p.port
+	if len(config.Ports) > 0 {
+		port = config.Ports[0].ContainerPort
+	}
+
+	// The agent runs in the product's JVM, so it needs no image and no container of its own.
+	if p.agentContainer != "" {
+		return p.injectJavaAgent(podSpec, port)
+	}
+
 	// Get image
 	if config.Image == "" {
 		return fmt.Errorf("sidecar %s: image is required but not set", p.name)
@@ -112,12 +258,6 @@ func (p *JMXExporterSidecarProvider) Inject(podSpec *corev1.PodSpec, config *Sid
 		pullPolicy = config.ImagePullPolicy
 	}
 
-	// Get port
-	port := p.port
-	if len(config.Ports) > 0 {
-		port = config.Ports[0].ContainerPort
-	}
-
 	// Create JMX Exporter container
 	container := &corev1.Container{
 		Name:            p.name,
@@ -135,7 +275,7 @@ func (p *JMXExporterSidecarProvider) Inject(podSpec *corev1.PodSpec, config *Sid
 			"-jar",
 			JMXExporterJarPath,
 			fmt.Sprintf("%d", port),
-			path.Join(JMXExporterConfigMountPath, JMXExporterConfigFileName),
+			p.configFilePath(),
 		},
 		VolumeMounts: []corev1.VolumeMount{
 			{
@@ -201,20 +341,7 @@ func (p *JMXExporterSidecarProvider) Inject(podSpec *corev1.PodSpec, config *Sid
 	AddOrReplaceInitContainer(podSpec, container)
 
 	// Add required volumes if not present
-	volumes := []corev1.Volume{
-		{
-			Name: JMXExporterConfigVolumeName,
-			VolumeSource: corev1.VolumeSource{
-				ConfigMap: &corev1.ConfigMapVolumeSource{
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: p.configMapName,
-					},
-				},
-			},
-		},
-	}
-
-	AddVolumes(podSpec, volumes)
+	AddVolumes(podSpec, []corev1.Volume{p.configVolume()})
 
 	// Caller-supplied volumes back the caller-supplied VolumeMounts applied above; without them
 	// a config.VolumeMounts entry would reference a volume the pod does not declare.
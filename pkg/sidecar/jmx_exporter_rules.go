@@ -0,0 +1,176 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JMXMetricType is the Prometheus type a JMX exporter rule exports its samples as.
+type JMXMetricType string
+
+const (
+	JMXMetricTypeGauge   JMXMetricType = "GAUGE"
+	JMXMetricTypeCounter JMXMetricType = "COUNTER"
+	JMXMetricTypeUntyped JMXMetricType = "UNTYPED"
+)
+
+// JMXExporterRule is one entry of the exporter's `rules` list. The exporter matches each MBean
+// attribute, rendered as `domain<key=value, ...><nested keys>attribute: value`, against the rules in
+// order and exports it by the FIRST rule whose Pattern matches; an attribute no rule matches is not
+// exported at all.
+//
+// Name, Value and every Labels value may reference the pattern's capture groups as $1, $2, ...,
+// which is how a rule extracts labels from an MBean name:
+//
+//	sidecar.JMXExporterRule{
+//	    Pattern: `kafka.server<type=(.+), name=(.+)><>Count`,
+//	    Name:    "kafka_server_$1_$2_total",
+//	    Type:    sidecar.JMXMetricTypeCounter,
+//	}
+type JMXExporterRule struct {
+	// Pattern is the regular expression matched against the rendered attribute. It is a Java
+	// regular expression, anchored at both ends by the exporter. Required.
+	Pattern string `yaml:"pattern"`
+	// Name is the metric name. Empty exports the attribute under the exporter's default name,
+	// derived from the MBean, and then no Labels may be set.
+	Name string `yaml:"name,omitempty"`
+	// Value overrides the sample value, for attributes whose value is not a number.
+	Value string `yaml:"value,omitempty"`
+	// ValueFactor multiplies the sample value, e.g. 0.001 to turn milliseconds into seconds.
+	ValueFactor float64 `yaml:"valueFactor,omitempty"`
+	// Help is the metric's HELP text.
+	Help string `yaml:"help,omitempty"`
+	// Type defaults to UNTYPED in the exporter.
+	Type JMXMetricType `yaml:"type,omitempty"`
+	// Labels are the labels added to every sample the rule exports.
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// AttrNameSnakeCase converts the attribute name to snake case before matching.
+	AttrNameSnakeCase bool `yaml:"attrNameSnakeCase,omitempty"`
+	// Cache caches whether an attribute matched, for attributes whose rendering never changes.
+	Cache bool `yaml:"cache,omitempty"`
+}
+
+// JMXExporterCatchAllRule exports every attribute no earlier rule matched under the exporter's
+// default naming. A product's default rule set typically ends with it, so a metric the product
+// did not anticipate is still scraped, just under a less tidy name.
+func JMXExporterCatchAllRule() JMXExporterRule {
+	return JMXExporterRule{Pattern: ".*"}
+}
+
+// JMXExporterConfig is the exporter configuration the framework renders into the role group
+// ConfigMap (see JMXExporterSidecarProvider.WithGeneratedConfig), so a product ships rules rather than a
+// ConfigMap it creates and maintains beside the operator.
+type JMXExporterConfig struct {
+	// HostPort is the JMX remote endpoint the standalone exporter connects to, typically
+	// "localhost:<the product's JMX port>". Required for the sidecar, forbidden for the java
+	// agent, which reads the MBeans of the JVM it runs in.
+	HostPort string `yaml:"hostPort,omitempty"`
+	// StartDelaySeconds delays serving until the product has registered its MBeans.
+	StartDelaySeconds int `yaml:"startDelaySeconds,omitempty"`
+	// LowercaseOutputName and LowercaseOutputLabelNames lowercase the exported names.
+	LowercaseOutputName       bool `yaml:"lowercaseOutputName,omitempty"`
+	LowercaseOutputLabelNames bool `yaml:"lowercaseOutputLabelNames,omitempty"`
+	// IncludeObjectNames and ExcludeObjectNames restrict the MBeans queried at all, which is far
+	// cheaper than matching every attribute of every MBean and discarding most of them.
+	IncludeObjectNames []string `yaml:"includeObjectNames,omitempty"`
+	ExcludeObjectNames []string `yaml:"excludeObjectNames,omitempty"`
+
+	// Rules are the caller's rules — a product's per-cluster additions, or a user's.
+	Rules []JMXExporterRule `yaml:"-"`
+	// DefaultRules are the product's default rule set. They are rendered AFTER Rules: the exporter
+	// stops at the first match, so a rule in Rules overrides the default for the attributes it
+	// matches and leaves the rest to the defaults.
+	DefaultRules []JMXExporterRule `yaml:"-"`
+}
+
+// renderedJMXExporterConfig is the on-disk shape: the two rule lists flattened in precedence order.
+type renderedJMXExporterConfig struct {
+	JMXExporterConfig `yaml:",inline"`
+	Rules             []JMXExporterRule `yaml:"rules,omitempty"`
+}
+
+// captureReference matches a $N capture group reference in a rule's name, value or labels.
+var captureReference = regexp.MustCompile(`\$(\d+)`)
+
+// Validate checks the configuration for the mistakes the exporter only reports at startup — in
+// the container log of a pod that is otherwise running, with no metrics and nothing else to say
+// why.
+func (c *JMXExporterConfig) Validate() error {
+	var errs []error
+	for i, rule := range slices.Concat(c.Rules, c.DefaultRules) {
+		if err := rule.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("jmx-exporter rule %d (%q): %w", i, rule.Pattern, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *JMXExporterRule) validate() error {
+	if r.Pattern == "" {
+		return errors.New("pattern is required")
+	}
+	switch r.Type {
+	case "", JMXMetricTypeGauge, JMXMetricTypeCounter, JMXMetricTypeUntyped:
+	default:
+		return fmt.Errorf("type %q is not one of GAUGE, COUNTER or UNTYPED", r.Type)
+	}
+	if r.Name == "" && len(r.Labels) > 0 {
+		return errors.New("labels require a name: the exporter's default naming takes no labels")
+	}
+
+	// The pattern is a Java regular expression. Where Go can compile it — the common case — the
+	// capture references are checked against its groups; a reference past the last group renders
+	// as the literal "$3" in every exported name. Constructs RE2 lacks (lookaround,
+	// backreferences) are left to the exporter.
+	if compiled, err := regexp.Compile(r.Pattern); err == nil {
+		templates := append([]string{r.Name, r.Value}, slices.Sorted(maps.Values(r.Labels))...)
+		for _, template := range templates {
+			for _, match := range captureReference.FindAllStringSubmatch(template, -1) {
+				if group, _ := strconv.Atoi(match[1]); group > compiled.NumSubexp() {
+					return fmt.Errorf("%q references capture group $%d, but the pattern has %d",
+						template, group, compiled.NumSubexp())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Render validates the configuration and renders it as the exporter's YAML file. The output is
+// stable — labels are emitted in key order — so an unchanged configuration never rewrites the
+// ConfigMap.
+func (c *JMXExporterConfig) Render() (string, error) {
+	if err := c.Validate(); err != nil {
+		return "", err
+	}
+	out, err := yaml.Marshal(renderedJMXExporterConfig{
+		JMXExporterConfig: *c,
+		Rules:             slices.Concat(c.Rules, c.DefaultRules),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render jmx-exporter config: %w", err)
+	}
+	return string(out), nil
+}
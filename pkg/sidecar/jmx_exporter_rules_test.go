@@ -0,0 +1,88 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sidecar_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/zncdatadev/operator-go/pkg/sidecar"
+	"gopkg.in/yaml.v3"
+)
+
+var _ = Describe("JMXExporterConfig", func() {
+	It("should render the caller's rules ahead of the product defaults, so they take precedence", func() {
+		cfg := &sidecar.JMXExporterConfig{
+			HostPort:            "localhost:9999",
+			LowercaseOutputName: true,
+			Rules: []sidecar.JMXExporterRule{{
+				Pattern: `kafka.server<type=(.+), name=(.+)><>Count`,
+				Name:    "kafka_server_$1_$2_total",
+				Type:    sidecar.JMXMetricTypeCounter,
+				Labels:  map[string]string{"type": "$1"},
+			}},
+			DefaultRules: []sidecar.JMXExporterRule{sidecar.JMXExporterCatchAllRule()},
+		}
+
+		content, err := cfg.Render()
+		Expect(err).NotTo(HaveOccurred())
+
+		var rendered map[string]any
+		Expect(yaml.Unmarshal([]byte(content), &rendered)).To(Succeed())
+		Expect(rendered).To(HaveKeyWithValue("hostPort", "localhost:9999"))
+		Expect(rendered).To(HaveKeyWithValue("lowercaseOutputName", true))
+		Expect(rendered).NotTo(HaveKey("defaultRules"))
+		Expect(rendered["rules"]).To(Equal([]any{
+			map[string]any{
+				"pattern": `kafka.server<type=(.+), name=(.+)><>Count`,
+				"name":    "kafka_server_$1_$2_total",
+				"type":    "COUNTER",
+				"labels":  map[string]any{"type": "$1"},
+			},
+			map[string]any{"pattern": ".*"},
+		}))
+
+		again, err := cfg.Render()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again).To(Equal(content))
+	})
+
+	It("should reject rules the exporter would only fail on at startup", func() {
+		cfg := &sidecar.JMXExporterConfig{Rules: []sidecar.JMXExporterRule{
+			{Name: "no_pattern"},
+			{Pattern: "a", Type: "HISTOGRAM"},
+			{Pattern: "a", Labels: map[string]string{"k": "v"}},
+			{Pattern: `java.lang<type=(.+)>`, Name: "jvm_$1_$2"},
+		}}
+
+		err := cfg.Validate()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("pattern is required"))
+		Expect(err.Error()).To(ContainSubstring(`type "HISTOGRAM"`))
+		Expect(err.Error()).To(ContainSubstring("labels require a name"))
+		Expect(err.Error()).To(ContainSubstring("capture group $2, but the pattern has 1"))
+
+		_, err = cfg.Render()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should leave patterns Go cannot compile to the exporter", func() {
+		cfg := &sidecar.JMXExporterConfig{Rules: []sidecar.JMXExporterRule{
+			{Pattern: `(?<!foo)bar<(.+)>`, Name: "bar_$1"},
+		}}
+		Expect(cfg.Validate()).To(Succeed())
+	})
+})
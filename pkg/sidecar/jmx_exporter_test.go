@@ -17,13 +17,16 @@ limitations under the License.
 package sidecar_test
 
 import (
+	"context"
 	"path"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/zncdatadev/operator-go/pkg/sidecar"
+	"github.com/zncdatadev/operator-go/pkg/testutil"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var _ = Describe("JMXExporterSidecarProvider", func() {
@@ -423,6 +426,119 @@ var _ = Describe("JMXExporterSidecarProvider", func() {
 	})
 })
 
+var _ = Describe("JMXExporterSidecarProvider with a generated configuration", func() {
+	const (
+		namespace  = "test-ns"
+		roleGroup  = "kafka-broker-default"
+		testImage  = "test-product-image:latest"
+		configPath = "/kubedoop/mount/config/jmx-exporter/jmx_exporter.yaml"
+	)
+
+	var podSpec *corev1.PodSpec
+
+	BeforeEach(func() {
+		podSpec = &corev1.PodSpec{Containers: []corev1.Container{{Name: "kafka", Image: testImage}}}
+	})
+
+	rules := func(hostPort string) *sidecar.JMXExporterConfig {
+		return &sidecar.JMXExporterConfig{
+			HostPort:     hostPort,
+			DefaultRules: []sidecar.JMXExporterRule{sidecar.JMXExporterCatchAllRule()},
+		}
+	}
+
+	It("should render jmx_exporter.yaml and mount only that key of the role group ConfigMap", func() {
+		provider := sidecar.NewJMXExporterSidecarProvider().WithGeneratedConfig(roleGroup, rules("localhost:9999"))
+
+		data, err := provider.ConfigMapData()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(HaveKeyWithValue(sidecar.JMXExporterGeneratedConfigFileName, ContainSubstring("hostPort: localhost:9999")))
+
+		Expect(provider.Inject(podSpec, &sidecar.SidecarConfig{Enabled: true, Image: testImage})).To(Succeed())
+		Expect(podSpec.InitContainers[0].Command).To(HaveExactElements("java", "-jar", sidecar.JMXExporterJarPath, "5556", configPath))
+		Expect(podSpec.Volumes).To(ContainElement(HaveField("VolumeSource.ConfigMap", And(
+			HaveField("Name", roleGroup),
+			HaveField("Items", ConsistOf(corev1.KeyToPath{Key: "jmx_exporter.yaml", Path: "jmx_exporter.yaml"})),
+		))))
+	})
+
+	It("should render nothing for a product-maintained ConfigMap", func() {
+		data, err := sidecar.NewJMXExporterSidecarProvider().ConfigMapData()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(BeNil())
+	})
+
+	It("should require hostPort for the sidecar and forbid it for the java agent", func() {
+		_, err := sidecar.NewJMXExporterSidecarProvider().WithGeneratedConfig(roleGroup, rules("")).ConfigMapData()
+		Expect(err).To(MatchError(ContainSubstring("hostPort is required")))
+
+		_, err = sidecar.NewJMXExporterSidecarProvider().
+			WithGeneratedConfig(roleGroup, rules("localhost:9999")).
+			AsJavaAgent("kafka", "KAFKA_OPTS").
+			ConfigMapData()
+		Expect(err).To(MatchError(ContainSubstring("hostPort must be empty")))
+	})
+
+	It("should fail validation when the role group ConfigMap lacks the rendered key", func() {
+		provider := sidecar.NewJMXExporterSidecarProvider().WithGeneratedConfig(roleGroup, rules("localhost:9999"))
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: roleGroup, Namespace: namespace}}
+
+		err := provider.Validate(context.Background(), testutil.NewFakeClientWithObjects(cm), namespace)
+		Expect(err).To(MatchError(ContainSubstring(`no "jmx_exporter.yaml" key`)))
+
+		cm.Data = map[string]string{sidecar.JMXExporterGeneratedConfigFileName: "rules: []\n"}
+		Expect(provider.Validate(context.Background(), testutil.NewFakeClientWithObjects(cm), namespace)).To(Succeed())
+	})
+
+	Describe("as a java agent", func() {
+		var provider *sidecar.JMXExporterSidecarProvider
+
+		BeforeEach(func() {
+			provider = sidecar.NewJMXExporterSidecarProvider().
+				WithGeneratedConfig(roleGroup, rules("")).
+				AsJavaAgent("kafka", "KAFKA_OPTS")
+		})
+
+		It("should wire the agent into the main container instead of adding a sidecar", func() {
+			podSpec.Containers[0].Env = []corev1.EnvVar{{Name: "KAFKA_OPTS", Value: "-Xmx1g"}}
+
+			// No image: the agent runs in the product's JVM.
+			Expect(provider.Inject(podSpec, nil)).To(Succeed())
+
+			Expect(podSpec.InitContainers).To(BeEmpty())
+			main := podSpec.Containers[0]
+			Expect(main.Env).To(ConsistOf(corev1.EnvVar{
+				Name:  "KAFKA_OPTS",
+				Value: "-Xmx1g -javaagent:/kubedoop/jmx/jmx_prometheus_javaagent.jar=5556:" + configPath,
+			}))
+			Expect(main.Ports).To(ContainElement(corev1.ContainerPort{Name: "metrics", ContainerPort: 5556, Protocol: corev1.ProtocolTCP}))
+			Expect(main.VolumeMounts).To(ContainElement(HaveField("MountPath", sidecar.JMXExporterConfigMountPath)))
+			Expect(podSpec.Volumes).To(ContainElement(HaveField("Name", sidecar.JMXExporterConfigVolumeName)))
+		})
+
+		It("should be idempotent", func() {
+			Expect(provider.Inject(podSpec, nil)).To(Succeed())
+			Expect(provider.Inject(podSpec, nil)).To(Succeed())
+
+			Expect(podSpec.Containers[0].Env).To(HaveLen(1))
+			Expect(podSpec.Containers[0].Env[0].Value).To(HavePrefix("-javaagent:"))
+			Expect(podSpec.Containers[0].Ports).To(HaveLen(1))
+			Expect(podSpec.Volumes).To(HaveLen(1))
+		})
+
+		It("should fail for a missing container or an option it cannot append to", func() {
+			Expect(sidecar.NewJMXExporterSidecarProvider().AsJavaAgent("broker", "KAFKA_OPTS").Inject(podSpec, nil)).
+				To(MatchError(ContainSubstring(`container "broker" is not in the pod`)))
+
+			podSpec.Containers[0].Env = []corev1.EnvVar{{
+				Name:      "KAFKA_OPTS",
+				ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{Key: "opts"}},
+			}}
+			Expect(provider.Inject(podSpec, nil)).To(MatchError(ContainSubstring("set from a reference")))
+		})
+	})
+})
+
 var _ = Describe("JMXExporter constants", func() {
 	It("should have correct default values", func() {
 		Expect(sidecar.JMXExporterSidecarName).To(Equal("jmx-exporter"))
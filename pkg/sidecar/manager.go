@@ -216,6 +216,35 @@ func (m *SidecarManager) InjectAll(podSpec *corev1.PodSpec) error {
 	return nil
 }
 
+// ConfigMapData merges the role group ConfigMap entries of every enabled provider implementing
+// ConfigDataProvider. Two providers claiming the same file is an error rather than a silent
+// last-wins: one of them would mount the other's configuration.
+func (m *SidecarManager) ConfigMapData() (map[string]string, error) {
+	data := map[string]string{}
+	owners := map[string]string{}
+	for _, name := range m.injectionOrder() {
+		if config, exists := m.configs[name]; exists && config != nil && !config.Enabled {
+			continue
+		}
+		provider, ok := m.providers[name].(ConfigDataProvider)
+		if !ok {
+			continue
+		}
+		entries, err := provider.ConfigMapData()
+		if err != nil {
+			return nil, fmt.Errorf("sidecar %s: %w", name, err)
+		}
+		for _, key := range sortedKeys(entries) {
+			if owner, taken := owners[key]; taken {
+				return nil, fmt.Errorf("sidecars %s and %s both render config map key %q", owner, name, key)
+			}
+			owners[key] = name
+			data[key] = entries[key]
+		}
+	}
+	return data, nil
+}
+
 // Inject injects a specific sidecar into the pod spec.
 func (m *SidecarManager) Inject(podSpec *corev1.PodSpec, name string) error {
 	provider, exists := m.providers[name]
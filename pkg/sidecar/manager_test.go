@@ -539,6 +539,37 @@ var _ = Describe("SidecarManager", func() {
 		})
 	})
 
+	Describe("ConfigMapData", func() {
+		generated := func(name string) *sidecar.JMXExporterSidecarProvider {
+			return sidecar.NewJMXExporterSidecarProvider().WithGeneratedConfig(name, &sidecar.JMXExporterConfig{
+				HostPort: "localhost:9999",
+				Rules:    []sidecar.JMXExporterRule{sidecar.JMXExporterCatchAllRule()},
+			})
+		}
+
+		It("should merge the files of enabled providers and skip disabled ones", func() {
+			manager.Register(generated("rg"), nil)
+			manager.Register(&mockSidecarProvider{name: "plain"}, &sidecar.SidecarConfig{Enabled: true})
+
+			data, err := manager.ConfigMapData()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(HaveKey(sidecar.JMXExporterGeneratedConfigFileName))
+
+			manager.Register(generated("rg"), &sidecar.SidecarConfig{Enabled: false})
+			data, err = manager.ConfigMapData()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(data).To(BeEmpty())
+		})
+
+		It("should refuse two providers rendering the same file", func() {
+			manager.Register(generated("rg"), nil)
+			manager.Register(&renamedProvider{SidecarProvider: generated("rg"), name: "jmx-exporter-2"}, nil)
+
+			_, err := manager.ConfigMapData()
+			Expect(err).To(MatchError(ContainSubstring(`sidecars jmx-exporter and jmx-exporter-2 both render config map key "jmx_exporter.yaml"`)))
+		})
+	})
+
 	Describe("Validate with custom ConfigMap names", func() {
 		It("should validate Vector with custom ConfigMap name", func() {
 			customCM := &corev1.ConfigMap{
@@ -893,3 +924,15 @@ var _ = Describe("Native-sidecar container injection", func() {
 		})
 	})
 })
+
+// renamedProvider registers a provider under another name, keeping its optional interfaces.
+type renamedProvider struct {
+	sidecar.SidecarProvider
+	name string
+}
+
+func (p *renamedProvider) Name() string { return p.name }
+
+func (p *renamedProvider) ConfigMapData() (map[string]string, error) {
+	return p.SidecarProvider.(sidecar.ConfigDataProvider).ConfigMapData()
+}
@@ -30,6 +30,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	policyv1 "k8s.io/api/policy/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
@@ -163,6 +164,13 @@ func (e *TestEnv) Start() error {
 		return fmt.Errorf("failed to add rbacv1 to scheme: %w", err)
 	}
 
+	// storagev1 is needed by GenericReconcilerConfig.VolumeExpansion, which reads the StorageClass
+	// of every claim it grows.
+	if err := storagev1.AddToScheme(e.Scheme); err != nil {
+		_ = e.Env.Stop()
+		return fmt.Errorf("failed to add storagev1 to scheme: %w", err)
+	}
+
 	// Add project-specific types to scheme
 	if err := v1alpha1.AddToScheme(e.Scheme); err != nil {
 		_ = e.Env.Stop()
@@ -23,6 +23,9 @@ import (
 	"strings"
 	"text/template"
 
+	"gopkg.in/yaml.v3"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
 	"github.com/zncdatadev/operator-go/pkg/productlogging"
 )
 
@@ -34,9 +37,19 @@ type VectorConfigData struct {
 	// extraction are rendered as "<LogDir>*/*.<suffix>" and "^<LogDir>(?P<container>...)".
 	LogDir string
 
-	// AggregatorAddress is the address of the Vector aggregator.
+	// AggregatorAddress is the address of the Vector aggregator. Empty renders no aggregator sink,
+	// which is valid only when Sinks ships the logs somewhere else.
 	AggregatorAddress string
 
+	// Sinks are further destinations for the enriched stream, e.g. a LokiSink for a team without
+	// an aggregator. See Sink.
+	Sinks []Sink
+
+	// Filters are the shipping rules of the containers that have any, keyed by the value events
+	// carry as .container (productlogging.LogDirSegment of the producer). They are compiled into
+	// filter, remap and sample transforms between extended_logs and the sinks; see filterStages.
+	Filters map[string]v1alpha1.LogFilterSpec
+
 	// Namespace is the Kubernetes namespace of the workload.
 	Namespace string
 
@@ -57,8 +70,20 @@ type VectorConfigData struct {
 // template helpers), so a hostile or merely malformed input — an aggregator address carrying a
 // quote or a newline — cannot break out of its scalar and emit a config Vector rejects.
 func RenderVectorConfig(data VectorConfigData) (string, error) {
-	if data.AggregatorAddress == "" {
-		return "", fmt.Errorf("AggregatorAddress is required")
+	if data.AggregatorAddress == "" && len(data.Sinks) == 0 {
+		return "", fmt.Errorf("AggregatorAddress or at least one sink is required")
+	}
+	stages, sinkInputs, err := filterStages(data.Filters)
+	if err != nil {
+		return "", err
+	}
+	transformComponents, err := renderComponents(stages)
+	if err != nil {
+		return "", err
+	}
+	sinkComponents, err := renderSinks(data.Sinks, sinkInputs)
+	if err != nil {
+		return "", err
 	}
 	if data.LogDir == "" {
 		return "", fmt.Errorf("LogDir is required")
@@ -86,13 +111,65 @@ func RenderVectorConfig(data VectorConfigData) (string, error) {
 	}
 
 	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
+	if err := tmpl.Execute(&buf, renderData{
+		VectorConfigData:    data,
+		TransformComponents: transformComponents,
+		SinkInputs:          sinkInputs,
+		SinkComponents:      sinkComponents,
+	}); err != nil {
 		return "", err
 	}
 
 	return buf.String(), nil
 }
 
+// renderData is what the template executes on: the caller's data plus the rendered filter stages
+// and sinks, and the stream the sinks read.
+type renderData struct {
+	VectorConfigData
+	TransformComponents string
+	SinkInputs          []string
+	SinkComponents      string
+}
+
+// renderSinks renders each sink as an entry of the `sinks` map, wired to inputs.
+func renderSinks(sinks []Sink, inputs []string) (string, error) {
+	if err := validateSinks(sinks); err != nil {
+		return "", err
+	}
+	components := make([]namedComponent, 0, len(sinks))
+	for _, sink := range sinks {
+		component, err := sink.Component()
+		if err != nil {
+			return "", err
+		}
+		component["inputs"] = inputs
+		components = append(components, namedComponent{sink.Name(), component})
+	}
+	return renderComponents(components)
+}
+
+// renderComponents renders each component as an entry of the map it belongs to (`transforms` or
+// `sinks`), indented to sit under it and preceded by a blank line like the template's own. The
+// components are YAML-encoded rather than templated, so a value carrying quotes or line breaks
+// cannot escape its scalar, and their keys come out sorted, so the file is stable.
+func renderComponents(components []namedComponent) (string, error) {
+	var out strings.Builder
+	for _, component := range components {
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(map[string]any{component.name: component.body}); err != nil {
+			return "", fmt.Errorf("failed to render component %q: %w", component.name, err)
+		}
+		out.WriteString("\n")
+		for line := range strings.Lines(buf.String()) {
+			out.WriteString("  " + line)
+		}
+	}
+	return out.String(), nil
+}
+
 // logFileSuffix resolves a producer framework to the rolling log-file suffix its file appender
 // writes, so the source globs below are derived from the same constant the appender uses
 // (productlogging.LogFileSuffix) instead of repeating the literals. An unknown framework fails
@@ -43,9 +43,13 @@ package vector
 //
 //   - sources glob per-container log files ("<LogDir><container>/<file>"), one source per
 //     producer format: plain stdout/stderr, log4j 1.x XMLLayout events, log4j2 XMLLayout
-//     events, python JSON lines and airlift JSON lines. The suffix of each framework glob comes
+//     events, python JSON lines, airlift JSON lines, java.util.logging XMLFormatter records and
+//     structured JSON lines (slog / zap / pino). The suffix of each framework glob comes
 //     from productlogging.LogFileSuffix (the "logSuffix" template helper), the same constant the
 //     file appenders are named from, so the collector and the producers cannot drift.
+//     files_jul fingerprints by device and inode rather than by the first line: every file
+//     XMLFormatter writes opens with the same header, and its rotated generations match the
+//     glob too, so first-line checksums would take them all for one file.
 //
 //   - the processed_files_* transforms parse each format at the edge and normalize every
 //     event to the stable schema: .timestamp / .logger / .level / .message, collecting
@@ -54,6 +58,13 @@ package vector
 //   - extended_logs_files extracts .container / .file from the source path;
 //     extended_logs stamps the flat .namespace / .cluster / .role / .roleGroup fields.
 //
+//   - VectorConfigData.Filters, when set, add the filter_logs, redact_logs and sampled_logs_*
+//     stages after extended_logs (see filterStages); without them nothing is rendered here.
+//
+//   - the "aggregator" sink ships the last stage — extended_logs when there are no filters — to
+//     the discovered Vector aggregator, and every VectorConfigData.Sink is rendered after it,
+//     reading the same stream. Either may be absent, not both.
+//
 // The v0.12.6 template escaped VRL braces as literal "{{" / "}}" to survive its template
 // parser; those rendered as nested VRL blocks ("{ { ... } }"), which are semantically
 // identical to the single-brace blocks written here (validated against the real vector
@@ -110,7 +121,24 @@ sources:
   files_airlift:
     type: file
     include:
-      - {{.LogDir}}*/*.airlift.json
+      - {{.LogDir}}*/*{{logSuffix "airlift"}}
+
+  files_jul:
+    type: file
+    include:
+      - {{.LogDir}}*/*{{logSuffix "jul"}}
+    fingerprint:
+      strategy: device_and_inode
+    multiline:
+      mode: halt_before
+      start_pattern: ^<record>
+      condition_pattern: ^<record>
+      timeout_ms: 1000
+
+  files_json:
+    type: file
+    include:
+      - {{.LogDir}}*/*{{logSuffix "json"}}
 
 transforms:
   processed_files_stdout:
@@ -408,6 +436,209 @@ transforms:
       .level = parsed_event.level
       .thread = parsed_event.thread
 
+  processed_files_jul:
+    inputs:
+      - files_jul
+    type: remap
+    source: |
+      raw_message = string!(.message)
+
+      .timestamp = now()
+      .logger = ""
+      .level = "INFO"
+      .message = ""
+      .errors = []
+
+      parts = split(raw_message, "<record>", limit: 2)
+      if length(parts) < 2 {
+        abort
+      }
+      record_xml = "<record>" + replace(string!(parts[1]), "</log>", "")
+
+      parsed_record, err = parse_xml(record_xml)
+      if err != null {
+        error = "XML not parsable: " + err
+        .errors = push(.errors, error)
+        log(error, level: "warn")
+        .message = raw_message
+      } else {
+        record = object(parsed_record.record) ?? {}
+
+        millis, err = to_int(record.millis)
+        if err == null {
+          parsed_timestamp, err = from_unix_timestamp(millis, unit: "milliseconds")
+          if err == null {
+            .timestamp = parsed_timestamp
+          } else {
+            .errors = push(.errors, "Timestamp not parsable, using current time instead: " + err)
+          }
+        } else {
+          .errors = push(.errors, "Timestamp not found, using current time instead.")
+        }
+
+        .logger, err = to_string(record.logger)
+        if err != null || is_empty(.logger) {
+          .errors = push(.errors, "Logger not found.")
+        }
+
+        level, err = string(record.level)
+        if err != null {
+          .errors = push(.errors, "Level not found, using \"" + .level + "\" instead.")
+        } else if level == "SEVERE" {
+          .level = "ERROR"
+        } else if level == "WARNING" {
+          .level = "WARN"
+        } else if level == "INFO" {
+          .level = "INFO"
+        } else if level == "CONFIG" || level == "FINE" {
+          .level = "DEBUG"
+        } else if level == "FINER" || level == "FINEST" {
+          .level = "TRACE"
+        } else {
+          .errors = push(.errors, "Level \"" + level + "\" unknown, using \"" + .level + "\" instead.")
+        }
+
+        exception = null
+        thrown = object(record.exception) ?? null
+        if thrown != null {
+          exception = to_string(thrown.message) ?? ""
+          frames = thrown.frame
+          if is_object(frames) {
+            frames = [frames]
+          }
+          for_each(array(frames) ?? []) -> |_index, frame| {
+            class = to_string(frame.class) ?? ""
+            method = to_string(frame.method) ?? ""
+            exception = exception + "\n        at " + class + "." + method
+            line = to_string(frame.line) ?? ""
+            if !is_empty(line) {
+              exception = exception + "(line " + line + ")"
+            }
+          }
+        }
+
+        message, err = to_string(record.message)
+        if err != null || is_empty(message) {
+          message = null
+          .errors = push(.errors, "Message not found.")
+        }
+        .message = join!(compact([message, exception]), "\n")
+      }
+
+  processed_files_json:
+    inputs:
+      - files_json
+    type: remap
+    source: |
+      raw_message = string!(.message)
+
+      .timestamp = now()
+      .logger = ""
+      .level = "INFO"
+      .message = ""
+      .errors = []
+
+      parsed_event, err = parse_json(raw_message)
+      if err != null {
+        error = "JSON not parsable: " + err
+        .errors = push(.errors, error)
+        log(error, level: "warn")
+        .message = raw_message
+      } else if !is_object(parsed_event) {
+        error = "Parsed event is not a JSON object."
+        .errors = push(.errors, error)
+        log(error, level: "warn")
+        .message = raw_message
+      } else {
+        event = object!(parsed_event)
+
+        time = event.time
+        if time == null {
+          time = event.ts
+        }
+        if time == null {
+          time = event.timestamp
+        }
+        if is_string(time) {
+          parsed_timestamp, err = parse_timestamp(string!(time), "%+")
+          if err == null {
+            .timestamp = parsed_timestamp
+          } else {
+            .errors = push(.errors, "Timestamp not parsable, using current time instead: " + err)
+          }
+        } else if is_integer(time) || is_float(time) {
+          millis = int(time) ?? 0
+          if is_float(time) {
+            millis = to_int(float!(time) * 1000)
+          }
+          parsed_timestamp, err = from_unix_timestamp(millis, unit: "milliseconds")
+          if err == null {
+            .timestamp = parsed_timestamp
+          } else {
+            .errors = push(.errors, "Timestamp not parsable, using current time instead: " + err)
+          }
+        } else {
+          .errors = push(.errors, "Timestamp not found, using current time instead.")
+        }
+
+        logger = event.logger
+        if logger == null {
+          logger = event.name
+        }
+        .logger, err = string(logger)
+        if err != null || is_empty(.logger) {
+          .errors = push(.errors, "Logger not found.")
+        }
+
+        level = event.level
+        if is_integer(level) {
+          number = int!(level)
+          if number <= 10 {
+            .level = "TRACE"
+          } else if number <= 20 {
+            .level = "DEBUG"
+          } else if number <= 30 {
+            .level = "INFO"
+          } else if number <= 40 {
+            .level = "WARN"
+          } else if number <= 50 {
+            .level = "ERROR"
+          } else {
+            .level = "FATAL"
+          }
+        } else if is_string(level) {
+          name = upcase(string!(level))
+          if includes(["TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"], name) {
+            .level = name
+          } else if name == "WARNING" {
+            .level = "WARN"
+          } else if includes(["DPANIC", "PANIC", "CRITICAL"], name) {
+            .level = "FATAL"
+          } else {
+            .errors = push(.errors, "Level \"" + name + "\" unknown, using \"" + .level + "\" instead.")
+          }
+        } else {
+          .errors = push(.errors, "Level not found, using \"" + .level + "\" instead.")
+        }
+
+        stacktrace = event.stacktrace
+        if stacktrace == null {
+          stacktrace = event.err.stack
+        }
+        stacktrace = string(stacktrace) ?? null
+
+        message = event.msg
+        if message == null {
+          message = event.message
+        }
+        message, err = string(message)
+        if err != null || is_empty(message) {
+          message = null
+          .errors = push(.errors, "Message not found.")
+        }
+        .message = join!(compact([message, stacktrace]), "\n")
+      }
+
   extended_logs_files:
     inputs:
       - processed_files_*
@@ -425,14 +656,14 @@ transforms:
       .cluster = {{quote .ClusterName}}
       .role = {{quote .RoleName}}
       .roleGroup = {{quote .RoleGroupName}}
-
-sinks:
+{{.TransformComponents}}
+sinks:{{if .AggregatorAddress}}
   aggregator:
-    inputs:
-      - extended_logs
+    inputs:{{range .SinkInputs}}
+      - {{.}}{{end}}
     type: vector
     address: {{quote .AggregatorAddress}}
-
+{{end}}{{.SinkComponents}}
   metrics:
     inputs:
       - internal_metrics
@@ -291,6 +291,8 @@ func TestRenderVectorConfig_ContainsAllSources(t *testing.T) {
 		"files_log4j2",
 		"files_py",
 		"files_airlift",
+		"files_jul",
+		"files_json",
 		"vector",
 	}
 
@@ -308,6 +310,8 @@ func TestRenderVectorConfig_ContainsAllSources(t *testing.T) {
 		"/kubedoop/log/*/*.log4j2.xml",
 		"/kubedoop/log/*/*.py.json",
 		"/kubedoop/log/*/*.airlift.json",
+		"/kubedoop/log/*/*.jul.xml",
+		"/kubedoop/log/*/*.log.json",
 	}
 	for _, glob := range expectedGlobs {
 		if !strings.Contains(result, glob) {
@@ -330,6 +334,8 @@ func TestRenderVectorConfig_ContainsAllTransforms(t *testing.T) {
 		"processed_files_log4j2",
 		"processed_files_py",
 		"processed_files_airlift",
+		"processed_files_jul",
+		"processed_files_json",
 		"extended_logs_files",
 		"extended_logs",
 	}
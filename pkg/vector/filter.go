@@ -0,0 +1,284 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vector
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+)
+
+// DefaultRedactionReplacement is what a LogRedactionSpec without a Replacement writes in place
+// of each match.
+const DefaultRedactionReplacement = "[REDACTED]"
+
+// The IDs of the filter stages. None may match the template's `processed_files_*` and
+// `extended_logs_*` input globs: a stage matching one would be wired back into the stream it
+// reads, which Vector rejects as a cycle.
+const (
+	filterLogsComponent  = "filter_logs"
+	redactLogsComponent  = "redact_logs"
+	sampleRouteComponent = "route_sampled_logs"
+	sampledLogsPrefix    = "sampled_logs_"
+	enrichedLogsStream   = "extended_logs"
+)
+
+// logLevels are the levels of the normalized event schema, least severe first. Every
+// processed_files_* transform maps its format onto these, so they are all a filter can see.
+var logLevels = []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+
+// alwaysShippedLevels are the levels sampling never drops.
+var alwaysShippedLevels = []string{"WARN", "ERROR", "FATAL"}
+
+// filterContainerPattern is the shape of a VectorConfigData.Filters key: the value the pipeline
+// extracts as .container, i.e. productlogging.LogDirSegment. It also names a route and a
+// component, so it is checked rather than trusted.
+var filterContainerPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// namedComponent is one transform of the filter stages, rendered under its ID.
+type namedComponent struct {
+	name string
+	body map[string]any
+}
+
+// ValidateFilters rejects the rules Vector would refuse to load, or would load and then silently
+// misapply: a redaction pattern its regex engine cannot compile fails the whole topology at
+// startup, in the log of a sidecar nobody is watching, and the pod ships nothing.
+// RenderVectorConfig runs it too; it is exported so a caller can report a rule as the user's
+// mistake rather than as a failed render.
+func ValidateFilters(filters map[string]v1alpha1.LogFilterSpec) error {
+	var errs []error
+	for _, container := range slices.Sorted(maps.Keys(filters)) {
+		if err := validateFilter(container, filters[container]); err != nil {
+			errs = append(errs, fmt.Errorf("log filter for container %q: %w", container, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validateFilter(container string, filter v1alpha1.LogFilterSpec) error {
+	var errs []error
+	if !filterContainerPattern.MatchString(container) {
+		errs = append(errs, errors.New("the container must be a lowercase RFC 1123 label"))
+	}
+	if filter.MinLevel != "" && !slices.Contains(logLevels, filter.MinLevel) {
+		errs = append(errs, fmt.Errorf("minLevel %q is not one of %s", filter.MinLevel, strings.Join(logLevels, ", ")))
+	}
+	for _, logger := range slices.Concat(filter.IncludeLoggers, filter.ExcludeLoggers) {
+		if logger == "" {
+			errs = append(errs, errors.New("a logger name must not be empty"))
+		}
+	}
+	for i, redaction := range filter.Redactions {
+		if err := validateRedaction(redaction); err != nil {
+			errs = append(errs, fmt.Errorf("redaction %d: %w", i, err))
+		}
+	}
+	if filter.SampleRate < 0 {
+		errs = append(errs, fmt.Errorf("sampleRate %d must be at least 1", filter.SampleRate))
+	}
+	return errors.Join(errs...)
+}
+
+// replacementReference matches a $N capture group reference in a redaction's replacement.
+var replacementReference = regexp.MustCompile(`\$(\d+)`)
+
+func validateRedaction(redaction v1alpha1.LogRedactionSpec) error {
+	if redaction.Pattern == "" {
+		return errors.New("pattern is required")
+	}
+	// Vector's regex engine and Go's share the RE2 syntax, so what Go cannot compile — lookaround,
+	// backreferences, an unbalanced group — Vector cannot either.
+	compiled, err := regexp.Compile(redaction.Pattern)
+	if err != nil {
+		return fmt.Errorf("pattern %q: %w", redaction.Pattern, err)
+	}
+	// A reference past the last group expands to nothing, so the secret is removed but so is
+	// whatever the replacement meant to keep around it.
+	for _, match := range replacementReference.FindAllStringSubmatch(redaction.Replacement, -1) {
+		if group, _ := strconv.Atoi(match[1]); group > compiled.NumSubexp() {
+			return fmt.Errorf("replacement %q references capture group $%d, but the pattern has %d",
+				redaction.Replacement, group, compiled.NumSubexp())
+		}
+	}
+	return nil
+}
+
+// filterStages compiles filters into the transforms between extended_logs and the sinks, in
+// pipeline order, and returns them with the inputs the sinks read in their place. Without rules
+// there are no stages and the sinks read extended_logs, exactly as before filters existed.
+//
+// Rules are keyed by container, but each stage is ONE transform for all containers, branching on
+// .container inside: a chain per container would need a route in front of everything and a
+// sink reading every branch, and the pipeline would grow with the container count even where
+// only one container has rules.
+func filterStages(filters map[string]v1alpha1.LogFilterSpec) ([]namedComponent, []string, error) {
+	if err := ValidateFilters(filters); err != nil {
+		return nil, nil, err
+	}
+	containers := slices.Sorted(maps.Keys(filters))
+
+	var stages []namedComponent
+	input := enrichedLogsStream
+	if condition := filterCondition(containers, filters); condition != "" {
+		stages = append(stages, namedComponent{filterLogsComponent, map[string]any{
+			"type":      "filter",
+			"inputs":    []string{input},
+			"condition": condition,
+		}})
+		input = filterLogsComponent
+	}
+	if source := redactSource(containers, filters); source != "" {
+		stages = append(stages, namedComponent{redactLogsComponent, map[string]any{
+			"type":   "remap",
+			"inputs": []string{input},
+			"source": source,
+		}})
+		input = redactLogsComponent
+	}
+
+	// Sampling is per container and Vector's sample transform has one rate, so the stream is
+	// routed by container first; whatever no route claims passes through unsampled.
+	routes := map[string]any{}
+	var sampled []namedComponent
+	for _, container := range containers {
+		rate := filters[container].SampleRate
+		if rate <= 1 {
+			continue
+		}
+		routes[container] = ".container == " + quoteDoubleQuoted(container)
+		sampled = append(sampled, namedComponent{sampledLogsPrefix + container, map[string]any{
+			"type":    "sample",
+			"inputs":  []string{sampleRouteComponent + "." + container},
+			"rate":    rate,
+			"exclude": "includes(" + vrlStringArray(alwaysShippedLevels) + ", .level)",
+		}})
+	}
+	if len(sampled) == 0 {
+		return stages, []string{input}, nil
+	}
+	stages = append(stages, namedComponent{sampleRouteComponent, map[string]any{
+		"type":   "route",
+		"inputs": []string{input},
+		"route":  routes,
+	}})
+	sinkInputs := []string{sampleRouteComponent + "._unmatched"}
+	for _, sample := range sampled {
+		stages = append(stages, sample)
+		sinkInputs = append(sinkInputs, sample.name)
+	}
+	return stages, sinkInputs, nil
+}
+
+// filterCondition renders the VRL condition of the filter stage: per container, the conjunction
+// of its level and logger rules; every other container passes. Empty when no container selects.
+func filterCondition(containers []string, filters map[string]v1alpha1.LogFilterSpec) string {
+	var branches []string
+	for _, container := range containers {
+		filter := filters[container]
+		var terms []string
+		if filter.MinLevel != "" && filter.MinLevel != logLevels[0] {
+			shipped := logLevels[slices.Index(logLevels, filter.MinLevel):]
+			terms = append(terms, "includes("+vrlStringArray(shipped)+", .level)")
+		}
+		if len(filter.IncludeLoggers) > 0 {
+			terms = append(terms, loggerMatch(filter.IncludeLoggers))
+		}
+		if len(filter.ExcludeLoggers) > 0 {
+			terms = append(terms, "!"+loggerMatch(filter.ExcludeLoggers))
+		}
+		if len(terms) > 0 {
+			branches = append(branches, "if .container == "+quoteDoubleQuoted(container)+" {\n  "+
+				strings.Join(terms, " && ")+"\n}")
+		}
+	}
+	if len(branches) == 0 {
+		return ""
+	}
+	// The chain ends in an else: a VRL condition must be a boolean, and an if without one is null
+	// when it does not match, which Vector refuses to load. The condition is a pure expression —
+	// no local variables — as Vector evaluates conditions read-only.
+	return strings.Join(branches, " else ") + " else {\n  true\n}\n"
+}
+
+// loggerMatch renders a VRL expression matching the loggers and their descendants. The "."
+// boundary keeps "org.apache.kafka" from matching "org.apache.kafkaesque".
+func loggerMatch(loggers []string) string {
+	matches := make([]string, 0, len(loggers))
+	for _, logger := range loggers {
+		matches = append(matches, ".logger == "+quoteDoubleQuoted(logger)+
+			" || starts_with(string(.logger) ?? \"\", "+quoteDoubleQuoted(logger+".")+")")
+	}
+	return "(" + strings.Join(matches, " || ") + ")"
+}
+
+// redactSource renders the VRL program of the redaction stage. Empty when no container redacts.
+func redactSource(containers []string, filters map[string]v1alpha1.LogFilterSpec) string {
+	var out strings.Builder
+	for _, container := range containers {
+		redactions := filters[container].Redactions
+		if len(redactions) == 0 {
+			continue
+		}
+		out.WriteString("if .container == " + quoteDoubleQuoted(container) + " {\n")
+		// Coerced once and checked: the message is a string after every processed_files_* stage,
+		// but an infallible program cannot assume that, and an event it cannot redact is shipped
+		// as it is rather than dropped.
+		out.WriteString("  message, err = string(.message)\n  if err == null {\n")
+		for _, redaction := range redactions {
+			replacement := redaction.Replacement
+			if replacement == "" {
+				replacement = DefaultRedactionReplacement
+			}
+			out.WriteString("    message = replace(message, " + vrlRegex(redaction.Pattern) + ", " +
+				quoteDoubleQuoted(escapeInterpolation(replacement)) + ")\n")
+		}
+		out.WriteString("    .message = message\n  }\n}\n")
+	}
+	return out.String()
+}
+
+// vrlRegex renders pattern as a VRL regex literal. The literal is delimited by single quotes, so
+// a quote in the pattern is written as the \x27 escape, which matches the same character; a
+// line break becomes \n so the literal stays on its line. validateRedaction has compiled the
+// pattern, so no quote is already escaped — RE2 rejects \' — and the rewrite cannot split one.
+// A '$' is escaped for Vector's interpolation, as escapeInterpolation does.
+func vrlRegex(pattern string) string {
+	return "r'" + strings.NewReplacer("'", `\x27`, "\n", `\n`, "\r", `\r`, "$", "$$").Replace(pattern) + "'"
+}
+
+// escapeInterpolation escapes s for the environment interpolation Vector runs over the whole
+// config file before parsing it: "$1" or "${name}" would otherwise be replaced by a variable —
+// empty, for a capture reference — and "$$" is its escape for a literal '$'.
+func escapeInterpolation(s string) string {
+	return strings.ReplaceAll(s, "$", "$$")
+}
+
+// vrlStringArray renders values as a VRL array of string literals.
+func vrlStringArray(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, quoteDoubleQuoted(value))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vector
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+)
+
+// renderedFilterConfig is the part of a rendered vector.yaml the filter stages touch.
+type renderedFilterConfig struct {
+	Transforms map[string]map[string]any `yaml:"transforms"`
+	Sinks      map[string]map[string]any `yaml:"sinks"`
+}
+
+func renderFilters(t *testing.T, filters map[string]v1alpha1.LogFilterSpec) renderedFilterConfig {
+	t.Helper()
+	data := defaultConfigData()
+	data.Filters = filters
+	data.Sinks = []Sink{&ConsoleSink{ID: "stdout"}}
+	out, err := RenderVectorConfig(data)
+	if err != nil {
+		t.Fatalf("RenderVectorConfig() error = %v", err)
+	}
+	var parsed renderedFilterConfig
+	if err := yaml.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("rendered config is not valid YAML: %v\n%s", err, out)
+	}
+	return parsed
+}
+
+func TestRenderVectorConfig_FilterStagesChainBetweenEnrichmentAndSinks(t *testing.T) {
+	cfg := renderFilters(t, map[string]v1alpha1.LogFilterSpec{
+		"kafka": {
+			MinLevel:       "INFO",
+			IncludeLoggers: []string{"org.apache"},
+			ExcludeLoggers: []string{"org.apache.noisy"},
+			Redactions:     []v1alpha1.LogRedactionSpec{{Pattern: `password='[^']*'`}},
+			SampleRate:     10,
+		},
+		"zookeeper": {SampleRate: 1},
+	})
+
+	filter := cfg.Transforms["filter_logs"]
+	if filter["type"] != "filter" || filter["inputs"].([]any)[0] != "extended_logs" {
+		t.Fatalf("filter_logs = %v, want a filter reading extended_logs", filter)
+	}
+	condition := filter["condition"].(string)
+	for _, want := range []string{
+		`if .container == "kafka" {`,
+		`includes(["INFO", "WARN", "ERROR", "FATAL"], .level)`,
+		`(.logger == "org.apache" || starts_with(string(.logger) ?? "", "org.apache."))`,
+		`!(.logger == "org.apache.noisy" || starts_with(string(.logger) ?? "", "org.apache.noisy."))`,
+		"} else {\n  true\n}",
+	} {
+		if !strings.Contains(condition, want) {
+			t.Errorf("condition misses %q:\n%s", want, condition)
+		}
+	}
+
+	redact := cfg.Transforms["redact_logs"]
+	if redact["inputs"].([]any)[0] != "filter_logs" {
+		t.Errorf("redact_logs reads %v, want filter_logs", redact["inputs"])
+	}
+	// The single quotes of the pattern would end the VRL regex literal.
+	if want := `replace(message, r'password=\x27[^\x27]*\x27', "[REDACTED]")`; !strings.Contains(redact["source"].(string), want) {
+		t.Errorf("redact_logs source misses %q:\n%s", want, redact["source"])
+	}
+
+	route := cfg.Transforms["route_sampled_logs"]
+	if route["inputs"].([]any)[0] != "redact_logs" {
+		t.Errorf("route_sampled_logs reads %v, want redact_logs", route["inputs"])
+	}
+	if routes := route["route"].(map[string]any); len(routes) != 1 || routes["kafka"] != `.container == "kafka"` {
+		t.Errorf("routes = %v, want kafka only: a rate of 1 samples nothing", routes)
+	}
+	sample := cfg.Transforms["sampled_logs_kafka"]
+	if sample["rate"] != 10 || sample["exclude"] != `includes(["WARN", "ERROR", "FATAL"], .level)` {
+		t.Errorf("sampled_logs_kafka = %v, want rate 10 sparing WARN and above", sample)
+	}
+
+	for _, name := range []string{"aggregator", "stdout"} {
+		inputs := cfg.Sinks[name]["inputs"].([]any)
+		if len(inputs) != 2 || inputs[0] != "route_sampled_logs._unmatched" || inputs[1] != "sampled_logs_kafka" {
+			t.Errorf("sink %s reads %v, want the unsampled and the sampled stream", name, inputs)
+		}
+	}
+}
+
+func TestRenderVectorConfig_FilterStagesOnlyForRulesThatAreSet(t *testing.T) {
+	cfg := renderFilters(t, map[string]v1alpha1.LogFilterSpec{
+		"kafka": {Redactions: []v1alpha1.LogRedactionSpec{{Pattern: `token=(\w+)`, Replacement: "token=***"}}},
+	})
+	if _, ok := cfg.Transforms["filter_logs"]; ok {
+		t.Error("filter_logs rendered without level or logger rules")
+	}
+	if _, ok := cfg.Transforms["route_sampled_logs"]; ok {
+		t.Error("route_sampled_logs rendered without a sample rate")
+	}
+	if inputs := cfg.Transforms["redact_logs"]["inputs"].([]any); inputs[0] != "extended_logs" {
+		t.Errorf("redact_logs reads %v, want extended_logs", inputs)
+	}
+	if inputs := cfg.Sinks["aggregator"]["inputs"].([]any); len(inputs) != 1 || inputs[0] != "redact_logs" {
+		t.Errorf("aggregator reads %v, want redact_logs", inputs)
+	}
+
+	// An empty filter is no filter: the pipeline is the one rendered without rules.
+	cfg = renderFilters(t, map[string]v1alpha1.LogFilterSpec{"kafka": {}})
+	if inputs := cfg.Sinks["aggregator"]["inputs"].([]any); len(inputs) != 1 || inputs[0] != "extended_logs" {
+		t.Errorf("aggregator reads %v, want extended_logs", inputs)
+	}
+}
+
+// Vector interpolates environment variables across the whole config file before VRL sees it, so
+// a capture reference or an end anchor must reach it as "$$".
+func TestRenderVectorConfig_RedactionEscapesInterpolation(t *testing.T) {
+	cfg := renderFilters(t, map[string]v1alpha1.LogFilterSpec{
+		"kafka": {Redactions: []v1alpha1.LogRedactionSpec{{Pattern: `token=(\w+)$`, Replacement: "token=$1"}}},
+	})
+	if want := `replace(message, r'token=(\w+)$$', "token=$$1")`; !strings.Contains(cfg.Transforms["redact_logs"]["source"].(string), want) {
+		t.Errorf("redact_logs source misses %q:\n%s", want, cfg.Transforms["redact_logs"]["source"])
+	}
+}
+
+func TestValidateFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters map[string]v1alpha1.LogFilterSpec
+		wantErr string
+	}{
+		{
+			name:    "lookaround Vector cannot compile",
+			filters: map[string]v1alpha1.LogFilterSpec{"kafka": {Redactions: []v1alpha1.LogRedactionSpec{{Pattern: `(?<=key=)\w+`}}}},
+			wantErr: "redaction 0: pattern",
+		},
+		{
+			name:    "replacement referencing a missing group",
+			filters: map[string]v1alpha1.LogFilterSpec{"kafka": {Redactions: []v1alpha1.LogRedactionSpec{{Pattern: `key=\w+`, Replacement: "key=$1"}}}},
+			wantErr: "references capture group $1, but the pattern has 0",
+		},
+		{
+			name:    "unknown level",
+			filters: map[string]v1alpha1.LogFilterSpec{"kafka": {MinLevel: "WARNING"}},
+			wantErr: `minLevel "WARNING"`,
+		},
+		{
+			name:    "empty logger",
+			filters: map[string]v1alpha1.LogFilterSpec{"kafka": {ExcludeLoggers: []string{""}}},
+			wantErr: "logger name must not be empty",
+		},
+		{
+			name:    "negative sample rate",
+			filters: map[string]v1alpha1.LogFilterSpec{"kafka": {SampleRate: -1}},
+			wantErr: "sampleRate -1",
+		},
+		{
+			name:    "container that is not a log directory",
+			filters: map[string]v1alpha1.LogFilterSpec{"Kafka": {MinLevel: "INFO"}},
+			wantErr: "lowercase RFC 1123 label",
+		},
+		{
+			name:    "valid",
+			filters: map[string]v1alpha1.LogFilterSpec{"kafka": {MinLevel: "WARN", SampleRate: 5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateFilters(tt.filters)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateFilters() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateFilters() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -72,6 +72,16 @@ func WithLogVolumeSize(quantity resource.Quantity) ProviderOption {
 	}
 }
 
+// WithSinks passes the sinks rendered into vector.yaml (VectorConfigData.Sinks), so the agent
+// container gets what they need to reach their destinations: credentials as environment
+// variables or mounted files, and any script to run before vector starts. The config and the
+// container are built on different code paths, so the same list must reach both.
+func WithSinks(sinks []Sink) ProviderOption {
+	return func(p *VectorSidecarProvider) {
+		p.sinks = append([]Sink(nil), sinks...)
+	}
+}
+
 // Compile-time interface assertion.
 var _ sidecar.SidecarProvider = (*VectorSidecarProvider)(nil)
 
@@ -84,6 +94,7 @@ type VectorSidecarProvider struct {
 	dataVolumeSize *resource.Quantity
 	logVolumeSize  *resource.Quantity
 	producers      []productlogging.ContainerLogging
+	sinks          []Sink
 }
 
 // NewVectorSidecarProvider creates a new VectorSidecarProvider with the given product image and options.
@@ -165,7 +176,7 @@ func (p *VectorSidecarProvider) Inject(podSpec *corev1.PodSpec, config *sidecar.
 		Name:            p.name,
 		Image:           image,
 		ImagePullPolicy: pullPolicy,
-		Command:         vectorCommand(p.producers),
+		Command:         vectorCommand(p.producers, p.sinkScripts()),
 		VolumeMounts: []corev1.VolumeMount{
 			{
 				Name:      VectorConfigVolumeName,
@@ -229,6 +240,16 @@ func (p *VectorSidecarProvider) Inject(podSpec *corev1.PodSpec, config *sidecar.
 		SecurityContext: defaultSecurityContext(),
 	}
 
+	// What the sinks need to reach their destinations. Their volumes are added with the
+	// provider's own below; ordering of env and mounts follows the sink order, which is stable.
+	var sinkVolumes []corev1.Volume
+	for _, sink := range p.sinks {
+		req := sink.Requirements()
+		container.Env = append(container.Env, req.Env...)
+		sidecar.AddVolumeMounts(container, req.VolumeMounts)
+		sinkVolumes = append(sinkVolumes, req.Volumes...)
+	}
+
 	// Apply resources if provided
 	if config.Resources != nil {
 		container.Resources = *config.Resources
@@ -311,6 +332,7 @@ func (p *VectorSidecarProvider) Inject(podSpec *corev1.PodSpec, config *sidecar.
 	}
 
 	sidecar.AddVolumes(podSpec, volumes)
+	sidecar.AddVolumes(podSpec, sinkVolumes)
 
 	// RW-mount the shared log volume on each producer container present in the PodSpec.
 	// Producers are expected to exist by now — the main
@@ -360,24 +382,42 @@ func (p *VectorSidecarProvider) Inject(podSpec *corev1.PodSpec, config *sidecar.
 // from — so the pre-created path is the path the producer writes to. log4j 1.x's
 // RollingFileAppender and Python's FileHandler
 // do not create parent directories, so without this step their file appenders would fail to
-// open on startup. With no producers declared the command execs vector directly.
-func vectorCommand(producers []productlogging.ContainerLogging) []string {
-	if len(producers) == 0 {
+// open on startup. The sinks' scripts (SinkRequirements.Script, e.g. exporting S3 credentials)
+// run after it, in the same shell, so what they export reaches vector. With no producers and no
+// sink scripts the command execs vector directly.
+func vectorCommand(producers []productlogging.ContainerLogging, sinkScripts []string) []string {
+	if len(producers) == 0 && len(sinkScripts) == 0 {
 		return []string{
 			VectorSidecarName,
 			"--config",
 			VectorConfigMountPath + "/" + VectorConfigFileName,
 		}
 	}
-	dirs := make([]string, 0, len(producers))
-	for _, p := range producers {
-		dirs = append(dirs, productlogging.LogDirFor(p))
+	var steps []string
+	if len(producers) > 0 {
+		dirs := make([]string, 0, len(producers))
+		for _, p := range producers {
+			dirs = append(dirs, productlogging.LogDirFor(p))
+		}
+		steps = append(steps, "mkdir -p "+strings.Join(dirs, " "))
 	}
-	script := "mkdir -p " + strings.Join(dirs, " ") +
+	steps = append(steps, sinkScripts...)
+	script := strings.Join(steps, " && ") +
 		" && exec " + VectorSidecarName + " --config " + VectorConfigMountPath + "/" + VectorConfigFileName
 	return []string{"/bin/sh", "-c", script}
 }
 
+// sinkScripts returns the sinks' start-up scripts, in sink order.
+func (p *VectorSidecarProvider) sinkScripts() []string {
+	var scripts []string
+	for _, sink := range p.sinks {
+		if script := sink.Requirements().Script; script != "" {
+			scripts = append(scripts, script)
+		}
+	}
+	return scripts
+}
+
 // defaultSecurityContext returns a hardened security context for the Vector container: the
 // framework-wide sidecar baseline plus a read-only root filesystem.
 //
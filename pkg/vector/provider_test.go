@@ -18,10 +18,14 @@ package vector
 
 import (
 	"context"
+	"net/url"
+	"slices"
 	"strings"
 	"testing"
 
+	commonsv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
 	"github.com/zncdatadev/operator-go/pkg/productlogging"
+	"github.com/zncdatadev/operator-go/pkg/s3"
 	"github.com/zncdatadev/operator-go/pkg/sidecar"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -411,6 +415,38 @@ func TestProvider_Inject_CommandPreCreatesProducerLogDirs(t *testing.T) {
 	}
 }
 
+// TestProvider_Inject_SinkRequirements asserts the agent gets what its sinks need to reach their
+// destinations: the password as a Secret-backed variable, the S3 credentials mounted and exported
+// ahead of vector in the same shell.
+func TestProvider_Inject_SinkRequirements(t *testing.T) {
+	loki := &LokiSink{Endpoint: "http://loki:3100", Auth: &BasicAuth{User: "vector", Password: corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "loki"}, Key: "password",
+	}}}
+	s3Sink := &S3Sink{Bucket: "logs", Connection: &s3.ConnectionInfo{
+		Endpoint:    url.URL{Scheme: "http", Host: "minio:9000"},
+		Credentials: &commonsv1alpha1.Credentials{SecretClass: "s3-credentials"},
+	}}
+	p := NewVectorSidecarProvider("test-product:latest", WithSinks([]Sink{loki, s3Sink}))
+	podSpec := &corev1.PodSpec{Containers: []corev1.Container{{Name: "main", Image: "main-image"}}}
+	if err := p.Inject(podSpec, &sidecar.SidecarConfig{Enabled: true}); err != nil {
+		t.Fatalf("Inject() error = %v", err)
+	}
+
+	container := vectorInitContainer(podSpec)
+	if len(container.Env) != 1 || container.Env[0].Name != "VECTOR_SINK_LOKI_PASSWORD" {
+		t.Errorf("Env = %+v, want the loki password variable", container.Env)
+	}
+	mounted := slices.ContainsFunc(container.VolumeMounts, func(m corev1.VolumeMount) bool { return m.Name == "vector-s3-credentials" })
+	added := slices.ContainsFunc(podSpec.Volumes, func(v corev1.Volume) bool { return v.Name == "vector-s3-credentials" })
+	if !mounted || !added {
+		t.Error("the s3 credentials volume must be added and mounted on the agent")
+	}
+	script := container.Command[len(container.Command)-1]
+	if !strings.Contains(script, "export AWS_ACCESS_KEY_ID=") || !strings.HasSuffix(script, "&& exec vector --config /etc/vector/vector.yaml") {
+		t.Errorf("script must export the credentials before exec'ing vector, got %q", script)
+	}
+}
+
 func TestProvider_Inject_VolumeMounts(t *testing.T) {
 	p := NewVectorSidecarProvider("test-product:latest")
 	podSpec := &corev1.PodSpec{
@@ -0,0 +1,467 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/zncdatadev/operator-go/pkg/s3"
+)
+
+// Sink is a destination the agent ships the enriched log stream to, next to (or instead of) the
+// Vector aggregator found through DiscoverAggregatorAddress. Every sink reads extended_logs, so
+// the events it receives carry the same .namespace / .cluster / .role / .roleGroup / .container
+// fields the aggregator gets.
+//
+// The typed implementations below cover the common destinations. A product can implement Sink
+// itself for anything else Vector supports; Component is all the renderer needs.
+type Sink interface {
+	// Name is the sink's component ID in vector.yaml. It must be unique within the pipeline, made
+	// of letters, digits, '_' and '-', and must not be one of the IDs the pipeline itself uses
+	// ("aggregator", "metrics").
+	Name() string
+
+	// Component returns the sink's vector.yaml component without `inputs`, which the renderer
+	// wires to extended_logs. It is encoded as YAML, so values need no escaping.
+	Component() (map[string]any, error)
+
+	// Requirements returns what the sink needs on the agent's container beyond its component:
+	// secrets, start-up script and network egress. The zero value needs nothing.
+	Requirements() SinkRequirements
+}
+
+// SinkRequirements is what a sink needs from the pod to actually reach its destination. The
+// Vector provider applies the container parts (WithSinks); the reconciler opens EgressPorts on
+// the role group's NetworkPolicy.
+type SinkRequirements struct {
+	// Env is set on the agent container. Secrets reach the sink this way — a component refers to
+	// them as "${NAME}", which Vector interpolates at startup — so no credential is written into
+	// vector.yaml, which lives in a ConfigMap anyone with read access to the namespace can see.
+	Env []corev1.EnvVar
+
+	// Volumes and VolumeMounts deliver file-based credentials to the agent container.
+	Volumes      []corev1.Volume
+	VolumeMounts []corev1.VolumeMount
+
+	// Script is a shell fragment the agent container runs before exec'ing vector, e.g. exporting
+	// mounted credential files as environment variables.
+	Script string
+
+	// EgressPorts are the TCP ports the sink connects to.
+	EgressPorts []int32
+}
+
+// BasicAuth is HTTP basic authentication for a sink. The password is read from a Secret into the
+// agent's environment; see SinkRequirements.Env.
+type BasicAuth struct {
+	User     string
+	Password corev1.SecretKeySelector
+}
+
+// Compile-time interface assertions.
+var (
+	_ Sink = (*LokiSink)(nil)
+	_ Sink = (*ElasticsearchSink)(nil)
+	_ Sink = (*KafkaSink)(nil)
+	_ Sink = (*S3Sink)(nil)
+	_ Sink = (*FileSink)(nil)
+	_ Sink = (*ConsoleSink)(nil)
+)
+
+// Component IDs the pipeline itself uses.
+const (
+	aggregatorSinkName = "aggregator"
+	metricsSinkName    = "metrics"
+)
+
+var sinkNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// validateSinks checks the sink set as a whole: IDs are interpolated as YAML keys and into
+// environment variable names, and two sinks with one ID would silently become one. Two IDs that
+// differ only in case or in '-' versus '_' are distinct components but share a password variable,
+// so one sink would authenticate with the other's password; they are refused as well.
+func validateSinks(sinks []Sink) error {
+	seen := map[string]bool{aggregatorSinkName: true, metricsSinkName: true}
+	envOwners := map[string]string{}
+	var errs []error
+	for _, sink := range sinks {
+		name := sink.Name()
+		switch {
+		case !sinkNamePattern.MatchString(name):
+			errs = append(errs, fmt.Errorf("sink %q: the ID must consist of letters, digits, '_' and '-'", name))
+		case seen[name]:
+			errs = append(errs, fmt.Errorf("sink %q: the ID is already used in the pipeline", name))
+		case envOwners[passwordEnv(name)] != "":
+			errs = append(errs, fmt.Errorf("sink %q: the ID maps to the same environment variable %s as sink %q",
+				name, passwordEnv(name), envOwners[passwordEnv(name)]))
+		default:
+			envOwners[passwordEnv(name)] = name
+		}
+		seen[name] = true
+	}
+	return errors.Join(errs...)
+}
+
+// passwordEnv is the environment variable a sink's basic-auth password is delivered in.
+func passwordEnv(sinkName string) string {
+	return "VECTOR_SINK_" + strings.ToUpper(strings.ReplaceAll(sinkName, "-", "_")) + "_PASSWORD"
+}
+
+// basicAuth renders auth into component and returns the env var carrying its password.
+func basicAuth(sinkName string, auth *BasicAuth, component map[string]any) []corev1.EnvVar {
+	if auth == nil {
+		return nil
+	}
+	env := passwordEnv(sinkName)
+	component["auth"] = map[string]any{
+		"strategy": "basic",
+		"user":     auth.User,
+		"password": "${" + env + "}",
+	}
+	password := auth.Password
+	return []corev1.EnvVar{{Name: env, ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &password}}}
+}
+
+// urlPort returns the TCP port an http(s) endpoint connects to.
+func urlPort(endpoint string) (int32, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return 0, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return 0, fmt.Errorf("endpoint %q must be an http or https URL", endpoint)
+	}
+	if p := u.Port(); p != "" {
+		port, err := strconv.ParseInt(p, 10, 32)
+		return int32(port), err
+	}
+	if u.Scheme == "https" {
+		return 443, nil
+	}
+	return 80, nil
+}
+
+// LokiSink ships logs to Grafana Loki.
+type LokiSink struct {
+	// ID is the component ID; empty means "loki".
+	ID string
+	// Endpoint is Loki's base URL, e.g. "http://loki-gateway.monitoring.svc:80". Required.
+	Endpoint string
+	// TenantID sets X-Scope-OrgID for a multi-tenant Loki.
+	TenantID string
+	// Labels are the stream labels, as Vector templates over the event. Empty means
+	// DefaultLokiLabels. Keep them low-cardinality: Loki indexes every distinct label set.
+	Labels map[string]string
+	// Auth is optional basic authentication.
+	Auth *BasicAuth
+}
+
+// DefaultLokiLabels labels each stream with the enrichment fields, which keeps one stream per
+// container of a role group replica.
+func DefaultLokiLabels() map[string]string {
+	return map[string]string{
+		"namespace":  "{{ namespace }}",
+		"cluster":    "{{ cluster }}",
+		"role":       "{{ role }}",
+		"role_group": "{{ roleGroup }}",
+		"container":  "{{ container }}",
+		"pod":        "{{ pod }}",
+	}
+}
+
+func (s *LokiSink) Name() string { return defaultString(s.ID, "loki") }
+
+func (s *LokiSink) Component() (map[string]any, error) {
+	if _, err := urlPort(s.Endpoint); err != nil {
+		return nil, fmt.Errorf("loki sink: %w", err)
+	}
+	labels := s.Labels
+	if len(labels) == 0 {
+		labels = DefaultLokiLabels()
+	}
+	component := map[string]any{
+		"type":     "loki",
+		"endpoint": s.Endpoint,
+		"encoding": map[string]any{"codec": "json"},
+		"labels":   labels,
+	}
+	if s.TenantID != "" {
+		component["tenant_id"] = s.TenantID
+	}
+	basicAuth(s.Name(), s.Auth, component)
+	return component, nil
+}
+
+func (s *LokiSink) Requirements() SinkRequirements {
+	port, _ := urlPort(s.Endpoint)
+	return SinkRequirements{
+		Env:         basicAuth(s.Name(), s.Auth, map[string]any{}),
+		EgressPorts: nonZero(port),
+	}
+}
+
+// ElasticsearchSink ships logs to Elasticsearch or OpenSearch through the bulk API.
+type ElasticsearchSink struct {
+	// ID is the component ID; empty means "elasticsearch".
+	ID string
+	// Endpoints are the cluster's http(s) URLs. Required.
+	Endpoints []string
+	// Index is the index name, a Vector template; empty means "kubedoop-logs-%Y.%m.%d".
+	Index string
+	// APIVersion is "auto", "v6", "v7" or "v8"; empty means "auto". Set "v7" for OpenSearch,
+	// which answers the version probe "auto" relies on with its own version number.
+	APIVersion string
+	// Auth is optional basic authentication.
+	Auth *BasicAuth
+}
+
+func (s *ElasticsearchSink) Name() string { return defaultString(s.ID, "elasticsearch") }
+
+func (s *ElasticsearchSink) Component() (map[string]any, error) {
+	if len(s.Endpoints) == 0 {
+		return nil, errors.New("elasticsearch sink: at least one endpoint is required")
+	}
+	for _, endpoint := range s.Endpoints {
+		if _, err := urlPort(endpoint); err != nil {
+			return nil, fmt.Errorf("elasticsearch sink: %w", err)
+		}
+	}
+	switch s.APIVersion {
+	case "", "auto", "v6", "v7", "v8":
+	default:
+		return nil, fmt.Errorf("elasticsearch sink: apiVersion %q is not one of auto, v6, v7 or v8", s.APIVersion)
+	}
+	component := map[string]any{
+		"type":        "elasticsearch",
+		"endpoints":   s.Endpoints,
+		"api_version": defaultString(s.APIVersion, "auto"),
+		"mode":        "bulk",
+		"bulk":        map[string]any{"index": defaultString(s.Index, "kubedoop-logs-%Y.%m.%d")},
+	}
+	basicAuth(s.Name(), s.Auth, component)
+	return component, nil
+}
+
+func (s *ElasticsearchSink) Requirements() SinkRequirements {
+	var ports []int32
+	for _, endpoint := range s.Endpoints {
+		port, _ := urlPort(endpoint)
+		ports = append(ports, nonZero(port)...)
+	}
+	return SinkRequirements{Env: basicAuth(s.Name(), s.Auth, map[string]any{}), EgressPorts: ports}
+}
+
+// KafkaSink ships logs to a Kafka topic, one JSON event per record.
+type KafkaSink struct {
+	// ID is the component ID; empty means "kafka".
+	ID string
+	// BootstrapServers are the brokers' host:port addresses. Required.
+	BootstrapServers []string
+	// Topic is the topic, a Vector template. Required.
+	Topic string
+	// Compression is "none", "gzip", "snappy", "lz4" or "zstd"; empty leaves Vector's default.
+	Compression string
+}
+
+func (s *KafkaSink) Name() string { return defaultString(s.ID, "kafka") }
+
+func (s *KafkaSink) Component() (map[string]any, error) {
+	if len(s.BootstrapServers) == 0 || s.Topic == "" {
+		return nil, errors.New("kafka sink: bootstrap servers and a topic are required")
+	}
+	for _, server := range s.BootstrapServers {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			return nil, fmt.Errorf("kafka sink: bootstrap server %q must be host:port: %w", server, err)
+		}
+	}
+	component := map[string]any{
+		"type":              "kafka",
+		"bootstrap_servers": strings.Join(s.BootstrapServers, ","),
+		"topic":             s.Topic,
+		"encoding":          map[string]any{"codec": "json"},
+	}
+	if s.Compression != "" {
+		component["compression"] = s.Compression
+	}
+	return component, nil
+}
+
+func (s *KafkaSink) Requirements() SinkRequirements {
+	var ports []int32
+	for _, server := range s.BootstrapServers {
+		if _, p, err := net.SplitHostPort(server); err == nil {
+			if port, err := strconv.ParseInt(p, 10, 32); err == nil {
+				ports = append(ports, int32(port))
+			}
+		}
+	}
+	return SinkRequirements{EgressPorts: ports}
+}
+
+// S3Sink archives logs to an S3 bucket as gzipped, newline-delimited JSON objects.
+type S3Sink struct {
+	// ID is the component ID; empty means "s3".
+	ID string
+	// Connection is the resolved connection (s3.ResolveConnection or s3.ResolveBucket). Required.
+	Connection *s3.ConnectionInfo
+	// Bucket is the bucket name. Required.
+	Bucket string
+	// KeyPrefix is the object key prefix, a Vector template; empty means
+	// "logs/{{ namespace }}/{{ cluster }}/{{ role }}/{{ roleGroup }}/%F/".
+	KeyPrefix string
+}
+
+// s3SinkRegion is the signing region used when the connection declares none. Self-hosted
+// backends accept any region, but the AWS SDK refuses to sign without one.
+const s3SinkRegion = "us-east-1"
+
+func (s *S3Sink) Name() string { return defaultString(s.ID, "s3") }
+
+func (s *S3Sink) Component() (map[string]any, error) {
+	if s.Connection == nil || s.Bucket == "" {
+		return nil, errors.New("s3 sink: a connection and a bucket are required")
+	}
+	return map[string]any{
+		"type":             "aws_s3",
+		"bucket":           s.Bucket,
+		"endpoint":         s.Connection.Endpoint.String(),
+		"region":           defaultString(s.Connection.Region, s3SinkRegion),
+		"force_path_style": s.Connection.PathStyle,
+		"key_prefix":       defaultString(s.KeyPrefix, "logs/{{ namespace }}/{{ cluster }}/{{ role }}/{{ roleGroup }}/%F/"),
+		"compression":      "gzip",
+		"encoding":         map[string]any{"codec": "json"},
+		"framing":          map[string]any{"method": "newline_delimited"},
+	}, nil
+}
+
+// Requirements mounts the connection's credentials and exports them as the AWS SDK variables
+// before vector starts, which is where Vector's default credential chain looks. An anonymous
+// connection needs neither.
+func (s *S3Sink) Requirements() SinkRequirements {
+	var req SinkRequirements
+	if s.Connection == nil {
+		return req
+	}
+	if port, err := urlPort(s.Connection.Endpoint.String()); err == nil {
+		req.EgressPorts = nonZero(port)
+	}
+	volumeName := credentialsVolumeName(s.Name())
+	if provisioner := s.Connection.CredentialsProvisioner(volumeName); provisioner != nil {
+		req.Volumes = provisioner.Volumes()
+		req.VolumeMounts = provisioner.VolumeMounts()
+		req.Script = s3.CredentialsExportScript(s3.CredentialsMountPath(volumeName))
+	}
+	return req
+}
+
+// credentialsVolumeName returns the volume a sink's credentials are mounted from. A volume name
+// is a DNS-1123 label, which a sink ID is not: '_' becomes '-', and an ID too long for the label
+// is truncated with a hash suffix, as the reconciler does for role group resource names. Two IDs
+// that differ only in case or in '-' versus '_' map to one volume, but validateSinks refuses
+// those already.
+func credentialsVolumeName(sinkName string) string {
+	const (
+		maxLen = 63
+		prefix = "vector-"
+		suffix = "-credentials"
+	)
+	name := strings.ToLower(strings.ReplaceAll(sinkName, "_", "-"))
+	if len(prefix)+len(name)+len(suffix) <= maxLen {
+		return prefix + name + suffix
+	}
+	sum := sha256.Sum256([]byte(name))
+	hash := hex.EncodeToString(sum[:])[:8]
+	return prefix + name[:maxLen-len(prefix)-len(suffix)-len(hash)-1] + "-" + hash + suffix
+}
+
+// FileSink writes the enriched stream to a file on the agent's data volume — a local record
+// for debugging a pipeline, not durable storage: the volume is an emptyDir of
+// VectorDataVolumeSize.
+type FileSink struct {
+	// ID is the component ID; empty means "file".
+	ID string
+	// Path is the file path, a Vector template under VectorDataMountPath; empty means
+	// "<VectorDataMountPath>/logs/%Y-%m-%d.log".
+	Path string
+}
+
+func (s *FileSink) Name() string { return defaultString(s.ID, "file") }
+
+func (s *FileSink) Component() (map[string]any, error) {
+	file := defaultString(s.Path, path.Join(VectorDataMountPath, "logs", "%Y-%m-%d.log"))
+	// The agent's root filesystem is read-only; the data volume is the one place it can write.
+	if !strings.HasPrefix(path.Clean(file), VectorDataMountPath+"/") {
+		return nil, fmt.Errorf("file sink: path %q must be under %s, the agent's only writable volume", file, VectorDataMountPath)
+	}
+	return map[string]any{
+		"type":     "file",
+		"path":     file,
+		"encoding": map[string]any{"codec": "json"},
+	}, nil
+}
+
+func (s *FileSink) Requirements() SinkRequirements { return SinkRequirements{} }
+
+// ConsoleSink writes the enriched stream to the agent container's stdout (or stderr), so
+// `kubectl logs -c vector` shows every role group log in the normalized schema.
+type ConsoleSink struct {
+	// ID is the component ID; empty means "console".
+	ID string
+	// Stderr writes to stderr instead of stdout.
+	Stderr bool
+}
+
+func (s *ConsoleSink) Name() string { return defaultString(s.ID, "console") }
+
+func (s *ConsoleSink) Component() (map[string]any, error) {
+	target := "stdout"
+	if s.Stderr {
+		target = "stderr"
+	}
+	return map[string]any{
+		"type":     "console",
+		"target":   target,
+		"encoding": map[string]any{"codec": "json"},
+	}, nil
+}
+
+func (s *ConsoleSink) Requirements() SinkRequirements { return SinkRequirements{} }
+
+func defaultString(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
+
+func nonZero(port int32) []int32 {
+	if port == 0 {
+		return nil
+	}
+	return []int32{port}
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2024 ZNCDataDev.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vector
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	commonsv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/s3"
+)
+
+func renderSinksOnly(t *testing.T, sinks ...Sink) map[string]any {
+	t.Helper()
+	data := defaultConfigData()
+	data.AggregatorAddress = ""
+	data.Sinks = sinks
+	result, err := RenderVectorConfig(data)
+	if err != nil {
+		t.Fatalf("RenderVectorConfig() error = %v", err)
+	}
+	var parsed struct {
+		Sinks map[string]any `yaml:"sinks"`
+	}
+	if err := yaml.Unmarshal([]byte(result), &parsed); err != nil {
+		t.Fatalf("rendered vector.yaml is not valid YAML: %v\n%s", err, result)
+	}
+	return parsed.Sinks
+}
+
+// Without an aggregator the sinks are the pipeline: each reads the enriched stream, the agent's
+// metrics sink is untouched, and no aggregator sink is rendered.
+func TestRenderVectorConfig_SinksWithoutAggregator(t *testing.T) {
+	sinks := renderSinksOnly(t,
+		&LokiSink{Endpoint: "http://loki:3100", TenantID: "data"},
+		&KafkaSink{BootstrapServers: []string{"kafka-0:9092", "kafka-1:9092"}, Topic: "logs"},
+		&ConsoleSink{},
+	)
+
+	if _, ok := sinks["aggregator"]; ok {
+		t.Error("an aggregator sink was rendered without an aggregator address")
+	}
+	if _, ok := sinks["metrics"]; !ok {
+		t.Error("the agent's own metrics sink is missing")
+	}
+	for _, name := range []string{"loki", "kafka", "console"} {
+		sink, ok := sinks[name].(map[string]any)
+		if !ok {
+			t.Fatalf("sink %q missing from %v", name, sinks)
+		}
+		if inputs := sink["inputs"]; len(inputs.([]any)) != 1 || inputs.([]any)[0] != "extended_logs" {
+			t.Errorf("sink %q inputs = %v, want [extended_logs]", name, inputs)
+		}
+	}
+	loki := sinks["loki"].(map[string]any)
+	if loki["type"] != "loki" || loki["tenant_id"] != "data" {
+		t.Errorf("loki sink = %v", loki)
+	}
+	if labels := loki["labels"].(map[string]any); labels["role_group"] != "{{ roleGroup }}" {
+		t.Errorf("loki sink labels = %v, want the enrichment fields", labels)
+	}
+	if kafka := sinks["kafka"].(map[string]any); kafka["bootstrap_servers"] != "kafka-0:9092,kafka-1:9092" {
+		t.Errorf("kafka bootstrap_servers = %v", kafka["bootstrap_servers"])
+	}
+}
+
+// Sinks render after the aggregator, which keeps its exact rendering.
+func TestRenderVectorConfig_SinksBesideAggregator(t *testing.T) {
+	data := defaultConfigData()
+	data.Sinks = []Sink{&FileSink{}}
+	result, err := RenderVectorConfig(data)
+	if err != nil {
+		t.Fatalf("RenderVectorConfig() error = %v", err)
+	}
+	want := "    address: \"vector-aggregator:9000\"\n\n  file:\n"
+	if !strings.Contains(result, want) {
+		t.Errorf("file sink not rendered after the aggregator; got:\n%s", result)
+	}
+	if !strings.Contains(result, "path: /kubedoop/vector/var/logs/%Y-%m-%d.log") {
+		t.Error("file sink should default to the agent's data volume")
+	}
+}
+
+// A password never lands in the ConfigMap: the component names an environment variable, which
+// Requirements fills from the Secret.
+func TestSinkBasicAuthReadsThePasswordFromTheEnvironment(t *testing.T) {
+	sink := &ElasticsearchSink{
+		ID:         "opensearch",
+		Endpoints:  []string{"https://opensearch:9200"},
+		APIVersion: "v7",
+		Auth: &BasicAuth{User: "vector", Password: corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "opensearch-credentials"},
+			Key:                  "password",
+		}},
+	}
+	auth := renderSinksOnly(t, sink)["opensearch"].(map[string]any)["auth"].(map[string]any)
+	if auth["password"] != "${VECTOR_SINK_OPENSEARCH_PASSWORD}" {
+		t.Errorf("auth.password = %v, want an environment reference", auth["password"])
+	}
+
+	req := sink.Requirements()
+	if len(req.Env) != 1 || req.Env[0].Name != "VECTOR_SINK_OPENSEARCH_PASSWORD" ||
+		req.Env[0].ValueFrom.SecretKeyRef.Name != "opensearch-credentials" {
+		t.Errorf("Requirements().Env = %+v", req.Env)
+	}
+	if len(req.EgressPorts) != 1 || req.EgressPorts[0] != 9200 {
+		t.Errorf("Requirements().EgressPorts = %v, want [9200]", req.EgressPorts)
+	}
+}
+
+// The S3 sink takes the connection a product already resolved, and mounts its credentials for
+// the AWS SDK's default chain.
+func TestS3SinkUsesTheResolvedConnection(t *testing.T) {
+	sink := &S3Sink{
+		Bucket: "logs",
+		Connection: &s3.ConnectionInfo{
+			Endpoint:    url.URL{Scheme: "http", Host: "minio:9000"},
+			PathStyle:   true,
+			Credentials: &commonsv1alpha1.Credentials{SecretClass: "s3-credentials"},
+		},
+	}
+	component := renderSinksOnly(t, sink)["s3"].(map[string]any)
+	if component["type"] != "aws_s3" || component["endpoint"] != "http://minio:9000" ||
+		component["region"] != "us-east-1" || component["force_path_style"] != true {
+		t.Errorf("s3 sink = %v", component)
+	}
+
+	req := sink.Requirements()
+	if len(req.Volumes) != 1 || len(req.VolumeMounts) != 1 {
+		t.Fatalf("Requirements() = %+v, want the credentials volume", req)
+	}
+	if !strings.Contains(req.Script, "export AWS_ACCESS_KEY_ID=") ||
+		!strings.Contains(req.Script, "/kubedoop/secret/vector-s3-credentials/ACCESS_KEY") {
+		t.Errorf("Requirements().Script = %q", req.Script)
+	}
+}
+
+// A sink ID may carry '_' and run long; the credentials volume it names must still be a
+// DNS-1123 label, or the API server rejects the pod.
+func TestS3SinkCredentialsVolumeIsALabel(t *testing.T) {
+	for _, id := range []string{"archive_logs", "ARCHIVE", strings.Repeat("cold_storage", 6)} {
+		sink := &S3Sink{
+			ID:     id,
+			Bucket: "logs",
+			Connection: &s3.ConnectionInfo{
+				Endpoint:    url.URL{Scheme: "http", Host: "minio:9000"},
+				Credentials: &commonsv1alpha1.Credentials{SecretClass: "s3-credentials"},
+			},
+		}
+		req := sink.Requirements()
+		if len(req.Volumes) != 1 {
+			t.Fatalf("%s: Requirements() = %+v, want the credentials volume", id, req)
+		}
+		if errs := validation.IsDNS1123Label(req.Volumes[0].Name); len(errs) > 0 {
+			t.Errorf("%s: volume name %q: %v", id, req.Volumes[0].Name, errs)
+		}
+		if req.VolumeMounts[0].Name != req.Volumes[0].Name {
+			t.Errorf("%s: mount %q does not name volume %q", id, req.VolumeMounts[0].Name, req.Volumes[0].Name)
+		}
+	}
+	if got := credentialsVolumeName("archive_logs"); got != "vector-archive-logs-credentials" {
+		t.Errorf("credentialsVolumeName(archive_logs) = %q", got)
+	}
+}
+
+func TestRenderVectorConfig_RejectsBadSinks(t *testing.T) {
+	tests := []struct {
+		name  string
+		sinks []Sink
+		want  string
+	}{
+		{"nothing to ship to", nil, "AggregatorAddress or at least one sink is required"},
+		{"duplicate ID", []Sink{&ConsoleSink{}, &ConsoleSink{Stderr: true}}, `"console": the ID is already used`},
+		{"reserved ID", []Sink{&ConsoleSink{ID: "metrics"}}, `"metrics": the ID is already used`},
+		{"invalid ID", []Sink{&ConsoleSink{ID: "a b"}}, "letters, digits"},
+		{"IDs sharing a password variable", []Sink{&ConsoleSink{ID: "a-b"}, &ConsoleSink{ID: "a_b"}},
+			`"a_b": the ID maps to the same environment variable VECTOR_SINK_A_B_PASSWORD as sink "a-b"`},
+		{"loki without a URL", []Sink{&LokiSink{Endpoint: "loki:3100"}}, "must be an http or https URL"},
+		{"kafka without a topic", []Sink{&KafkaSink{BootstrapServers: []string{"kafka:9092"}}}, "a topic are required"},
+		{"file off the data volume", []Sink{&FileSink{Path: "/tmp/logs.json"}}, "must be under /kubedoop/vector/var"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := defaultConfigData()
+			data.AggregatorAddress = ""
+			data.Sinks = tt.sinks
+			_, err := RenderVectorConfig(data)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("RenderVectorConfig() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -17,15 +17,22 @@ limitations under the License.
 package webhook_test
 
 import (
+	"strings"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
 
 	commonsv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/productversion"
+	"github.com/zncdatadev/operator-go/pkg/security"
 	"github.com/zncdatadev/operator-go/pkg/webhook"
 )
 
@@ -45,6 +52,20 @@ var _ = Describe("ValidateGenericClusterSpec", func() {
 		Expect(errs).To(BeEmpty())
 	})
 
+	It("accepts a digest-pinned Custom image and rejects an unparsable one", func() {
+		pinned := &commonsv1alpha1.GenericClusterSpec{Image: &commonsv1alpha1.ImageSpec{
+			Custom: "quay.io/zncdatadev/hdfs:3.3.6@sha256:" + strings.Repeat("0f", 32),
+		}}
+		Expect(webhook.ValidateGenericClusterSpec(pinned, field.NewPath("spec"))).To(BeEmpty())
+
+		broken := &commonsv1alpha1.GenericClusterSpec{Image: &commonsv1alpha1.ImageSpec{
+			Custom: "quay.io/zncdatadev/hdfs:3.3.6@sha256:0f",
+		}}
+		errs := webhook.ValidateGenericClusterSpec(broken, field.NewPath("spec"))
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Field).To(Equal("spec.image.custom"))
+	})
+
 	It("should return no errors for valid non-custom image", func() {
 		spec := &commonsv1alpha1.GenericClusterSpec{
 			Image: &commonsv1alpha1.ImageSpec{
@@ -200,3 +221,202 @@ var _ = Describe("ValidationErrors.ToStatusError", func() {
 		Expect(statusErr.ErrStatus.Details.Causes).To(HaveLen(2))
 	})
 })
+
+var _ = Describe("ValidateStorageUpdate", func() {
+	capacity := func(q string) *commonsv1alpha1.RoleGroupConfigSpec {
+		return &commonsv1alpha1.RoleGroupConfigSpec{Resources: &commonsv1alpha1.ResourcesSpec{
+			Storage: &commonsv1alpha1.StorageResource{Capacity: ptr.To(resource.MustParse(q))},
+		}}
+	}
+	spec := func(roleCfg, groupCfg *commonsv1alpha1.RoleGroupConfigSpec) *commonsv1alpha1.GenericClusterSpec {
+		return &commonsv1alpha1.GenericClusterSpec{Roles: map[string]commonsv1alpha1.RoleSpec{
+			"datanode": {Config: roleCfg, RoleGroups: map[string]commonsv1alpha1.RoleGroupSpec{
+				"default": {Config: groupCfg},
+			}},
+		}}
+	}
+
+	It("should allow a capacity increase", func() {
+		Expect(webhook.ValidateStorageUpdate(spec(nil, capacity("10Gi")), spec(nil, capacity("20Gi")), nil,
+			field.NewPath("spec"))).To(BeEmpty())
+	})
+
+	It("should forbid a capacity decrease on the role group", func() {
+		errs := webhook.ValidateStorageUpdate(spec(nil, capacity("20Gi")), spec(nil, capacity("10Gi")), nil, field.NewPath("spec"))
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Type).To(Equal(field.ErrorTypeForbidden))
+		Expect(errs[0].Field).To(Equal("spec.roles[datanode].roleGroups[default].config.resources.storage.capacity"))
+	})
+
+	It("should compare the capacity the role group inherits", func() {
+		// Dropping a larger group override falls back to the role's value — a shrink as real as
+		// editing the number down.
+		errs := webhook.ValidateStorageUpdate(spec(capacity("5Gi"), capacity("50Gi")), spec(capacity("5Gi"), nil), nil, field.NewPath("spec"))
+		Expect(errs).To(HaveLen(1))
+
+		// And a role-level decrease reaches every role group that does not override it.
+		errs = webhook.ValidateStorageUpdate(spec(capacity("50Gi"), nil), spec(capacity("20Gi"), nil), nil, field.NewPath("spec"))
+		Expect(errs).To(HaveLen(1))
+	})
+
+	It("should compare against the default when neither level states a capacity", func() {
+		errs := webhook.ValidateStorageUpdate(spec(nil, nil), spec(nil, capacity("1Gi")), nil, field.NewPath("spec"))
+		Expect(errs).To(HaveLen(1), "unset means DefaultStorageCapacity, so 1Gi is a shrink")
+	})
+
+	It("should fold the product's config defaults beneath the role", func() {
+		// Dropping the role's override falls back to the product default, not DefaultStorageCapacity.
+		defaults := map[string]*commonsv1alpha1.RoleGroupConfigSpec{"datanode": capacity("100Gi")}
+		errs := webhook.ValidateStorageUpdate(spec(nil, nil), spec(capacity("50Gi"), nil), defaults, field.NewPath("spec"))
+		Expect(errs).To(HaveLen(1), "the role group was built with the 100Gi default")
+		Expect(webhook.ValidateStorageUpdate(spec(capacity("50Gi"), nil), spec(nil, nil), defaults, field.NewPath("spec"))).To(BeEmpty())
+	})
+
+	It("should forbid shrinking a named volume, folded per name", func() {
+		volumes := func(vols map[string]string) *commonsv1alpha1.RoleGroupConfigSpec {
+			cfg := &commonsv1alpha1.RoleGroupConfigSpec{Resources: &commonsv1alpha1.ResourcesSpec{
+				Volumes: map[string]commonsv1alpha1.VolumeResource{},
+			}}
+			for name, q := range vols {
+				vol := commonsv1alpha1.VolumeResource{MountPath: "/data/" + name}
+				if q != "" {
+					vol.Capacity = ptr.To(resource.MustParse(q))
+				}
+				cfg.Resources.Volumes[name] = vol
+			}
+			return cfg
+		}
+		role := volumes(map[string]string{"journal": "20Gi", "cache": "5Gi"})
+
+		errs := webhook.ValidateStorageUpdate(
+			spec(role, volumes(map[string]string{"journal": "50Gi"})),
+			spec(role, volumes(map[string]string{"journal": "30Gi"})),
+			nil, field.NewPath("spec"))
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Type).To(Equal(field.ErrorTypeForbidden))
+		Expect(errs[0].Field).To(Equal("spec.roles[datanode].roleGroups[default].config.resources.volumes[journal].capacity"))
+
+		// A group entry without a capacity inherits the role's, so dropping its 50Gi is a shrink too.
+		errs = webhook.ValidateStorageUpdate(
+			spec(role, volumes(map[string]string{"journal": "50Gi"})),
+			spec(role, volumes(map[string]string{"journal": ""})),
+			nil, field.NewPath("spec"))
+		Expect(errs).To(HaveLen(1))
+
+		// Growing one volume, or adding and removing others, is no shrink.
+		Expect(webhook.ValidateStorageUpdate(
+			spec(role, nil),
+			spec(volumes(map[string]string{"journal": "40Gi", "wal": "1Gi"}), nil),
+			nil, field.NewPath("spec"))).To(BeEmpty())
+	})
+
+	It("should not compare role groups the update adds or removes", func() {
+		newSpec := &commonsv1alpha1.GenericClusterSpec{Roles: map[string]commonsv1alpha1.RoleSpec{
+			"datanode": {RoleGroups: map[string]commonsv1alpha1.RoleGroupSpec{"small": {Config: capacity("1Gi")}}},
+		}}
+		Expect(webhook.ValidateStorageUpdate(spec(nil, capacity("20Gi")), newSpec, nil, field.NewPath("spec"))).To(BeEmpty())
+	})
+})
+
+var _ = Describe("ValidateProductVersion", func() {
+	catalog := &productversion.Catalog{
+		Versions: map[string]productversion.Version{
+			"3.7.1": {Deprecated: true},
+			"3.8.0": {},
+			"3.9.0": {},
+		},
+		// 3.6.0 is retired: no longer in Versions, but still with a way out.
+		Upgrades: map[string][]string{"3.6.0": {"3.7.1"}, "3.7.1": {"3.8.0"}, "3.8.0": {"3.9.0"}},
+	}
+	defaults := commonsv1alpha1.ImageSpec{ProductVersion: "3.8.0", KubedoopVersion: "0.2.0"}
+	spec := func(version string) *commonsv1alpha1.GenericClusterSpec {
+		if version == "" {
+			return &commonsv1alpha1.GenericClusterSpec{}
+		}
+		return &commonsv1alpha1.GenericClusterSpec{Image: &commonsv1alpha1.ImageSpec{ProductVersion: version}}
+	}
+
+	It("should allow an upgrade along a declared edge", func() {
+		warnings, errs := webhook.ValidateProductVersion(spec("3.8.0"), spec("3.9.0"), defaults, catalog, field.NewPath("spec"))
+		Expect(errs).To(BeEmpty())
+		Expect(warnings).To(BeEmpty())
+	})
+
+	It("should forbid skipping a version", func() {
+		_, errs := webhook.ValidateProductVersion(spec("3.7.1"), spec("3.9.0"), defaults, catalog, field.NewPath("spec"))
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Type).To(Equal(field.ErrorTypeForbidden))
+		Expect(errs[0].Field).To(Equal("spec.image.productVersion"))
+	})
+
+	It("should compare the version the operator's defaults resolve to", func() {
+		// Dropping a pinned 3.7.1 lands the cluster on the default, 3.8.0: an allowed upgrade.
+		_, errs := webhook.ValidateProductVersion(spec("3.7.1"), spec(""), defaults, catalog, field.NewPath("spec"))
+		Expect(errs).To(BeEmpty())
+
+		// Dropping a pinned 3.9.0 is a downgrade to 3.8.0.
+		_, errs = webhook.ValidateProductVersion(spec("3.9.0"), spec(""), defaults, catalog, field.NewPath("spec"))
+		Expect(errs).To(HaveLen(1))
+	})
+
+	It("should refuse an unsupported version on create", func() {
+		_, errs := webhook.ValidateProductVersion(nil, spec("4.0.0"), defaults, catalog, field.NewPath("spec"))
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Type).To(Equal(field.ErrorTypeNotSupported))
+	})
+
+	It("should warn, without refusing, on a deprecated version", func() {
+		warnings, errs := webhook.ValidateProductVersion(nil, spec("3.7.1"), defaults, catalog, field.NewPath("spec"))
+		Expect(errs).To(BeEmpty())
+		Expect(warnings).To(ConsistOf(`product version "3.7.1" is deprecated`))
+	})
+
+	It("should not re-judge a version the update leaves alone", func() {
+		_, errs := webhook.ValidateProductVersion(spec("3.6.0"), spec("3.6.0"), defaults, catalog, field.NewPath("spec"))
+		Expect(errs).To(BeEmpty())
+	})
+
+	It("should let a cluster on a retired version leave it along its edges", func() {
+		warnings, errs := webhook.ValidateProductVersion(spec("3.6.0"), spec("3.7.1"), defaults, catalog, field.NewPath("spec"))
+		Expect(errs).To(BeEmpty())
+		Expect(warnings).To(ConsistOf(`product version "3.7.1" is deprecated`))
+
+		_, errs = webhook.ValidateProductVersion(spec("3.6.0"), spec("3.8.0"), defaults, catalog, field.NewPath("spec"))
+		Expect(errs).To(HaveLen(1), "a retired version's edges are not followed transitively either")
+		Expect(errs[0].Type).To(Equal(field.ErrorTypeForbidden))
+	})
+})
+
+var _ = Describe("PodSecurityWarnings", func() {
+	raw := func(s string) *k8sruntime.RawExtension { return &k8sruntime.RawExtension{Raw: []byte(s)} }
+
+	It("should warn about each forbidden value a podOverrides patch states", func() {
+		spec := &commonsv1alpha1.GenericClusterSpec{Roles: map[string]commonsv1alpha1.RoleSpec{
+			"server": {RoleGroups: map[string]commonsv1alpha1.RoleGroupSpec{
+				"default": {PodOverrides: raw(`{"spec":{
+					"containers":[{"name":"main","securityContext":{"privileged":true}}],
+					"volumes":[{"name":"host","hostPath":{"path":"/var/run"}}]}}`)},
+			}},
+		}}
+
+		warnings := webhook.PodSecurityWarnings(spec, security.PodSecurityBaseline, field.NewPath("spec"))
+		Expect(warnings).To(ConsistOf(
+			"spec.roles[server].roleGroups[default].podOverrides: spec.containers[main].securityContext.privileged must not be true (baseline Pod Security Standard)",
+			"spec.roles[server].roleGroups[default].podOverrides: spec.volumes[host].hostPath hostPath volumes are forbidden (baseline Pod Security Standard)",
+		))
+	})
+
+	It("should not warn about fields a patch leaves to the framework", func() {
+		spec := &commonsv1alpha1.GenericClusterSpec{Roles: map[string]commonsv1alpha1.RoleSpec{
+			"server": {PodOverrides: raw(`{"spec":{"containers":[{"name":"main","env":[{"name":"A","value":"b"}]}]}}`)},
+		}}
+		Expect(webhook.PodSecurityWarnings(spec, security.PodSecurityRestricted, field.NewPath("spec"))).To(BeEmpty())
+	})
+
+	It("should leave an undecodable patch to the reconciler", func() {
+		spec := &commonsv1alpha1.GenericClusterSpec{Roles: map[string]commonsv1alpha1.RoleSpec{
+			"server": {PodOverrides: raw(`{"spec":{"containers":"not-a-list"}}`)},
+		}}
+		Expect(webhook.PodSecurityWarnings(spec, security.PodSecurityRestricted, field.NewPath("spec"))).To(BeEmpty())
+	})
+})
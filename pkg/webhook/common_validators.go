@@ -17,10 +17,20 @@ limitations under the License.
 package webhook
 
 import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	commonsv1alpha1 "github.com/zncdatadev/operator-go/pkg/apis/commons/v1alpha1"
+	"github.com/zncdatadev/operator-go/pkg/productversion"
+	"github.com/zncdatadev/operator-go/pkg/security"
 )
 
 // ValidateGenericClusterSpec validates the common fields of a GenericClusterSpec.
@@ -59,8 +69,12 @@ func ValidateGenericClusterSpec(spec *commonsv1alpha1.GenericClusterSpec, fldPat
 func validateImageSpec(image *commonsv1alpha1.ImageSpec, fldPath *field.Path) field.ErrorList {
 	var errs field.ErrorList
 
-	// If Custom is set, no further validation needed
+	// A custom reference replaces every structured field, so it is the only thing left to check. A
+	// digest-pinned one (`repo/name@sha256:…`) is as valid as a tagged one.
 	if image.Custom != "" {
+		if err := commonsv1alpha1.ValidateImageReference(image.Custom); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("custom"), image.Custom, err.Error()))
+		}
 		return errs
 	}
 
@@ -95,3 +109,211 @@ func validateImageSpec(image *commonsv1alpha1.ImageSpec, fldPath *field.Path) fi
 
 	return errs
 }
+
+// ValidateStorageUpdate rejects an update that shrinks a data volume of a role group that exists
+// on both sides of it. A PVC can grow in place (see GenericReconcilerConfig.VolumeExpansion) but
+// never shrink, so a smaller capacity can only ever be dropped by the reconciler with an
+// ImmutableFieldIgnored warning; refusing it at admission tells the user while they are still
+// looking.
+//
+// The capacities compared are the ones the role group's StatefulSet is built with, folded as the
+// reconciler folds them: the role group's config, over the role's, over the product's defaults
+// for the role — its RoleDeclaration.ConfigDefaults, keyed by role name; nil when it has none —
+// else DefaultStorageCapacity. So removing an override that was larger than the value beneath it
+// is a shrink too. That holds for resources.storage and for each named volume of
+// resources.volumes present on both sides; a volume the update adds or removes is a new claim
+// template or one left behind, not a resize. Role groups added or removed by the update are not
+// compared either.
+//
+// Example:
+//
+//	func (v *MyValidator) ValidateUpdate(ctx, oldCR, newCR *MyCluster) (Warnings, error) {
+//	    fldErrs := webhook.ValidateStorageUpdate(&oldCR.Spec.GenericClusterSpec, &newCR.Spec.GenericClusterSpec,
+//	        map[string]*commonsv1alpha1.RoleGroupConfigSpec{"datanode": datanodeDefaults}, field.NewPath("spec"))
+//	    ...
+//	}
+func ValidateStorageUpdate(
+	oldSpec, newSpec *commonsv1alpha1.GenericClusterSpec,
+	defaults map[string]*commonsv1alpha1.RoleGroupConfigSpec,
+	fldPath *field.Path,
+) field.ErrorList {
+	var errs field.ErrorList
+	if oldSpec == nil || newSpec == nil {
+		return errs
+	}
+
+	rolesPath := fldPath.Child("roles")
+	for _, roleName := range slices.Sorted(maps.Keys(newSpec.Roles)) {
+		newRole := newSpec.Roles[roleName]
+		oldRole, ok := oldSpec.Roles[roleName]
+		if !ok {
+			continue
+		}
+		for _, groupName := range slices.Sorted(maps.Keys(newRole.RoleGroups)) {
+			oldGroup, ok := oldRole.RoleGroups[groupName]
+			if !ok {
+				continue
+			}
+			newGroup := newRole.RoleGroups[groupName]
+			oldLayers := []*commonsv1alpha1.RoleGroupConfigSpec{defaults[roleName], oldRole.Config, oldGroup.Config}
+			newLayers := []*commonsv1alpha1.RoleGroupConfigSpec{defaults[roleName], newRole.Config, newGroup.Config}
+			resourcesPath := rolesPath.Key(roleName).Child("roleGroups").Key(groupName).Child("config", "resources")
+
+			was, now := storageCapacity(oldLayers), storageCapacity(newLayers)
+			if now.Cmp(was) < 0 {
+				errs = append(errs, field.Forbidden(resourcesPath.Child("storage", "capacity"), fmt.Sprintf(
+					"data volume capacity cannot shrink from %s to %s: a PersistentVolumeClaim can only grow",
+					was.String(), now.String())))
+			}
+
+			oldVolumes, newVolumes := volumeCapacities(oldLayers), volumeCapacities(newLayers)
+			for _, name := range slices.Sorted(maps.Keys(newVolumes)) {
+				was, ok := oldVolumes[name]
+				now := newVolumes[name]
+				if ok && now.Cmp(was) < 0 {
+					errs = append(errs, field.Forbidden(resourcesPath.Child("volumes").Key(name).Child("capacity"), fmt.Sprintf(
+						"volume %q capacity cannot shrink from %s to %s: a PersistentVolumeClaim can only grow",
+						name, was.String(), now.String())))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// ValidateProductVersion checks the product version spec.image resolves to against the product's
+// version catalog: an unsupported version is refused, a deprecated one is allowed with a warning,
+// and on update a move the catalog's upgrade edges do not allow is refused — a skipped major
+// version, a downgrade. Pass a nil oldSpec from ValidateCreate.
+//
+// Both sides are resolved with the same defaults — the operator's ImageResolution.Defaults — so
+// what is compared is the version the CR asks for, and an update that leaves spec.image alone is
+// never refused for it. A version that is unchanged is not re-judged either, the way Kubernetes
+// ratchets validation: a cluster whose version the operator has since dropped can still be edited,
+// while its reconcile fails Catalog.Check for that role group until it moves. It can move along
+// the upgrade edges the catalog keeps for the retired version (see Catalog.Upgrades). The
+// reconciler separately checks each role group against the version it actually runs, which also
+// covers a version taken from new operator defaults; this is the earlier, `kubectl apply`-time
+// view of the same rule.
+//
+// Example:
+//
+//	func (v *MyValidator) ValidateUpdate(ctx, oldCR, newCR *MyCluster) (Warnings, error) {
+//	    warnings, fldErrs := webhook.ValidateProductVersion(&oldCR.Spec.GenericClusterSpec,
+//	        &newCR.Spec.GenericClusterSpec, imageDefaults, catalog, field.NewPath("spec"))
+//	    ...
+//	}
+func ValidateProductVersion(
+	oldSpec, newSpec *commonsv1alpha1.GenericClusterSpec,
+	defaults commonsv1alpha1.ImageSpec,
+	catalog *productversion.Catalog,
+	fldPath *field.Path,
+) (admission.Warnings, field.ErrorList) {
+	if catalog == nil || newSpec == nil {
+		return nil, nil
+	}
+	path := fldPath.Child("image", "productVersion")
+	version := newSpec.Image.ResolvedProductVersion(defaults)
+	previous := ""
+	if oldSpec != nil {
+		previous = oldSpec.Image.ResolvedProductVersion(defaults)
+		if previous == version {
+			warning, _ := catalog.Check(version)
+			if warning != "" {
+				return admission.Warnings{warning}, nil
+			}
+			return nil, nil
+		}
+	}
+
+	warning, err := catalog.Check(version)
+	if err != nil {
+		return nil, field.ErrorList{field.NotSupported(path, version, catalog.SupportedVersions())}
+	}
+	if err := catalog.CheckUpgrade(previous, version); err != nil {
+		return nil, field.ErrorList{field.Forbidden(path, err.Error())}
+	}
+	if warning != "" {
+		return admission.Warnings{warning}, nil
+	}
+	return nil, nil
+}
+
+// storageCapacity returns the capacity of a role group's primary data volume, from config layers
+// ordered lowest precedence first.
+func storageCapacity(layers []*commonsv1alpha1.RoleGroupConfigSpec) resource.Quantity {
+	for _, cfg := range slices.Backward(layers) {
+		if cfg != nil && cfg.Resources != nil && cfg.Resources.Storage != nil && cfg.Resources.Storage.Capacity != nil {
+			return *cfg.Resources.Storage.Capacity
+		}
+	}
+	return resource.MustParse(commonsv1alpha1.DefaultStorageCapacity)
+}
+
+// volumeCapacities returns the capacity of each named data volume, from config layers ordered
+// lowest precedence first. A name folds per field as the reconciler's volume fold does, so a layer
+// that states a volume without a capacity inherits the one beneath it, and a volume no layer sizes
+// gets DefaultStorageCapacity.
+func volumeCapacities(layers []*commonsv1alpha1.RoleGroupConfigSpec) map[string]resource.Quantity {
+	capacities := map[string]resource.Quantity{}
+	for _, cfg := range layers {
+		if cfg == nil || cfg.Resources == nil {
+			continue
+		}
+		for name, vol := range cfg.Resources.Volumes {
+			if _, ok := capacities[name]; !ok || vol.Capacity != nil {
+				capacities[name] = vol.GetCapacity()
+			}
+		}
+	}
+	return capacities
+}
+
+// PodSecurityWarnings returns an admission warning for every podOverrides patch in spec that
+// states a value the given Pod Security Standard forbids — a `privileged: true`, a hostPath, an
+// added capability.
+//
+// They are warnings, not errors, because a patch is judged on its own: the admission webhook never
+// sees the finished pod, and whether the operator enforces the level is its
+// GenericReconcilerConfig.PodSecurity, not the webhook's. The reconciler re-checks the built pod and
+// reports (or, in strict mode, rejects) there; this is the earlier, `kubectl apply`-time view of the
+// same finding. A patch that does not decode is left to the reconciler, which reports it as
+// PodOverrideIgnored.
+//
+// Example:
+//
+//	func (v *MyValidator) ValidateCreate(ctx, cr *MyCluster) (Warnings, error) {
+//	    warnings := webhook.PodSecurityWarnings(&cr.Spec.GenericClusterSpec, security.PodSecurityRestricted, field.NewPath("spec"))
+//	    ...
+//	    return warnings, nil
+//	}
+func PodSecurityWarnings(spec *commonsv1alpha1.GenericClusterSpec, level security.PodSecurityLevel, fldPath *field.Path) admission.Warnings {
+	if spec == nil {
+		return nil
+	}
+	var warnings admission.Warnings
+	check := func(raw *k8sruntime.RawExtension, path *field.Path) {
+		if raw == nil || len(raw.Raw) == 0 {
+			return
+		}
+		var tmpl corev1.PodTemplateSpec
+		if err := json.Unmarshal(raw.Raw, &tmpl); err != nil {
+			return
+		}
+		for _, v := range security.CheckPodSecurityOverride(level, &tmpl.Spec) {
+			warnings = append(warnings, fmt.Sprintf("%s: spec.%s %s (%s Pod Security Standard)",
+				path.String(), v.Field, v.Reason, level))
+		}
+	}
+
+	rolesPath := fldPath.Child("roles")
+	for _, roleName := range slices.Sorted(maps.Keys(spec.Roles)) {
+		role := spec.Roles[roleName]
+		check(role.PodOverrides, rolesPath.Key(roleName).Child("podOverrides"))
+		for _, groupName := range slices.Sorted(maps.Keys(role.RoleGroups)) {
+			group := role.RoleGroups[groupName]
+			check(group.PodOverrides, rolesPath.Key(roleName).Child("roleGroups").Key(groupName).Child("podOverrides"))
+		}
+	}
+	return warnings
+}